@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <board-file>",
+	Short: "Make a board match a board file",
+	Long: `Compare a board with a JSON or YAML board file, print the differences and
+change the board to match: lists in order, labels with their colors and
+template cards with their checklists.
+
+The board is found by ID, URL or exact name among your open boards, and
+created if there is none. Lists, labels and cards are matched by name, or by a
+name in their renamed_from, ignoring case. Missing ones are created, renamed
+ones renamed, lists are put in the order of the file and cards moved to their
+list. Checklists and items are only added.
+
+Lists and cards that are not in the file are archived only with prune: true
+in the file; cards on a list that is archived go with it. Labels that are not
+in the file are always left alone, because Trello cannot archive labels and
+deleting one could not be undone if a later change failed.
+
+With --dry-run the differences are printed and nothing is changed. Otherwise
+the changes run as a transactional batch: if one fails, the completed ones are
+undone.`,
+	Example: `  trello-cli apply board.yaml --dry-run
+  trello-cli apply board.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		state, err := batch.LoadBoardState(args[0])
+		if err != nil {
+			return err
+		}
+
+		auth, err := getAuthFromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		trelloClient, err := newClient(cmd, auth)
+		if err != nil {
+			return err
+		}
+
+		live, err := readLiveBoard(trelloClient, state)
+		if err != nil {
+			return err
+		}
+		diff, err := batch.DiffBoard(state, live)
+		if err != nil {
+			return fmt.Errorf("invalid board file:\n%w", err)
+		}
+
+		output, err := diff.Format(format, formatterOptions())
+		if err != nil {
+			return err
+		}
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun || len(diff.Changes) == 0 {
+			if !quiet {
+				fmt.Println(output)
+			}
+			return nil
+		}
+
+		// The diff goes to stderr so that stdout holds only the results
+		if !quiet {
+			fmt.Fprintln(os.Stderr, output)
+		}
+		return executeBatchOperations(cmd, &batch.BatchFile{Operations: diff.Operations(), Transactional: true}, "", nil)
+	},
+}
+
+// readLiveBoard reads the board of a board file with its open lists, labels
+// and open cards, and the checklists of the cards in the file. It returns nil
+// when the board does not exist.
+func readLiveBoard(trelloClient *client.Client, state *batch.BoardState) (*batch.LiveBoard, error) {
+	boardID, err := findApplyBoard(trelloClient, state.Board)
+	if err != nil || boardID == "" {
+		return nil, err
+	}
+
+	board, err := trelloClient.GetBoard(boardID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get board: %w", err)
+	}
+	lists, err := board.GetLists(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lists: %w", err)
+	}
+	labels, err := board.GetLabels(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	cards, err := board.GetCards(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cards: %w", err)
+	}
+
+	live := &batch.LiveBoard{ID: board.ID, Name: board.Name, Desc: board.Desc}
+	for _, list := range lists {
+		live.Lists = append(live.Lists, batch.LiveList{ID: list.ID, Name: list.Name, Pos: float64(list.Pos)})
+	}
+	for _, label := range labels {
+		live.Labels = append(live.Labels, batch.LiveLabel{ID: label.ID, Name: label.Name, Color: label.Color})
+	}
+
+	// Only the cards of the file can need checklists
+	inFile := map[string]bool{}
+	for _, card := range state.Cards {
+		for _, name := range append([]string{card.Name}, card.RenamedFrom...) {
+			inFile[strings.ToLower(name)] = true
+		}
+	}
+	for _, card := range cards {
+		liveCard := batch.LiveCard{ID: card.ID, Name: card.Name, ListID: card.IDList, Desc: card.Desc, LabelIDs: card.IDLabels}
+		if inFile[strings.ToLower(card.Name)] {
+			checklists, err := getCardChecklists(trelloClient, card.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, checklist := range checklists {
+				liveChecklist := batch.LiveChecklist{ID: checklist.ID, Name: checklist.Name}
+				for _, item := range checklist.CheckItems {
+					liveChecklist.Items = append(liveChecklist.Items, item.Name)
+				}
+				liveCard.Checklists = append(liveCard.Checklists, liveChecklist)
+			}
+		}
+		live.Cards = append(live.Cards, liveCard)
+	}
+	return live, nil
+}
+
+// findApplyBoard returns the ID of the board of a board file, or "" when there
+// is none. Unlike other board references a name must match exactly, ignoring
+// case, so that a new board is not mistaken for one with a longer name.
+func findApplyBoard(trelloClient *client.Client, ref string) (string, error) {
+	if client.IsID(ref) || strings.Contains(ref, "trello.com/") {
+		return trelloClient.ResolveBoard(ref, client.MatchExact)
+	}
+
+	member, err := trelloClient.GetMember("me", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get member: %w", err)
+	}
+	boards, err := member.GetBoards(nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get boards: %w", err)
+	}
+
+	var found []string
+	for _, board := range boards {
+		if !board.Closed && strings.EqualFold(board.Name, ref) {
+			found = append(found, board.ID)
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("%d open boards are named %q; use the board ID or URL in the file", len(found), ref)
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+func init() {
+	applyCmd.Flags().Bool("dry-run", false, "Print the differences without changing anything")
+
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/fake"
+)
+
+func TestApplyOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	desc := "Every release"
+	state := &batch.BoardState{
+		Board:  "Release Train",
+		Lists:  []batch.ListState{{Name: "Backlog"}, {Name: "Doing"}, {Name: "Done"}},
+		Labels: []batch.LabelState{{Name: "Bug", Color: "red"}},
+		Cards: []batch.CardState{{
+			Name: "Release", List: "Backlog", Desc: &desc, Labels: []string{"Bug"},
+			Checklists: []batch.ChecklistState{{Name: "Steps", Items: []string{"Tag", "Publish"}}},
+		}},
+	}
+
+	apply := func() int {
+		t.Helper()
+		live, err := readLiveBoard(trelloClient, state)
+		if err != nil {
+			t.Fatalf("Failed to read the board: %v", err)
+		}
+		diff, err := batch.DiffBoard(state, live)
+		if err != nil {
+			t.Fatalf("Failed to diff the board: %v", err)
+		}
+
+		operations := diff.Operations()
+		if err := checkReversible(operations); err != nil {
+			t.Fatalf("Unexpected irreversible operations: %v", err)
+		}
+		processor := batch.NewBatchProcessor(false)
+		processor.ProcessTransaction(operations, func(op batch.Operation) (interface{}, *batch.Rollback, error) {
+			return processOperationWithRollback(trelloClient, op)
+		})
+		for i, result := range processor.GetResults() {
+			if !result.Success {
+				t.Fatalf("Operation %d failed: %s", i+1, result.Error)
+			}
+		}
+		return len(diff.Changes)
+	}
+
+	if changes := apply(); changes == 0 {
+		t.Fatal("Expected the new board to be created")
+	}
+	if changes := apply(); changes != 0 {
+		t.Errorf("Expected no changes after applying, got %d", changes)
+	}
+
+	// Rename and reorder lists, and move the card
+	state.Lists = []batch.ListState{{Name: "Done"}, {Name: "Todo", RenamedFrom: []string{"Backlog"}}, {Name: "Doing"}}
+	state.Cards[0].List = "Doing"
+	state.Cards[0].Checklists[0].Items = append(state.Cards[0].Checklists[0].Items, "Announce")
+	if changes := apply(); changes != 4 {
+		t.Errorf("Expected a rename, a reorder, a move and an item, got %d changes", changes)
+	}
+	if changes := apply(); changes != 0 {
+		t.Errorf("Expected no changes after applying, got %d", changes)
+	}
+
+	live, _ := readLiveBoard(trelloClient, state)
+	var names []string
+	for _, list := range live.Lists {
+		names = append(names, list.Name)
+	}
+	if len(names) != 3 || names[0] != "Done" || names[1] != "Todo" || names[2] != "Doing" {
+		t.Errorf("Expected the lists in the order of the file, got %v", names)
+	}
+	if card := live.Cards[0]; card.ListID != live.Lists[2].ID || len(card.LabelIDs) != 1 || len(card.Checklists[0].Items) != 3 {
+		t.Errorf("Expected the card on Doing with its label and 3 items, got %+v", card)
+	}
+}
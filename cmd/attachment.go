@@ -4,95 +4,85 @@ import (
 	"fmt"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var attachmentListCmd = &cobra.Command{
-	Use:   "list --card <card-id>",
-	Short: "List all attachments on a card",
-	Long:  "List all attachments on a specific card.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cardID, _ := cmd.Flags().GetString("card")
-		if cardID == "" {
-			return fmt.Errorf("card ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
-
-		attachments, err := card.GetAttachments(nil)
-		if err != nil {
-			return fmt.Errorf("failed to get attachments: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatAttachments(attachments)
-		if err != nil {
-			return err
-		}
+var attachmentOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list", Required: []string{"card_id"}},
+		use:      "list --card <card>",
+		short:    "List all attachments on a card",
+		long:     "List all attachments on a specific card.",
+		examples: []string{"trello-cli attachment list --card 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		fields:   map[string]string{"card_id": "ID, URL or name of the card to list attachments from"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			card, err := getCard(trelloClient, op.Data["card_id"].(string))
+			if err != nil {
+				return nil, err
+			}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			attachments, err := card.GetAttachments(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get attachments: %w", err)
+			}
+			return attachments, nil
+		},
 	},
-}
-
-var attachmentAddCmd = &cobra.Command{
-	Use:   "add --card <card-id> <url>",
-	Short: "Add an attachment to a card",
-	Long:  "Add an attachment to a specific card by URL.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cardID, _ := cmd.Flags().GetString("card")
-		if cardID == "" {
-			return fmt.Errorf("card ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		url := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
-
-		attachment := trello.Attachment{
-			URL: url,
-		}
+	{
+		Action:   batch.Action{Name: "add", Required: []string{"card_id", "url"}, Mutating: true},
+		use:      "add --card <card> <url>",
+		short:    "Add an attachment to a card",
+		long:     "Add an attachment to a specific card by URL.",
+		examples: []string{"trello-cli attachment add --card 5f8b8c8d8e8f8a8b8c8d8e8f https://example.com/spec.pdf"},
+		args:     []string{"url"},
+		fields: map[string]string{
+			"card_id": "ID, URL or name of the card to attach to",
+			"url":     "URL to attach",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			card, err := getCard(trelloClient, op.Data["card_id"].(string))
+			if err != nil {
+				return nil, err
+			}
 
-		err = card.AddURLAttachment(&attachment)
-		if err != nil {
-			return fmt.Errorf("failed to add attachment: %w", err)
-		}
+			attachment := trello.Attachment{
+				URL: op.Data["url"].(string),
+			}
 
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
+			err = card.AddURLAttachment(&attachment)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to add attachment: %w", err)
 			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Attachment added to card '%s'", card.Name)))
-		}
-		return nil
+			return &attachment, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			attachment := result.(*trello.Attachment)
+			cardID := op.Data["card_id"].(string)
+			return rollback("attachment", "delete", attachment.ID, map[string]interface{}{"card_id": cardID}, func() (interface{}, error) {
+				return noResult(trelloClient.DeleteAttachment(cardID, attachment.ID))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete", Required: []string{"id", "card_id"}, Mutating: true},
+		use:      "delete --card <card> <attachment-id>",
+		short:    "Delete an attachment",
+		long:     "Delete an attachment from a card permanently.",
+		examples: []string{"trello-cli attachment delete --card 5f8b8c8d8e8f8a8b8c8d8e8f 5f8b8c8d8e8f8a8b8c8d8e90"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":      "ID of the attachment to delete",
+			"card_id": "ID, URL or name of the card the attachment is on",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			err := trelloClient.DeleteAttachment(op.Data["card_id"].(string), op.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete attachment: %w", err)
+			}
+			return success("Attachment %s deleted successfully", op.ID), nil
+		},
 	},
 }
 
@@ -100,14 +90,11 @@ func init() {
 	attachmentCmd := &cobra.Command{
 		Use:   "attachment",
 		Short: "Manage Trello attachments",
-		Long:  "Commands for managing Trello attachments including listing and adding attachments to cards.",
+		Long:  "Commands for managing Trello attachments including listing, adding, and deleting attachments on cards.",
 	}
 
-	attachmentCmd.AddCommand(attachmentListCmd)
-	attachmentCmd.AddCommand(attachmentAddCmd)
-
-	attachmentListCmd.Flags().String("card", "", "Card ID")
-	attachmentAddCmd.Flags().String("card", "", "Card ID")
+	registerOperations("attachment", attachmentOperations...)
+	addOperationCommands(attachmentCmd, "attachment")
 
 	rootCmd.AddCommand(attachmentCmd)
 }
@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 
-	"github.com/adlio/trello"
 	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
 	"github.com/spf13/cobra"
@@ -12,7 +19,32 @@ import (
 var batchFileCmd = &cobra.Command{
 	Use:   "file <batch-file>",
 	Short: "Execute batch operations from a file",
-	Long:  "Execute batch operations from a JSON or YAML file.",
+	Long: `Execute batch operations from a JSON or YAML file.
+
+Operations can be named with ref and used by later operations through
+${ops.<ref>.<field>} placeholders in id, data and parameters, for example
+${ops.newlist.id}. The placeholders are checked before anything runs.
+
+Templates are expanded first: an operation with foreach is repeated for each
+item of an inline list, CSV file or JSON file, and text/template expressions
+such as {{ .vars.board }} or {{ .item.name }} in ref, id, data and parameters
+are filled in from the vars key, --var name=value and the foreach item.
+
+With --dry-run every operation is validated and its board, list, card and
+label references are resolved with read-only requests, and the plan is printed
+instead of running anything.
+
+With transactional: true in the file, the first failure undoes every completed
+operation in reverse order, and the results show each undo.
+
+Completed operations are recorded in a journal next to the file
+(<batch-file>.journal, or --journal). If the batch fails or is interrupted, the
+journal is kept and "trello-cli batch resume" runs the rest; after a fully
+successful run it is removed.
+
+With --concurrency (or a concurrency key in the file) independent operations
+run in parallel; an operation using a ref waits for it. Results are still
+reported in input order.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filename := args[0]
@@ -21,478 +53,514 @@ var batchFileCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to load batch file: %w", err)
 		}
+		if err := expandBatch(cmd, batchFile, filepath.Dir(filename)); err != nil {
+			return err
+		}
+
+		journalPath, _ := cmd.Flags().GetString("journal")
+		if journalPath == "" {
+			journalPath = filename + ".journal"
+		}
 
-		return executeBatchOperations(cmd, batchFile)
+		return executeBatchOperations(cmd, batchFile, journalPath, nil)
 	},
 }
 
 var batchStdinCmd = &cobra.Command{
 	Use:   "stdin",
 	Short: "Execute batch operations from stdin",
-	Long:  "Execute batch operations from JSON or YAML piped to stdin.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		batchFile, err := batch.LoadBatchFromStdin()
-		if err != nil {
-			return fmt.Errorf("failed to load batch from stdin: %w", err)
-		}
-
-		return executeBatchOperations(cmd, batchFile)
-	},
-}
-
-func executeBatchOperations(cmd *cobra.Command, batchFile *batch.BatchFile) error {
-	auth, err := getAuthFromContext(cmd.Context())
-	if err != nil {
-		return err
-	}
-	trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-	processor := batch.NewBatchProcessor(batchFile.ContinueOnError)
+	Long: `Execute batch operations from JSON or YAML piped to stdin.
 
-	processor.ProcessOperations(batchFile.Operations, func(op batch.Operation) (interface{}, error) {
-		return processOperation(trelloClient, op)
-	})
+Operations can be named with ref and used by later operations through
+${ops.<ref>.<field>} placeholders in id, data and parameters, for example
+${ops.newlist.id}. The placeholders are checked before anything runs.
 
-	// Format and output results
-	results, err := processor.FormatResults(format)
-	if err != nil {
-		return err
-	}
+Templates are expanded first: an operation with foreach is repeated for each
+item of an inline list, CSV file or JSON file, and text/template expressions
+such as {{ .vars.board }} or {{ .item.name }} in ref, id, data and parameters
+are filled in from the vars key, --var name=value and the foreach item.
 
-	if !quiet {
-		fmt.Println(results)
-	}
+With --dry-run every operation is validated and its board, list, card and
+label references are resolved with read-only requests, and the plan is printed
+instead of running anything.
 
-	// Return error if any operations failed
-	if processor.GetErrorCount() > 0 {
-		return fmt.Errorf("batch processing completed with %d error(s)", processor.GetErrorCount())
-	}
+With transactional: true in the input, the first failure undoes every completed
+operation in reverse order, and the results show each undo.
 
-	return nil
-}
+With --journal, completed operations are recorded so that a failed or
+interrupted batch can be finished with "trello-cli batch resume".
 
-func processOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	// Validate operation
-	if err := batch.ValidateOperation(op); err != nil {
-		return nil, err
-	}
-
-	// Process based on operation type and action
-	switch op.Type {
-	case "board":
-		return processBoardOperation(trelloClient, op)
-	case "list":
-		return processListOperation(trelloClient, op)
-	case "card":
-		return processCardOperation(trelloClient, op)
-	case "label":
-		return processLabelOperation(trelloClient, op)
-	case "checklist":
-		return processChecklistOperation(trelloClient, op)
-	case "member":
-		return processMemberOperation(trelloClient, op)
-	case "attachment":
-		return processAttachmentOperation(trelloClient, op)
-	default:
-		return nil, fmt.Errorf("unsupported operation type: %s", op.Type)
-	}
-}
+With --concurrency (or a concurrency key in the input) independent operations
+run in parallel; an operation using a ref waits for it. Results are still
+reported in input order.
 
-func processBoardOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "get":
-		if op.ID == "" {
-			return nil, fmt.Errorf("board ID is required for get action")
-		}
-		return trelloClient.GetBoard(op.ID, nil)
-	case "create":
-		if name, ok := op.Data["name"].(string); ok {
-			board := trello.NewBoard(name)
-			err := trelloClient.CreateBoard(&board, nil)
-			return &board, err
-		}
-		return nil, fmt.Errorf("board name is required for create action")
-	case "delete":
-		if op.ID == "" {
-			return nil, fmt.Errorf("board ID is required for delete action")
-		}
-		board, err := trelloClient.GetBoard(op.ID, nil)
-		if err != nil {
-			return nil, err
-		}
-		return nil, board.Delete(nil)
-	case "add-member":
-		if op.ID == "" {
-			return nil, fmt.Errorf("board ID is required for add-member action")
-		}
-		email, emailOk := op.Data["email"].(string)
-		if !emailOk || email == "" {
-			return nil, fmt.Errorf("email is required for add-member action")
+With --ndjson the input is one operation per line, and each runs as soon as its
+line arrives. Each result is written as one JSON line as soon as it completes,
+with the index of its operation, followed by a summary line. Options are given
+as flags, since there is no batch file object to hold them.`,
+	Example: `  cat operations.json | trello-cli batch stdin
+  generate-ops | trello-cli batch stdin --ndjson --continue-on-error --concurrency 4`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if ndjson, _ := cmd.Flags().GetBool("ndjson"); ndjson {
+			return streamBatchOperations(cmd, os.Stdin, os.Stdout)
 		}
 
-		board, err := trelloClient.GetBoard(op.ID, nil)
+		batchFile, err := batch.LoadBatchFromStdin()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get board: %w", err)
+			return fmt.Errorf("failed to load batch from stdin: %w", err)
+		}
+		if err := expandBatch(cmd, batchFile, ""); err != nil {
+			return err
 		}
 
-		member := trello.Member{Email: email}
-		_, err = board.AddMember(&member, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add member: %w", err)
+		if continueOnError, _ := cmd.Flags().GetBool("continue-on-error"); continueOnError {
+			batchFile.ContinueOnError = true
 		}
 
-		return map[string]string{"status": "success", "message": fmt.Sprintf("member %s added to board", email)}, nil
-	default:
-		return nil, fmt.Errorf("unsupported board action: %s", op.Action)
-	}
+		journalPath, _ := cmd.Flags().GetString("journal")
+		return executeBatchOperations(cmd, batchFile, journalPath, nil)
+	},
 }
 
-func processListOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "get":
-		if op.ID == "" {
-			return nil, fmt.Errorf("list ID is required for get action")
-		}
-		return trelloClient.GetList(op.ID, nil)
-	case "create":
-		if name, ok := op.Data["name"].(string); ok {
-			if boardID, ok := op.Data["board_id"].(string); ok {
-				board, err := trelloClient.GetBoard(boardID, trello.Defaults())
-				if err != nil {
-					return nil, fmt.Errorf("failed to get board: %w", err)
-				}
-				list, err := board.CreateList(name, trello.Defaults())
-				if err != nil {
-					return nil, err
-				}
-				return list, nil
-			}
-			return nil, fmt.Errorf("board_id is required for create action")
-		}
-		return nil, fmt.Errorf("list name is required for create action")
-	case "archive":
-		if op.ID == "" {
-			return nil, fmt.Errorf("list ID is required for archive action")
-		}
-		list, err := trelloClient.GetList(op.ID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get list: %w", err)
-		}
-
-		err = list.Archive()
+var batchResumeCmd = &cobra.Command{
+	Use:   "resume <journal>",
+	Short: "Finish a failed or interrupted batch from its journal",
+	Long: `Finish a failed or interrupted batch from its journal.
+
+The journal holds the batch itself and the operations that completed, so the
+original file is not needed. Completed operations are skipped and reported as
+such, and the outputs of completed operations with a ref stay available to
+${ops.<ref>.<field>} placeholders. Failed and unstarted operations run again.
+The journal is removed once every operation has completed.
+
+An operation that was running when the batch was killed may have completed
+without being recorded; check it before resuming.`,
+	Example: `  trello-cli batch file import.yaml     # interrupted with Ctrl-C
+  trello-cli batch resume import.yaml.journal`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		journal, err := batch.OpenJournal(args[0])
 		if err != nil {
-			return nil, fmt.Errorf("failed to archive list: %w", err)
+			return err
 		}
 
-		return map[string]string{"status": "success", "message": fmt.Sprintf("list %s archived", op.ID)}, nil
-	default:
-		return nil, fmt.Errorf("unsupported list action: %s", op.Action)
-	}
+		return executeBatchOperations(cmd, journal.Batch(), journal.Path(), journal)
+	},
 }
 
-func processCardOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "get":
-		if op.ID == "" {
-			return nil, fmt.Errorf("card ID is required for get action")
-		}
-		return trelloClient.GetCard(op.ID, nil)
-	case "create":
-		if name, ok := op.Data["name"].(string); ok {
-			if listID, ok := op.Data["list_id"].(string); ok {
-				card := trello.Card{
-					Name:   name,
-					IDList: listID,
-				}
-				// Add description if provided
-				if desc, ok := op.Data["desc"].(string); ok {
-					card.Desc = desc
-				}
-				// Add position if provided (can be float64 or string "top"/"bottom")
-				if pos, ok := op.Data["pos"].(float64); ok {
-					card.Pos = pos
-				} else if posStr, ok := op.Data["pos"].(string); ok {
-					// Handle "top" and "bottom" as special values
-					if posStr == "top" {
-						card.Pos = 0
-					}
-					// "bottom" is default, no need to set
-				}
-				err := trelloClient.CreateCard(&card, nil)
-				return &card, err
-			}
-			return nil, fmt.Errorf("list_id is required for create action")
-		}
-		return nil, fmt.Errorf("card name is required for create action")
-	case "move":
-		if op.ID == "" {
-			return nil, fmt.Errorf("card ID is required for move action")
-		}
-		listID, listOk := op.Data["list_id"].(string)
-		if !listOk || listID == "" {
-			return nil, fmt.Errorf("list_id is required for move action")
+var batchValidateCmd = &cobra.Command{
+	Use:   "validate <batch-file>",
+	Short: "Check a batch file without running it",
+	Long: `Check a JSON or YAML batch file without running it or contacting Trello.
+
+Every problem is reported with the operation and line it is on: unknown keys,
+values of the wrong type, unsupported actions, missing required fields, card
+update values that cannot be parsed, references to refs that do not exist or
+come later, and operations a transactional batch cannot undo. Use - to read
+the batch from stdin.
+
+The command exits with an error if there is any problem. To also check that
+the boards, lists, cards and labels named in the batch exist, use --dry-run
+on "trello-cli batch file".
+
+Templates are expanded first, with the --var values given; a problem with an
+operation repeated by foreach names the item.`,
+	Example: `  trello-cli batch validate operations.yaml
+  trello-cli batch validate import.yaml --var board="Sprint 42"
+  cat operations.json | trello-cli batch validate - --format markdown`,
+	Annotations: map[string]string{skipAuthAnnotation: "true"},
+	Args:        cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		dir := ""
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+			dir = filepath.Dir(args[0])
 		}
-
-		card, err := trelloClient.GetCard(op.ID, nil)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
+			return fmt.Errorf("failed to read batch file: %w", err)
 		}
-
-		err = card.MoveToList(listID, nil)
+		vars, err := parseVars(cmd)
 		if err != nil {
-			return nil, fmt.Errorf("failed to move card: %w", err)
+			return err
 		}
 
-		return map[string]string{"status": "success", "message": fmt.Sprintf("card moved to list %s", listID)}, nil
-	case "copy":
-		if op.ID == "" {
-			return nil, fmt.Errorf("card ID is required for copy action")
-		}
-		listID, listOk := op.Data["list_id"].(string)
-		if !listOk || listID == "" {
-			return nil, fmt.Errorf("list_id is required for copy action")
-		}
-
-		card, err := trelloClient.GetCard(op.ID, nil)
+		validation := batch.ValidateBatchWithVars(data, vars, dir, checkOperation)
+		output, err := validation.Format(format, formatterOptions())
 		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
+			return err
 		}
-
-		copiedCard, err := card.CopyToList(listID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to copy card: %w", err)
+		if !quiet {
+			fmt.Println(output)
 		}
-
-		return copiedCard, nil
-	case "delete":
-		if op.ID == "" {
-			return nil, fmt.Errorf("card ID is required for delete action")
+		if len(validation.Problems) > 0 {
+			return fmt.Errorf("batch file has %d problem(s)", len(validation.Problems))
 		}
+		return nil
+	},
+}
 
-		card, err := trelloClient.GetCard(op.ID, nil)
+var batchSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for batch files",
+	Long: `Print the JSON Schema (draft 2020-12) for batch files.
+
+The schema lists the actions of each operation type and the id and data keys
+each action requires, so editors can check and complete batch files and LLMs
+can produce valid ones. It is the same for JSON and YAML files.`,
+	Example:     `  trello-cli batch schema > batch.schema.json`,
+	Annotations: map[string]string{skipAuthAnnotation: "true"},
+	Args:        cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := batch.SchemaJSON()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
+			return err
 		}
+		fmt.Print(string(schema))
+		return nil
+	},
+}
 
-		err = card.Delete()
-		if err != nil {
-			return nil, fmt.Errorf("failed to delete card: %w", err)
+// parseVars returns the --var name=value overrides of a command
+func parseVars(cmd *cobra.Command) (map[string]string, error) {
+	pairs, _ := cmd.Flags().GetStringArray("var")
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q: expected name=value", pair)
 		}
+		vars[name] = value
+	}
+	return vars, nil
+}
 
-		return map[string]string{"status": "success", "message": "card deleted"}, nil
-    
-	case "archive":
-		if op.ID == "" {
-			return nil, fmt.Errorf("card ID is required for archive action")
-		}
-		card, err := trelloClient.GetCard(op.ID, nil)
-		if err != nil {
-			return nil, err
-		}
-		err = card.Archive()
-		if err != nil {
-			return nil, fmt.Errorf("failed to archive card: %w", err)
-		}
-		return map[string]string{"status": "success", "message": "card archived"}, nil
-    
-	default:
-		return nil, fmt.Errorf("unsupported card action: %s", op.Action)
+// expandBatch expands the templates of a loaded batch with the --var
+// overrides of the command; foreach files are relative to dir
+func expandBatch(cmd *cobra.Command, batchFile *batch.BatchFile, dir string) error {
+	vars, err := parseVars(cmd)
+	if err != nil {
+		return err
 	}
+	if err := batchFile.Expand(vars, dir); err != nil {
+		return fmt.Errorf("failed to expand batch templates:\n%w", err)
+	}
+	return nil
 }
 
-func processLabelOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "get":
-		if op.ID == "" {
-			return nil, fmt.Errorf("label ID is required for get action")
-		}
-		return trelloClient.GetLabel(op.ID, nil)
-	case "create":
-		name, nameOk := op.Data["name"].(string)
-		color, colorOk := op.Data["color"].(string)
-		boardID, boardOk := op.Data["board_id"].(string)
+// executeBatchOperations validates and runs a batch, journaling completed
+// operations at journalPath unless it is empty. resumed is the journal of an
+// earlier run of the batch, whose completed operations are skipped.
+func executeBatchOperations(cmd *cobra.Command, batchFile *batch.BatchFile, journalPath string, resumed *batch.Journal) error {
+	if resumed != nil {
+		defer resumed.Close()
+	}
+
+	auth, err := getAuthFromContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+	// The flag wins over the file; a missing concurrency key (0) means one at a time
+	concurrency := batchFile.Concurrency
+	if cmd.Flags().Changed("concurrency") {
+		concurrency, _ = cmd.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return fmt.Errorf("invalid --concurrency %d: must be at least 1", concurrency)
+		}
+	} else if concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d in batch file: must be at least 1", concurrency)
+	}
+
+	// Check every ${ops.<ref>.<field>} placeholder before anything runs
+	if err := batch.ValidateReferences(batchFile.Operations); err != nil {
+		return fmt.Errorf("invalid operation references:\n%w", err)
+	}
 
-		if !nameOk || name == "" {
-			return nil, fmt.Errorf("label name is required for create action")
+	if batchFile.Transactional {
+		if batchFile.ContinueOnError {
+			return fmt.Errorf("a transactional batch stops at the first failure; remove continue_on_error")
 		}
-		if !colorOk || color == "" {
-			return nil, fmt.Errorf("label color is required for create action")
+		if concurrency > 1 {
+			return fmt.Errorf("a transactional batch runs one operation at a time; remove concurrency")
 		}
-		if !boardOk || boardID == "" {
-			return nil, fmt.Errorf("board_id is required for create action")
+		if err := checkReversible(batchFile.Operations); err != nil {
+			return fmt.Errorf("a transactional batch can only contain operations that can be undone:\n%w", err)
 		}
+	}
+
+	// All workers share one client, and with it the request budget and
+	// the name lookup cache
+	trelloClient, err := newClient(cmd, auth)
+	if err != nil {
+		return err
+	}
 
-		board, err := trelloClient.GetBoard(boardID, nil)
+	// A dry run only reads from Trello and prints the plan instead of results
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		plan := planBatchOperations(trelloClient, batchFile.Operations)
+		output, err := plan.Format(format, formatterOptions())
 		if err != nil {
-			return nil, fmt.Errorf("failed to get board: %w", err)
+			return err
 		}
-
-		label := trello.Label{
-			Name:  name,
-			Color: color,
+		if !quiet {
+			fmt.Println(output)
 		}
-
-		err = board.CreateLabel(&label, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create label: %w", err)
+		if plan.ErrorCount() > 0 {
+			return fmt.Errorf("dry run found problems in %d operation(s)", plan.ErrorCount())
 		}
-		return &label, nil
-	case "add":
-		cardID, cardOk := op.Data["card_id"].(string)
-		labelID, labelOk := op.Data["label_id"].(string)
+		return nil
+	}
 
-		if !cardOk || cardID == "" {
-			return nil, fmt.Errorf("card_id is required for add action")
+	processor := batch.NewBatchProcessor(batchFile.ContinueOnError)
+	processor.SetConcurrency(concurrency)
+
+	journal := resumed
+	if journal != nil {
+		if err := processor.Resume(journal); err != nil {
+			return err
 		}
-		if !labelOk || labelID == "" {
-			return nil, fmt.Errorf("label_id is required for add action")
+	} else if journalPath != "" {
+		journal, err = batch.CreateJournal(journalPath, batchFile)
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s holds an unfinished run of this batch; finish it with \"trello-cli batch resume %s\" or delete it to start over", journalPath, journalPath)
 		}
-
-		card, err := trelloClient.GetCard(cardID, nil)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
+			return err
 		}
+		defer journal.Close()
+		processor.SetJournal(journal)
+	}
 
-		err = card.AddIDLabel(labelID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add label: %w", err)
-		}
+	stopInterrupts := interruptBatch(processor, journalPath)
+	defer stopInterrupts()
+
+	if batchFile.Transactional {
+		processor.ProcessTransaction(batchFile.Operations, func(op batch.Operation) (interface{}, *batch.Rollback, error) {
+			return processOperationWithRollback(trelloClient, op)
+		})
+	} else {
+		processor.ProcessOperations(batchFile.Operations, func(op batch.Operation) (interface{}, error) {
+			return processOperation(trelloClient, op)
+		})
+	}
 
-		return map[string]string{"status": "success", "message": "label added to card"}, nil
-	default:
-		return nil, fmt.Errorf("unsupported label action: %s", op.Action)
+	// Format and output results
+	results, err := processor.FormatResults(format, formatterOptions())
+	if err != nil {
+		return err
 	}
-}
 
-func processChecklistOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "get":
-		if op.ID == "" {
-			return nil, fmt.Errorf("checklist ID is required for get action")
-		}
-		return trelloClient.GetChecklist(op.ID, nil)
-	case "create":
-		name, nameOk := op.Data["name"].(string)
-		cardID, cardOk := op.Data["card_id"].(string)
+	if !quiet {
+		fmt.Println(results)
+	}
 
-		if !nameOk || name == "" {
-			return nil, fmt.Errorf("checklist name is required for create action")
-		}
-		if !cardOk || cardID == "" {
-			return nil, fmt.Errorf("card_id is required for create action")
+	// Keep the journal for a resume unless everything completed, or nothing
+	// did, as after a transaction that was rolled back
+	resumeHint := ""
+	if journal != nil {
+		finished := processor.GetErrorCount() == 0 && !processor.Interrupted()
+		if finished || len(journal.Completed()) == 0 {
+			if err := journal.Remove(); err != nil {
+				return fmt.Errorf("failed to remove journal: %w", err)
+			}
+		} else {
+			resumeHint = fmt.Sprintf("; resume with: trello-cli batch resume %s", journal.Path())
 		}
+	}
 
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
-		}
+	// Return error if any operations failed
+	if processor.Interrupted() {
+		return fmt.Errorf("batch interrupted after %d operation(s)%s", len(processor.GetResults()), resumeHint)
+	}
+	if batchFile.Transactional && processor.GetErrorCount() > 0 {
+		return fmt.Errorf("batch failed and was rolled back: %d operation(s) undone, %d could not be undone",
+			processor.GetRollbackCount(), processor.GetRollbackErrorCount())
+	}
+	if processor.GetErrorCount() > 0 {
+		return fmt.Errorf("batch processing completed with %d error(s)%s", processor.GetErrorCount(), resumeHint)
+	}
 
-		checklist, err := trelloClient.CreateChecklist(card, name, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create checklist: %w", err)
-		}
-		return checklist, nil
-	case "add-item":
-		checklistID, checklistOk := op.Data["checklist_id"].(string)
-		itemName, itemOk := op.Data["item_name"].(string)
+	return nil
+}
 
-		if !checklistOk || checklistID == "" {
-			return nil, fmt.Errorf("checklist_id is required for add-item action")
-		}
-		if !itemOk || itemName == "" {
-			return nil, fmt.Errorf("item_name is required for add-item action")
+// streamBatchOperations runs operations read from in, one JSON object per
+// line, and writes each result to out as one JSON line as soon as it
+// completes, then a summary line
+func streamBatchOperations(cmd *cobra.Command, in io.Reader, out io.Writer) error {
+	for _, flag := range []string{"dry-run", "journal", "var"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--%s cannot be used with --ndjson", flag)
 		}
+	}
 
-		checklist, err := trelloClient.GetChecklist(checklistID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get checklist: %w", err)
-		}
+	auth, err := getAuthFromContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if concurrency < 1 {
+		return fmt.Errorf("invalid --concurrency %d: must be at least 1", concurrency)
+	}
+	continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
 
-		item, err := checklist.CreateCheckItem(itemName, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add item: %w", err)
+	trelloClient, err := newClient(cmd, auth)
+	if err != nil {
+		return err
+	}
+	processor := batch.NewBatchProcessor(continueOnError)
+	processor.SetConcurrency(concurrency)
+
+	stopInterrupts := interruptBatch(processor, "")
+	defer stopInterrupts()
+
+	encoder := json.NewEncoder(out)
+	summary, err := processor.ProcessStream(in, func(op batch.Operation) (interface{}, error) {
+		return processOperation(trelloClient, op)
+	}, func(result batch.StreamResult) {
+		if !quiet {
+			encoder.Encode(result)
 		}
+	})
+	if !quiet {
+		encoder.Encode(map[string]batch.StreamSummary{"summary": summary})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read operations: %w", err)
+	}
 
-		return item, nil
-	default:
-		return nil, fmt.Errorf("unsupported checklist action: %s", op.Action)
+	if processor.Interrupted() {
+		return fmt.Errorf("batch interrupted after %d operation(s)", summary.Total)
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("batch processing completed with %d error(s)", summary.Failed)
 	}
+	return nil
 }
 
-func processMemberOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "get":
-		if op.ID == "" {
-			return nil, fmt.Errorf("member ID or username is required for get action")
-		}
-		return trelloClient.GetMember(op.ID, nil)
-	case "boards":
-		if op.ID == "" {
-			return nil, fmt.Errorf("member ID or username is required for boards action")
-		}
-		member, err := trelloClient.GetMember(op.ID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get member: %w", err)
+// interruptBatch makes the first Ctrl-C (or SIGTERM) stop the batch once the
+// running operations finish, so they are reported and journaled; a second one
+// exits at once. The returned function stops listening.
+func interruptBatch(processor *batch.BatchProcessor, journalPath string) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		processor.Interrupt()
+		fmt.Fprintln(os.Stderr, "Interrupted: finishing running operations (press Ctrl-C again to exit now)")
+
+		select {
+		case <-signals:
+			if journalPath != "" {
+				fmt.Fprintf(os.Stderr, "Resume with: trello-cli batch resume %s\n", journalPath)
+			}
+			os.Exit(130)
+		case <-done:
 		}
+	}()
 
-		boards, err := member.GetBoards(nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get member boards: %w", err)
-		}
-		return boards, nil
-	default:
-		return nil, fmt.Errorf("unsupported member action: %s", op.Action)
+	return func() {
+		signal.Stop(signals)
+		close(done)
 	}
 }
 
-func processAttachmentOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
-	switch op.Action {
-	case "list":
-		cardID, cardOk := op.Data["card_id"].(string)
+// resolveOperation returns a copy of op with board, list, card and label
+// references in id and data resolved to IDs. data["board_id"] scopes the
+// lookup of list, card and label names. Operations that change Trello only
+// accept whole names.
+func resolveOperation(trelloClient *client.Client, op batch.Operation) (batch.Operation, error) {
+	match := client.MatchPartial
+	if batch.IsMutating(op) {
+		match = client.MatchExact
+	}
 
-		if !cardOk || cardID == "" {
-			return nil, fmt.Errorf("card_id is required for list action")
-		}
+	data := make(map[string]interface{}, len(op.Data))
+	for key, val := range op.Data {
+		data[key] = val
+	}
+	op.Data = data
 
-		card, err := trelloClient.GetCard(cardID, nil)
+	scope := ""
+	if boardRef, ok := data["board_id"].(string); ok && boardRef != "" {
+		boardID, err := trelloClient.ResolveBoard(boardRef, match)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
+			return op, err
 		}
+		data["board_id"] = boardID
+		scope = boardID
+	}
 
-		attachments, err := card.GetAttachments(nil)
+	if op.ID != "" {
+		var err error
+		switch op.Type {
+		case "board":
+			op.ID, err = trelloClient.ResolveBoard(op.ID, match)
+		case "list":
+			op.ID, err = trelloClient.ResolveList(scope, op.ID, match)
+		case "card":
+			op.ID, err = trelloClient.ResolveCard(scope, op.ID, match)
+		case "label":
+			op.ID, err = trelloClient.ResolveLabel(scope, op.ID, match)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to get attachments: %w", err)
+			return op, err
 		}
-		return attachments, nil
-	case "add":
-		cardID, cardOk := op.Data["card_id"].(string)
-		url, urlOk := op.Data["url"].(string)
+	}
 
-		if !cardOk || cardID == "" {
-			return nil, fmt.Errorf("card_id is required for add action")
+	if listRef, ok := data["list_id"].(string); ok && listRef != "" {
+		listScope := scope
+		if listScope == "" && op.Type == "card" && op.ID != "" && !client.IsID(listRef) {
+			// Target lists of a move or copy default to the card's board
+			boardID, err := trelloClient.CardBoardID(op.ID)
+			if err != nil {
+				return op, err
+			}
+			listScope = boardID
 		}
-		if !urlOk || url == "" {
-			return nil, fmt.Errorf("url is required for add action")
+		listID, err := trelloClient.ResolveList(listScope, listRef, match)
+		if err != nil {
+			return op, err
 		}
+		data["list_id"] = listID
+	}
 
-		card, err := trelloClient.GetCard(cardID, nil)
+	if cardRef, ok := data["card_id"].(string); ok && cardRef != "" {
+		cardID, err := trelloClient.ResolveCard(scope, cardRef, match)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get card: %w", err)
+			return op, err
 		}
+		data["card_id"] = cardID
+	}
 
-		attachment := trello.Attachment{
-			URL: url,
+	if labelRef, ok := data["label_id"].(string); ok && labelRef != "" {
+		labelScope := scope
+		if labelScope == "" && !client.IsID(labelRef) {
+			// Label names are looked up on the card's own board
+			if cardID, ok := data["card_id"].(string); ok && cardID != "" {
+				boardID, err := trelloClient.CardBoardID(cardID)
+				if err != nil {
+					return op, err
+				}
+				labelScope = boardID
+			}
 		}
-
-		err = card.AddURLAttachment(&attachment)
+		labelID, err := trelloClient.ResolveLabel(labelScope, labelRef, match)
 		if err != nil {
-			return nil, fmt.Errorf("failed to add attachment: %w", err)
+			return op, err
 		}
-
-		return &attachment, nil
-	default:
-		return nil, fmt.Errorf("unsupported attachment action: %s", op.Action)
+		data["label_id"] = labelID
 	}
+
+	return op, nil
 }
 
 func init() {
@@ -502,8 +570,24 @@ func init() {
 		Long:  "Execute multiple Trello operations from a file or stdin for automation and scripting.",
 	}
 
+	for _, c := range []*cobra.Command{batchFileCmd, batchStdinCmd} {
+		c.Flags().Int("concurrency", 1, "Number of operations to run in parallel (overrides the concurrency key)")
+		c.Flags().Bool("dry-run", false, "Validate operations and resolve references without changing anything, and print the plan")
+	}
+	batchFileCmd.Flags().String("journal", "", "Journal of completed operations (default <batch-file>.journal)")
+	batchStdinCmd.Flags().String("journal", "", "Journal of completed operations, to resume the batch if it fails")
+	batchStdinCmd.Flags().Bool("ndjson", false, "Read one operation per line and write each result as a JSON line as it completes")
+	batchStdinCmd.Flags().Bool("continue-on-error", false, "Keep going after an operation fails (overrides the continue_on_error key)")
+	batchResumeCmd.Flags().Int("concurrency", 1, "Number of operations to run in parallel (overrides the concurrency key)")
+	for _, c := range []*cobra.Command{batchFileCmd, batchStdinCmd, batchValidateCmd} {
+		c.Flags().StringArray("var", nil, "Set a template variable, as name=value (repeatable; overrides the vars key)")
+	}
+
 	batchCmd.AddCommand(batchFileCmd)
 	batchCmd.AddCommand(batchStdinCmd)
+	batchCmd.AddCommand(batchResumeCmd)
+	batchCmd.AddCommand(batchValidateCmd)
+	batchCmd.AddCommand(batchSchemaCmd)
 
 	rootCmd.AddCommand(batchCmd)
 }
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/client"
+)
+
+// batchPlanner builds the plan of a dry run. It only reads from Trello: names,
+// short links and URLs are resolved like resolveOperation does, and every
+// resolved ID is looked up to confirm that it exists.
+type batchPlanner struct {
+	client *client.Client
+
+	// created maps the type and lowercased name of everything the batch
+	// creates to the index of the operation creating it
+	created map[string]map[string]int
+
+	// names caches the names of the objects looked up so far by ID
+	names map[string]string
+}
+
+// planBatchOperations returns what operations would do, without changing anything
+func planBatchOperations(trelloClient *client.Client, operations []batch.Operation) *batch.Plan {
+	planner := &batchPlanner{
+		client:  trelloClient,
+		created: make(map[string]map[string]int),
+		names:   make(map[string]string),
+	}
+
+	plan := &batch.Plan{}
+	for i, op := range operations {
+		plan.Steps = append(plan.Steps, planner.planOperation(i, op))
+	}
+	return plan
+}
+
+func (p *batchPlanner) planOperation(index int, op batch.Operation) batch.PlanStep {
+	step := batch.PlanStep{Operation: op, Mutating: batch.IsMutating(op)}
+
+	if err := batch.ValidateOperation(op); err != nil {
+		step.Errors = append(step.Errors, err.Error())
+		return step
+	}
+	if err := batch.ValidateAction(op); err != nil {
+		step.Errors = append(step.Errors, strings.Split(err.Error(), "\n")...)
+	}
+	if err := checkOperation(op); err != nil {
+		step.Errors = append(step.Errors, err.Error())
+	}
+
+	p.resolve(&step)
+
+	if spec := lookupOperation(op.Type, op.Action); spec != nil && (spec.Name == "create" || spec.Name == "ensure") {
+		if name, ok := op.Data["name"].(string); ok && name != "" {
+			if p.created[op.Type] == nil {
+				p.created[op.Type] = make(map[string]int)
+			}
+			if _, ok := p.created[op.Type][strings.ToLower(name)]; !ok {
+				p.created[op.Type][strings.ToLower(name)] = index
+			}
+		}
+	}
+	return step
+}
+
+// resolve resolves the references of an operation in the same order as
+// resolveOperation, recording each one in the step
+func (p *batchPlanner) resolve(step *batch.PlanStep) {
+	op := step.Operation
+
+	// scope is the resolved board; deferred means the board is only known
+	// at run time, so names on it cannot be looked up yet
+	scope, deferred := "", false
+	if ref, ok := op.Data["board_id"].(string); ok && ref != "" {
+		var found bool
+		scope, found = p.lookup(step, "board_id", "board", "", false, ref)
+		deferred = !found
+	}
+
+	id := ""
+	if op.ID != "" {
+		switch op.Type {
+		case "board", "list", "card", "label":
+			id, _ = p.lookup(step, "id", op.Type, scope, deferred, op.ID)
+		}
+	}
+
+	if ref, ok := op.Data["list_id"].(string); ok && ref != "" {
+		listScope := scope
+		if listScope == "" && !deferred && op.Type == "card" && id != "" && !client.IsID(ref) {
+			// Target lists of a move or copy default to the card's board
+			if boardID, err := p.client.CardBoardID(id); err == nil {
+				listScope = boardID
+			}
+		}
+		p.lookup(step, "list_id", "list", listScope, deferred, ref)
+	}
+
+	cardID := ""
+	if ref, ok := op.Data["card_id"].(string); ok && ref != "" {
+		cardID, _ = p.lookup(step, "card_id", "card", scope, deferred, ref)
+	}
+
+	if ref, ok := op.Data["label_id"].(string); ok && ref != "" {
+		labelScope := scope
+		if labelScope == "" && !deferred && cardID != "" && !client.IsID(ref) {
+			// Label names are looked up on the card's own board
+			if boardID, err := p.client.CardBoardID(cardID); err == nil {
+				labelScope = boardID
+			}
+		}
+		p.lookup(step, "label_id", "label", labelScope, deferred, ref)
+	}
+}
+
+// lookup resolves ref to the ID of an existing object of the given kind and
+// records the outcome in step. It returns false when the object cannot be
+// known before the batch runs or does not exist.
+func (p *batchPlanner) lookup(step *batch.PlanStep, field, kind, scope string, deferred bool, ref string) (string, bool) {
+	resolution := batch.Resolution{Field: field, Input: ref}
+	defer func() { step.Resolved = append(step.Resolved, resolution) }()
+
+	if batch.ContainsReference(ref) {
+		resolution.Note = "filled in when the batch runs"
+		return "", false
+	}
+	if deferred && !client.IsID(ref) && kind != "board" {
+		resolution.Note = "looked up when the batch runs, once its board exists"
+		return "", false
+	}
+
+	// Names are matched as the batch will match them when it runs
+	match := client.MatchPartial
+	if batch.IsMutating(step.Operation) {
+		match = client.MatchExact
+	}
+
+	var id string
+	var err error
+	switch kind {
+	case "board":
+		id, err = p.client.ResolveBoard(ref, match)
+	case "list":
+		id, err = p.client.ResolveList(scope, ref, match)
+	case "card":
+		id, err = p.client.ResolveCard(scope, ref, match)
+	case "label":
+		id, err = p.client.ResolveLabel(scope, ref, match)
+	}
+	if err == nil {
+		resolution.ID = id
+		resolution.Name, err = p.name(kind, id)
+	}
+	if err != nil {
+		if index, ok := p.created[kind][strings.ToLower(ref)]; ok {
+			resolution.ID = ""
+			resolution.Note = fmt.Sprintf("created by operation %d", index+1)
+			return "", false
+		}
+		step.Errors = append(step.Errors, fmt.Sprintf("%s: %v", field, err))
+		return "", false
+	}
+	return id, true
+}
+
+// name looks up the name of an object by ID, which also confirms it exists
+func (p *batchPlanner) name(kind, id string) (string, error) {
+	if name, ok := p.names[id]; ok {
+		return name, nil
+	}
+
+	args := trello.Arguments{"fields": "name"}
+	var name string
+	var err error
+	switch kind {
+	case "board":
+		var board *trello.Board
+		if board, err = p.client.GetBoard(id, args); err == nil {
+			name = board.Name
+		}
+	case "list":
+		var list *trello.List
+		if list, err = p.client.GetList(id, args); err == nil {
+			name = list.Name
+		}
+	case "card":
+		var card *trello.Card
+		if card, err = p.client.GetCard(id, args); err == nil {
+			name = card.Name
+		}
+	case "label":
+		var label *trello.Label
+		if label, err = p.client.GetLabel(id, args); err == nil {
+			name = label.Name
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s %s not found: %w", kind, id, err)
+	}
+
+	p.names[id] = name
+	return name, nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/client"
+)
+
+// checkReversible returns an error naming every operation that a
+// transactional batch could not undo
+func checkReversible(operations []batch.Operation) error {
+	var problems []error
+	for i, op := range operations {
+		if !batch.IsReversible(op) {
+			problems = append(problems, fmt.Errorf("operation %d: %s %s cannot be undone", i+1, op.Type, op.Action))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// processOperationWithRollback runs an operation like processOperation and
+// returns how to undo it. What the undo needs to restore, such as the list a
+// card was on before a move, is read before the operation runs.
+func processOperationWithRollback(trelloClient *client.Client, op batch.Operation) (interface{}, *batch.Rollback, error) {
+	spec, err := validateOperation(op)
+	if err != nil {
+		return nil, nil, err
+	}
+	op, err = resolveOperation(trelloClient, op)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var before interface{}
+	if spec.before != nil {
+		before, err = spec.before(trelloClient, op)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	data, err := spec.run(trelloClient, op)
+	if err == nil && spec.addsObjects() {
+		trelloClient.ForgetLookups()
+	}
+	if err != nil || spec.undo == nil {
+		// Gets and lists change nothing
+		return data, nil, err
+	}
+	return data, spec.undo(trelloClient, op, data, before), nil
+}
+
+// rollback returns an undo running fn, reported as the operation typ action
+// on id with data
+func rollback(typ, action, id string, data map[string]interface{}, fn func() (interface{}, error)) *batch.Rollback {
+	return &batch.Rollback{
+		Operation: batch.Operation{Type: typ, Resource: typ, Action: action, ID: id, Data: data},
+		Undo:      fn,
+	}
+}
+
+// noResult returns err without a result, for undos with nothing to report
+func noResult(err error) (interface{}, error) {
+	return nil, err
+}
+
+// previousCardValues returns the values a card had for the keys of a card
+// update, in the form cardUpdateArgs accepts
+func previousCardValues(card *trello.Card, data map[string]interface{}) map[string]interface{} {
+	formatDate := func(t *time.Time) string {
+		if t == nil {
+			return "none"
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	return previousValues(map[string]interface{}{
+		"name":         card.Name,
+		"desc":         card.Desc,
+		"due":          formatDate(card.Due),
+		"start":        formatDate(card.Start),
+		"due_complete": card.DueComplete,
+		"pos":          card.Pos,
+		"closed":       card.Closed,
+	}, data)
+}
+
+// previousValues returns the values in all for the keys of an update
+func previousValues(all, data map[string]interface{}) map[string]interface{} {
+	previous := make(map[string]interface{})
+	for key := range data {
+		if value, ok := all[key]; ok {
+			previous[key] = value
+		}
+	}
+	return previous
+}
@@ -2,11 +2,19 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/adlio/trello"
 	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/danbruder/trello-cli/internal/fake"
+	"github.com/danbruder/trello-cli/internal/formatter"
 )
 
 func TestBatchOperationsIntegration(t *testing.T) {
@@ -49,7 +57,7 @@ func testLabelOperations(t *testing.T, trelloClient *client.Client) {
 		ID:       "invalid-label-id", // This should fail
 	}
 
-	result, err := processLabelOperation(trelloClient, op)
+	result, err := processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid label ID")
 	}
@@ -67,7 +75,7 @@ func testLabelOperations(t *testing.T, trelloClient *client.Client) {
 		},
 	}
 
-	result, err = processLabelOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid board ID")
 	}
@@ -84,7 +92,7 @@ func testLabelOperations(t *testing.T, trelloClient *client.Client) {
 		},
 	}
 
-	result, err = processLabelOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid card ID")
 	}
@@ -97,7 +105,7 @@ func testLabelOperations(t *testing.T, trelloClient *client.Client) {
 		Action:   "invalid",
 	}
 
-	result, err = processLabelOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for unsupported action")
 	}
@@ -113,7 +121,7 @@ func testChecklistOperations(t *testing.T, trelloClient *client.Client) {
 		ID:       "invalid-checklist-id", // This should fail
 	}
 
-	result, err := processChecklistOperation(trelloClient, op)
+	result, err := processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid checklist ID")
 	}
@@ -130,7 +138,7 @@ func testChecklistOperations(t *testing.T, trelloClient *client.Client) {
 		},
 	}
 
-	result, err = processChecklistOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid card ID")
 	}
@@ -147,7 +155,7 @@ func testChecklistOperations(t *testing.T, trelloClient *client.Client) {
 		},
 	}
 
-	result, err = processChecklistOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid checklist ID")
 	}
@@ -160,7 +168,7 @@ func testChecklistOperations(t *testing.T, trelloClient *client.Client) {
 		Action:   "invalid",
 	}
 
-	result, err = processChecklistOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for unsupported action")
 	}
@@ -176,7 +184,7 @@ func testMemberOperations(t *testing.T, trelloClient *client.Client) {
 		ID:       "invalid-member-id", // This should fail
 	}
 
-	result, err := processMemberOperation(trelloClient, op)
+	result, err := processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid member ID")
 	}
@@ -190,7 +198,7 @@ func testMemberOperations(t *testing.T, trelloClient *client.Client) {
 		ID:       "invalid-member-id", // This should fail
 	}
 
-	result, err = processMemberOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid member ID")
 	}
@@ -203,7 +211,7 @@ func testMemberOperations(t *testing.T, trelloClient *client.Client) {
 		Action:   "invalid",
 	}
 
-	result, err = processMemberOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for unsupported action")
 	}
@@ -221,7 +229,7 @@ func testAttachmentOperations(t *testing.T, trelloClient *client.Client) {
 		},
 	}
 
-	result, err := processAttachmentOperation(trelloClient, op)
+	result, err := processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid card ID")
 	}
@@ -238,7 +246,7 @@ func testAttachmentOperations(t *testing.T, trelloClient *client.Client) {
 		},
 	}
 
-	result, err = processAttachmentOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for invalid card ID")
 	}
@@ -251,7 +259,7 @@ func testAttachmentOperations(t *testing.T, trelloClient *client.Client) {
 		Action:   "invalid",
 	}
 
-	result, err = processAttachmentOperation(trelloClient, op)
+	result, err = processOperation(trelloClient, op)
 	if err == nil {
 		t.Error("expected error for unsupported action")
 	}
@@ -342,7 +350,7 @@ func TestBatchFileIntegration(t *testing.T) {
 	}
 
 	// Test result formatting
-	jsonResult, err := processor.FormatResults("json")
+	jsonResult, err := processor.FormatResults("json", formatter.Options{})
 	if err != nil {
 		t.Errorf("failed to format results as JSON: %v", err)
 	}
@@ -352,7 +360,7 @@ func TestBatchFileIntegration(t *testing.T) {
 		t.Errorf("expected valid JSON output, got error: %v", err)
 	}
 
-	markdownResult, err := processor.FormatResults("markdown")
+	markdownResult, err := processor.FormatResults("markdown", formatter.Options{})
 	if err != nil {
 		t.Errorf("failed to format results as Markdown: %v", err)
 	}
@@ -428,6 +436,19 @@ func TestBatchOperationValidation(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "valid comment operation",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "add",
+				Data: map[string]interface{}{
+					"card_id": "test-card",
+					"text":    "Looks good",
+				},
+			},
+			expectErr: false,
+		},
 		{
 			name: "invalid operation type",
 			operation: batch.Operation{
@@ -486,7 +507,7 @@ func TestProcessBoardOperationValidation(t *testing.T) {
 				Data:     map[string]interface{}{},
 			},
 			expectError: true,
-			errorMsg:    "board name is required",
+			errorMsg:    "name is required for create action",
 		},
 		{
 			name: "Delete board without ID",
@@ -539,7 +560,7 @@ func TestProcessBoardOperationValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processBoardOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -592,7 +613,7 @@ func TestProcessListOperationValidation(t *testing.T) {
 				},
 			},
 			expectError: true,
-			errorMsg:    "list name is required",
+			errorMsg:    "name is required for create action",
 		},
 		{
 			name: "Create list without board_id",
@@ -632,7 +653,7 @@ func TestProcessListOperationValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processListOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -685,7 +706,7 @@ func TestProcessCardOperationValidation(t *testing.T) {
 				},
 			},
 			expectError: true,
-			errorMsg:    "card name is required",
+			errorMsg:    "name is required for create action",
 		},
 		{
 			name: "Create card without list_id",
@@ -774,11 +795,51 @@ func TestProcessCardOperationValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "card ID is required",
 		},
+		{
+			name: "Update card without ID",
+			operation: batch.Operation{
+				Type:     "card",
+				Resource: "card",
+				Action:   "update",
+				ID:       "",
+				Data: map[string]interface{}{
+					"name": "Renamed",
+				},
+			},
+			expectError: true,
+			errorMsg:    "card ID is required",
+		},
+		{
+			name: "Update card without fields",
+			operation: batch.Operation{
+				Type:     "card",
+				Resource: "card",
+				Action:   "update",
+				ID:       "test-card-id",
+				Data:     map[string]interface{}{},
+			},
+			expectError: true,
+			errorMsg:    "at least one field to update is required",
+		},
+		{
+			name: "Update card with invalid due date",
+			operation: batch.Operation{
+				Type:     "card",
+				Resource: "card",
+				Action:   "update",
+				ID:       "test-card-id",
+				Data: map[string]interface{}{
+					"due": "next tuesday",
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid due",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processCardOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -832,7 +893,7 @@ func TestProcessLabelOperationValidation(t *testing.T) {
 				},
 			},
 			expectError: true,
-			errorMsg:    "label name is required",
+			errorMsg:    "name is required for create action",
 		},
 		{
 			name: "Create label without color",
@@ -846,7 +907,7 @@ func TestProcessLabelOperationValidation(t *testing.T) {
 				},
 			},
 			expectError: true,
-			errorMsg:    "label color is required",
+			errorMsg:    "color is required for create action",
 		},
 		{
 			name: "Create label without board_id",
@@ -892,7 +953,7 @@ func TestProcessLabelOperationValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processLabelOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -945,7 +1006,7 @@ func TestProcessChecklistOperationValidation(t *testing.T) {
 				},
 			},
 			expectError: true,
-			errorMsg:    "checklist name is required",
+			errorMsg:    "name is required for create action",
 		},
 		{
 			name: "Create checklist without card_id",
@@ -990,7 +1051,7 @@ func TestProcessChecklistOperationValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processChecklistOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -1030,7 +1091,7 @@ func TestProcessMemberOperationValidation(t *testing.T) {
 				ID:       "",
 			},
 			expectError: true,
-			errorMsg:    "member ID or username is required",
+			errorMsg:    "member ID is required",
 		},
 		{
 			name: "Get member boards without ID",
@@ -1041,7 +1102,7 @@ func TestProcessMemberOperationValidation(t *testing.T) {
 				ID:       "",
 			},
 			expectError: true,
-			errorMsg:    "member ID or username is required",
+			errorMsg:    "member ID is required",
 		},
 		{
 			name: "Unsupported member action",
@@ -1057,7 +1118,7 @@ func TestProcessMemberOperationValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processMemberOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -1130,7 +1191,7 @@ func TestProcessAttachmentOperationValidation(t *testing.T) {
 			operation: batch.Operation{
 				Type:     "attachment",
 				Resource: "attachment",
-				Action:   "delete",
+				Action:   "rename",
 			},
 			expectError: true,
 			errorMsg:    "unsupported attachment action",
@@ -1139,7 +1200,7 @@ func TestProcessAttachmentOperationValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := processAttachmentOperation(trelloClient, tt.operation)
+			_, err := processOperation(trelloClient, tt.operation)
 
 			if tt.expectError {
 				if err == nil {
@@ -1156,6 +1217,166 @@ func TestProcessAttachmentOperationValidation(t *testing.T) {
 	}
 }
 
+// TestProcessCommentOperationValidation tests comment operation validation
+func TestProcessCommentOperationValidation(t *testing.T) {
+	auth := &client.AuthConfig{
+		APIKey: "test-key",
+		Token:  "test-token",
+	}
+	trelloClient := client.NewClient(auth.APIKey, auth.Token)
+
+	tests := []struct {
+		name        string
+		operation   batch.Operation
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name: "List comments without card_id",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "list",
+				Data:     map[string]interface{}{},
+			},
+			expectError: true,
+			errorMsg:    "card_id is required",
+		},
+		{
+			name: "Add comment without text",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "add",
+				Data: map[string]interface{}{
+					"card_id": "test-card",
+				},
+			},
+			expectError: true,
+			errorMsg:    "text is required",
+		},
+		{
+			name: "Edit comment without ID",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "edit",
+				Data: map[string]interface{}{
+					"card_id": "test-card",
+					"text":    "Updated",
+				},
+			},
+			expectError: true,
+			errorMsg:    "comment ID is required",
+		},
+		{
+			name: "Edit comment without text",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "edit",
+				ID:       "test-comment",
+				Data: map[string]interface{}{
+					"card_id": "test-card",
+				},
+			},
+			expectError: true,
+			errorMsg:    "text is required",
+		},
+		{
+			name: "Delete comment without ID",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "delete",
+				Data: map[string]interface{}{
+					"card_id": "test-card",
+				},
+			},
+			expectError: true,
+			errorMsg:    "comment ID is required",
+		},
+		{
+			name: "Unsupported comment action",
+			operation: batch.Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "pin",
+				Data: map[string]interface{}{
+					"card_id": "test-card",
+				},
+			},
+			expectError: true,
+			errorMsg:    "unsupported comment action",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := processOperation(trelloClient, tt.operation)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if tt.errorMsg != "" && !contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestResolveOperation tests reference resolution that needs no API calls
+func TestResolveOperation(t *testing.T) {
+	trelloClient := client.NewClient("test-key", "test-token")
+
+	t.Run("IDs pass through without modifying the original data", func(t *testing.T) {
+		op := batch.Operation{
+			Type:     "card",
+			Resource: "card",
+			Action:   "move",
+			ID:       "5f8b8c8d8e8f8a8b8c8d8e01",
+			Data: map[string]interface{}{
+				"list_id": "5f8b8c8d8e8f8a8b8c8d8e02",
+			},
+		}
+
+		resolved, err := resolveOperation(trelloClient, op)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resolved.ID != op.ID || resolved.Data["list_id"] != op.Data["list_id"] {
+			t.Errorf("Expected IDs to be unchanged, got %+v", resolved)
+		}
+
+		resolved.Data["list_id"] = "changed"
+		if op.Data["list_id"] == "changed" {
+			t.Errorf("resolveOperation should copy the data map")
+		}
+	})
+
+	t.Run("List name without board scope", func(t *testing.T) {
+		op := batch.Operation{
+			Type:     "card",
+			Resource: "card",
+			Action:   "create",
+			Data: map[string]interface{}{
+				"name":    "Test Card",
+				"list_id": "Doing",
+			},
+		}
+
+		_, err := resolveOperation(trelloClient, op)
+		if err == nil || !contains(err.Error(), "board_id") {
+			t.Errorf("Expected error mentioning board_id, got %v", err)
+		}
+	})
+}
+
 // TestProcessOperationRouting tests that operations are routed to correct processors
 func TestProcessOperationRouting(t *testing.T) {
 	auth := &client.AuthConfig{
@@ -1223,6 +1444,557 @@ func TestProcessOperationRouting(t *testing.T) {
 	}
 }
 
+// TestBatchWorkflowOffline runs a whole batch against the in-memory fake API
+// newOfflineClient returns a client of the fake API served at url. Its rate
+// limit, per test, is high enough not to slow the test down.
+func newOfflineClient(t *testing.T, url string) *client.Client {
+	t.Helper()
+	trelloClient, err := client.NewClientWithOptions(t.Name(), t.Name(), client.Options{BaseURL: url + "/1", RateLimit: 1000})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return trelloClient
+}
+
+func TestBatchWorkflowOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	operations := []batch.Operation{
+		{Type: "board", Resource: "board", Action: "create", Data: map[string]interface{}{"name": "Offline"}},
+		{Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Review", "board_id": "Offline"}},
+		{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Write docs", "list_id": "To Do", "board_id": "Offline"}},
+		{Type: "label", Resource: "label", Action: "create", Data: map[string]interface{}{"name": "Docs", "color": "blue", "board_id": "Offline"}},
+		{Type: "label", Resource: "label", Action: "add", Data: map[string]interface{}{"card_id": "Write docs", "label_id": "Docs", "board_id": "Offline"}},
+		{Type: "comment", Resource: "comment", Action: "add", Data: map[string]interface{}{"card_id": "Write docs", "text": "Started", "board_id": "Offline"}},
+		{Type: "card", Resource: "card", Action: "move", ID: "Write docs", Data: map[string]interface{}{"list_id": "Review", "board_id": "Offline"}},
+	}
+
+	processor := batch.NewBatchProcessor(false)
+	processor.ProcessOperations(operations, func(op batch.Operation) (interface{}, error) {
+		return processOperation(trelloClient, op)
+	})
+	for i, result := range processor.GetResults() {
+		if !result.Success {
+			t.Fatalf("Operation %d failed: %s", i, result.Error)
+		}
+	}
+
+	cardID, err := trelloClient.ResolveCard("Offline", "Write docs", client.MatchExact)
+	if err != nil {
+		t.Fatalf("Failed to resolve card: %v", err)
+	}
+	card, err := trelloClient.GetCard(cardID, nil)
+	if err != nil {
+		t.Fatalf("Failed to get card: %v", err)
+	}
+	list, err := trelloClient.GetList(card.IDList, nil)
+	if err != nil {
+		t.Fatalf("Failed to get list: %v", err)
+	}
+	if list.Name != "Review" || len(card.Labels) != 1 || card.Badges.Comments != 1 {
+		t.Errorf("Unexpected card state: list %s, %d label(s), %d comment(s)", list.Name, len(card.Labels), card.Badges.Comments)
+	}
+}
+
+// TestBatchLookupsAfterCreateOffline refers by name to boards, lists and
+// cards created after earlier operations looked up names
+func TestBatchLookupsAfterCreateOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+	board := trello.NewBoard("Existing")
+	if err := trelloClient.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+
+	// Each pass creates objects of its own, named with its suffix
+	operations := func(suffix string) []batch.Operation {
+		return []batch.Operation{
+			{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "First" + suffix, "list_id": "To Do", "board_id": "Existing"}},
+			{Type: "board", Resource: "board", Action: "create", Data: map[string]interface{}{"name": "Created" + suffix}},
+			{Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Review", "board_id": "Created" + suffix}},
+			{Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Later" + suffix, "board_id": "Existing"}},
+			{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Second" + suffix, "list_id": "Later" + suffix, "board_id": "Existing"}},
+			{Type: "card", Resource: "card", Action: "archive", ID: "Second" + suffix, Data: map[string]interface{}{"board_id": "Existing"}},
+		}
+	}
+
+	passes := map[string]func(*client.Client, batch.Operation) (interface{}, error){
+		" (plain)": processOperation,
+		" (transactional)": func(c *client.Client, op batch.Operation) (interface{}, error) {
+			data, _, err := processOperationWithRollback(c, op)
+			return data, err
+		},
+	}
+	for suffix, process := range passes {
+		processor := batch.NewBatchProcessor(false)
+		processor.ProcessOperations(operations(suffix), func(op batch.Operation) (interface{}, error) {
+			return process(trelloClient, op)
+		})
+		for i, result := range processor.GetResults() {
+			if !result.Success {
+				t.Fatalf("Operation %d%s failed: %s", i, suffix, result.Error)
+			}
+		}
+	}
+}
+
+// TestBatchConcurrencyOffline archives cards in parallel against the fake API
+func TestBatchConcurrencyOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient, err := client.NewClientWithOptions("fake", "fake", client.Options{BaseURL: server.URL + "/1", RateLimit: client.DefaultRateLimit})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	board := trello.NewBoard("Cleanup")
+	if err := trelloClient.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+	lists, err := board.GetLists(nil)
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+
+	var operations []batch.Operation
+	for i := 0; i < 6; i++ {
+		card := trello.Card{Name: fmt.Sprintf("Card %d", i), IDList: lists[0].ID}
+		if err := trelloClient.CreateCard(&card, nil); err != nil {
+			t.Fatalf("Failed to create card: %v", err)
+		}
+		operations = append(operations, batch.Operation{Type: "card", Resource: "card", Action: "archive", ID: card.ID})
+	}
+
+	processor := batch.NewBatchProcessor(false)
+	processor.SetConcurrency(3)
+	processor.ProcessOperations(operations, func(op batch.Operation) (interface{}, error) {
+		return processOperation(trelloClient, op)
+	})
+
+	results := processor.GetResults()
+	if len(results) != len(operations) || processor.GetErrorCount() != 0 {
+		t.Fatalf("Expected %d successful results, got %d with %d error(s)", len(operations), len(results), processor.GetErrorCount())
+	}
+	for i, result := range results {
+		if result.Operation.ID != operations[i].ID {
+			t.Errorf("Result %d is for %s, expected %s", i, result.Operation.ID, operations[i].ID)
+		}
+	}
+
+	open, err := board.GetCards(nil)
+	if err != nil {
+		t.Fatalf("Failed to get cards: %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("Expected every card to be archived, %d still open", len(open))
+	}
+}
+
+// TestBatchConcurrencySpeedupOffline times a bulk archive against a fake API
+// that answers updates slowly, with one worker and with several sharing the
+// budget
+func TestBatchConcurrencySpeedupOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	const latency = 50 * time.Millisecond
+	api := fake.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			time.Sleep(latency)
+		}
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	setup := newOfflineClient(t, server.URL)
+	board := trello.NewBoard("Bulk")
+	if err := setup.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+	lists, err := board.GetLists(nil)
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+	var operations []batch.Operation
+	for i := 0; i < 32; i++ {
+		card := trello.Card{Name: fmt.Sprintf("Card %d", i), IDList: lists[0].ID}
+		if err := setup.CreateCard(&card, nil); err != nil {
+			t.Fatalf("Failed to create card: %v", err)
+		}
+		operations = append(operations, batch.Operation{Type: "card", Resource: "card", Action: "archive", ID: card.ID})
+	}
+
+	// Each run archives 16 cards with a budget of its own
+	archive := func(concurrency int, operations []batch.Operation) time.Duration {
+		token := fmt.Sprintf("speedup-%d", concurrency)
+		trelloClient, err := client.NewClientWithOptions(token, token, client.Options{BaseURL: server.URL + "/1", RateLimit: 30})
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		processor := batch.NewBatchProcessor(false)
+		processor.SetConcurrency(concurrency)
+		start := time.Now()
+		processor.ProcessOperations(operations, func(op batch.Operation) (interface{}, error) {
+			return processOperation(trelloClient, op)
+		})
+		elapsed := time.Since(start)
+		if processor.GetErrorCount() != 0 {
+			t.Fatalf("Expected every archive to succeed, got %d error(s)", processor.GetErrorCount())
+		}
+		return elapsed
+	}
+
+	serial := archive(1, operations[:16])
+	concurrent := archive(8, operations[16:])
+	if serial < 16*latency {
+		t.Fatalf("Expected one worker to wait for each response, took %v", serial)
+	}
+	t.Logf("one worker: %v, 8 workers: %v", serial, concurrent)
+	if concurrent > serial/3 {
+		t.Errorf("Expected 8 workers to be at least 3 times faster than one, took %v against %v", concurrent, serial)
+	}
+}
+
+// TestBatchReferencesOffline chains operations through ${ops.<ref>.<field>}
+func TestBatchReferencesOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	operations := []batch.Operation{
+		{Ref: "board", Type: "board", Resource: "board", Action: "create", Data: map[string]interface{}{"name": "Launch"}},
+		{Ref: "list", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Launch Tasks", "board_id": "${ops.board.id}"}},
+		{Ref: "card", Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Announce", "list_id": "${ops.list.id}"}},
+		{Type: "comment", Resource: "comment", Action: "add", Data: map[string]interface{}{"card_id": "${ops.card.id}", "text": "Tracked on ${ops.board.url}"}},
+	}
+	if err := batch.ValidateReferences(operations); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+
+	processor := batch.NewBatchProcessor(false)
+	processor.SetConcurrency(2)
+	processor.ProcessOperations(operations, func(op batch.Operation) (interface{}, error) {
+		return processOperation(trelloClient, op)
+	})
+	for i, result := range processor.GetResults() {
+		if !result.Success {
+			t.Fatalf("Operation %d failed: %s", i, result.Error)
+		}
+	}
+
+	results := processor.GetResults()
+	board := results[0].Data.(*trello.Board)
+	card := results[2].Data.(*trello.Card)
+	if card.IDBoard != board.ID || card.IDList != results[1].Data.(*trello.List).ID {
+		t.Errorf("Expected the card on the new list and board, got board %s list %s", card.IDBoard, card.IDList)
+	}
+
+	comments, err := trelloClient.GetCardComments(card.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Data.Text != "Tracked on "+board.URL {
+		t.Errorf("Expected the comment to carry the board URL, got %d comment(s)", len(comments))
+	}
+}
+
+// TestBatchDryRunOffline plans a batch against the fake API and checks that
+// nothing but GET requests were made
+func TestBatchDryRunOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var methods []string
+	api := fake.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	board := trello.NewBoard("Planning")
+	if err := trelloClient.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+	lists, err := board.GetLists(nil)
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+	card := trello.Card{Name: "Fix login bug", IDList: lists[0].ID}
+	if err := trelloClient.CreateCard(&card, nil); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+
+	operations := []batch.Operation{
+		{Ref: "review", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Review", "board_id": "Planning"}},
+		{Type: "card", Resource: "card", Action: "move", ID: "fix login bug", Data: map[string]interface{}{"list_id": "Review", "board_id": "Planning"}},
+		{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Docs", "list_id": "${ops.review.id}"}},
+		{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "No list"}},
+		{Type: "card", Resource: "card", Action: "update", ID: card.ID, Data: map[string]interface{}{"due": "someday"}},
+		{Type: "label", Resource: "label", Action: "add", Data: map[string]interface{}{"card_id": "Fix login", "label_id": "Missing", "board_id": "Planning"}},
+	}
+
+	methods = nil
+	plan := planBatchOperations(trelloClient, operations)
+	for _, method := range methods {
+		if method != http.MethodGet {
+			t.Fatalf("Expected only GET requests, got %v", methods)
+		}
+	}
+
+	steps := plan.Steps
+	if len(steps) != len(operations) {
+		t.Fatalf("Expected %d steps, got %d", len(operations), len(steps))
+	}
+	for i, expectErrors := range []bool{false, false, false, true, true, true} {
+		if (len(steps[i].Errors) > 0) != expectErrors {
+			t.Errorf("Step %d: expected errors=%v, got %v", i+1, expectErrors, steps[i].Errors)
+		}
+	}
+
+	if r := steps[0].Resolved[0]; r.ID != board.ID || r.Name != "Planning" {
+		t.Errorf("Expected the board to resolve to %s, got %+v", board.ID, r)
+	}
+	if r := steps[1].Resolved; len(r) != 3 || r[1].ID != card.ID || r[2].Note != "created by operation 1" {
+		t.Errorf("Unexpected move resolutions: %+v", r)
+	}
+	if r := steps[2].Resolved[0]; r.Note != "filled in when the batch runs" {
+		t.Errorf("Expected the placeholder to be left for run time, got %+v", r)
+	}
+	if !strings.Contains(strings.Join(steps[3].Errors, "\n"), "list_id is required") {
+		t.Errorf("Expected a missing list_id, got %v", steps[3].Errors)
+	}
+	if !strings.Contains(strings.Join(steps[5].Errors, "\n"), "label_id") {
+		t.Errorf("Expected an unknown label, got %v", steps[5].Errors)
+	}
+
+	lists, _ = board.GetLists(nil)
+	if len(lists) != 3 {
+		t.Errorf("Expected the dry run to leave the board alone, got %d list(s)", len(lists))
+	}
+}
+
+// TestBatchTransactionOffline rolls back a failed transactional batch against
+// the fake API and checks that the board is back to where it started
+func TestBatchTransactionOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	board := trello.NewBoard("Release")
+	if err := trelloClient.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+	lists, err := board.GetLists(nil)
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+	labels, err := board.GetLabels(nil)
+	if err != nil {
+		t.Fatalf("Failed to get labels: %v", err)
+	}
+	existing := trello.Card{Name: "Existing", Desc: "old", IDList: lists[0].ID}
+	if err := trelloClient.CreateCard(&existing, nil); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+
+	on := func(data map[string]interface{}) map[string]interface{} {
+		data["board_id"] = "Release"
+		return data
+	}
+	operations := []batch.Operation{
+		{Type: "list", Resource: "list", Action: "create", Data: on(map[string]interface{}{"name": "Staging"})},
+		{Type: "card", Resource: "card", Action: "create", Data: on(map[string]interface{}{"name": "New", "list_id": "To Do"})},
+		{Type: "card", Resource: "card", Action: "move", ID: "Existing", Data: on(map[string]interface{}{"list_id": "Doing"})},
+		{Type: "card", Resource: "card", Action: "update", ID: existing.ID, Data: map[string]interface{}{"desc": "new", "due": "2026-03-01"}},
+		{Ref: "label", Type: "label", Resource: "label", Action: "create", Data: on(map[string]interface{}{"name": "Hotfix", "color": "red"})},
+		{Type: "label", Resource: "label", Action: "add", Data: map[string]interface{}{"card_id": existing.ID, "label_id": "${ops.label.id}"}},
+		{Type: "comment", Resource: "comment", Action: "add", Data: map[string]interface{}{"card_id": existing.ID, "text": "Moved"}},
+		{Ref: "qa", Type: "checklist", Resource: "checklist", Action: "create", Data: map[string]interface{}{"name": "QA", "card_id": existing.ID}},
+		{Type: "checklist", Resource: "checklist", Action: "add-item", Data: map[string]interface{}{"checklist_id": "${ops.qa.id}", "item_name": "Smoke test"}},
+		{Type: "attachment", Resource: "attachment", Action: "add", Data: map[string]interface{}{"card_id": existing.ID, "url": "https://example.com/notes"}},
+		{Type: "card", Resource: "card", Action: "archive", ID: existing.ID},
+		{Type: "card", Resource: "card", Action: "get", ID: "Missing", Data: on(map[string]interface{}{})},
+	}
+	if err := checkReversible(operations); err != nil {
+		t.Fatalf("Unexpected irreversible operations: %v", err)
+	}
+
+	processor := batch.NewBatchProcessor(false)
+	processor.ProcessTransaction(operations, func(op batch.Operation) (interface{}, *batch.Rollback, error) {
+		return processOperationWithRollback(trelloClient, op)
+	})
+
+	results := processor.GetResults()
+	if len(results) != len(operations) || processor.GetErrorCount() != 1 {
+		t.Fatalf("Expected the last operation to fail, got %d result(s) with %d error(s)", len(results), processor.GetErrorCount())
+	}
+	if processor.GetRollbackCount() != len(operations)-1 || processor.GetRollbackErrorCount() != 0 {
+		for i, result := range results {
+			if result.Rollback != nil && !result.Rollback.Success {
+				t.Errorf("Rollback of operation %d failed: %s", i+1, result.Rollback.Error)
+			}
+		}
+		t.Fatalf("Expected %d operations to be undone, got %d", len(operations)-1, processor.GetRollbackCount())
+	}
+
+	card, err := trelloClient.GetCard(existing.ID, trello.Arguments{"checklists": "all", "attachments": "true"})
+	if err != nil {
+		t.Fatalf("Failed to get card: %v", err)
+	}
+	if card.IDList != lists[0].ID || card.Desc != "old" || card.Due != nil || card.Closed {
+		t.Errorf("Expected the card to be restored, got list %s, desc %q, due %v, closed %v", card.IDList, card.Desc, card.Due, card.Closed)
+	}
+	if len(card.Labels) != 0 || len(card.Checklists) != 0 || len(card.Attachments) != 0 || card.Badges.Comments != 0 {
+		t.Errorf("Expected no labels, checklists, attachments or comments, got %d, %d, %d, %d",
+			len(card.Labels), len(card.Checklists), len(card.Attachments), card.Badges.Comments)
+	}
+
+	openLists, _ := board.GetLists(nil)
+	cards, _ := board.GetCards(nil)
+	boardLabels, _ := board.GetLabels(nil)
+	if len(openLists) != len(lists) || len(cards) != 1 || len(boardLabels) != len(labels) {
+		t.Errorf("Expected the board to be back to %d lists, 1 card and %d labels, got %d, %d and %d",
+			len(lists), len(labels), len(openLists), len(cards), len(boardLabels))
+	}
+}
+
+// TestBatchResumeOffline fails a batch midway against the fake API and
+// resumes it from its journal without creating anything twice
+func TestBatchResumeOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	batchFile := &batch.BatchFile{Operations: []batch.Operation{
+		{Ref: "board", Type: "board", Resource: "board", Action: "create", Data: map[string]interface{}{"name": "Resume"}},
+		{Ref: "list", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Backlog", "board_id": "${ops.board.id}"}},
+		{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "First", "list_id": "${ops.list.id}"}},
+		{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Second", "list_id": "${ops.list.id}"}},
+	}}
+	path := t.TempDir() + "/batch.journal"
+
+	journal, err := batch.CreateJournal(path, batchFile)
+	if err != nil {
+		t.Fatalf("Failed to create journal: %v", err)
+	}
+	processor := batch.NewBatchProcessor(false)
+	processor.SetJournal(journal)
+	processor.ProcessOperations(batchFile.Operations, func(op batch.Operation) (interface{}, error) {
+		if op.Data["name"] == "Second" {
+			return nil, fmt.Errorf("connection reset")
+		}
+		return processOperation(trelloClient, op)
+	})
+	journal.Close()
+	if processor.GetErrorCount() != 1 {
+		t.Fatalf("Expected the last operation to fail, got %d error(s)", processor.GetErrorCount())
+	}
+
+	journal, err = batch.OpenJournal(path)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer journal.Close()
+	processor = batch.NewBatchProcessor(false)
+	if err := processor.Resume(journal); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	processor.ProcessOperations(journal.Batch().Operations, func(op batch.Operation) (interface{}, error) {
+		return processOperation(trelloClient, op)
+	})
+	if processor.GetErrorCount() != 0 || processor.GetSkippedCount() != 3 {
+		t.Fatalf("Expected 3 skipped operations and no errors, got %d and %d", processor.GetSkippedCount(), processor.GetErrorCount())
+	}
+
+	list, err := trelloClient.GetList(processor.GetResults()[3].Data.(*trello.Card).IDList, nil)
+	if err != nil {
+		t.Fatalf("Failed to get list: %v", err)
+	}
+	cards, err := list.GetCards(nil)
+	if err != nil {
+		t.Fatalf("Failed to get cards: %v", err)
+	}
+	if len(cards) != 2 {
+		t.Errorf("Expected both cards on the list created by the first run, got %d", len(cards))
+	}
+}
+
+// TestBatchUpsertOffline runs a batch file using the upsert aliases twice:
+// the second run finds everything the first one created
+func TestBatchUpsertOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	data := []byte(`operations:
+  - {ref: board, type: board, resource: board, action: upsert, data: {name: Upsert}}
+  - {ref: list, type: list, resource: list, action: upsert, data: {name: Backlog, board_id: "${ops.board.id}"}}
+  - {type: card, resource: card, action: upsert, data: {name: Task, list_id: "${ops.list.id}"}}
+  - {type: label, resource: label, action: upsert, data: {name: Bug, color: red, board_id: "${ops.board.id}"}}
+`)
+	if err := batch.ValidateBatch(data, checkOperation).Err(); err != nil {
+		t.Fatalf("Expected the batch file to be valid: %v", err)
+	}
+	batchFile, err := batch.LoadBatchFromReader(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Failed to load batch file: %v", err)
+	}
+	if err := batch.ValidateReferences(batchFile.Operations); err != nil {
+		t.Fatalf("Invalid references: %v", err)
+	}
+
+	for _, status := range []string{ensureCreated, ensureUnchanged} {
+		processor := batch.NewBatchProcessor(false)
+		processor.ProcessOperations(batchFile.Operations, func(op batch.Operation) (interface{}, error) {
+			return processOperation(trelloClient, op)
+		})
+		for i, result := range processor.GetResults() {
+			if !result.Success {
+				t.Fatalf("Operation %d failed: %s", i, result.Error)
+			}
+			if e, ok := result.Data.(*ensured); !ok || e.Status != status {
+				t.Errorf("Operation %d: expected %s, got %+v", i, status, result.Data)
+			}
+		}
+	}
+}
+
+func TestCheckReversible(t *testing.T) {
+	err := checkReversible([]batch.Operation{
+		{Type: "card", Action: "archive"},
+		{Type: "card", Action: "delete"},
+		{Type: "board", Action: "delete"},
+	})
+	if err == nil {
+		t.Fatal("Expected deletes to be rejected")
+	}
+	for _, expected := range []string{"operation 2: card delete cannot be undone", "operation 3: board delete cannot be undone"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in %q", expected, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), "operation 1") {
+		t.Errorf("Expected archive to be reversible, got %q", err.Error())
+	}
+}
+
 // Helper function to check if string contains substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsHelper(s, substr))
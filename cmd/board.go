@@ -2,203 +2,302 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var boardListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List all boards",
-	Long:  "List all boards accessible to the authenticated user.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		// Get current member
-		member, err := trelloClient.GetMember("me", nil)
-		if err != nil {
-			return fmt.Errorf("failed to get current member: %w", err)
-		}
-
-		// Get boards
-		boards, err := member.GetBoards(nil)
-		if err != nil {
-			return fmt.Errorf("failed to get boards: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatBoards(boards)
-		if err != nil {
-			return err
-		}
+// boardUpdateFields are the keys of a board update
+var boardUpdateFields = updateFields{strings: []string{"name", "desc"}, bools: []string{"closed"}}
+
+var boardOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list"},
+		use:      "list",
+		short:    "List all boards",
+		long:     "List all boards accessible to the authenticated user.",
+		examples: []string{"trello-cli board list", "trello-cli board list --format json", "trello-cli board list --fields name,desc,url"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			member, err := trelloClient.GetMember("me", nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current member: %w", err)
+			}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			boards, err := member.GetBoards(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get boards: %w", err)
+			}
+			return boards, nil
+		},
 	},
-}
-
-var boardGetCmd = &cobra.Command{
-	Use:   "get <board-id>",
-	Short: "Get board details",
-	Long:  "Get detailed information about a specific board.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		boardID := args[0]
-		board, err := trelloClient.GetBoard(boardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatBoard(board)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+	{
+		Action:   batch.Action{Name: "get", Required: []string{"id"}},
+		use:      "get <board>",
+		short:    "Get board details",
+		long:     "Get detailed information about a specific board.",
+		examples: []string{"trello-cli board get 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli board get <board-id> --format json"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, URL or name of the board to retrieve"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board, err := trelloClient.GetBoard(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
+			return board, nil
+		},
 	},
-}
-
-var boardCreateCmd = &cobra.Command{
-	Use:   "create <name>",
-	Short: "Create a new board",
-	Long:  "Create a new Trello board with the specified name.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		boardName := args[0]
-		board := trello.NewBoard(boardName)
-
-		// Set description if provided
-		desc, _ := cmd.Flags().GetString("desc")
-		if desc != "" {
-			board.Desc = desc
-		}
-
-		err = trelloClient.CreateBoard(&board, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create board: %w", err)
-		}
+	{
+		Action:   batch.Action{Name: "create", Required: []string{"name"}, Optional: []string{"desc", "default_lists"}, Mutating: true},
+		use:      "create <name>",
+		short:    "Create a new board",
+		long:     "Create a new Trello board with the specified name. Like on Trello, it gets the To Do, Doing and Done lists unless --default-lists=false is given.",
+		examples: []string{"trello-cli board create \"My New Board\"", "trello-cli board create \"Project Board\" --desc \"Board for project management\"", "trello-cli board create \"Empty Board\" --default-lists=false"},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":          "Name of the board to create",
+			"desc":          "Board description",
+			"default_lists": "Create the To Do, Doing and Done lists (default true)",
+		},
+		check: checkDefaultLists,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return createBoard(trelloClient, op.Data)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			return deleteCreatedBoard(result.(*trello.Board))
+		},
+	},
+	{
+		Action:   batch.Action{Name: "ensure", Aliases: []string{"upsert"}, Required: []string{"name"}, Optional: []string{"desc", "default_lists"}, Mutating: true},
+		use:      "ensure <name>",
+		short:    "Create a board unless you have it",
+		long:     "Find your open board with the name, ignoring case, and create it only if there is none, so that running it again changes nothing. A --desc that differs replaces the description of an existing board; --default-lists only applies to a new one. The result reports whether the board was created, updated or unchanged.",
+		examples: []string{"trello-cli board ensure \"Project Board\" --desc \"Board for project management\"", "trello-cli board ensure \"Sprint 42\" --default-lists=false"},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":          "Name of the board to find or create",
+			"desc":          "Board description",
+			"default_lists": "Create the To Do, Doing and Done lists on a new board (default true)",
+		},
+		check: checkDefaultLists,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			member, err := trelloClient.GetMember("me", nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current member: %w", err)
+			}
+			boards, err := member.GetBoards(trello.Arguments{"filter": "open"})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get boards: %w", err)
+			}
 
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
+			name := op.Data["name"].(string)
+			i, err := findByName("board", name, len(boards), func(i int) string { return boards[i].Name })
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				board, err := createBoard(trelloClient, op.Data)
+				if err != nil {
+					return nil, err
+				}
+				return &ensured{Status: ensureCreated, Object: board, kind: "board", name: name}, nil
+			}
 
-		output, err := f.FormatBoard(&board)
-		if err != nil {
-			return err
-		}
+			board := boards[i]
+			result := &ensured{Status: ensureUnchanged, Object: board, kind: "board", name: board.Name}
+			desc, ok := op.Data["desc"].(string)
+			if !ok || desc == board.Desc {
+				return result, nil
+			}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			previous := map[string]interface{}{"desc": board.Desc}
+			updated, err := trelloClient.UpdateBoard(board.ID, trello.Arguments{"desc": desc})
+			if err != nil {
+				return nil, fmt.Errorf("failed to update board: %w", err)
+			}
+			result.Status, result.Object, result.previous = ensureUpdated, updated, previous
+			return result, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			e := result.(*ensured)
+			board := e.Object.(*trello.Board)
+			switch e.Status {
+			case ensureCreated:
+				return deleteCreatedBoard(board)
+			case ensureUpdated:
+				return rollback("board", "update", board.ID, e.previous, func() (interface{}, error) {
+					args, err := boardUpdateFields.args(e.previous)
+					if err != nil {
+						return nil, err
+					}
+					return trelloClient.UpdateBoard(board.ID, args)
+				})
+			}
+			return nil
+		},
 	},
-}
+	{
+		Action:   batch.Action{Name: "update", Required: []string{"id"}, Optional: boardUpdateFields.keys(), Mutating: true},
+		use:      "update <board>",
+		short:    "Update a board",
+		long:     "Update the name, description or closed state of a board. Only the flags that are given are sent.",
+		examples: []string{"trello-cli board update <board-id> --name \"Renamed board\"", "trello-cli board update \"Old Project\" --closed"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":     "ID, URL or name of the board to update",
+			"name":   "New board name",
+			"desc":   "New board description",
+			"closed": "Close (true) or reopen (false) the board",
+		},
+		check: boardUpdateFields.check,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			args, err := boardUpdateFields.args(op.Data)
+			if err != nil {
+				return nil, err
+			}
 
-var boardDeleteCmd = &cobra.Command{
-	Use:   "delete <board-id>",
-	Short: "Delete a board",
-	Long:  "Delete a Trello board permanently.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
+			board, err := trelloClient.UpdateBoard(op.ID, args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update board: %w", err)
+			}
+			return board, nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board, err := trelloClient.GetBoard(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
+			return board, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			board := before.(*trello.Board)
+			previous := previousValues(map[string]interface{}{
+				"name":   board.Name,
+				"desc":   board.Desc,
+				"closed": board.Closed,
+			}, op.Data)
+			return rollback("board", "update", op.ID, previous, func() (interface{}, error) {
+				args, err := boardUpdateFields.args(previous)
+				if err != nil {
+					return nil, err
+				}
+				return trelloClient.UpdateBoard(op.ID, args)
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete", Required: []string{"id"}, Mutating: true},
+		use:      "delete <board>",
+		short:    "Delete a board",
+		long:     "Delete a Trello board permanently.",
+		examples: []string{"trello-cli board delete 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, URL or name of the board to delete"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board, err := trelloClient.GetBoard(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
 
-		boardID := args[0]
-		board, err := trelloClient.GetBoard(boardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
+			err = board.Delete(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete board: %w", err)
+			}
+			return success("Board '%s' deleted successfully", board.Name), nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "add-member", Required: []string{"id", "email"}, Mutating: true},
+		use:      "add-member <board> <email>",
+		short:    "Add a member to a board",
+		long:     "Add a member to a board by email address.",
+		examples: []string{"trello-cli board add-member 5f8b8c8d8e8f8a8b8c8d8e8f user@example.com"},
+		args:     []string{"id", "email"},
+		fields: map[string]string{
+			"id":    "ID, URL or name of the board",
+			"email": "Email address of the member to add",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			email := op.Data["email"].(string)
+			board, err := trelloClient.GetBoard(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
 
-		err = board.Delete(nil)
-		if err != nil {
-			return fmt.Errorf("failed to delete board: %w", err)
-		}
+			member := trello.Member{Email: email}
+			_, err = board.AddMember(&member, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add member: %w", err)
+			}
+			return success("Member %s added to board '%s'", email, board.Name), nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "remove-member", Required: []string{"id", "member_id"}, Mutating: true},
+		use:      "remove-member <board> <member>",
+		short:    "Remove a member from a board",
+		long:     "Remove a member from a board by username or ID.",
+		examples: []string{"trello-cli board remove-member 5f8b8c8d8e8f8a8b8c8d8e8f john_doe"},
+		args:     []string{"id", "member_id"},
+		fields: map[string]string{
+			"id":        "ID, URL or name of the board",
+			"member_id": "Username or ID of the member to remove",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board, err := trelloClient.GetBoard(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
+			member, err := trelloClient.GetMember(op.Data["member_id"].(string), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get member: %w", err)
+			}
 
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
+			err = trelloClient.RemoveBoardMember(board.ID, member.ID)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to remove member: %w", err)
 			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Board '%s' deleted successfully", board.Name)))
-		}
-		return nil
+			return success("Member %s removed from board '%s'", member.Username, board.Name), nil
+		},
 	},
 }
 
-var boardAddMemberCmd = &cobra.Command{
-	Use:   "add-member <board-id> <email>",
-	Short: "Add a member to a board",
-	Long:  "Add a member to a board by email address.",
-	Args:  cobra.ExactArgs(2),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		boardID := args[0]
-		email := args[1]
+// createBoard creates the board of create and ensure data
+func createBoard(trelloClient *client.Client, data map[string]interface{}) (*trello.Board, error) {
+	board := trello.NewBoard(data["name"].(string))
+	if desc, ok := data["desc"].(string); ok {
+		board.Desc = desc
+	}
+	args := trello.Arguments{}
+	if val, ok := data["default_lists"]; ok {
+		defaultLists, _ := parseBool(val)
+		args["defaultLists"] = strconv.FormatBool(defaultLists)
+	}
 
-		board, err := trelloClient.GetBoard(boardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
+	err := trelloClient.CreateBoard(&board, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create board: %w", err)
+	}
+	return &board, nil
+}
 
-		member := trello.Member{Email: email}
-		_, err = board.AddMember(&member, nil)
-		if err != nil {
-			return fmt.Errorf("failed to add member: %w", err)
+// checkDefaultLists checks the optional default_lists of create data
+func checkDefaultLists(data map[string]interface{}) error {
+	if val, ok := data["default_lists"]; ok {
+		if _, err := parseBool(val); err != nil {
+			return fmt.Errorf("default_lists must be true or false")
 		}
+	}
+	return nil
+}
 
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-			if err != nil {
-				return err
-			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Member %s added to board '%s'", email, board.Name)))
-		}
-		return nil
-	},
+// deleteCreatedBoard undoes the creation of a board
+func deleteCreatedBoard(board *trello.Board) *batch.Rollback {
+	return rollback("board", "delete", board.ID, nil, func() (interface{}, error) {
+		return noResult(board.Delete())
+	})
 }
 
 func init() {
@@ -208,13 +307,8 @@ func init() {
 		Long:  "Commands for managing Trello boards including listing, creating, updating, and deleting boards.",
 	}
 
-	boardCmd.AddCommand(boardListCmd)
-	boardCmd.AddCommand(boardGetCmd)
-	boardCmd.AddCommand(boardCreateCmd)
-	boardCmd.AddCommand(boardDeleteCmd)
-	boardCmd.AddCommand(boardAddMemberCmd)
-
-	boardCreateCmd.Flags().String("desc", "", "Board description")
+	registerOperations("board", boardOperations...)
+	addOperationCommands(boardCmd, "board")
 
 	rootCmd.AddCommand(boardCmd)
 }
@@ -2,289 +2,472 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var cardListCmd = &cobra.Command{
-	Use:   "list --list <list-id>",
-	Short: "List all cards in a list",
-	Long:  "List all cards in a specific list.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		listID, _ := cmd.Flags().GetString("list")
-		if listID == "" {
-			return fmt.Errorf("list ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		list, err := trelloClient.GetList(listID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get list: %w", err)
-		}
-
-		cards, err := list.GetCards(nil)
-		if err != nil {
-			return fmt.Errorf("failed to get cards: %w", err)
-		}
+// cardUpdateFields are the keys of a card update
+var cardUpdateFields = updateFields{
+	strings: []string{"name", "desc"},
+	dates:   []string{"due", "start"},
+	pos:     true,
+	bools:   []string{"due_complete", "closed"},
+}
 
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
+// getCard returns a card for the actions that work on one
+func getCard(trelloClient *client.Client, cardID string) (*trello.Card, error) {
+	card, err := trelloClient.GetCard(cardID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card: %w", err)
+	}
+	return card, nil
+}
 
-		output, err := f.FormatCards(cards)
-		if err != nil {
-			return err
-		}
+var cardOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list", Required: []string{"list_id"}},
+		use:      "list --list <list>",
+		short:    "List all cards in a list",
+		long:     "List all cards in a specific list.",
+		examples: []string{"trello-cli card list --list 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli card list --list <list-id> --fields name,desc,due"},
+		fields:   map[string]string{"list_id": "ID or name of the list to list cards from"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			list, err := trelloClient.GetList(op.Data["list_id"].(string), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get list: %w", err)
+			}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			cards, err := list.GetCards(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cards: %w", err)
+			}
+			return cards, nil
+		},
 	},
-}
-
-var cardGetCmd = &cobra.Command{
-	Use:   "get <card-id>",
-	Short: "Get card details",
-	Long:  "Get detailed information about a specific card.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
+	{
+		Action:   batch.Action{Name: "get", Required: []string{"id"}},
+		use:      "get <card>",
+		short:    "Get card details",
+		long:     "Get detailed information about a specific card.",
+		examples: []string{"trello-cli card get 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli card get <card-id> --format json"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, URL or name of the card to retrieve"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return getCard(trelloClient, op.ID)
+		},
+	},
+	{
+		Action:   batch.Action{Name: "create", Required: []string{"name", "list_id"}, Optional: []string{"desc", "pos"}, Mutating: true},
+		use:      "create --list <list> <name>",
+		short:    "Create a new card",
+		long:     "Create a new card in a specific list.",
+		examples: []string{"trello-cli card create --list 5f8b8c8d8e8f8a8b8c8d8e8f \"My New Card\"", "trello-cli card create --list <list-id> \"Task Card\" --desc \"Description of the task\"", "trello-cli card create --board \"Sprint 42\" --list \"Doing\" \"Task Card\""},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":    "Name of the card to create",
+			"list_id": "ID or name of the list to create the card in",
+			"desc":    "Card description",
+			"pos":     "Position in the list (top, bottom or a number)",
+		},
+		check: checkPos,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return createCard(trelloClient, op.Data)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			return deleteCreatedCard(result.(*trello.Card))
+		},
+	},
+	{
+		Action:   batch.Action{Name: "ensure", Aliases: []string{"upsert"}, Required: []string{"name", "list_id"}, Optional: cardEnsureFields.keys(), Mutating: true},
+		use:      "ensure --list <list> <name>",
+		short:    "Create a card unless the list has it",
+		long:     "Find the open card with the name on the list, ignoring case, and create it only if there is none, so that running it again changes nothing. The description, dates and due complete state that are given and differ are updated on an existing card. The result reports whether the card was created, updated or unchanged.",
+		examples: []string{"trello-cli card ensure --board \"Sprint 42\" --list Backlog \"Release checklist\" --desc \"Steps for every release\"", "trello-cli card ensure --list <list-id> \"Renew certificate\" --due 2026-12-01"},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":         "Name of the card to find or create",
+			"list_id":      "ID or name of the list the card is on",
+			"desc":         "Card description",
+			"due":          "Due date (YYYY-MM-DD or RFC3339, empty or \"none\" to clear)",
+			"start":        "Start date (YYYY-MM-DD or RFC3339, empty or \"none\" to clear)",
+			"due_complete": "Mark the due date as complete",
+		},
+		check: func(data map[string]interface{}) error {
+			_, err := cardEnsureValues(data)
 			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			wanted, err := cardEnsureValues(op.Data)
+			if err != nil {
+				return nil, err
+			}
 
-		cardID := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
+			list := &trello.List{ID: op.Data["list_id"].(string)}
+			list.SetClient(trelloClient.Client)
+			cards, err := list.GetCards(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cards: %w", err)
+			}
 
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
+			name := op.Data["name"].(string)
+			i, err := findByName("card", name, len(cards), func(i int) string { return cards[i].Name })
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				card, err := createCard(trelloClient, op.Data)
+				if err != nil {
+					return nil, err
+				}
+				return &ensured{Status: ensureCreated, Object: card, kind: "card", name: name}, nil
+			}
 
-		output, err := f.FormatCard(card)
-		if err != nil {
-			return err
-		}
+			card := cards[i]
+			result := &ensured{Status: ensureUnchanged, Object: card, kind: "card", name: card.Name}
+			changed, previous := changedValues(wanted, previousCardValues(card, wanted))
+			if len(changed) == 0 {
+				return result, nil
+			}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			args, err := cardUpdateArgs(changed)
+			if err != nil {
+				return nil, err
+			}
+			if err := card.Update(args); err != nil {
+				return nil, fmt.Errorf("failed to update card: %w", err)
+			}
+			result.Status, result.previous = ensureUpdated, previous
+			return result, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			e := result.(*ensured)
+			card := e.Object.(*trello.Card)
+			switch e.Status {
+			case ensureCreated:
+				return deleteCreatedCard(card)
+			case ensureUpdated:
+				return rollback("card", "update", card.ID, e.previous, func() (interface{}, error) {
+					args, err := cardUpdateArgs(e.previous)
+					if err != nil {
+						return nil, err
+					}
+					return card, card.Update(args)
+				})
+			}
+			return nil
+		},
 	},
-}
-
-var cardCreateCmd = &cobra.Command{
-	Use:   "create --list <list-id> <name>",
-	Short: "Create a new card",
-	Long:  "Create a new card in a specific list.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		listID, _ := cmd.Flags().GetString("list")
-		if listID == "" {
-			return fmt.Errorf("list ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
+	{
+		Action:   batch.Action{Name: "update", Required: []string{"id"}, Optional: cardUpdateFields.keys(), Mutating: true},
+		use:      "update <card>",
+		short:    "Update a card",
+		long:     "Update the name, description, dates, position or closed state of a card. Only the flags that are given are sent.",
+		examples: []string{"trello-cli card update 5f8b8c8d8e8f8a8b8c8d8e8f --name \"Renamed card\"", "trello-cli card update <card-id> --due 2026-03-01 --pos top", "trello-cli card update <card-id> --due-complete"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":           "ID, URL or name of the card to update",
+			"name":         "New card name",
+			"desc":         "New card description",
+			"due":          "Due date (YYYY-MM-DD or RFC3339, empty or \"none\" to clear)",
+			"start":        "Start date (YYYY-MM-DD or RFC3339, empty or \"none\" to clear)",
+			"pos":          "Position in the list (top, bottom or a number)",
+			"due_complete": "Mark the due date as complete",
+			"closed":       "Archive (true) or unarchive (false) the card",
+		},
+		check: cardUpdateFields.check,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			updateArgs, err := cardUpdateArgs(op.Data)
+			if err != nil {
+				return nil, err
+			}
 
-		cardName := args[0]
-		card := trello.Card{
-			Name:   cardName,
-			IDList: listID,
-		}
+			card, err := getCard(trelloClient, op.ID)
+			if err != nil {
+				return nil, err
+			}
 
-		// Set description if provided
-		desc, _ := cmd.Flags().GetString("desc")
-		if desc != "" {
-			card.Desc = desc
-		}
+			err = card.Update(updateArgs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update card: %w", err)
+			}
+			return card, nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return getCard(trelloClient, op.ID)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			previous := previousCardValues(before.(*trello.Card), op.Data)
+			card := &trello.Card{ID: op.ID}
+			card.SetClient(trelloClient.Client)
+			return rollback("card", "update", op.ID, previous, func() (interface{}, error) {
+				args, err := cardUpdateArgs(previous)
+				if err != nil {
+					return nil, err
+				}
+				return card, card.Update(args)
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "move", Required: []string{"id", "list_id"}, Mutating: true},
+		use:      "move <card> --list <list>",
+		short:    "Move a card to another list",
+		long:     "Move a card from its current list to another list. List names are looked up on the card's board unless --board is given.",
+		examples: []string{"trello-cli card move 5f8b8c8d8e8f8a8b8c8d8e8f --list 5f8b8c8d8e8f8a8b8c8d8e8g", "trello-cli card move <card-id> --list Done"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":      "ID, URL or name of the card to move",
+			"list_id": "ID or name of the target list",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			listID := op.Data["list_id"].(string)
+			card, err := getCard(trelloClient, op.ID)
+			if err != nil {
+				return nil, err
+			}
 
-		err = trelloClient.CreateCard(&card, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create card: %w", err)
-		}
+			err = card.MoveToList(listID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to move card: %w", err)
+			}
+			return success("Card '%s' moved to list %s", card.Name, listID), nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return getCard(trelloClient, op.ID)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			previous := before.(*trello.Card)
+			card := &trello.Card{ID: op.ID}
+			card.SetClient(trelloClient.Client)
+			args := trello.Arguments{"pos": strconv.FormatFloat(previous.Pos, 'f', -1, 64)}
+			return rollback("card", "move", op.ID, map[string]interface{}{"list_id": previous.IDList}, func() (interface{}, error) {
+				return card, card.MoveToList(previous.IDList, args)
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "copy", Required: []string{"id", "list_id"}, Mutating: true},
+		use:      "copy <card> --list <list>",
+		short:    "Copy a card to another list",
+		long:     "Create a copy of a card in another list. List names are looked up on the card's board unless --board is given.",
+		examples: []string{"trello-cli card copy 5f8b8c8d8e8f8a8b8c8d8e8f --list 5f8b8c8d8e8f8a8b8c8d8e8g"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":      "ID, URL or name of the card to copy",
+			"list_id": "ID or name of the target list",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			card, err := getCard(trelloClient, op.ID)
+			if err != nil {
+				return nil, err
+			}
 
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
+			copiedCard, err := card.CopyToList(op.Data["list_id"].(string), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to copy card: %w", err)
+			}
+			return copiedCard, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			card := result.(*trello.Card)
+			return rollback("card", "delete", card.ID, nil, func() (interface{}, error) {
+				return noResult(card.Delete())
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete", Required: []string{"id"}, Mutating: true},
+		use:      "delete <card>",
+		short:    "Delete a card",
+		long:     "Delete a Trello card permanently.",
+		examples: []string{"trello-cli card delete 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, URL or name of the card to delete"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			card, err := getCard(trelloClient, op.ID)
+			if err != nil {
+				return nil, err
+			}
 
-		output, err := f.FormatCard(&card)
-		if err != nil {
-			return err
-		}
+			err = card.Delete()
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete card: %w", err)
+			}
+			return success("Card '%s' deleted successfully", card.Name), nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "archive", Required: []string{"id"}, Mutating: true},
+		use:      "archive <card>",
+		short:    "Archive a card",
+		long:     "Archive a Trello card.",
+		examples: []string{"trello-cli card archive 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, URL or name of the card to archive"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			card, err := getCard(trelloClient, op.ID)
+			if err != nil {
+				return nil, err
+			}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			err = card.Archive()
+			if err != nil {
+				return nil, fmt.Errorf("failed to archive card: %w", err)
+			}
+			return success("Card '%s' archived successfully", card.Name), nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			card := &trello.Card{ID: op.ID}
+			card.SetClient(trelloClient.Client)
+			return rollback("card", "update", op.ID, map[string]interface{}{"closed": false}, func() (interface{}, error) {
+				return card, card.Unarchive()
+			})
+		},
 	},
 }
 
-var cardMoveCmd = &cobra.Command{
-	Use:   "move <card-id> --list <list-id>",
-	Short: "Move a card to another list",
-	Long:  "Move a card from its current list to another list.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		listID, _ := cmd.Flags().GetString("list")
-		if listID == "" {
-			return fmt.Errorf("target list ID is required")
-		}
+// cardEnsureFields are the keys of a card ensure besides its name and list
+var cardEnsureFields = updateFields{
+	strings: []string{"desc"},
+	dates:   []string{"due", "start"},
+	bools:   []string{"due_complete"},
+}
 
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
+// createCard creates the card of create and ensure data on its list
+func createCard(trelloClient *client.Client, data map[string]interface{}) (*trello.Card, error) {
+	card := trello.Card{
+		Name:   data["name"].(string),
+		IDList: data["list_id"].(string),
+	}
+	if desc, ok := data["desc"].(string); ok {
+		card.Desc = desc
+	}
+	args := trello.Arguments{}
+	if pos, ok := data["pos"]; ok {
+		args["pos"], _ = parseCardPos(pos)
+	}
+	dates := make(map[string]interface{})
+	for _, key := range []string{"due", "start", "due_complete"} {
+		if value, ok := data[key]; ok {
+			dates[key] = value
 		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		cardID := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
+	}
+	if len(dates) > 0 {
+		dateArgs, err := cardEnsureFields.args(dates)
 		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
+			return nil, err
 		}
-
-		err = card.MoveToList(listID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to move card: %w", err)
+		for param, value := range dateArgs {
+			args[param] = value
 		}
+	}
 
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-			if err != nil {
-				return err
-			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Card '%s' moved to list %s", card.Name, listID)))
-		}
-		return nil
-	},
+	err := trelloClient.CreateCard(&card, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create card: %w", err)
+	}
+	return &card, nil
 }
 
-var cardCopyCmd = &cobra.Command{
-	Use:   "copy <card-id> --list <list-id>",
-	Short: "Copy a card to another list",
-	Long:  "Create a copy of a card in another list.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		listID, _ := cmd.Flags().GetString("list")
-		if listID == "" {
-			return fmt.Errorf("target list ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
+// deleteCreatedCard undoes the creation of a card
+func deleteCreatedCard(card *trello.Card) *batch.Rollback {
+	return rollback("card", "delete", card.ID, nil, func() (interface{}, error) {
+		return noResult(card.Delete())
+	})
+}
 
-		cardID := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
+// cardEnsureValues returns the values of card ensure data in the form
+// previousCardValues has them, to find the ones that differ
+func cardEnsureValues(data map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, key := range cardEnsureFields.keys() {
+		value, ok := data[key]
+		if !ok {
+			continue
 		}
-
-		_, err = card.CopyToList(listID, nil)
+		args, err := cardEnsureFields.args(map[string]interface{}{key: value})
 		if err != nil {
-			return fmt.Errorf("failed to copy card: %w", err)
-		}
-
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-			if err != nil {
-				return err
+			return nil, err
+		}
+		switch key {
+		case "due", "start":
+			if date := args[key]; date != "null" {
+				values[key] = date
+			} else {
+				values[key] = "none"
 			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Card '%s' copied to list %s", card.Name, listID)))
+		case "due_complete":
+			values[key] = args["dueComplete"] == "true"
+		default:
+			values[key] = value
 		}
-		return nil
-	},
+	}
+	return values, nil
 }
 
-var cardDeleteCmd = &cobra.Command{
-	Use:   "delete <card-id>",
-	Short: "Delete a card",
-	Long:  "Delete a Trello card permanently.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
+// cardUpdateArgs converts card update data into Trello API arguments.
+// Keys follow the batch file convention (name, desc, due, start, due_complete,
+// pos, closed) and only the keys present in data are included.
+func cardUpdateArgs(data map[string]interface{}) (trello.Arguments, error) {
+	return cardUpdateFields.args(data)
+}
 
-		cardID := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
+// parseCardDate converts a date into the RFC3339 form Trello expects.
+// An empty value or "none" clears the date.
+func parseCardDate(value string) (string, error) {
+	if value == "" || strings.EqualFold(value, "none") {
+		return "null", nil
+	}
 
-		err = card.Delete()
-		if err != nil {
-			return fmt.Errorf("failed to delete card: %w", err)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(time.RFC3339), nil
 		}
-
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-			if err != nil {
-				return err
-			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Card '%s' deleted successfully", card.Name)))
-		}
-		return nil
-	},
+	}
+	return "", fmt.Errorf("%q is not a date (use YYYY-MM-DD or RFC3339)", value)
 }
 
-var cardArchiveCmd = &cobra.Command{
-	Use:   "archive <card-id>",
-	Short: "Archive a card",
-	Long:  "Archive a Trello card.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
+// parseCardPos accepts "top", "bottom" or a numeric position
+func parseCardPos(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case string:
+		if v == "top" || v == "bottom" {
+			return v, nil
 		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		cardID := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return v, nil
 		}
+	}
+	return "", fmt.Errorf("pos must be \"top\", \"bottom\" or a number")
+}
 
-		err = card.Archive()
-		if err != nil {
-			return fmt.Errorf("failed to archive card: %w", err)
-		}
+// checkPos checks the optional pos of create data
+func checkPos(data map[string]interface{}) error {
+	if pos, ok := data["pos"]; ok {
+		_, err := parseCardPos(pos)
+		return err
+	}
+	return nil
+}
 
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-			if err != nil {
-				return err
-			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Card '%s' archived successfully", card.Name)))
-		}
-		return nil
-	},
+func parseBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("not a boolean: %v", value)
 }
 
 func init() {
@@ -294,19 +477,9 @@ func init() {
 		Long:  "Commands for managing Trello cards including listing, creating, updating, moving, copying, and deleting cards.",
 	}
 
-	cardCmd.AddCommand(cardListCmd)
-	cardCmd.AddCommand(cardGetCmd)
-	cardCmd.AddCommand(cardCreateCmd)
-	cardCmd.AddCommand(cardMoveCmd)
-	cardCmd.AddCommand(cardCopyCmd)
-	cardCmd.AddCommand(cardDeleteCmd)
-	cardCmd.AddCommand(cardArchiveCmd)
-
-	cardListCmd.Flags().String("list", "", "List ID")
-	cardCreateCmd.Flags().String("list", "", "List ID")
-	cardCreateCmd.Flags().String("desc", "", "Card description")
-	cardMoveCmd.Flags().String("list", "", "Target list ID")
-	cardCopyCmd.Flags().String("list", "", "Target list ID")
+	registerOperations("card", cardOperations...)
+	addOperationCommands(cardCmd, "card")
+	cardCmd.AddCommand(cardCommentCmd)
 
 	rootCmd.AddCommand(cardCmd)
 }
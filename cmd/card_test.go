@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestCardUpdateArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      map[string]interface{}
+		expected  map[string]string
+		expectErr bool
+	}{
+		{
+			name:     "Name only",
+			data:     map[string]interface{}{"name": "Renamed"},
+			expected: map[string]string{"name": "Renamed"},
+		},
+		{
+			name: "Dates are normalized to RFC3339",
+			data: map[string]interface{}{"due": "2026-03-01", "start": "2026-02-01T09:30:00Z"},
+			expected: map[string]string{
+				"due":   "2026-03-01T00:00:00Z",
+				"start": "2026-02-01T09:30:00Z",
+			},
+		},
+		{
+			name:     "Empty due clears the date",
+			data:     map[string]interface{}{"due": ""},
+			expected: map[string]string{"due": "null"},
+		},
+		{
+			name:     "Booleans from batch data",
+			data:     map[string]interface{}{"due_complete": true, "closed": "false"},
+			expected: map[string]string{"dueComplete": "true", "closed": "false"},
+		},
+		{
+			name:     "Numeric position",
+			data:     map[string]interface{}{"pos": float64(1024)},
+			expected: map[string]string{"pos": "1024"},
+		},
+		{
+			name:     "Named position",
+			data:     map[string]interface{}{"pos": "top"},
+			expected: map[string]string{"pos": "top"},
+		},
+		{
+			name:      "Invalid position",
+			data:      map[string]interface{}{"pos": "middle"},
+			expectErr: true,
+		},
+		{
+			name:      "Invalid date",
+			data:      map[string]interface{}{"due": "tomorrow"},
+			expectErr: true,
+		},
+		{
+			name:      "No fields",
+			data:      map[string]interface{}{},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := cardUpdateArgs(tt.data)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(args) != len(tt.expected) {
+				t.Errorf("Expected %d arguments, got %d: %v", len(tt.expected), len(args), args)
+			}
+			for key, want := range tt.expected {
+				if args[key] != want {
+					t.Errorf("Expected %s=%q, got %q", key, want, args[key])
+				}
+			}
+		})
+	}
+}
@@ -4,176 +4,286 @@ import (
 	"fmt"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var checklistListCmd = &cobra.Command{
-	Use:   "list --card <card-id>",
-	Short: "List all checklists on a card",
-	Long:  "List all checklists on a specific card.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cardID, _ := cmd.Flags().GetString("card")
-		if cardID == "" {
-			return fmt.Errorf("card ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		// Fetch card with checklists included
-		card, err := trelloClient.GetCard(cardID, trello.Arguments{
-			"checklists":       "all",
-			"checklist_fields": "all",
-			"checkItem_fields": "all",
-		})
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
-
-		// Get checklists from card fields
-		checklists := card.Checklists
-		if checklists == nil {
-			checklists = []*trello.Checklist{}
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatChecklists(checklists)
-		if err != nil {
-			return err
-		}
+// getCardChecklists returns the checklists of a card with their items
+func getCardChecklists(trelloClient *client.Client, cardID string) ([]*trello.Checklist, error) {
+	// Fetch card with checklists included
+	card, err := trelloClient.GetCard(cardID, trello.Arguments{
+		"checklists":       "all",
+		"checklist_fields": "all",
+		"checkItem_fields": "all",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card: %w", err)
+	}
 
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
-	},
+	// Get checklists from card fields
+	checklists := card.Checklists
+	if checklists == nil {
+		checklists = []*trello.Checklist{}
+	}
+	return checklists, nil
 }
 
-var checklistCreateCmd = &cobra.Command{
-	Use:   "create --card <card-id> <name>",
-	Short: "Create a new checklist",
-	Long:  "Create a new checklist on a specific card.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cardID, _ := cmd.Flags().GetString("card")
-		if cardID == "" {
-			return fmt.Errorf("card ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		checklistName := args[0]
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
-
-		checklist, err := trelloClient.CreateChecklist(card, checklistName, trello.Defaults())
-		if err != nil {
-			return fmt.Errorf("failed to create checklist: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatChecklist(checklist)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+var checklistOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list", Required: []string{"card_id"}},
+		use:      "list --card <card>",
+		short:    "List all checklists on a card",
+		long:     "List all checklists on a specific card.",
+		examples: []string{"trello-cli checklist list --card 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		fields:   map[string]string{"card_id": "ID, URL or name of the card to list checklists from"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return getCardChecklists(trelloClient, op.Data["card_id"].(string))
+		},
 	},
-}
-
-var checklistAddItemCmd = &cobra.Command{
-	Use:   "add-item <checklist-id> <name>",
-	Short: "Add an item to a checklist",
-	Long:  "Add a new item to an existing checklist.",
-	Args:  cobra.ExactArgs(2),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		checklistID := args[0]
-		itemName := args[1]
-
-		checklist, err := trelloClient.GetChecklist(checklistID, trello.Defaults())
-		if err != nil {
-			return fmt.Errorf("failed to get checklist: %w", err)
-		}
-
-		item, err := checklist.CreateCheckItem(itemName, trello.Defaults())
-		if err != nil {
-			return fmt.Errorf("failed to add item: %w", err)
-		}
-		_ = item // Suppress unused variable warning
-
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
+	{
+		Action:   batch.Action{Name: "get", Required: []string{"id"}},
+		use:      "get <checklist-id>",
+		short:    "Get checklist details",
+		long:     "Get detailed information about a specific checklist.",
+		examples: []string{"trello-cli checklist get 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID of the checklist to retrieve"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			checklist, err := trelloClient.GetChecklist(op.ID, nil)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to get checklist: %w", err)
 			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Item '%s' added to checklist '%s'", itemName, checklist.Name)))
-		}
-		return nil
+			return checklist, nil
+		},
 	},
-}
+	{
+		Action:   batch.Action{Name: "create", Required: []string{"name", "card_id"}, Mutating: true},
+		use:      "create --card <card> <name>",
+		short:    "Create a new checklist",
+		long:     "Create a new checklist on a specific card.",
+		examples: []string{"trello-cli checklist create --card 5f8b8c8d8e8f8a8b8c8d8e8f \"Release steps\""},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":    "Name of the checklist to create",
+			"card_id": "ID, URL or name of the card to create the checklist on",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			card, err := getCard(trelloClient, op.Data["card_id"].(string))
+			if err != nil {
+				return nil, err
+			}
 
-var checklistCompleteItemCmd = &cobra.Command{
-	Use:   "complete-item --card <card-id> <check-item-id>",
-	Short: "Mark a checklist item as complete",
-	Long:  "Mark a specific checklist item as complete.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cardID, _ := cmd.Flags().GetString("card")
-		if cardID == "" {
-			return fmt.Errorf("card ID is required")
-		}
+			checklist, err := trelloClient.CreateChecklist(card, op.Data["name"].(string), trello.Defaults())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create checklist: %w", err)
+			}
+			return checklist, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			checklist := result.(*trello.Checklist)
+			return rollback("checklist", "delete", checklist.ID, nil, func() (interface{}, error) {
+				return noResult(trelloClient.DeleteChecklist(checklist.ID))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete", Required: []string{"id"}, Mutating: true},
+		use:      "delete <checklist-id>",
+		short:    "Delete a checklist",
+		long:     "Delete a checklist and all of its items permanently.",
+		examples: []string{"trello-cli checklist delete 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID of the checklist to delete"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			err := trelloClient.DeleteChecklist(op.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete checklist: %w", err)
+			}
+			return success("Checklist %s deleted successfully", op.ID), nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "add-item", Required: []string{"checklist_id", "item_name"}, Mutating: true},
+		use:      "add-item <checklist-id> <name>",
+		short:    "Add an item to a checklist",
+		long:     "Add a new item to an existing checklist.",
+		examples: []string{"trello-cli checklist add-item 5f8b8c8d8e8f8a8b8c8d8e8f \"Tag the release\""},
+		args:     []string{"checklist_id", "item_name"},
+		fields: map[string]string{
+			"checklist_id": "ID of the checklist",
+			"item_name":    "Name of the item to add",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			checklist, err := trelloClient.GetChecklist(op.Data["checklist_id"].(string), trello.Defaults())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get checklist: %w", err)
+			}
 
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
+			item, err := checklist.CreateCheckItem(op.Data["item_name"].(string), trello.Defaults())
+			if err != nil {
+				return nil, fmt.Errorf("failed to add item: %w", err)
+			}
+			if item.IDChecklist == "" {
+				item.IDChecklist = checklist.ID
+			}
+			return item, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			item := result.(*trello.CheckItem)
+			checklistID := op.Data["checklist_id"].(string)
+			return rollback("checklist", "delete-item", item.ID, map[string]interface{}{"checklist_id": checklistID}, func() (interface{}, error) {
+				return noResult(trelloClient.DeleteCheckItem(checklistID, item.ID))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "ensure-item", Aliases: []string{"upsert-item"}, Required: []string{"checklist_id", "item_name"}, Optional: []string{"state"}, Mutating: true},
+		use:      "ensure-item <checklist-id> <name>",
+		short:    "Add an item to a checklist unless it has it",
+		long:     "Find the item with the name in the checklist, ignoring case, and add it only if there is none, so that running it again changes nothing. A --state that differs completes or reopens an existing item. The result reports whether the item was created, updated or unchanged.",
+		examples: []string{"trello-cli checklist ensure-item 5f8b8c8d8e8f8a8b8c8d8e8f \"Tag the release\"", "trello-cli checklist ensure-item <checklist-id> \"Publish\" --state complete"},
+		args:     []string{"checklist_id", "item_name"},
+		fields: map[string]string{
+			"checklist_id": "ID of the checklist",
+			"item_name":    "Name of the item to find or add",
+			"state":        "State of the item (complete or incomplete)",
+		},
+		check: func(data map[string]interface{}) error {
+			if state, ok := data["state"]; ok && state != "complete" && state != "incomplete" {
+				return fmt.Errorf("state must be \"complete\" or \"incomplete\"")
+			}
+			return nil
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			checklist, err := trelloClient.GetChecklist(op.Data["checklist_id"].(string), trello.Defaults())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get checklist: %w", err)
+			}
 
-		checkItemID := args[0]
+			name := op.Data["item_name"].(string)
+			state, _ := op.Data["state"].(string)
+			items := checklist.CheckItems
+			i, err := findByName("checklist item", name, len(items), func(i int) string { return items[i].Name })
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				args := trello.Defaults()
+				if state == "complete" {
+					args["checked"] = "true"
+				}
+				item, err := checklist.CreateCheckItem(name, args)
+				if err != nil {
+					return nil, fmt.Errorf("failed to add item: %w", err)
+				}
+				if item.IDChecklist == "" {
+					item.IDChecklist = checklist.ID
+				}
+				return &ensured{Status: ensureCreated, Object: item, kind: "checklist item", name: name}, nil
+			}
 
-		err = trelloClient.UpdateCheckItemState(cardID, checkItemID, "complete")
-		if err != nil {
-			return fmt.Errorf("failed to complete item: %w", err)
-		}
+			item := &items[i]
+			if item.IDChecklist == "" {
+				item.IDChecklist = checklist.ID
+			}
+			result := &ensured{Status: ensureUnchanged, Object: item, kind: "checklist item", name: item.Name}
+			if state == "" || state == item.State {
+				return result, nil
+			}
 
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
+			err = trelloClient.UpdateCheckItemState(checklist.IDCard, item.ID, state)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to update item: %w", err)
+			}
+			result.Status, result.previous = ensureUpdated, map[string]interface{}{"state": item.State}
+			item.State = state
+			return result, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			e := result.(*ensured)
+			item := e.Object.(*trello.CheckItem)
+			checklistID := op.Data["checklist_id"].(string)
+			switch e.Status {
+			case ensureCreated:
+				return rollback("checklist", "delete-item", item.ID, map[string]interface{}{"checklist_id": checklistID}, func() (interface{}, error) {
+					return noResult(trelloClient.DeleteCheckItem(checklistID, item.ID))
+				})
+			case ensureUpdated:
+				state := e.previous["state"].(string)
+				return rollback("checklist", state+"-item", item.ID, map[string]interface{}{"checklist_id": checklistID}, func() (interface{}, error) {
+					checklist, err := trelloClient.GetChecklist(checklistID, nil)
+					if err != nil {
+						return nil, err
+					}
+					return noResult(trelloClient.UpdateCheckItemState(checklist.IDCard, item.ID, state))
+				})
 			}
-			fmt.Println(f.FormatSuccess("Checklist item marked as complete"))
-		}
-		return nil
+			return nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "complete-item", Required: []string{"id", "card_id"}, Mutating: true},
+		use:      "complete-item --card <card> <check-item-id>",
+		short:    "Mark a checklist item as complete",
+		long:     "Mark a specific checklist item as complete.",
+		examples: []string{"trello-cli checklist complete-item --card 5f8b8c8d8e8f8a8b8c8d8e8f 5f8b8c8d8e8f8a8b8c8d8e90"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":      "ID of the checklist item",
+			"card_id": "ID, URL or name of the card the checklist is on",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			err := trelloClient.UpdateCheckItemState(op.Data["card_id"].(string), op.ID, "complete")
+			if err != nil {
+				return nil, fmt.Errorf("failed to complete item: %w", err)
+			}
+			return success("Checklist item marked as complete"), nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			cardID := op.Data["card_id"].(string)
+			checklists, err := getCardChecklists(trelloClient, cardID)
+			if err != nil {
+				return nil, err
+			}
+			for _, checklist := range checklists {
+				for _, item := range checklist.CheckItems {
+					if item.ID == op.ID {
+						return item.State, nil
+					}
+				}
+			}
+			return nil, fmt.Errorf("checklist item %s not found on card %s", op.ID, cardID)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			if before.(string) == "complete" {
+				// The item was already complete, so nothing changed
+				return nil
+			}
+			cardID := op.Data["card_id"].(string)
+			return rollback("checklist", "incomplete-item", op.ID, map[string]interface{}{"card_id": cardID}, func() (interface{}, error) {
+				return noResult(trelloClient.UpdateCheckItemState(cardID, op.ID, "incomplete"))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete-item", Required: []string{"checklist_id", "id"}, Mutating: true},
+		use:      "delete-item <checklist-id> <check-item-id>",
+		short:    "Delete a checklist item",
+		long:     "Delete an item from a checklist permanently.",
+		examples: []string{"trello-cli checklist delete-item 5f8b8c8d8e8f8a8b8c8d8e8f 5f8b8c8d8e8f8a8b8c8d8e90"},
+		args:     []string{"checklist_id", "id"},
+		fields: map[string]string{
+			"checklist_id": "ID of the checklist",
+			"id":           "ID of the checklist item to delete",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			err := trelloClient.DeleteCheckItem(op.Data["checklist_id"].(string), op.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete item: %w", err)
+			}
+			return success("Checklist item %s deleted successfully", op.ID), nil
+		},
 	},
 }
 
@@ -181,17 +291,11 @@ func init() {
 	checklistCmd := &cobra.Command{
 		Use:   "checklist",
 		Short: "Manage Trello checklists",
-		Long:  "Commands for managing Trello checklists including listing, creating, and adding items to checklists.",
+		Long:  "Commands for managing Trello checklists including listing, creating, deleting, and adding items to checklists.",
 	}
 
-	checklistCmd.AddCommand(checklistListCmd)
-	checklistCmd.AddCommand(checklistCreateCmd)
-	checklistCmd.AddCommand(checklistAddItemCmd)
-	checklistCmd.AddCommand(checklistCompleteItemCmd)
-
-	checklistListCmd.Flags().String("card", "", "Card ID")
-	checklistCreateCmd.Flags().String("card", "", "Card ID")
-	checklistCompleteItemCmd.Flags().String("card", "", "Card ID")
+	registerOperations("checklist", checklistOperations...)
+	addOperationCommands(checklistCmd, "checklist")
 
 	rootCmd.AddCommand(checklistCmd)
 }
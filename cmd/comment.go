@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var cardCommentCmd = &cobra.Command{
+	Use:   "comment",
+	Short: "Manage card comments",
+	Long:  "Commands for reading and writing comments on a Trello card.",
+}
+
+var commentOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list", Required: []string{"card_id"}},
+		use:      "list <card>",
+		short:    "List comments on a card",
+		long:     "List all comments on a specific card, newest first.",
+		examples: []string{"trello-cli card comment list 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"card_id"},
+		fields:   map[string]string{"card_id": "ID, URL or name of the card"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			comments, err := trelloClient.GetCardComments(op.Data["card_id"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get comments: %w", err)
+			}
+			return comments, nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "add", Required: []string{"card_id", "text"}, Mutating: true},
+		use:      "add <card> <text>",
+		short:    "Add a comment to a card",
+		long:     "Add a new comment to a specific card.",
+		examples: []string{"trello-cli card comment add 5f8b8c8d8e8f8a8b8c8d8e8f \"Looks good to me\""},
+		args:     []string{"card_id", "text"},
+		fields: map[string]string{
+			"card_id": "ID, URL or name of the card",
+			"text":    "Text of the comment",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			comment, err := trelloClient.AddCardComment(op.Data["card_id"].(string), op.Data["text"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("failed to add comment: %w", err)
+			}
+			return comment, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			cardID := op.Data["card_id"].(string)
+			comment := result.(*trello.Action)
+			return rollback("comment", "delete", comment.ID, map[string]interface{}{"card_id": cardID}, func() (interface{}, error) {
+				return noResult(trelloClient.DeleteCardComment(cardID, comment.ID))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "edit", Required: []string{"card_id", "id", "text"}, Mutating: true},
+		use:      "edit <card> <comment-id> <text>",
+		short:    "Edit a comment",
+		long:     "Replace the text of an existing comment on a card.",
+		examples: []string{"trello-cli card comment edit 5f8b8c8d8e8f8a8b8c8d8e8f 5f8b8c8d8e8f8a8b8c8d8e90 \"Updated text\""},
+		args:     []string{"card_id", "id", "text"},
+		fields: map[string]string{
+			"card_id": "ID, URL or name of the card",
+			"id":      "ID of the comment to edit",
+			"text":    "New text of the comment",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			comment, err := trelloClient.UpdateCardComment(op.Data["card_id"].(string), op.ID, op.Data["text"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("failed to edit comment: %w", err)
+			}
+			return comment, nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			comments, err := trelloClient.GetCardComments(op.Data["card_id"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get comments: %w", err)
+			}
+			for _, comment := range comments {
+				if comment.ID == op.ID {
+					return comment.Data.Text, nil
+				}
+			}
+			return nil, fmt.Errorf("comment %s not found on card %s", op.ID, op.Data["card_id"])
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			cardID := op.Data["card_id"].(string)
+			text := before.(string)
+			return rollback("comment", "edit", op.ID, map[string]interface{}{"card_id": cardID, "text": text}, func() (interface{}, error) {
+				return trelloClient.UpdateCardComment(cardID, op.ID, text)
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete", Required: []string{"card_id", "id"}, Mutating: true},
+		use:      "delete <card> <comment-id>",
+		short:    "Delete a comment",
+		long:     "Delete a comment from a card permanently.",
+		examples: []string{"trello-cli card comment delete 5f8b8c8d8e8f8a8b8c8d8e8f 5f8b8c8d8e8f8a8b8c8d8e90"},
+		args:     []string{"card_id", "id"},
+		fields: map[string]string{
+			"card_id": "ID, URL or name of the card",
+			"id":      "ID of the comment to delete",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			err := trelloClient.DeleteCardComment(op.Data["card_id"].(string), op.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete comment: %w", err)
+			}
+			return success("Comment %s deleted successfully", op.ID), nil
+		},
+	},
+}
+
+func init() {
+	registerOperations("comment", commentOperations...)
+	addOperationCommands(cardCommentCmd, "comment")
+}
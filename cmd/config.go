@@ -2,29 +2,40 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
 var configSetCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Set configuration values",
-	Long:  "Set configuration values for API credentials and default settings.",
+	Long: `Set configuration values for API credentials and default settings.
+
+Only the flags that are passed are changed; every other setting in the config
+file is kept. With --profile, the values are set in that profile instead of the
+top-level settings.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		apiKey, _ := cmd.Flags().GetString("api-key")
-		token, _ := cmd.Flags().GetString("token")
-		defaultFormat, _ := cmd.Flags().GetString("default-format")
-		maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
 
-		config := &client.Config{
-			APIKey:        apiKey,
-			Token:         token,
-			DefaultFormat: defaultFormat,
-			MaxTokens:     maxTokens,
+		changed, err := setConfigFlags(cmd, config, profile)
+		if err != nil {
+			return err
+		}
+		if changed == 0 {
+			return fmt.Errorf("at least one setting is required (--api-key, --token, --default-format, --max-tokens, --base-url)")
 		}
 
-		err := client.SaveConfig(config)
+		err = client.SaveConfig(config)
 		if err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
@@ -36,12 +47,67 @@ var configSetCmd = &cobra.Command{
 	},
 }
 
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Get a configuration value",
+	Long: `Print the value of a single setting as stored in the config file.
+Unset settings print an empty line. With --profile, the value is read from that profile.
+
+Keys: api_key, token, default_format, max_tokens, base_url`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		value, err := config.Get(profile, args[0])
+		if err != nil {
+			return err
+		}
+
+		if !quiet {
+			fmt.Println(value)
+		}
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a configuration value",
+	Long: `Remove a single setting from the config file so that its default applies again.
+With --profile, the value is removed from that profile.
+
+Keys: api_key, token, default_format, max_tokens, base_url`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := config.Unset(profile, args[0]); err != nil {
+			return err
+		}
+
+		if err := client.SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if !quiet {
+			fmt.Printf("Configuration value %s removed\n", args[0])
+		}
+		return nil
+	},
+}
+
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	Long:  "Display the current configuration settings.",
+	Long:  "Display the current configuration settings as stored in the config file. Use 'config resolve' to see the values commands actually use.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		config, err := client.LoadConfig()
+		config, err := client.ReadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
@@ -50,8 +116,16 @@ var configShowCmd = &cobra.Command{
 			fmt.Printf("Configuration file: %s\n", getConfigPath())
 			fmt.Printf("API Key: %s\n", maskString(config.APIKey))
 			fmt.Printf("Token: %s\n", maskString(config.Token))
-			fmt.Printf("Default Format: %s\n", config.DefaultFormat)
-			fmt.Printf("Max Tokens: %d\n", config.MaxTokens)
+			fmt.Printf("Default Format: %s\n", valueOrDefault(config.DefaultFormat, client.DefaultOutputFormat))
+			fmt.Printf("Max Tokens: %s\n", valueOrDefault(intString(config.MaxTokens), "unlimited"))
+			fmt.Printf("Base URL: %s\n", valueOrDefault(config.BaseURL, client.DefaultBaseURL))
+			fmt.Printf("Active Profile: %s\n", client.SelectProfile(profile, config))
+			if names := config.ProfileNames(); len(names) > 0 {
+				fmt.Printf("Profiles: %s\n", strings.Join(names, ", "))
+			}
+			if names := config.TemplateNames(); len(names) > 0 {
+				fmt.Printf("Templates: %s\n", strings.Join(names, ", "))
+			}
 		}
 		return nil
 	},
@@ -74,6 +148,359 @@ var configPathCmd = &cobra.Command{
 	},
 }
 
+var configResolveCmd = &cobra.Command{
+	Use:   "resolve",
+	Short: "Show which source each setting comes from",
+	Long: `Resolve every setting the way other commands do and show which source won.
+
+Each setting is resolved on its own with precedence: command-line flag, then
+environment variable, then the selected config file profile. Output settings
+missing from a profile fall back to the top-level config file settings, then
+to the built-in defaults.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := client.ResolveSettings(settingFlags(cmd))
+		if err != nil {
+			return err
+		}
+
+		if !quiet {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+			for _, setting := range settings.All() {
+				value := setting.Value
+				if setting.Key == "api_key" || setting.Key == "token" {
+					value = maskString(value)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, value, setting.Source)
+			}
+			return w.Flush()
+		}
+		return nil
+	},
+}
+
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage credential profiles",
+	Long: `Commands for managing named credential profiles in the config file.
+
+A profile holds its own API key, token and default settings. The profile in use
+is picked by --profile, then TRELLO_PROFILE, then the profile selected with
+'config profile use'. The top-level credentials form the "default" profile.`,
+}
+
+var configProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Long:  "List the profiles in the config file. The active profile is marked with *.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := client.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		active := client.SelectProfile(profile, config)
+		names := append([]string{client.DefaultProfile}, config.ProfileNames()...)
+
+		width := 0
+		for _, name := range names {
+			if len(name) > width {
+				width = len(name)
+			}
+		}
+
+		if !quiet {
+			for _, name := range names {
+				p, err := config.Profile(name)
+				if err != nil {
+					return err
+				}
+				marker := " "
+				if name == active {
+					marker = "*"
+				}
+				fmt.Printf("%s %-*s  API Key: %s\n", marker, width, name, maskString(p.APIKey))
+			}
+		}
+		return nil
+	},
+}
+
+var configProfileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or update a profile",
+	Long:  "Add a named profile to the config file, or update the given settings of an existing one.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == client.DefaultProfile {
+			return fmt.Errorf("profile name %q is reserved for the top-level credentials; use 'config set' instead", name)
+		}
+
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		p, exists := config.Profiles[name]
+		if !exists || p == nil {
+			p = &client.Profile{}
+			if config.Profiles == nil {
+				config.Profiles = make(map[string]*client.Profile)
+			}
+			config.Profiles[name] = p
+		}
+
+		if _, err := setConfigFlags(cmd, config, name); err != nil {
+			return err
+		}
+		if p.APIKey == "" || p.Token == "" {
+			return fmt.Errorf("profile %q needs both --api-key and --token", name)
+		}
+
+		use, _ := cmd.Flags().GetBool("use")
+		if use {
+			config.CurrentProfile = name
+		}
+
+		if err := client.SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if !quiet {
+			action := "added"
+			if exists {
+				action = "updated"
+			}
+			fmt.Printf("Profile %q %s\n", name, action)
+		}
+		return nil
+	},
+}
+
+var configProfileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the profile to use by default",
+	Long:  "Make a profile the default for future commands. Use \"default\" to go back to the top-level credentials.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if _, err := config.Profile(name); err != nil {
+			return err
+		}
+
+		config.CurrentProfile = name
+		if name == client.DefaultProfile {
+			config.CurrentProfile = ""
+		}
+
+		if err := client.SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if !quiet {
+			fmt.Printf("Now using profile %q\n", name)
+		}
+		return nil
+	},
+}
+
+var configProfileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a profile",
+	Long:  "Remove a named profile from the config file. If it was selected, the top-level credentials are used again.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == client.DefaultProfile {
+			return fmt.Errorf("the %q profile cannot be removed", name)
+		}
+
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if _, ok := config.Profiles[name]; !ok {
+			return fmt.Errorf("profile %q not found in config file", name)
+		}
+
+		delete(config.Profiles, name)
+		if config.CurrentProfile == name {
+			config.CurrentProfile = ""
+		}
+
+		if err := client.SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if !quiet {
+			fmt.Printf("Profile %q removed\n", name)
+		}
+		return nil
+	},
+}
+
+var configTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage named output templates",
+	Long: `Commands for managing named output templates in the config file.
+
+A named template is used with --template <name>, like an inline template. Templates
+are shared by every profile.`,
+}
+
+var configTemplateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List named templates",
+	Long:  "List the named templates in the config file with their text.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if !quiet {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, name := range config.TemplateNames() {
+				fmt.Fprintf(w, "%s\t%s\n", name, strconv.Quote(config.Templates[name]))
+			}
+			w.Flush()
+		}
+		return nil
+	},
+}
+
+var configTemplateSetCmd = &cobra.Command{
+	Use:   "set <name> <template>",
+	Short: "Add or update a named template",
+	Long: `Store a template in the config file under a name. The template is given inline
+or as @file to read it from a file, and is checked before it is saved.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		text := args[1]
+		if path, ok := strings.CutPrefix(text, "@"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read template: %w", err)
+			}
+			text = string(data)
+		}
+		if _, err := formatter.NewTemplateFormatter(text, 0); err != nil {
+			return err
+		}
+
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		_, exists := config.Templates[name]
+		if config.Templates == nil {
+			config.Templates = make(map[string]string)
+		}
+		config.Templates[name] = text
+
+		if err := client.SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if !quiet {
+			action := "added"
+			if exists {
+				action = "updated"
+			}
+			fmt.Printf("Template %q %s\n", name, action)
+		}
+		return nil
+	},
+}
+
+var configTemplateRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a named template",
+	Long:  "Remove a named template from the config file.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		config, err := client.ReadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if _, ok := config.Templates[name]; !ok {
+			return fmt.Errorf("template %q not found in config file", name)
+		}
+		delete(config.Templates, name)
+
+		if err := client.SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if !quiet {
+			fmt.Printf("Template %q removed\n", name)
+		}
+		return nil
+	},
+}
+
+// setConfigFlags stores the settings given as flags of cmd, such as
+// --default-format, in the named profile (the top-level settings for "") and
+// returns how many there were
+func setConfigFlags(cmd *cobra.Command, config *client.Config, profileName string) (int, error) {
+	changed := 0
+	for _, key := range client.ConfigKeys {
+		flag := strings.ReplaceAll(key, "_", "-")
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+		value := cmd.Flags().Lookup(flag).Value.String()
+		if err := setConfigValue(config, profileName, key, value); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// setConfigValue validates value and stores it under key in the named
+// profile (the top-level settings for "")
+func setConfigValue(config *client.Config, profileName, key, value string) error {
+	if key == "default_format" {
+		if err := formatter.ValidateFormat(value); err != nil {
+			return fmt.Errorf("invalid default_format: %w", err)
+		}
+	}
+	return config.Set(profileName, key, value)
+}
+
+// valueOrDefault returns value, or a note naming the default when it is not set
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return fmt.Sprintf("(not set, defaults to %s)", defaultValue)
+	}
+	return value
+}
+
+func intString(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
 func getConfigPath() string {
 	path, err := client.GetConfigPath()
 	if err != nil {
@@ -97,16 +524,40 @@ func init() {
 		Use:   "config",
 		Short: "Manage configuration",
 		Long:  "Commands for managing Trello CLI configuration including setting credentials and defaults.",
+		// Config commands are how credentials get set up, so they must run without them
+		Annotations: map[string]string{skipAuthAnnotation: "true"},
 	}
 
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configUnsetCmd)
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configResolveCmd)
+	configCmd.AddCommand(configProfileCmd)
+	configCmd.AddCommand(configTemplateCmd)
+
+	configProfileCmd.AddCommand(configProfileListCmd)
+	configProfileCmd.AddCommand(configProfileAddCmd)
+	configProfileCmd.AddCommand(configProfileUseCmd)
+	configProfileCmd.AddCommand(configProfileRemoveCmd)
+
+	configTemplateCmd.AddCommand(configTemplateListCmd)
+	configTemplateCmd.AddCommand(configTemplateSetCmd)
+	configTemplateCmd.AddCommand(configTemplateRemoveCmd)
 
 	configSetCmd.Flags().String("api-key", "", "Trello API key")
 	configSetCmd.Flags().String("token", "", "Trello token")
-	configSetCmd.Flags().String("default-format", "json", "Default output format")
-	configSetCmd.Flags().Int("max-tokens", 4000, "Default maximum tokens")
+	configSetCmd.Flags().String("default-format", "", "Default output format (json, markdown, yaml, csv, tsv, table, template)")
+	configSetCmd.Flags().Int("max-tokens", 0, "Default maximum tokens")
+	configSetCmd.Flags().String("base-url", "", "Trello API base URL, e.g. a fake server")
+
+	configProfileAddCmd.Flags().String("api-key", "", "Trello API key")
+	configProfileAddCmd.Flags().String("token", "", "Trello token")
+	configProfileAddCmd.Flags().String("default-format", "", "Default output format for this profile")
+	configProfileAddCmd.Flags().Int("max-tokens", 0, "Default maximum tokens for this profile")
+	configProfileAddCmd.Flags().String("base-url", "", "Trello API base URL for this profile")
+	configProfileAddCmd.Flags().Bool("use", false, "Also make this the profile used by default")
 
 	rootCmd.AddCommand(configCmd)
 }
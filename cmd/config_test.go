@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/spf13/pflag"
 )
 
 func TestMaskString(t *testing.T) {
@@ -60,3 +65,84 @@ func TestGetConfigPath(t *testing.T) {
 		t.Logf("Config path: %s", path)
 	}
 }
+
+func TestResolveTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := &client.Config{Templates: map[string]string{"brief": "{{.ShortLink}} {{.Name}}"}}
+	if err := client.SaveConfig(config); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "card.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Name}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"brief", "{{.ShortLink}} {{.Name}}"},
+		{"@" + path, "{{.Name}}\n"},
+		{"{{.ID}}", "{{.ID}}"},
+	}
+	for _, tt := range tests {
+		text, err := resolveTemplate(tt.value)
+		if err != nil {
+			t.Errorf("resolveTemplate(%q): unexpected error: %v", tt.value, err)
+		} else if text != tt.expected {
+			t.Errorf("resolveTemplate(%q) = %q, want %q", tt.value, text, tt.expected)
+		}
+	}
+
+	if _, err := resolveTemplate("@" + filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Error("expected an error for a missing template file")
+	}
+}
+
+func TestConfigProfileAddValidation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func(quietBefore bool) { quiet = quietBefore }(quiet)
+	quiet = true
+
+	// run runs profile add with the flags, resetting them afterwards
+	run := func(flags map[string]string) error {
+		t.Helper()
+		defer configProfileAddCmd.Flags().VisitAll(func(f *pflag.Flag) {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+		for name, value := range flags {
+			if err := configProfileAddCmd.Flags().Set(name, value); err != nil {
+				t.Fatalf("failed to set --%s: %v", name, err)
+			}
+		}
+		return configProfileAddCmd.RunE(configProfileAddCmd, []string{"work"})
+	}
+
+	// Profiles follow the rules of config set
+	credentials := map[string]string{"api-key": "key", "token": "token"}
+	for flag, value := range map[string]string{"max-tokens": "-5", "default-format": "xml", "base-url": "ftp://host"} {
+		flags := map[string]string{flag: value}
+		for k, v := range credentials {
+			flags[k] = v
+		}
+		if err := run(flags); err == nil {
+			t.Errorf("expected --%s %s to be rejected", flag, value)
+		}
+	}
+	if config, err := client.ReadConfig(); err != nil || config.Profiles["work"] != nil {
+		t.Errorf("expected no profile to be saved, got %+v, %v", config, err)
+	}
+
+	if err := run(map[string]string{"api-key": "key", "token": "token", "max-tokens": "500", "base-url": "http://localhost:8080/"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := client.ReadConfig()
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if p := config.Profiles["work"]; p == nil || p.MaxTokens != 500 || p.BaseURL != "http://localhost:8080" {
+		t.Errorf("unexpected profile %+v", p)
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/formatter"
+)
+
+// What an ensure action did
+const (
+	ensureCreated   = "created"
+	ensureUpdated   = "updated"
+	ensureUnchanged = "unchanged"
+)
+
+// ensured is the result of an ensure action: the object it found or created,
+// and whether it was created, updated or left unchanged
+type ensured struct {
+	Status string
+	Object interface{}
+
+	// kind and name describe the object in messages
+	kind string
+	name string
+
+	// previous holds the values an update replaced, to undo it
+	previous map[string]interface{}
+}
+
+// MarshalJSON encodes the object with its status among its fields, so that
+// references such as ${ops.<ref>.id} reach the object like after a create
+func (e *ensured) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["status"] = e.Status
+	return json.Marshal(fields)
+}
+
+// message describes what the ensure action did
+func (e *ensured) message() string {
+	kind := strings.ToUpper(e.kind[:1]) + e.kind[1:]
+	switch e.Status {
+	case ensureUpdated:
+		changed := make([]string, 0, len(e.previous))
+		for key := range e.previous {
+			changed = append(changed, key)
+		}
+		sort.Strings(changed)
+		return fmt.Sprintf("%s '%s' updated (%s)", kind, e.name, strings.Join(changed, ", "))
+	case ensureUnchanged:
+		return fmt.Sprintf("%s '%s' already exists, unchanged", kind, e.name)
+	}
+	return fmt.Sprintf("%s '%s' created", kind, e.name)
+}
+
+// formatEnsured formats the result of an ensure action. Markdown and tables
+// state the status above the object; the data formats show it among its
+// fields, and templates get the object alone.
+func formatEnsured(f formatter.Formatter, e *ensured) (string, error) {
+	switch f.(type) {
+	case *formatter.MarkdownFormatter, *formatter.TableFormatter:
+		message := f.FormatSuccess(e.message())
+		if _, ok := e.Object.(*trello.CheckItem); ok {
+			return message, nil
+		}
+		output, err := formatResult(f, e.Object)
+		if err != nil {
+			return "", err
+		}
+		return message + "\n" + output, nil
+	case *formatter.TemplateFormatter:
+		return formatResult(f, e.Object)
+	}
+
+	switch e.Object.(type) {
+	case *trello.Board:
+		return f.FormatBoard(e)
+	case *trello.List:
+		return f.FormatList(e)
+	case *trello.Card:
+		return f.FormatCard(e)
+	case *trello.Label:
+		return f.FormatLabel(e)
+	}
+	// Checklist items are formatted like their checklists
+	return f.FormatChecklist(e)
+}
+
+// findByName returns the index of the one name among n that matches name,
+// ignoring case, or -1 when none does. Ensure actions need the name to be
+// unique within its parent to know which object they are about.
+func findByName(kind, name string, n int, nameAt func(i int) string) (int, error) {
+	found := -1
+	for i := 0; i < n; i++ {
+		if !strings.EqualFold(nameAt(i), name) {
+			continue
+		}
+		if found >= 0 {
+			return -1, fmt.Errorf("more than one %s is named %q; use update with its ID instead", kind, name)
+		}
+		found = i
+	}
+	return found, nil
+}
+
+// changedValues returns the values in wanted that differ from current, and
+// the current values they replace
+func changedValues(wanted, current map[string]interface{}) (changed, previous map[string]interface{}) {
+	changed = make(map[string]interface{})
+	previous = make(map[string]interface{})
+	for key, value := range wanted {
+		if current[key] != value {
+			changed[key] = value
+			previous[key] = current[key]
+		}
+	}
+	return changed, previous
+}
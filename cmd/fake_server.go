@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/danbruder/trello-cli/internal/fake"
+	"github.com/spf13/cobra"
+)
+
+var fakeServerCmd = &cobra.Command{
+	Use:   "fake-server",
+	Short: "Run an in-memory fake Trello API",
+	Long: `Run an in-memory fake of the Trello API for offline testing.
+
+The fake implements the members, boards, lists, cards, labels, checklists,
+attachments and comments endpoints the CLI uses. It accepts any non-empty API
+key and token and forgets everything when it stops. Point other trello-cli
+invocations at it with --base-url or TRELLO_BASE_URL.`,
+	Example: `  trello-cli fake-server --addr 127.0.0.1:8080 &
+  export TRELLO_BASE_URL=http://127.0.0.1:8080/1 TRELLO_API_KEY=fake TRELLO_TOKEN=fake
+  trello-cli batch file workflow.yaml`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{skipAuthAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+
+		baseURL := fmt.Sprintf("http://%s/1", listener.Addr())
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Fake Trello API listening on %s\n", baseURL)
+			fmt.Fprintf(os.Stderr, "export TRELLO_BASE_URL=%s TRELLO_API_KEY=fake TRELLO_TOKEN=fake\n", baseURL)
+		}
+
+		return http.Serve(listener, fake.New())
+	},
+}
+
+func init() {
+	fakeServerCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on (port 0 picks a free port)")
+
+	rootCmd.AddCommand(fakeServerCmd)
+}
@@ -2,166 +2,304 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var labelListCmd = &cobra.Command{
-	Use:   "list --board <board-id>",
-	Short: "List all labels on a board",
-	Long:  "List all labels available on a specific board.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		boardID, _ := cmd.Flags().GetString("board")
-		if boardID == "" {
-			return fmt.Errorf("board ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		board, err := trelloClient.GetBoard(boardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
-
-		labels, err := board.GetLabels(nil)
-		if err != nil {
-			return fmt.Errorf("failed to get labels: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatLabels(labels)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
-	},
+// labelUpdateFields are the keys of a label update
+var labelUpdateFields = updateFields{strings: []string{"name", "color"}}
+
+// labelColors is the usage of the color flags
+const labelColors = "Label color (red, yellow, orange, green, blue, purple, pink, lime, sky, grey)"
+
+// getLabel returns a label for the actions that work on one
+func getLabel(trelloClient *client.Client, labelID string) (*trello.Label, error) {
+	label, err := trelloClient.GetLabel(labelID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get label: %w", err)
+	}
+	return label, nil
 }
 
-var labelCreateCmd = &cobra.Command{
-	Use:   "create --board <board-id> --name <name> --color <color>",
-	Short: "Create a new label",
-	Long:  "Create a new label on a specific board.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		boardID, _ := cmd.Flags().GetString("board")
-		name, _ := cmd.Flags().GetString("name")
-		color, _ := cmd.Flags().GetString("color")
-
-		if boardID == "" {
-			return fmt.Errorf("board ID is required")
-		}
-		if name == "" {
-			return fmt.Errorf("label name is required")
-		}
-		if color == "" {
-			return fmt.Errorf("label color is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		board, err := trelloClient.GetBoard(boardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
-
-		label := trello.Label{
-			Name:  name,
-			Color: color,
-		}
-
-		err = board.CreateLabel(&label, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create label: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatLabel(&label)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+var labelOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list", Required: []string{"board_id"}},
+		use:      "list --board <board>",
+		short:    "List all labels on a board",
+		long:     "List all labels available on a specific board.",
+		examples: []string{"trello-cli label list --board 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		fields:   map[string]string{"board_id": "ID, URL or name of the board to list labels from"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board, err := trelloClient.GetBoard(op.Data["board_id"].(string), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
+
+			labels, err := board.GetLabels(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get labels: %w", err)
+			}
+			return labels, nil
+		},
 	},
-}
+	{
+		Action:   batch.Action{Name: "get", Required: []string{"id"}},
+		use:      "get <label>",
+		short:    "Get label details",
+		long:     "Get detailed information about a specific label. Label names and colors are looked up on the board given with --board.",
+		examples: []string{"trello-cli label get 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli label get --board \"Sprint 42\" Bug"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, name or color of the label to retrieve"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return getLabel(trelloClient, op.ID)
+		},
+	},
+	{
+		Action:   batch.Action{Name: "create", Required: []string{"name", "color", "board_id"}, Mutating: true},
+		use:      "create --board <board> --name <name> --color <color>",
+		short:    "Create a new label",
+		long:     "Create a new label on a specific board.",
+		examples: []string{"trello-cli label create --board 5f8b8c8d8e8f8a8b8c8d8e8f --name Bug --color red"},
+		fields: map[string]string{
+			"name":     "Label name",
+			"color":    labelColors,
+			"board_id": "ID, URL or name of the board to create the label on",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return createLabel(trelloClient, op.Data)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			return deleteCreatedLabel(trelloClient, result.(*trello.Label))
+		},
+	},
+	{
+		Action:   batch.Action{Name: "ensure", Aliases: []string{"upsert"}, Required: []string{"name", "color", "board_id"}, Mutating: true},
+		use:      "ensure --board <board> --name <name> --color <color>",
+		short:    "Create a label unless the board has it",
+		long:     "Find the label with the name and color on the board, ignoring the case of the name, and create it only if there is none, so that running it again changes nothing. When the only label with the name has another color, it is recolored instead. The result reports whether the label was created, updated or unchanged.",
+		examples: []string{"trello-cli label ensure --board \"Sprint 42\" --name Bug --color red"},
+		fields: map[string]string{
+			"name":     "Name of the label to find or create",
+			"color":    labelColors,
+			"board_id": "ID, URL or name of the board the label is on",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board := &trello.Board{ID: op.Data["board_id"].(string)}
+			board.SetClient(trelloClient.Client)
+			labels, err := board.GetLabels(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get labels: %w", err)
+			}
+
+			name, color := op.Data["name"].(string), op.Data["color"].(string)
+			for _, label := range labels {
+				if strings.EqualFold(label.Name, name) && label.Color == color {
+					return &ensured{Status: ensureUnchanged, Object: label, kind: "label", name: label.Name}, nil
+				}
+			}
+
+			i, err := findByName("label", name, len(labels), func(i int) string { return labels[i].Name })
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				label, err := createLabel(trelloClient, op.Data)
+				if err != nil {
+					return nil, err
+				}
+				return &ensured{Status: ensureCreated, Object: label, kind: "label", name: name}, nil
+			}
+
+			previous := map[string]interface{}{"color": labels[i].Color}
+			label, err := trelloClient.UpdateLabel(labels[i].ID, trello.Arguments{"color": color})
+			if err != nil {
+				return nil, fmt.Errorf("failed to update label: %w", err)
+			}
+			return &ensured{Status: ensureUpdated, Object: label, kind: "label", name: label.Name, previous: previous}, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			e := result.(*ensured)
+			label := e.Object.(*trello.Label)
+			switch e.Status {
+			case ensureCreated:
+				return deleteCreatedLabel(trelloClient, label)
+			case ensureUpdated:
+				return rollback("label", "update", label.ID, e.previous, func() (interface{}, error) {
+					args, err := labelUpdateFields.args(e.previous)
+					if err != nil {
+						return nil, err
+					}
+					return trelloClient.UpdateLabel(label.ID, args)
+				})
+			}
+			return nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "update", Required: []string{"id"}, Optional: labelUpdateFields.keys(), Mutating: true},
+		use:      "update <label>",
+		short:    "Update a label",
+		long:     "Update the name or color of a label. Only the flags that are given are sent.",
+		examples: []string{"trello-cli label update 5f8b8c8d8e8f8a8b8c8d8e8f --name Blocker --color purple", "trello-cli label update --board \"Sprint 42\" Bug --color orange"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":    "ID, name or color of the label to update",
+			"name":  "New label name",
+			"color": labelColors,
+		},
+		check: labelUpdateFields.check,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			args, err := labelUpdateFields.args(op.Data)
+			if err != nil {
+				return nil, err
+			}
+
+			label, err := trelloClient.UpdateLabel(op.ID, args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update label: %w", err)
+			}
+			return label, nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return getLabel(trelloClient, op.ID)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			label := before.(*trello.Label)
+			previous := previousValues(map[string]interface{}{
+				"name":  label.Name,
+				"color": label.Color,
+			}, op.Data)
+			return rollback("label", "update", op.ID, previous, func() (interface{}, error) {
+				args, err := labelUpdateFields.args(previous)
+				if err != nil {
+					return nil, err
+				}
+				return trelloClient.UpdateLabel(op.ID, args)
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "add", Required: []string{"card_id", "label_id"}, Mutating: true},
+		use:      "add <card> <label>",
+		short:    "Add a label to a card",
+		long:     "Add an existing label to a specific card. The label can be given by ID, name or color.",
+		examples: []string{"trello-cli label add 5f8b8c8d8e8f8a8b8c8d8e8f Bug"},
+		args:     []string{"card_id", "label_id"},
+		fields: map[string]string{
+			"card_id":  "ID, URL or name of the card",
+			"label_id": "ID, name or color of the label to add",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			labelID := op.Data["label_id"].(string)
+			card, err := getCard(trelloClient, op.Data["card_id"].(string))
+			if err != nil {
+				return nil, err
+			}
+
+			err = card.AddIDLabel(labelID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add label: %w", err)
+			}
+			return success("Label %s added to card '%s'", labelID, card.Name), nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			cardID := op.Data["card_id"].(string)
+			labelID := op.Data["label_id"].(string)
+			return rollback("label", "remove", "", map[string]interface{}{"card_id": cardID, "label_id": labelID}, func() (interface{}, error) {
+				return noResult(trelloClient.RemoveCardLabel(cardID, labelID))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "remove", Required: []string{"card_id", "label_id"}, Mutating: true},
+		use:      "remove <card> <label>",
+		short:    "Remove a label from a card",
+		long:     "Remove a label from a specific card. The label can be given by ID, name or color.",
+		examples: []string{"trello-cli label remove 5f8b8c8d8e8f8a8b8c8d8e8f Bug"},
+		args:     []string{"card_id", "label_id"},
+		fields: map[string]string{
+			"card_id":  "ID, URL or name of the card",
+			"label_id": "ID, name or color of the label to remove",
+		},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			labelID := op.Data["label_id"].(string)
+			card, err := getCard(trelloClient, op.Data["card_id"].(string))
+			if err != nil {
+				return nil, err
+			}
 
-var labelAddCmd = &cobra.Command{
-	Use:   "add <card-id> <label-id>",
-	Short: "Add a label to a card",
-	Long:  "Add an existing label to a specific card.",
-	Args:  cobra.ExactArgs(2),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		cardID := args[0]
-		labelID := args[1]
-
-		card, err := trelloClient.GetCard(cardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get card: %w", err)
-		}
-
-		err = card.AddIDLabel(labelID)
-		if err != nil {
-			return fmt.Errorf("failed to add label: %w", err)
-		}
-
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
+			err = trelloClient.RemoveCardLabel(card.ID, labelID)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to remove label: %w", err)
 			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("Label %s added to card '%s'", labelID, card.Name)))
-		}
-		return nil
+			return success("Label %s removed from card '%s'", labelID, card.Name), nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			labelID := op.Data["label_id"].(string)
+			card := &trello.Card{ID: op.Data["card_id"].(string)}
+			card.SetClient(trelloClient.Client)
+			return rollback("label", "add", "", map[string]interface{}{"card_id": card.ID, "label_id": labelID}, func() (interface{}, error) {
+				return noResult(card.AddIDLabel(labelID))
+			})
+		},
+	},
+	{
+		Action:   batch.Action{Name: "delete", Required: []string{"id"}, Mutating: true},
+		use:      "delete <label>",
+		short:    "Delete a label",
+		long:     "Delete a label from its board permanently. The label is removed from every card.",
+		examples: []string{"trello-cli label delete 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID, name or color of the label to delete"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			err := trelloClient.DeleteLabel(op.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete label: %w", err)
+			}
+			return success("Label %s deleted successfully", op.ID), nil
+		},
 	},
 }
 
+// createLabel creates the label of create and ensure data on its board
+func createLabel(trelloClient *client.Client, data map[string]interface{}) (*trello.Label, error) {
+	board, err := trelloClient.GetBoard(data["board_id"].(string), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get board: %w", err)
+	}
+
+	label := trello.Label{
+		Name:  data["name"].(string),
+		Color: data["color"].(string),
+	}
+
+	err = board.CreateLabel(&label, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+	return &label, nil
+}
+
+// deleteCreatedLabel undoes the creation of a label
+func deleteCreatedLabel(trelloClient *client.Client, label *trello.Label) *batch.Rollback {
+	return rollback("label", "delete", label.ID, nil, func() (interface{}, error) {
+		return noResult(trelloClient.DeleteLabel(label.ID))
+	})
+}
+
 func init() {
 	labelCmd := &cobra.Command{
 		Use:   "label",
 		Short: "Manage Trello labels",
-		Long:  "Commands for managing Trello labels including listing, creating, and adding labels to cards.",
+		Long:  "Commands for managing Trello labels including listing, creating, updating, and adding labels to cards.",
 	}
 
-	labelCmd.AddCommand(labelListCmd)
-	labelCmd.AddCommand(labelCreateCmd)
-	labelCmd.AddCommand(labelAddCmd)
-
-	labelListCmd.Flags().String("board", "", "Board ID")
-	labelCreateCmd.Flags().String("board", "", "Board ID")
-	labelCreateCmd.Flags().String("name", "", "Label name")
-	labelCreateCmd.Flags().String("color", "", "Label color (red, yellow, orange, green, blue, purple, pink, lime, sky, grey)")
+	registerOperations("label", labelOperations...)
+	addOperationCommands(labelCmd, "label")
 
 	rootCmd.AddCommand(labelCmd)
 }
@@ -2,174 +2,259 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var listListCmd = &cobra.Command{
-	Use:   "list --board <board-id>",
-	Short: "List all lists on a board",
-	Long:  "List all lists on a specific board.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		boardID, _ := cmd.Flags().GetString("board")
-		if boardID == "" {
-			return fmt.Errorf("board ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		board, err := trelloClient.GetBoard(boardID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
-
-		lists, err := board.GetLists(nil)
-		if err != nil {
-			return fmt.Errorf("failed to get lists: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatLists(lists)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+// listUpdateFields are the keys of a list update
+var listUpdateFields = updateFields{strings: []string{"name"}, pos: true, bools: []string{"closed"}}
+
+var listOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "list", Required: []string{"board_id"}},
+		use:      "list --board <board>",
+		short:    "List all lists on a board",
+		long:     "List all lists on a specific board.",
+		examples: []string{"trello-cli list list --board 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli list list --board <board-id> --fields name,closed"},
+		fields:   map[string]string{"board_id": "ID, URL or name of the board to list lists from"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board, err := trelloClient.GetBoard(op.Data["board_id"].(string), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board: %w", err)
+			}
+
+			lists, err := board.GetLists(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get lists: %w", err)
+			}
+			return lists, nil
+		},
 	},
-}
+	{
+		Action:   batch.Action{Name: "get", Required: []string{"id"}},
+		use:      "get <list>",
+		short:    "Get list details",
+		long:     "Get detailed information about a specific list.",
+		examples: []string{"trello-cli list get 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli list get --board \"Sprint 42\" Doing"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID or name of the list to retrieve"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			list, err := trelloClient.GetList(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get list: %w", err)
+			}
+			return list, nil
+		},
+	},
+	{
+		Action:   batch.Action{Name: "create", Required: []string{"name", "board_id"}, Optional: []string{"pos"}, Mutating: true},
+		use:      "create --board <board> <name>",
+		short:    "Create a new list",
+		long:     "Create a new list on a specific board.",
+		examples: []string{"trello-cli list create --board 5f8b8c8d8e8f8a8b8c8d8e8f \"New List\"", "trello-cli list create --board \"Sprint 42\" \"Review\" --pos top"},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":     "Name of the list to create",
+			"board_id": "ID, URL or name of the board to create the list on",
+			"pos":      "Position on the board (top, bottom or a number)",
+		},
+		check: checkPos,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			return createList(trelloClient, op.Data)
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			return archiveCreatedList(result.(*trello.List))
+		},
+	},
+	{
+		Action:   batch.Action{Name: "ensure", Aliases: []string{"upsert"}, Required: []string{"name", "board_id"}, Optional: []string{"pos"}, Mutating: true},
+		use:      "ensure --board <board> <name>",
+		short:    "Create a list unless the board has it",
+		long:     "Find the open list with the name on the board, ignoring case, and create it only if there is none, so that running it again changes nothing. A numeric --pos also moves an existing list; top and bottom only place a new one. The result reports whether the list was created, updated or unchanged.",
+		examples: []string{"trello-cli list ensure --board \"Sprint 42\" Review", "trello-cli list ensure --board <board-id> Backlog --pos 1024"},
+		args:     []string{"name"},
+		fields: map[string]string{
+			"name":     "Name of the list to find or create",
+			"board_id": "ID, URL or name of the board the list is on",
+			"pos":      "Position on the board (top, bottom or a number)",
+		},
+		check: checkPos,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			board := &trello.Board{ID: op.Data["board_id"].(string)}
+			board.SetClient(trelloClient.Client)
+			lists, err := board.GetLists(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get lists: %w", err)
+			}
+
+			name := op.Data["name"].(string)
+			i, err := findByName("list", name, len(lists), func(i int) string { return lists[i].Name })
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				list, err := createList(trelloClient, op.Data)
+				if err != nil {
+					return nil, err
+				}
+				return &ensured{Status: ensureCreated, Object: list, kind: "list", name: name}, nil
+			}
 
-var listGetCmd = &cobra.Command{
-	Use:   "get <list-id>",
-	Short: "Get list details",
-	Long:  "Get detailed information about a specific list.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		listID := args[0]
-		list, err := trelloClient.GetList(listID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get list: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatList(list)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			list := lists[i]
+			result := &ensured{Status: ensureUnchanged, Object: list, kind: "list", name: list.Name}
+			pos, _ := parseCardPos(op.Data["pos"])
+			wanted, err := strconv.ParseFloat(pos, 64)
+			if err != nil {
+				// Without a numeric pos, or with top or bottom, the list stays
+				return result, nil
+			}
+			changed, previous := changedValues(
+				map[string]interface{}{"pos": float64(float32(wanted))},
+				map[string]interface{}{"pos": float64(list.Pos)},
+			)
+			if len(changed) == 0 {
+				return result, nil
+			}
+
+			args, err := listUpdateFields.args(changed)
+			if err != nil {
+				return nil, err
+			}
+			if err := list.Update(args); err != nil {
+				return nil, fmt.Errorf("failed to update list: %w", err)
+			}
+			result.Status, result.previous = ensureUpdated, previous
+			return result, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			e := result.(*ensured)
+			list := e.Object.(*trello.List)
+			switch e.Status {
+			case ensureCreated:
+				return archiveCreatedList(list)
+			case ensureUpdated:
+				return rollback("list", "update", list.ID, e.previous, func() (interface{}, error) {
+					args, err := listUpdateFields.args(e.previous)
+					if err != nil {
+						return nil, err
+					}
+					return list, list.Update(args)
+				})
+			}
+			return nil
+		},
 	},
-}
+	{
+		Action:   batch.Action{Name: "update", Required: []string{"id"}, Optional: listUpdateFields.keys(), Mutating: true},
+		use:      "update <list>",
+		short:    "Update a list",
+		long:     "Update the name, position or closed state of a list. Only the flags that are given are sent.",
+		examples: []string{"trello-cli list update 5f8b8c8d8e8f8a8b8c8d8e8f --name \"Done\"", "trello-cli list update --board \"Sprint 42\" Backlog --pos top"},
+		args:     []string{"id"},
+		fields: map[string]string{
+			"id":     "ID or name of the list to update",
+			"name":   "New list name",
+			"pos":    "Position on the board (top, bottom or a number)",
+			"closed": "Archive (true) or unarchive (false) the list",
+		},
+		check: listUpdateFields.check,
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			args, err := listUpdateFields.args(op.Data)
+			if err != nil {
+				return nil, err
+			}
 
-var listCreateCmd = &cobra.Command{
-	Use:   "create --board <board-id> <name>",
-	Short: "Create a new list",
-	Long:  "Create a new list on a specific board.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		boardID, _ := cmd.Flags().GetString("board")
-		if boardID == "" {
-			return fmt.Errorf("board ID is required")
-		}
-
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		listName := args[0]
-
-		board, err := trelloClient.GetBoard(boardID, trello.Defaults())
-		if err != nil {
-			return fmt.Errorf("failed to get board: %w", err)
-		}
-
-		list, err := board.CreateList(listName, trello.Defaults())
-		if err != nil {
-			return fmt.Errorf("failed to create list: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatList(list)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+			list := &trello.List{ID: op.ID}
+			list.SetClient(trelloClient.Client)
+			err = list.Update(args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update list: %w", err)
+			}
+			return list, nil
+		},
+		before: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			list, err := trelloClient.GetList(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get list: %w", err)
+			}
+			return list, nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			list := before.(*trello.List)
+			previous := previousValues(map[string]interface{}{
+				"name":   list.Name,
+				"pos":    float64(list.Pos),
+				"closed": list.Closed,
+			}, op.Data)
+			return rollback("list", "update", op.ID, previous, func() (interface{}, error) {
+				args, err := listUpdateFields.args(previous)
+				if err != nil {
+					return nil, err
+				}
+				return list, list.Update(args)
+			})
+		},
 	},
-}
+	{
+		Action:   batch.Action{Name: "archive", Required: []string{"id"}, Mutating: true},
+		use:      "archive <list>",
+		short:    "Archive a list",
+		long:     "Archive a Trello list.",
+		examples: []string{"trello-cli list archive 5f8b8c8d8e8f8a8b8c8d8e8f"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "ID or name of the list to archive"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			list, err := trelloClient.GetList(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get list: %w", err)
+			}
 
-var listArchiveCmd = &cobra.Command{
-	Use:   "archive <list-id>",
-	Short: "Archive a list",
-	Long:  "Archive a Trello list.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		listID := args[0]
-		list, err := trelloClient.GetList(listID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get list: %w", err)
-		}
-
-		err = list.Archive()
-		if err != nil {
-			return fmt.Errorf("failed to archive list: %w", err)
-		}
-
-		if !quiet {
-			f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
+			err = list.Archive()
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to archive list: %w", err)
 			}
-			fmt.Println(f.FormatSuccess(fmt.Sprintf("List '%s' archived successfully", list.Name)))
-		}
-		return nil
+			return success("List '%s' archived successfully", list.Name), nil
+		},
+		undo: func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback {
+			list := &trello.List{ID: op.ID}
+			list.SetClient(trelloClient.Client)
+			return rollback("list", "unarchive", op.ID, nil, func() (interface{}, error) {
+				return noResult(list.Unarchive())
+			})
+		},
 	},
 }
 
+// createList creates the list of create and ensure data on its board
+func createList(trelloClient *client.Client, data map[string]interface{}) (*trello.List, error) {
+	board, err := trelloClient.GetBoard(data["board_id"].(string), trello.Defaults())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get board: %w", err)
+	}
+
+	args := trello.Defaults()
+	if pos, ok := data["pos"]; ok {
+		args["pos"], _ = parseCardPos(pos)
+	}
+	list, err := board.CreateList(data["name"].(string), args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list: %w", err)
+	}
+	return list, nil
+}
+
+// archiveCreatedList undoes the creation of a list. Trello cannot delete
+// lists, so a created list is archived.
+func archiveCreatedList(list *trello.List) *batch.Rollback {
+	return rollback("list", "archive", list.ID, nil, func() (interface{}, error) {
+		return noResult(list.Archive())
+	})
+}
+
 func init() {
 	listCmd := &cobra.Command{
 		Use:   "list",
@@ -177,13 +262,8 @@ func init() {
 		Long:  "Commands for managing Trello lists including listing, creating, updating, and archiving lists.",
 	}
 
-	listCmd.AddCommand(listListCmd)
-	listCmd.AddCommand(listGetCmd)
-	listCmd.AddCommand(listCreateCmd)
-	listCmd.AddCommand(listArchiveCmd)
-
-	listListCmd.Flags().String("board", "", "Board ID")
-	listCreateCmd.Flags().String("board", "", "Board ID")
+	registerOperations("list", listOperations...)
+	addOperationCommands(listCmd, "list")
 
 	rootCmd.AddCommand(listCmd)
 }
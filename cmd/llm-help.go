@@ -168,6 +168,7 @@ trello-cli card get <card-id> --fields name,desc,checklists --max-tokens 1000
 • Main help:     trello-cli --help
 • Command help:  trello-cli <command> --help
 • Batch docs:    trello-cli batch --help
+• Batch schema:  trello-cli batch schema (check files with trello-cli batch validate)
 • Config:        trello-cli config show
 
 ═══════════════════════════════════════════════════════════════════════════════
@@ -3,85 +3,48 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/danbruder/trello-cli/internal/batch"
 	"github.com/danbruder/trello-cli/internal/client"
-	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
-var memberGetCmd = &cobra.Command{
-	Use:   "get <username-or-id>",
-	Short: "Get member information",
-	Long:  "Get detailed information about a specific member.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		usernameOrID := args[0]
-		member, err := trelloClient.GetMember(usernameOrID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get member: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatMember(member)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+var memberOperations = []*operationSpec{
+	{
+		Action:   batch.Action{Name: "get", Required: []string{"id"}},
+		use:      "get <username-or-id>",
+		short:    "Get member information",
+		long:     "Get detailed information about a specific member.",
+		examples: []string{"trello-cli member get me", "trello-cli member get john_doe"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "Username or ID of the member, or me"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			member, err := trelloClient.GetMember(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get member: %w", err)
+			}
+			return member, nil
+		},
 	},
-}
-
-var memberBoardsCmd = &cobra.Command{
-	Use:   "boards <username-or-id>",
-	Short: "List member's boards",
-	Long:  "List all boards that a specific member has access to.",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		auth, err := getAuthFromContext(cmd.Context())
-		if err != nil {
-			return err
-		}
-		trelloClient := client.NewClient(auth.APIKey, auth.Token)
-
-		usernameOrID := args[0]
-		member, err := trelloClient.GetMember(usernameOrID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to get member: %w", err)
-		}
-
-		boards, err := member.GetBoards(nil)
-		if err != nil {
-			return fmt.Errorf("failed to get boards: %w", err)
-		}
-
-		// Format output
-		f, err := formatter.NewFormatter(format, fields, maxTokens, verbose)
-		if err != nil {
-			return err
-		}
-
-		output, err := f.FormatBoards(boards)
-		if err != nil {
-			return err
-		}
-
-		if !quiet {
-			fmt.Println(output)
-		}
-		return nil
+	{
+		Action:   batch.Action{Name: "boards", Required: []string{"id"}},
+		use:      "boards <username-or-id>",
+		short:    "List member's boards",
+		long:     "List all boards that a specific member has access to.",
+		examples: []string{"trello-cli member boards me"},
+		args:     []string{"id"},
+		fields:   map[string]string{"id": "Username or ID of the member, or me"},
+		run: func(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+			member, err := trelloClient.GetMember(op.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get member: %w", err)
+			}
+
+			boards, err := member.GetBoards(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get member boards: %w", err)
+			}
+			return boards, nil
+		},
 	},
 }
 
@@ -92,8 +55,8 @@ func init() {
 		Long:  "Commands for managing Trello members including getting member information and listing boards.",
 	}
 
-	memberCmd.AddCommand(memberGetCmd)
-	memberCmd.AddCommand(memberBoardsCmd)
+	registerOperations("member", memberOperations...)
+	addOperationCommands(memberCmd, "member")
 
 	rootCmd.AddCommand(memberCmd)
 }
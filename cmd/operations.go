@@ -0,0 +1,425 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/danbruder/trello-cli/internal/formatter"
+	"github.com/spf13/cobra"
+)
+
+// operationSpec is an action of the operation registry. Every action is both
+// a batch action and a CLI command: the arguments and flags of the command
+// fill in a batch.Operation, which then goes through the same validation,
+// name resolution and run function as an operation of a batch file.
+type operationSpec struct {
+	batch.Action
+
+	// use, short, long and examples describe the CLI command. The
+	// positional arguments in use fill in the fields of args, in order;
+	// the other fields of the action are flags.
+	use      string
+	short    string
+	long     string
+	examples []string
+	args     []string
+
+	// fields describes each field of the action, for its flags and the schema
+	fields map[string]string
+
+	// check validates the values in data, beyond their being present
+	check func(data map[string]interface{}) error
+
+	// run performs the operation once its references are resolved to IDs
+	run func(trelloClient *client.Client, op batch.Operation) (interface{}, error)
+
+	// before reads what undo needs to restore, before the operation runs
+	before func(trelloClient *client.Client, op batch.Operation) (interface{}, error)
+
+	// undo returns how to undo the completed operation, or nil when there
+	// is nothing to undo. A mutating action without undo is irreversible.
+	undo func(trelloClient *client.Client, op batch.Operation, result, before interface{}) *batch.Rollback
+
+	// command is the CLI command built for the action
+	command *cobra.Command
+}
+
+// registry holds every action, in the order they were registered
+var registry []*operationSpec
+
+// boolFields are the fields holding true or false, which are bool flags
+var boolFields = map[string]bool{"closed": true, "due_complete": true, "default_lists": true}
+
+// registerOperations adds the actions of an operation type to the registry
+// and makes them known to batch validation, dry runs and the batch schema
+func registerOperations(typ string, specs ...*operationSpec) {
+	for _, spec := range specs {
+		spec.Type = typ
+		spec.Irreversible = spec.Mutating && spec.undo == nil
+		batch.RegisterAction(spec.Action)
+		registry = append(registry, spec)
+	}
+}
+
+// lookupOperation returns the registered action of an operation, found by
+// its name or one of its aliases, or nil
+func lookupOperation(typ, action string) *operationSpec {
+	for _, spec := range registry {
+		if spec.Type == typ && (spec.Name == action || containsString(spec.Aliases, action)) {
+			return spec
+		}
+	}
+	return nil
+}
+
+// processOperation validates an operation, resolves the names, short links
+// and URLs in it to IDs and runs it. CLI commands and batches both run their
+// operations through it.
+func processOperation(trelloClient *client.Client, op batch.Operation) (interface{}, error) {
+	spec, err := validateOperation(op)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err = resolveOperation(trelloClient, op)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := spec.run(trelloClient, op)
+	if err == nil && spec.addsObjects() {
+		// Later operations may look up what was added by name
+		trelloClient.ForgetLookups()
+	}
+	return result, err
+}
+
+// addsObjects reports whether the action can add boards, lists, cards or
+// labels, which name lookups cache
+func (spec *operationSpec) addsObjects() bool {
+	switch spec.Name {
+	case "create", "copy", "ensure":
+		return spec.Mutating
+	}
+	return false
+}
+
+// validateOperation checks an operation before it runs and returns its action
+func validateOperation(op batch.Operation) (*operationSpec, error) {
+	if err := batch.ValidateOperation(op); err != nil {
+		return nil, err
+	}
+	if err := batch.ValidateAction(op); err != nil {
+		return nil, err
+	}
+
+	spec := lookupOperation(op.Type, op.Action)
+	if spec.check != nil {
+		if err := spec.check(op.Data); err != nil {
+			return nil, err
+		}
+	}
+	return spec, nil
+}
+
+// checkOperation runs the check of an operation's action the way the
+// operation will. Placeholders are only known at run time, so they are left
+// out.
+func checkOperation(op batch.Operation) error {
+	spec := lookupOperation(op.Type, op.Action)
+	if spec == nil || spec.check == nil {
+		return nil
+	}
+
+	data := make(map[string]interface{}, len(op.Data))
+	for key, val := range op.Data {
+		if s, ok := val.(string); !ok || !batch.ContainsReference(s) {
+			data[key] = val
+		}
+	}
+	return spec.check(data)
+}
+
+// flagName returns the CLI flag of a field: list_id is --list and
+// due_complete is --due-complete
+func flagName(field string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(field, "_id"), "_", "-")
+}
+
+// flagFields returns the fields of the action given as flags
+func (spec *operationSpec) flagFields() []string {
+	var names []string
+	for _, field := range append(append([]string{}, spec.Required...), spec.Optional...) {
+		if field != "id" && !containsString(spec.args, field) {
+			names = append(names, field)
+		}
+	}
+	if spec.named() && !containsString(names, "board_id") {
+		// board_id scopes the lookup of list, card and label names
+		names = append(names, "board_id")
+	}
+	return names
+}
+
+// named reports whether the action takes a list, card or label, which can
+// be given by name
+func (spec *operationSpec) named() bool {
+	for _, field := range append(append([]string{}, spec.Required...), spec.Optional...) {
+		switch field {
+		case "list_id", "card_id", "label_id":
+			return true
+		case "id":
+			if spec.Type == "list" || spec.Type == "card" || spec.Type == "label" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// operationCommand builds the CLI command of a registered action
+func operationCommand(typ, action string) *cobra.Command {
+	spec := lookupOperation(typ, action)
+	if spec == nil {
+		panic(fmt.Sprintf("no operation registered for %s %s", typ, action))
+	}
+
+	cmd := &cobra.Command{
+		Use:     spec.use,
+		Aliases: spec.Aliases,
+		Short:   spec.short,
+		Long:    spec.long,
+		Example: "  " + strings.Join(spec.examples, "\n  "),
+		Args:    cobra.ExactArgs(len(spec.args)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			op := batch.Operation{Type: spec.Type, Resource: spec.Type, Action: spec.Name, Data: map[string]interface{}{}}
+			for i, field := range spec.args {
+				if field == "id" {
+					op.ID = args[i]
+				} else {
+					op.Data[field] = args[i]
+				}
+			}
+			for _, field := range spec.flagFields() {
+				name := flagName(field)
+				if !cmd.Flags().Changed(name) {
+					continue
+				}
+				if boolFields[field] {
+					op.Data[field], _ = cmd.Flags().GetBool(name)
+				} else {
+					op.Data[field], _ = cmd.Flags().GetString(name)
+				}
+			}
+			return runOperationCommand(cmd, op)
+		},
+	}
+
+	for _, field := range spec.flagFields() {
+		name := flagName(field)
+		usage := spec.fields[field]
+		if field == "board_id" && usage == "" {
+			usage = "Board ID, URL or name used to look up lists, cards and labels by name"
+		}
+		if boolFields[field] {
+			cmd.Flags().Bool(name, false, usage)
+		} else {
+			cmd.Flags().String(name, "", usage)
+		}
+		if containsString(spec.Required, field) {
+			cmd.MarkFlagRequired(name)
+		}
+	}
+
+	spec.command = cmd
+	return cmd
+}
+
+// addOperationCommands adds the commands of every action of an operation type
+func addOperationCommands(parent *cobra.Command, typ string) {
+	for _, spec := range registry {
+		if spec.Type == typ {
+			parent.AddCommand(operationCommand(typ, spec.Name))
+		}
+	}
+}
+
+// runOperationCommand runs the operation built from a CLI command and prints
+// its result
+func runOperationCommand(cmd *cobra.Command, op batch.Operation) error {
+	auth, err := getAuthFromContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+	trelloClient, err := newClient(cmd, auth)
+	if err != nil {
+		return err
+	}
+
+	result, err := processOperation(trelloClient, op)
+	if err != nil {
+		return err
+	}
+	if quiet {
+		return nil
+	}
+
+	// Format output
+	f, err := formatter.NewFormatterWithOptions(format, fields, maxTokens, verbose, formatterOptions())
+	if err != nil {
+		return err
+	}
+
+	output, err := formatResult(f, result)
+	if err != nil {
+		return err
+	}
+	fmt.Println(output)
+	return nil
+}
+
+// formatResult formats the result of an operation with the formatter method
+// for its type
+func formatResult(f formatter.Formatter, result interface{}) (string, error) {
+	switch r := result.(type) {
+	case *trello.Board:
+		return f.FormatBoard(r)
+	case []*trello.Board:
+		return f.FormatBoards(r)
+	case *trello.List:
+		return f.FormatList(r)
+	case []*trello.List:
+		return f.FormatLists(r)
+	case *trello.Card:
+		return f.FormatCard(r)
+	case []*trello.Card:
+		return f.FormatCards(r)
+	case *trello.Label:
+		return f.FormatLabel(r)
+	case []*trello.Label:
+		return f.FormatLabels(r)
+	case *trello.Checklist:
+		return f.FormatChecklist(r)
+	case []*trello.Checklist:
+		return f.FormatChecklists(r)
+	case *trello.CheckItem:
+		return f.FormatSuccess(fmt.Sprintf("Item '%s' added to checklist %s", r.Name, r.IDChecklist)), nil
+	case *trello.Member:
+		return f.FormatMember(r)
+	case *trello.Attachment:
+		return f.FormatAttachment(r)
+	case []*trello.Attachment:
+		return f.FormatAttachments(r)
+	case *trello.Action:
+		return f.FormatComment(r)
+	case []*trello.Action:
+		return f.FormatComments(r)
+	case map[string]string:
+		return f.FormatSuccess(r["message"]), nil
+	case *ensured:
+		return formatEnsured(f, r)
+	}
+	return "", fmt.Errorf("unsupported result type %T", result)
+}
+
+// success is the result of an operation that returns no object
+func success(message string, args ...interface{}) map[string]string {
+	return map[string]string{"status": "success", "message": fmt.Sprintf(message, args...)}
+}
+
+// updateFields lists the data keys an update action accepts, by how their
+// values are sent to Trello
+type updateFields struct {
+	strings []string
+	dates   []string
+	pos     bool
+	bools   []string
+}
+
+// apiParams maps the data keys whose API parameter has another name
+var apiParams = map[string]string{"due_complete": "dueComplete"}
+
+// keys returns every key of the update
+func (u updateFields) keys() []string {
+	keys := append(append([]string{}, u.strings...), u.dates...)
+	if u.pos {
+		keys = append(keys, "pos")
+	}
+	return append(keys, u.bools...)
+}
+
+// args converts update data into Trello API arguments. Only the keys
+// present in data are included, and at least one is required.
+func (u updateFields) args(data map[string]interface{}) (trello.Arguments, error) {
+	args := trello.Arguments{}
+
+	for _, key := range u.strings {
+		if val, ok := data[key]; ok {
+			str, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string", key)
+			}
+			args[key] = str
+		}
+	}
+
+	for _, key := range u.dates {
+		if val, ok := data[key]; ok {
+			str, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a date string", key)
+			}
+			date, err := parseCardDate(str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			args[key] = date
+		}
+	}
+
+	if val, ok := data["pos"]; ok && u.pos {
+		pos, err := parseCardPos(val)
+		if err != nil {
+			return nil, err
+		}
+		args["pos"] = pos
+	}
+
+	for _, key := range u.bools {
+		if val, ok := data[key]; ok {
+			b, err := parseBool(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be true or false", key)
+			}
+			param := key
+			if name, ok := apiParams[key]; ok {
+				param = name
+			}
+			args[param] = strconv.FormatBool(b)
+		}
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("at least one field to update is required (%s)", strings.Join(u.keys(), ", "))
+	}
+	return args, nil
+}
+
+// check checks update data the way args converts it
+func (u updateFields) check(data map[string]interface{}) error {
+	_, err := u.args(data)
+	return err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/batch"
+	"github.com/danbruder/trello-cli/internal/fake"
+	"github.com/danbruder/trello-cli/internal/formatter"
+	"github.com/spf13/cobra"
+)
+
+func TestBatchSchemaPublished(t *testing.T) {
+	schema, err := batch.SchemaJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The published copy must match; regenerate it with
+	// trello-cli batch schema > docs/public/batch.schema.json
+	published, err := os.ReadFile("../docs/public/batch.schema.json")
+	if err != nil {
+		t.Fatalf("failed to read the published schema: %v", err)
+	}
+	if !bytes.Equal(schema, published) {
+		t.Error("docs/public/batch.schema.json is out of date; regenerate it with trello-cli batch schema")
+	}
+}
+
+func TestOperationRegistry(t *testing.T) {
+	for _, spec := range registry {
+		name := spec.Type + " " + spec.Name
+		if spec.run == nil || spec.command == nil {
+			t.Errorf("%s: expected a run function and a command", name)
+			continue
+		}
+		if spec.Irreversible != (spec.Mutating && spec.undo == nil) {
+			t.Errorf("%s: expected irreversible only for mutating actions without undo", name)
+		}
+		for _, field := range append(append([]string{}, spec.Required...), spec.Optional...) {
+			if spec.fields[field] == "" {
+				t.Errorf("%s: field %s has no description", name, field)
+			}
+		}
+		if len(spec.args) != len(spec.schema().Arguments) {
+			t.Errorf("%s: expected one <argument> in %q per arg, got %d args", name, spec.use, len(spec.args))
+		}
+		for _, field := range spec.flagFields() {
+			if spec.command.Flags().Lookup(flagName(field)) == nil {
+				t.Errorf("%s: expected a --%s flag for %s", name, flagName(field), field)
+			}
+		}
+	}
+
+	// Every ensure command also answers to upsert, as a command and in batch files
+	for _, args := range [][]string{{"board", "upsert"}, {"card", "upsert"}, {"list", "upsert"}, {"label", "upsert"}, {"checklist", "upsert-item"}} {
+		found, _, err := rootCmd.Find(args)
+		if spec := lookupCommand(found); err != nil || spec == nil || !strings.HasPrefix(spec.Name, "ensure") {
+			t.Errorf("expected %s to be an ensure command, got %v", strings.Join(args, " "), err)
+		}
+		if spec := lookupOperation(args[0], args[1]); spec == nil || spec.command != found {
+			t.Errorf("expected %s to be a batch action of the same command", strings.Join(args, " "))
+		}
+	}
+
+	// Every command of an operation type is an action of the registry
+	for _, cmd := range rootCmd.Commands() {
+		for _, sub := range append(cmd.Commands(), cardCommentCmd.Commands()...) {
+			if containsString(schemaTypeOrder, cmd.Name()) && sub.Runnable() && lookupCommand(sub) == nil {
+				t.Errorf("%s is not in the operation registry", sub.CommandPath())
+			}
+		}
+	}
+}
+
+// lookupCommand returns the registered action a command was built for
+func lookupCommand(cmd *cobra.Command) *operationSpec {
+	for _, spec := range registry {
+		if spec.command == cmd {
+			return spec
+		}
+	}
+	return nil
+}
+
+func TestOperationActionsOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	run := func(typ, action, id string, data map[string]interface{}) interface{} {
+		t.Helper()
+		result, err := processOperation(trelloClient, batch.Operation{Type: typ, Resource: typ, Action: action, ID: id, Data: data})
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", typ, action, err)
+		}
+		return result
+	}
+
+	board := run("board", "create", "", map[string]interface{}{"name": "Registry"}).(*trello.Board)
+	updated := run("board", "update", "Registry", map[string]interface{}{"desc": "All actions"}).(*trello.Board)
+	if updated.Desc != "All actions" {
+		t.Errorf("Expected the board description to be updated, got %q", updated.Desc)
+	}
+
+	list := run("list", "create", "", map[string]interface{}{"name": "Backlog", "board_id": board.ID}).(*trello.List)
+	run("list", "update", "Backlog", map[string]interface{}{"name": "Icebox", "board_id": board.ID})
+	if got, _ := trelloClient.GetList(list.ID, nil); got.Name != "Icebox" {
+		t.Errorf("Expected the list to be renamed, got %q", got.Name)
+	}
+
+	card := run("card", "create", "", map[string]interface{}{"name": "Task", "list_id": list.ID, "pos": "top"}).(*trello.Card)
+	label := run("label", "create", "", map[string]interface{}{"name": "Bug", "color": "red", "board_id": board.ID}).(*trello.Label)
+	run("label", "update", "Bug", map[string]interface{}{"color": "purple", "board_id": board.ID})
+	run("label", "add", "", map[string]interface{}{"card_id": card.ID, "label_id": "Bug"})
+	run("label", "remove", "", map[string]interface{}{"card_id": card.ID, "label_id": label.ID})
+	if got, _ := trelloClient.GetCard(card.ID, nil); len(got.IDLabels) != 0 {
+		t.Errorf("Expected the label to be removed from the card, got %v", got.IDLabels)
+	}
+	run("label", "delete", label.ID, nil)
+	if labels, _ := board.GetLabels(nil); containsLabel(labels, label.ID) {
+		t.Error("Expected the label to be deleted")
+	}
+
+	checklist := run("checklist", "create", "", map[string]interface{}{"name": "QA", "card_id": card.ID}).(*trello.Checklist)
+	item := run("checklist", "add-item", "", map[string]interface{}{"checklist_id": checklist.ID, "item_name": "Smoke test"}).(*trello.CheckItem)
+	run("checklist", "complete-item", item.ID, map[string]interface{}{"card_id": card.ID})
+	run("checklist", "delete-item", item.ID, map[string]interface{}{"checklist_id": checklist.ID})
+	run("checklist", "delete", checklist.ID, nil)
+	if checklists := run("checklist", "list", "", map[string]interface{}{"card_id": card.ID}).([]*trello.Checklist); len(checklists) != 0 {
+		t.Errorf("Expected the checklist to be deleted, got %d", len(checklists))
+	}
+
+	attachment := run("attachment", "add", "", map[string]interface{}{"card_id": card.ID, "url": "https://example.com/spec"}).(*trello.Attachment)
+	run("attachment", "delete", attachment.ID, map[string]interface{}{"card_id": card.ID})
+	if attachments := run("attachment", "list", "", map[string]interface{}{"card_id": card.ID}).([]*trello.Attachment); len(attachments) != 0 {
+		t.Errorf("Expected the attachment to be deleted, got %d", len(attachments))
+	}
+
+	// Update validation is shared with the CLI
+	_, err := processOperation(trelloClient, batch.Operation{Type: "list", Resource: "list", Action: "update", ID: list.ID, Data: map[string]interface{}{"pos": "middle"}})
+	if err == nil || !strings.Contains(err.Error(), "pos") {
+		t.Errorf("Expected an invalid pos to be rejected, got %v", err)
+	}
+}
+
+func TestOperationRollbackOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	board := trello.NewBoard("Undo")
+	if err := trelloClient.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+	lists, _ := board.GetLists(nil)
+	label := trello.Label{Name: "Bug", Color: "red"}
+	if err := board.CreateLabel(&label, nil); err != nil {
+		t.Fatalf("Failed to create label: %v", err)
+	}
+	card := trello.Card{Name: "Task", IDList: lists[0].ID}
+	if err := trelloClient.CreateCard(&card, nil); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+	if err := card.AddIDLabel(label.ID); err != nil {
+		t.Fatalf("Failed to add label: %v", err)
+	}
+	comment, err := trelloClient.AddCardComment(card.ID, "First draft")
+	if err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+	checklist, err := trelloClient.CreateChecklist(&card, "QA", nil)
+	if err != nil {
+		t.Fatalf("Failed to create checklist: %v", err)
+	}
+	item, err := checklist.CreateCheckItem("Smoke test", nil)
+	if err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	operations := []batch.Operation{
+		{Type: "board", Resource: "board", Action: "update", ID: board.ID, Data: map[string]interface{}{"name": "Renamed", "closed": true}},
+		{Type: "list", Resource: "list", Action: "update", ID: lists[0].ID, Data: map[string]interface{}{"name": "Later", "pos": "bottom"}},
+		{Type: "label", Resource: "label", Action: "update", ID: label.ID, Data: map[string]interface{}{"name": "Defect"}},
+		{Type: "label", Resource: "label", Action: "remove", Data: map[string]interface{}{"card_id": card.ID, "label_id": label.ID}},
+		{Type: "comment", Resource: "comment", Action: "edit", ID: comment.ID, Data: map[string]interface{}{"card_id": card.ID, "text": "Final"}},
+		{Type: "checklist", Resource: "checklist", Action: "complete-item", ID: item.ID, Data: map[string]interface{}{"card_id": card.ID}},
+		{Type: "card", Resource: "card", Action: "get", ID: "Missing", Data: map[string]interface{}{"board_id": board.ID}},
+	}
+	if err := checkReversible(operations); err != nil {
+		t.Fatalf("Unexpected irreversible operations: %v", err)
+	}
+
+	processor := batch.NewBatchProcessor(false)
+	processor.ProcessTransaction(operations, func(op batch.Operation) (interface{}, *batch.Rollback, error) {
+		return processOperationWithRollback(trelloClient, op)
+	})
+	if processor.GetRollbackCount() != len(operations)-1 || processor.GetRollbackErrorCount() != 0 {
+		for i, result := range processor.GetResults() {
+			if result.Rollback != nil && !result.Rollback.Success {
+				t.Errorf("Rollback of operation %d failed: %s", i+1, result.Rollback.Error)
+			}
+		}
+		t.Fatalf("Expected %d operations to be undone, got %d", len(operations)-1, processor.GetRollbackCount())
+	}
+
+	restoredBoard, _ := trelloClient.GetBoard(board.ID, nil)
+	if restoredBoard.Name != "Undo" || restoredBoard.Closed {
+		t.Errorf("Expected the board to be restored, got %q, closed %v", restoredBoard.Name, restoredBoard.Closed)
+	}
+	restoredList, _ := trelloClient.GetList(lists[0].ID, nil)
+	if restoredList.Name != lists[0].Name || restoredList.Pos != lists[0].Pos {
+		t.Errorf("Expected the list to be restored, got %q at %v", restoredList.Name, restoredList.Pos)
+	}
+	restoredLabel, _ := trelloClient.GetLabel(label.ID, nil)
+	if restoredLabel.Name != "Bug" {
+		t.Errorf("Expected the label to be restored, got %q", restoredLabel.Name)
+	}
+	comments, _ := trelloClient.GetCardComments(card.ID)
+	if len(comments) != 1 || comments[0].Data.Text != "First draft" {
+		t.Errorf("Expected the comment to be restored, got %v", comments)
+	}
+	checklists, _ := getCardChecklists(trelloClient, card.ID)
+	restoredCard, _ := trelloClient.GetCard(card.ID, nil)
+	if len(restoredCard.IDLabels) != 1 || len(checklists) != 1 || checklists[0].CheckItems[0].State != "incomplete" {
+		t.Errorf("Expected the label back on the card and the item incomplete, got %v and %v", restoredCard.IDLabels, checklists)
+	}
+}
+
+func TestEnsureOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(fake.New())
+	defer server.Close()
+
+	trelloClient := newOfflineClient(t, server.URL)
+
+	ensure := func(typ, action string, data map[string]interface{}, status string) *ensured {
+		t.Helper()
+		result, err := processOperation(trelloClient, batch.Operation{Type: typ, Resource: typ, Action: action, Data: data})
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", typ, action, err)
+		}
+		e := result.(*ensured)
+		if e.Status != status {
+			t.Errorf("Expected %s %s to report %s, got %s", typ, action, status, e.Status)
+		}
+		return e
+	}
+
+	boardData := map[string]interface{}{"name": "Setup", "default_lists": false}
+	board := ensure("board", "ensure", boardData, ensureCreated).Object.(*trello.Board)
+	ensure("board", "ensure", map[string]interface{}{"name": "setup"}, ensureUnchanged)
+	ensure("board", "ensure", map[string]interface{}{"name": "Setup", "desc": "Team setup"}, ensureUpdated)
+
+	listData := map[string]interface{}{"name": "Backlog", "board_id": board.ID}
+	list := ensure("list", "ensure", listData, ensureCreated).Object.(*trello.List)
+	if again := ensure("list", "ensure", listData, ensureUnchanged).Object.(*trello.List); again.ID != list.ID {
+		t.Errorf("Expected the same list, got %s and %s", list.ID, again.ID)
+	}
+	ensure("list", "ensure", map[string]interface{}{"name": "Backlog", "board_id": "Setup", "pos": 5}, ensureUpdated)
+
+	labelData := map[string]interface{}{"name": "Bug", "color": "red", "board_id": board.ID}
+	ensure("label", "ensure", labelData, ensureCreated)
+	ensure("label", "ensure", labelData, ensureUnchanged)
+	recolored := ensure("label", "ensure", map[string]interface{}{"name": "bug", "color": "orange", "board_id": board.ID}, ensureUpdated)
+	if label := recolored.Object.(*trello.Label); label.Color != "orange" || recolored.previous["color"] != "red" {
+		t.Errorf("Expected the label to be recolored, got %+v", recolored)
+	}
+
+	cardData := map[string]interface{}{"name": "Release", "list_id": "Backlog", "board_id": "Setup", "due": "2026-11-01"}
+	card := ensure("card", "ensure", cardData, ensureCreated).Object.(*trello.Card)
+	ensure("card", "ensure", cardData, ensureUnchanged)
+	cardData["desc"] = "Every release"
+	if updated := ensure("card", "ensure", cardData, ensureUpdated); len(updated.previous) != 1 {
+		t.Errorf("Expected only the description to be updated, got %v", updated.previous)
+	}
+
+	checklist, err := trelloClient.CreateChecklist(card, "Steps", nil)
+	if err != nil {
+		t.Fatalf("Failed to create checklist: %v", err)
+	}
+	itemData := map[string]interface{}{"checklist_id": checklist.ID, "item_name": "Tag"}
+	ensure("checklist", "ensure-item", itemData, ensureCreated)
+	ensure("checklist", "ensure-item", itemData, ensureUnchanged)
+	itemData["state"] = "complete"
+	item := ensure("checklist", "ensure-item", itemData, ensureUpdated)
+
+	// The status joins the fields of the object, for references and output
+	encoded, err := json.Marshal(item)
+	if err != nil || !strings.Contains(string(encoded), `"status":"updated"`) || !strings.Contains(string(encoded), `"state":"complete"`) {
+		t.Errorf("Expected the item with its status, got %s, %v", encoded, err)
+	}
+	f, _ := formatter.NewFormatter("markdown", nil, 0, false)
+	if output, err := formatResult(f, item); err != nil || !strings.Contains(output, "Checklist item 'Tag' updated (state)") {
+		t.Errorf("Expected the status in the output, got %q, %v", output, err)
+	}
+
+	// In a transaction, what ensure created or updated is undone
+	operations := []batch.Operation{
+		{Type: "list", Resource: "list", Action: "ensure", Data: map[string]interface{}{"name": "Review", "board_id": board.ID}},
+		{Type: "card", Resource: "card", Action: "ensure", Data: map[string]interface{}{"name": "Release", "list_id": list.ID, "desc": "Changed"}},
+		{Type: "checklist", Resource: "checklist", Action: "ensure-item", Data: map[string]interface{}{"checklist_id": checklist.ID, "item_name": "Tag", "state": "incomplete"}},
+		{Type: "card", Resource: "card", Action: "get", ID: "Missing", Data: map[string]interface{}{"board_id": board.ID}},
+	}
+	if err := checkReversible(operations); err != nil {
+		t.Fatalf("Unexpected irreversible operations: %v", err)
+	}
+	processor := batch.NewBatchProcessor(false)
+	processor.ProcessTransaction(operations, func(op batch.Operation) (interface{}, *batch.Rollback, error) {
+		return processOperationWithRollback(trelloClient, op)
+	})
+	if processor.GetRollbackCount() != 3 || processor.GetRollbackErrorCount() != 0 {
+		t.Fatalf("Expected 3 operations to be undone, got %d", processor.GetRollbackCount())
+	}
+
+	lists, _ := board.GetLists(nil)
+	restoredCard, _ := trelloClient.GetCard(card.ID, nil)
+	checklists, _ := getCardChecklists(trelloClient, card.ID)
+	if len(lists) != 1 || restoredCard.Desc != "Every release" || checklists[0].CheckItems[0].State != "complete" {
+		t.Errorf("Expected the list archived, the description and the item state restored, got %d lists, %q and %v", len(lists), restoredCard.Desc, checklists[0].CheckItems)
+	}
+}
+
+func containsLabel(labels []*trello.Label, id string) bool {
+	for _, label := range labels {
+		if label.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -4,20 +4,33 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/danbruder/trello-cli/internal/client"
+	"github.com/danbruder/trello-cli/internal/formatter"
 	"github.com/spf13/cobra"
 )
 
 var (
-	apiKey    string
-	token     string
-	debug     bool
-	format    string
-	fields    []string
-	maxTokens int
-	verbose   bool
-	quiet     bool
+	apiKey     string
+	token      string
+	profile    string
+	baseURL    string
+	maxRetries int
+	rateLimit  float64
+	debug      bool
+	format     string
+	fields     []string
+	maxTokens  int
+	verbose    bool
+	quiet      bool
+	noColor    bool
+	tmpl       string
+	query      string
+
+	// templateText is the template that --template names
+	templateText string
 )
 
 // Version information set during build (set from main.go)
@@ -30,7 +43,24 @@ var (
 // contextKey is a custom type for context keys to prevent collisions
 type contextKey string
 
-const authContextKey contextKey = "auth"
+const (
+	authContextKey          contextKey = "auth"
+	clientOptionsContextKey contextKey = "clientOptions"
+)
+
+// skipAuthAnnotation marks commands (and their subcommands) that run without
+// Trello credentials, such as the config commands used to set them up.
+const skipAuthAnnotation = "skip-auth"
+
+// skipsAuth reports whether cmd or one of its parents is marked with skipAuthAnnotation
+func skipsAuth(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[skipAuthAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
 
 // getAuthFromContext safely retrieves authentication from context
 func getAuthFromContext(ctx context.Context) (*client.AuthConfig, error) {
@@ -41,6 +71,63 @@ func getAuthFromContext(ctx context.Context) (*client.AuthConfig, error) {
 	return auth, nil
 }
 
+// newClient creates a Trello client for the authenticated user, using the
+// connection options resolved for this command (e.g. --base-url)
+func newClient(cmd *cobra.Command, auth *client.AuthConfig) (*client.Client, error) {
+	opts, _ := cmd.Context().Value(clientOptionsContextKey).(client.Options)
+	return client.NewClientWithOptions(auth.APIKey, auth.Token, opts)
+}
+
+// settingFlags returns the global flags that override environment variables
+// and the config file. Flags left at their defaults are treated as not given.
+func settingFlags(cmd *cobra.Command) client.SettingFlags {
+	flags := client.SettingFlags{
+		APIKey:  apiKey,
+		Token:   token,
+		Profile: profile,
+		BaseURL: baseURL,
+	}
+	if cmd.Flags().Changed("format") {
+		flags.Format = format
+	}
+	if cmd.Flags().Changed("max-tokens") {
+		flags.MaxTokens = strconv.Itoa(maxTokens)
+	}
+	return flags
+}
+
+// resolveTemplate returns the text of the --template flag: the contents of a
+// file for @file, a named template from the config file, or else the value
+// itself
+func resolveTemplate(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read template: %w", err)
+		}
+		return string(data), nil
+	}
+
+	// An unreadable config file is treated like a missing one, as for the
+	// other settings
+	if config, err := client.ReadConfig(); err == nil {
+		if text, ok := config.Templates[value]; ok {
+			return text, nil
+		}
+	}
+	return value, nil
+}
+
+// formatterOptions returns the output options of formatters from the flags
+// and the environment
+func formatterOptions() formatter.Options {
+	return formatter.Options{
+		NoColor:  noColor || os.Getenv("NO_COLOR") != "",
+		Template: templateText,
+		Query:    query,
+	}
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "trello-cli",
 	Short: "A Trello CLI optimized for LLM use",
@@ -50,18 +137,74 @@ and flexible output formats.
 
 🤖 FOR LLMs: Run 'trello-cli llm-help' FIRST for best practices and usage guidelines.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Apply output defaults from the environment and config file to every
+		// command. Commands without credentials, such as the config commands
+		// that repair a broken setting, run with the flags alone when the
+		// settings cannot be resolved.
+		settings, err := client.ResolveSettings(settingFlags(cmd))
+		if err == nil {
+			format = settings.Format.Value
+			maxTokens = settings.MaxTokensValue()
+		} else if !skipsAuth(cmd) {
+			return err
+		}
+
+		if query != "" {
+			if err := formatter.ValidateQuery(query); err != nil {
+				return err
+			}
+		}
+
+		// A template picks the template format unless --format names another
+		templateText = ""
+		if tmpl != "" {
+			text, err := resolveTemplate(tmpl)
+			if err != nil {
+				return err
+			}
+			templateText = text
+			if !cmd.Flags().Changed("format") {
+				format = "template"
+			} else if format != "template" {
+				return fmt.Errorf("--template cannot be used with --format %s", format)
+			}
+		}
+
+		// Only the commands talking to Trello need credentials
+		if skipsAuth(cmd) {
+			return nil
+		}
+
+		if maxRetries < 0 {
+			return fmt.Errorf("invalid --max-retries %d: must be 0 or more", maxRetries)
+		}
+		if rateLimit < 0 {
+			return fmt.Errorf("invalid --rate-limit %g: must be 0 or more", rateLimit)
+		}
+
 		// Load authentication
-		auth, err := client.LoadAuth(apiKey, token)
+		auth, err := settings.Auth()
 		if err != nil {
 			return fmt.Errorf("authentication failed: %w", err)
 		}
 
 		if debug && !quiet {
 			fmt.Printf("Using credentials from: %s\n", auth.Source)
+			fmt.Printf("Using format %s from: %s\n", format, settings.Format.Source)
+			fmt.Printf("Using max tokens %d from: %s\n", maxTokens, settings.MaxTokens.Source)
+			fmt.Printf("Using API base URL %s from: %s\n", settings.BaseURL.Value, settings.BaseURL.Source)
+			fmt.Printf("Using up to %d retries and a rate limit of %g requests/s\n", maxRetries, rateLimit)
+		}
+
+		opts := client.Options{
+			BaseURL:    settings.BaseURL.Value,
+			MaxRetries: maxRetries,
+			RateLimit:  rateLimit,
 		}
 
-		// Store auth in command context for subcommands
+		// Store auth and client options in command context for subcommands
 		ctx := context.WithValue(cmd.Context(), authContextKey, auth)
+		ctx = context.WithValue(ctx, clientOptionsContextKey, opts)
 		cmd.SetContext(ctx)
 		return nil
 	},
@@ -71,12 +214,19 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "Trello API key (overrides env/config)")
 	rootCmd.PersistentFlags().StringVar(&token, "token", "", "Trello token (overrides env/config)")
-	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "json", "Output format (json, markdown)")
-	rootCmd.PersistentFlags().StringSliceVar(&fields, "fields", []string{}, "Specific fields to include in output")
-	rootCmd.PersistentFlags().IntVar(&maxTokens, "max-tokens", 0, "Maximum tokens in output (0 = unlimited)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config file profile to use (overrides TRELLO_PROFILE and current_profile)")
+	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Trello API base URL, e.g. a fake server (overrides TRELLO_BASE_URL/config)")
+	rootCmd.PersistentFlags().StringVarP(&format, "format", "f", "json", "Output format (json, markdown, yaml, csv, tsv, table, template) (overrides TRELLO_FORMAT/config)")
+	rootCmd.PersistentFlags().StringSliceVar(&fields, "fields", []string{}, "Specific fields to include in output (dotted such as labels.name, or -field to exclude)")
+	rootCmd.PersistentFlags().IntVar(&maxTokens, "max-tokens", 0, "Maximum tokens in output, 0 = unlimited (overrides TRELLO_MAX_TOKENS/config)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Quiet mode (minimal output)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colors in table output (also NO_COLOR)")
+	rootCmd.PersistentFlags().StringVar(&tmpl, "template", "", "Go template to render output with: inline, @file or the name of a config file template")
+	rootCmd.PersistentFlags().StringVar(&query, "query", "", "jq expression to filter, project or sort the result before formatting")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Debug mode (show API calls)")
+	rootCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", client.DefaultMaxRetries, "Retries for requests failing with 429 or 5xx, 0 = no retries")
+	rootCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", client.DefaultRateLimit, "Maximum API requests per second with your token (at most 30 per API key), 0 = the API client's fixed 8 per second")
 }
 
 // Execute runs the root command
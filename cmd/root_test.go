@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what run prints to stdout
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	run()
+	w.Close()
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func TestPersistentPreRunInvalidFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TRELLO_API_KEY", "key")
+	t.Setenv("TRELLO_TOKEN", "token")
+	defer func(debugBefore bool, retriesBefore int, rateBefore float64) {
+		debug, maxRetries, rateLimit = debugBefore, retriesBefore, rateBefore
+	}(debug, maxRetries, rateLimit)
+
+	command := lookupOperation("board", "list").command
+	tests := []struct {
+		name     string
+		retries  int
+		rate     float64
+		expected string
+	}{
+		{name: "Retries", retries: -1, rate: 10, expected: "invalid --max-retries -1"},
+		{name: "Rate limit", retries: 3, rate: -2, expected: "invalid --rate-limit -2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			debug, maxRetries, rateLimit = true, tt.retries, tt.rate
+
+			var err error
+			output := captureStdout(t, func() {
+				err = rootCmd.PersistentPreRunE(command, nil)
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected %q, got %v", tt.expected, err)
+			}
+			if output != "" {
+				t.Errorf("expected no settings to be printed before the error, got %q", output)
+			}
+		})
+	}
+}
+
+func TestPersistentPreRunSkipAuthSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TRELLO_API_KEY", "")
+	t.Setenv("TRELLO_TOKEN", "")
+	t.Setenv("TRELLO_FORMAT", "markdown")
+	defer func(formatBefore string, maxTokensBefore int) {
+		format, maxTokens = formatBefore, maxTokensBefore
+	}(format, maxTokens)
+
+	// Commands without credentials still use the output settings
+	format = "json"
+	if err := rootCmd.PersistentPreRunE(batchValidateCmd, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if format != "markdown" {
+		t.Errorf("expected the format of TRELLO_FORMAT, got %s", format)
+	}
+
+	// and run with the flags alone when the settings are broken
+	t.Setenv("TRELLO_PROFILE", "missing")
+	format = "json"
+	if err := rootCmd.PersistentPreRunE(configShowCmd, nil); err != nil {
+		t.Fatalf("expected the config command to run, got %v", err)
+	}
+	if format != "json" {
+		t.Errorf("expected the format of the flag, got %s", format)
+	}
+	if err := rootCmd.PersistentPreRunE(lookupOperation("board", "list").command, nil); err == nil {
+		t.Error("expected the unknown profile to stop a command talking to Trello")
+	}
+}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // CommandSchema represents the schema for a command
@@ -22,6 +24,7 @@ type SubcommandSchema struct {
 	Name        string       `json:"name"`
 	Description string       `json:"description"`
 	Usage       string       `json:"usage"`
+	Aliases     []string     `json:"aliases,omitempty"`
 	Arguments   []ArgSchema  `json:"arguments,omitempty"`
 	Flags       []FlagSchema `json:"flags,omitempty"`
 	Examples    []string     `json:"examples,omitempty"`
@@ -69,286 +72,189 @@ func init() {
 func buildSchema() CommandSchema {
 	return CommandSchema{
 		Name:        "trello-cli",
-		Description: "A comprehensive Trello CLI tool built in Go that provides full access to Trello's API with features optimized for LLM integration",
+		Description: "A comprehensive Trello CLI tool built in Go that provides full access to Trello's API with features optimized for LLM integration. Boards, lists, cards and labels can be referenced by ID, short link, Trello URL or name; list, card and label names are looked up within --board",
 		Usage:       "trello-cli [command]",
 		GlobalFlags: []FlagSchema{
 			{Name: "api-key", Description: "Trello API key (overrides env/config)", Type: "string", Required: false},
 			{Name: "token", Description: "Trello token (overrides env/config)", Type: "string", Required: false},
-			{Name: "format", Short: "f", Description: "Output format (json, markdown)", Type: "string", Default: "markdown", Required: false},
-			{Name: "fields", Description: "Specific fields to include in output", Type: "[]string", Required: false},
-			{Name: "max-tokens", Description: "Maximum tokens in output (0 = unlimited)", Type: "int", Default: "0", Required: false},
+			{Name: "profile", Description: "Config file profile to use (overrides TRELLO_PROFILE and current_profile)", Type: "string", Required: false},
+			{Name: "format", Short: "f", Description: "Output format (json, markdown, yaml, csv, tsv, table, template) (overrides TRELLO_FORMAT/config)", Type: "string", Default: "json", Required: false},
+			{Name: "fields", Description: "Specific fields to include in output (dotted such as labels.name, or -field to exclude)", Type: "[]string", Required: false},
+			{Name: "max-tokens", Description: "Maximum tokens in output, 0 = unlimited (overrides TRELLO_MAX_TOKENS/config)", Type: "int", Default: "0", Required: false},
 			{Name: "verbose", Short: "v", Description: "Verbose output", Type: "bool", Default: "false", Required: false},
 			{Name: "quiet", Short: "q", Description: "Quiet mode (minimal output)", Type: "bool", Default: "false", Required: false},
+			{Name: "no-color", Description: "Disable colors in table output (also NO_COLOR)", Type: "bool", Default: "false", Required: false},
+			{Name: "template", Description: "Go template to render output with: inline, @file or the name of a config file template", Type: "string", Required: false},
+			{Name: "query", Description: "jq expression to filter, project or sort the result before formatting", Type: "string", Required: false},
 			{Name: "debug", Description: "Debug mode (show API calls)", Type: "bool", Default: "false", Required: false},
+			{Name: "base-url", Description: "Trello API base URL, e.g. a fake server (overrides TRELLO_BASE_URL/config)", Type: "string", Default: "https://api.trello.com/1", Required: false},
+			{Name: "max-retries", Description: "Retries for requests failing with 429 or 5xx, 0 = no retries", Type: "int", Default: "3", Required: false},
+			{Name: "rate-limit", Description: "Maximum API requests per second with your token (at most 30 per API key), 0 = the API client's fixed 8 per second", Type: "float", Default: "10", Required: false},
 		},
-		Subcommands: []SubcommandSchema{
-			// Board commands
+		// Operation commands are generated from the registry
+		Subcommands: append(operationSchemas(), []SubcommandSchema{
+			// Board files
 			{
-				Name:        "board list",
-				Description: "List all boards accessible to the authenticated user",
-				Usage:       "trello-cli board list [flags]",
-				Examples:    []string{"trello-cli board list", "trello-cli board list --format json", "trello-cli board list --fields name,desc,url"},
+				Name:        "apply",
+				Description: "Make a board match a board file: print the differences, then create, rename, reorder or archive to converge",
+				Usage:       "trello-cli apply <board-file> [flags]",
+				Arguments:   []ArgSchema{{Name: "board-file", Description: "Path to the JSON or YAML board file", Required: true, Type: "string"}},
+				Flags:       []FlagSchema{{Name: "dry-run", Description: "Print the differences without changing anything", Type: "bool", Default: "false", Required: false}},
+				Examples:    []string{"trello-cli apply board.yaml --dry-run", "trello-cli apply board.yaml"},
 			},
+			// Batch commands
 			{
-				Name:        "board get",
-				Description: "Get detailed information about a specific board",
-				Usage:       "trello-cli board get <board-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "board-id", Description: "ID of the board to retrieve", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli board get 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli board get <board-id> --format json"},
+				Name:        "batch file",
+				Description: "Execute batch operations from a JSON or YAML file",
+				Usage:       "trello-cli batch file <file-path> [flags]",
+				Arguments:   []ArgSchema{{Name: "file-path", Description: "Path to the JSON file containing batch operations", Required: true, Type: "string"}},
+				Flags:       []FlagSchema{{Name: "concurrency", Description: "Number of operations to run in parallel (overrides the concurrency key)", Type: "int", Default: "1", Required: false}, {Name: "dry-run", Description: "Validate operations and resolve references without changing anything, and print the plan", Type: "bool", Default: "false", Required: false}, {Name: "journal", Description: "Journal of completed operations (default <file-path>.journal)", Type: "string", Required: false}, {Name: "var", Description: "Set a template variable, as name=value (repeatable; overrides the vars key)", Type: "stringArray", Required: false}},
+				Examples:    []string{"trello-cli batch file operations.json", "trello-cli batch file operations.json --format json", "trello-cli batch file archive-cards.json --concurrency 8", "trello-cli batch file operations.json --dry-run", "trello-cli batch file cards.yaml --var board=\"Sprint 42\""},
 			},
 			{
-				Name:        "board create",
-				Description: "Create a new Trello board",
-				Usage:       "trello-cli board create <name> [flags]",
-				Arguments:   []ArgSchema{{Name: "name", Description: "Name of the board to create", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "desc", Description: "Board description", Type: "string", Required: false}},
-				Examples:    []string{"trello-cli board create \"My New Board\"", "trello-cli board create \"Project Board\" --desc \"Board for project management\""},
+				Name:        "batch stdin",
+				Description: "Execute batch operations from JSON or YAML piped to stdin",
+				Usage:       "trello-cli batch stdin [flags]",
+				Flags:       []FlagSchema{{Name: "concurrency", Description: "Number of operations to run in parallel (overrides the concurrency key)", Type: "int", Default: "1", Required: false}, {Name: "dry-run", Description: "Validate operations and resolve references without changing anything, and print the plan", Type: "bool", Default: "false", Required: false}, {Name: "journal", Description: "Journal of completed operations, to resume the batch if it fails", Type: "string", Required: false}, {Name: "ndjson", Description: "Read one operation per line and write each result as a JSON line as it completes", Type: "bool", Default: "false", Required: false}, {Name: "continue-on-error", Description: "Keep going after an operation fails (overrides the continue_on_error key)", Type: "bool", Default: "false", Required: false}, {Name: "var", Description: "Set a template variable, as name=value (repeatable; overrides the vars key)", Type: "stringArray", Required: false}},
+				Examples:    []string{"cat operations.json | trello-cli batch stdin", "echo '{\"operations\":[...]}' | trello-cli batch stdin", "generate-ops | trello-cli batch stdin --ndjson --continue-on-error"},
 			},
 			{
-				Name:        "board delete",
-				Description: "Delete a board permanently",
-				Usage:       "trello-cli board delete <board-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "board-id", Description: "ID of the board to delete", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli board delete 5f8b8c8d8e8f8a8b8c8d8e8f"},
+				Name:        "batch resume",
+				Description: "Finish a failed or interrupted batch from its journal",
+				Usage:       "trello-cli batch resume <journal> [flags]",
+				Arguments:   []ArgSchema{{Name: "journal", Description: "Journal left by batch file or batch stdin --journal", Required: true, Type: "string"}},
+				Flags:       []FlagSchema{{Name: "concurrency", Description: "Number of operations to run in parallel (overrides the concurrency key)", Type: "int", Default: "1", Required: false}},
+				Examples:    []string{"trello-cli batch resume import.yaml.journal"},
 			},
 			{
-				Name:        "board add-member",
-				Description: "Add a member to a board",
-				Usage:       "trello-cli board add-member <board-id> <email> [flags]",
-				Arguments: []ArgSchema{
-					{Name: "board-id", Description: "ID of the board", Required: true, Type: "string"},
-					{Name: "email", Description: "Email address of the member to add", Required: true, Type: "string"},
-				},
-				Examples: []string{"trello-cli board add-member 5f8b8c8d8e8f8a8b8c8d8e8f user@example.com"},
+				Name:        "batch validate",
+				Description: "Check a batch file without running it, reporting every problem with its operation and line",
+				Usage:       "trello-cli batch validate <file-path> [flags]",
+				Arguments:   []ArgSchema{{Name: "file-path", Description: "Path to the JSON or YAML batch file, or - for stdin", Required: true, Type: "string"}},
+				Flags:       []FlagSchema{{Name: "var", Description: "Set a template variable, as name=value (repeatable; overrides the vars key)", Type: "stringArray", Required: false}},
+				Examples:    []string{"trello-cli batch validate operations.yaml", "cat operations.json | trello-cli batch validate -", "trello-cli batch validate import.yaml --var board=\"Sprint 42\""},
 			},
-
-			// Card commands
 			{
-				Name:        "card list",
-				Description: "List all cards in a specific list",
-				Usage:       "trello-cli card list --list <list-id> [flags]",
-				Flags:       []FlagSchema{{Name: "list", Description: "ID of the list to list cards from", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli card list --list 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli card list --list <list-id> --fields name,desc,due"},
+				Name:        "batch schema",
+				Description: "Print the JSON Schema for batch files",
+				Usage:       "trello-cli batch schema",
+				Examples:    []string{"trello-cli batch schema > batch.schema.json"},
 			},
+
+			// Config commands
 			{
-				Name:        "card get",
-				Description: "Get detailed information about a specific card",
-				Usage:       "trello-cli card get <card-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "card-id", Description: "ID of the card to retrieve", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli card get 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli card get <card-id> --format json"},
+				Name:        "config show",
+				Description: "Display the current configuration settings",
+				Usage:       "trello-cli config show [flags]",
+				Examples:    []string{"trello-cli config show"},
 			},
 			{
-				Name:        "card create",
-				Description: "Create a new card in a specific list",
-				Usage:       "trello-cli card create --list <list-id> <name> [flags]",
-				Arguments:   []ArgSchema{{Name: "name", Description: "Name of the card to create", Required: true, Type: "string"}},
+				Name:        "config set",
+				Description: "Set configuration values for API credentials and default settings. Only the given flags are changed; with --profile they are set in that profile",
+				Usage:       "trello-cli config set [flags]",
 				Flags: []FlagSchema{
-					{Name: "list", Description: "ID of the list to create the card in", Type: "string", Required: true},
-					{Name: "desc", Description: "Card description", Type: "string", Required: false},
+					{Name: "api-key", Description: "Trello API key", Type: "string", Required: false},
+					{Name: "token", Description: "Trello token", Type: "string", Required: false},
+					{Name: "default-format", Description: "Default output format (json, markdown, yaml, csv, tsv, table)", Type: "string", Required: false},
+					{Name: "max-tokens", Description: "Default maximum tokens", Type: "int", Required: false},
+					{Name: "base-url", Description: "Trello API base URL, e.g. a fake server", Type: "string", Required: false},
 				},
-				Examples: []string{"trello-cli card create --list 5f8b8c8d8e8f8a8b8c8d8e8f \"My New Card\"", "trello-cli card create --list <list-id> \"Task Card\" --desc \"Description of the task\""},
-			},
-			{
-				Name:        "card move",
-				Description: "Move a card to another list",
-				Usage:       "trello-cli card move <card-id> --list <target-list-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "card-id", Description: "ID of the card to move", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "list", Description: "ID of the target list", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli card move 5f8b8c8d8e8f8a8b8c8d8e8f --list 5f8b8c8d8e8f8a8b8c8d8e8g"},
-			},
-			{
-				Name:        "card copy",
-				Description: "Copy a card to another list",
-				Usage:       "trello-cli card copy <card-id> --list <target-list-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "card-id", Description: "ID of the card to copy", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "list", Description: "ID of the target list", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli card copy 5f8b8c8d8e8f8a8b8c8d8e8f --list 5f8b8c8d8e8f8a8b8c8d8e8g"},
-			},
-			{
-				Name:        "card archive",
-				Description: "Archive a card (soft delete)",
-				Usage:       "trello-cli card archive <card-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "card-id", Description: "ID of the card to archive", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli card archive 5f8b8c8d8e8f8a8b8c8d8e8f"},
-			},
-			{
-				Name:        "card delete",
-				Description: "Permanently delete a card",
-				Usage:       "trello-cli card delete <card-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "card-id", Description: "ID of the card to delete", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli card delete 5f8b8c8d8e8f8a8b8c8d8e8f"},
+				Examples: []string{"trello-cli config set --api-key \"key\" --token \"token\"", "trello-cli config set --default-format json"},
 			},
-
-			// List commands
 			{
-				Name:        "list list",
-				Description: "List all lists on a board",
-				Usage:       "trello-cli list list --board <board-id> [flags]",
-				Flags:       []FlagSchema{{Name: "board", Description: "ID of the board to list lists from", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli list list --board 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli list list --board <board-id> --fields name,closed"},
+				Name:        "config get",
+				Description: "Print a single value as stored in the config file (api_key, token, default_format, max_tokens, base_url)",
+				Usage:       "trello-cli config get <key>",
+				Arguments:   []ArgSchema{{Name: "key", Description: "Setting to read", Required: true, Type: "string"}},
+				Examples:    []string{"trello-cli config get default_format", "trello-cli --profile work config get token"},
 			},
 			{
-				Name:        "list get",
-				Description: "Get detailed information about a specific list",
-				Usage:       "trello-cli list get <list-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "list-id", Description: "ID of the list to retrieve", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli list get 5f8b8c8d8e8f8a8b8c8d8e8f"},
+				Name:        "config unset",
+				Description: "Remove a single value from the config file so that its default applies again",
+				Usage:       "trello-cli config unset <key>",
+				Arguments:   []ArgSchema{{Name: "key", Description: "Setting to remove", Required: true, Type: "string"}},
+				Examples:    []string{"trello-cli config unset max_tokens"},
 			},
 			{
-				Name:        "list create",
-				Description: "Create a new list on a board",
-				Usage:       "trello-cli list create --board <board-id> <name> [flags]",
-				Arguments:   []ArgSchema{{Name: "name", Description: "Name of the list to create", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "board", Description: "ID of the board to create the list on", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli list create --board 5f8b8c8d8e8f8a8b8c8d8e8f \"New List\""},
+				Name:        "config path",
+				Description: "Display the path to the configuration file",
+				Usage:       "trello-cli config path [flags]",
+				Examples:    []string{"trello-cli config path"},
 			},
 			{
-				Name:        "list archive",
-				Description: "Archive a list (soft delete)",
-				Usage:       "trello-cli list archive <list-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "list-id", Description: "ID of the list to archive", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli list archive 5f8b8c8d8e8f8a8b8c8d8e8f"},
+				Name:        "config resolve",
+				Description: "Show the value of each setting and which source won (flag > environment variable > config file profile)",
+				Usage:       "trello-cli config resolve [flags]",
+				Examples:    []string{"trello-cli config resolve", "trello-cli --profile bot config resolve"},
 			},
-
-			// Label commands
 			{
-				Name:        "label list",
-				Description: "List all labels on a board",
-				Usage:       "trello-cli label list --board <board-id> [flags]",
-				Flags:       []FlagSchema{{Name: "board", Description: "ID of the board to list labels from", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli label list --board 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli label list --board <board-id> --fields name,color"},
+				Name:        "config profile list",
+				Description: "List the credential profiles in the config file, marking the active one with *",
+				Usage:       "trello-cli config profile list [flags]",
+				Examples:    []string{"trello-cli config profile list"},
 			},
 			{
-				Name:        "label create",
-				Description: "Create a new label on a board",
-				Usage:       "trello-cli label create --board <board-id> --name <name> --color <color> [flags]",
+				Name:        "config profile add",
+				Description: "Add a named credential profile, or update the given settings of an existing one",
+				Usage:       "trello-cli config profile add <name> [flags]",
+				Arguments:   []ArgSchema{{Name: "name", Description: "Profile name", Required: true, Type: "string"}},
 				Flags: []FlagSchema{
-					{Name: "board", Description: "ID of the board to create the label on", Type: "string", Required: true},
-					{Name: "name", Description: "Name of the label", Type: "string", Required: true},
-					{Name: "color", Description: "Color of the label (red, yellow, orange, green, blue, purple, pink, lime, sky, grey)", Type: "string", Required: true},
+					{Name: "api-key", Description: "Trello API key", Type: "string", Required: false},
+					{Name: "token", Description: "Trello token", Type: "string", Required: false},
+					{Name: "default-format", Description: "Default output format for this profile", Type: "string", Required: false},
+					{Name: "max-tokens", Description: "Default maximum tokens for this profile", Type: "int", Required: false},
+					{Name: "base-url", Description: "Trello API base URL for this profile", Type: "string", Required: false},
+					{Name: "use", Description: "Also make this the profile used by default", Type: "bool", Default: "false", Required: false},
 				},
-				Examples: []string{"trello-cli label create --board 5f8b8c8d8e8f8a8b8c8d8e8f --name \"Important\" --color \"red\""},
+				Examples: []string{"trello-cli config profile add work --api-key \"key\" --token \"token\" --use"},
 			},
 			{
-				Name:        "label add",
-				Description: "Add a label to a card",
-				Usage:       "trello-cli label add <card-id> <label-id> [flags]",
-				Arguments: []ArgSchema{
-					{Name: "card-id", Description: "ID of the card to add the label to", Required: true, Type: "string"},
-					{Name: "label-id", Description: "ID of the label to add", Required: true, Type: "string"},
-				},
-				Examples: []string{"trello-cli label add 5f8b8c8d8e8f8a8b8c8d8e8f 5f8b8c8d8e8f8a8b8c8d8e8g"},
+				Name:        "config profile use",
+				Description: "Make a profile the default for future commands (\"default\" selects the top-level credentials)",
+				Usage:       "trello-cli config profile use <name>",
+				Arguments:   []ArgSchema{{Name: "name", Description: "Profile name", Required: true, Type: "string"}},
+				Examples:    []string{"trello-cli config profile use work", "trello-cli config profile use default"},
 			},
-
-			// Checklist commands
 			{
-				Name:        "checklist list",
-				Description: "List all checklists on a card",
-				Usage:       "trello-cli checklist list --card <card-id> [flags]",
-				Flags:       []FlagSchema{{Name: "card", Description: "ID of the card to list checklists from", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli checklist list --card 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli checklist list --card <card-id> --fields name,checkItems"},
+				Name:        "config profile remove",
+				Description: "Remove a named profile from the config file",
+				Usage:       "trello-cli config profile remove <name>",
+				Arguments:   []ArgSchema{{Name: "name", Description: "Profile name", Required: true, Type: "string"}},
+				Examples:    []string{"trello-cli config profile remove bot"},
 			},
 			{
-				Name:        "checklist create",
-				Description: "Create a new checklist on a card",
-				Usage:       "trello-cli checklist create --card <card-id> <name> [flags]",
-				Arguments:   []ArgSchema{{Name: "name", Description: "Name of the checklist to create", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "card", Description: "ID of the card to create the checklist on", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli checklist create --card 5f8b8c8d8e8f8a8b8c8d8e8f \"Task List\""},
+				Name:        "config template list",
+				Description: "List the named output templates in the config file",
+				Usage:       "trello-cli config template list",
+				Examples:    []string{"trello-cli config template list"},
 			},
 			{
-				Name:        "checklist add-item",
-				Description: "Add an item to a checklist",
-				Usage:       "trello-cli checklist add-item <checklist-id> <item-name> [flags]",
+				Name:        "config template set",
+				Description: "Add or update a named output template, given inline or as @file, for use with --template <name>",
+				Usage:       "trello-cli config template set <name> <template>",
 				Arguments: []ArgSchema{
-					{Name: "checklist-id", Description: "ID of the checklist to add the item to", Required: true, Type: "string"},
-					{Name: "item-name", Description: "Name of the item to add", Required: true, Type: "string"},
+					{Name: "name", Description: "Template name", Required: true, Type: "string"},
+					{Name: "template", Description: "Go template text, or @file", Required: true, Type: "string"},
 				},
-				Examples: []string{"trello-cli checklist add-item 5f8b8c8d8e8f8a8b8c8d8e8f \"Review code\""},
-			},
-			{
-				Name:        "checklist complete-item",
-				Description: "Mark a checklist item as complete",
-				Usage:       "trello-cli checklist complete-item --card <card-id> <check-item-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "check-item-id", Description: "ID of the checklist item to mark as complete", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "card", Description: "ID of the card containing the checklist", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli checklist complete-item --card 5f8b8c8d8e8f8a8b8c8d8e8f 67890abcdef12345"},
-			},
-
-			// Member commands
-			{
-				Name:        "member get",
-				Description: "Get detailed information about a member",
-				Usage:       "trello-cli member get <username-or-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "username-or-id", Description: "Username or ID of the member to retrieve", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli member get john_doe", "trello-cli member get me", "trello-cli member get 5f8b8c8d8e8f8a8b8c8d8e8f"},
-			},
-			{
-				Name:        "member boards",
-				Description: "List all boards that a member has access to",
-				Usage:       "trello-cli member boards <username-or-id> [flags]",
-				Arguments:   []ArgSchema{{Name: "username-or-id", Description: "Username or ID of the member", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli member boards john_doe", "trello-cli member boards me"},
-			},
-
-			// Attachment commands
-			{
-				Name:        "attachment list",
-				Description: "List all attachments on a card",
-				Usage:       "trello-cli attachment list --card <card-id> [flags]",
-				Flags:       []FlagSchema{{Name: "card", Description: "ID of the card to list attachments from", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli attachment list --card 5f8b8c8d8e8f8a8b8c8d8e8f", "trello-cli attachment list --card <card-id> --fields name,url,mimeType"},
-			},
-			{
-				Name:        "attachment add",
-				Description: "Add an attachment to a card",
-				Usage:       "trello-cli attachment add --card <card-id> <url> [flags]",
-				Arguments:   []ArgSchema{{Name: "url", Description: "URL of the file to attach", Required: true, Type: "string"}},
-				Flags:       []FlagSchema{{Name: "card", Description: "ID of the card to add the attachment to", Type: "string", Required: true}},
-				Examples:    []string{"trello-cli attachment add --card 5f8b8c8d8e8f8a8b8c8d8e8f \"https://example.com/file.pdf\""},
-			},
-
-			// Batch commands
-			{
-				Name:        "batch file",
-				Description: "Execute batch operations from a JSON or YAML file",
-				Usage:       "trello-cli batch file <file-path> [flags]",
-				Arguments:   []ArgSchema{{Name: "file-path", Description: "Path to the JSON file containing batch operations", Required: true, Type: "string"}},
-				Examples:    []string{"trello-cli batch file operations.json", "trello-cli batch file operations.json --format json"},
+				Examples: []string{"trello-cli config template set brief '{{.ShortLink}} {{.Name}}'", "trello-cli card list --list <list-id> --template brief"},
 			},
 			{
-				Name:        "batch stdin",
-				Description: "Execute batch operations from JSON or YAML piped to stdin",
-				Usage:       "trello-cli batch stdin [flags]",
-				Examples:    []string{"cat operations.json | trello-cli batch stdin", "echo '{\"operations\":[...]}' | trello-cli batch stdin"},
+				Name:        "config template remove",
+				Description: "Remove a named output template from the config file",
+				Usage:       "trello-cli config template remove <name>",
+				Arguments:   []ArgSchema{{Name: "name", Description: "Template name", Required: true, Type: "string"}},
+				Examples:    []string{"trello-cli config template remove brief"},
 			},
 
-			// Config commands
-			{
-				Name:        "config show",
-				Description: "Display the current configuration settings",
-				Usage:       "trello-cli config show [flags]",
-				Examples:    []string{"trello-cli config show"},
-			},
-			{
-				Name:        "config set",
-				Description: "Set configuration values for API credentials and default settings",
-				Usage:       "trello-cli config set [flags]",
-				Flags: []FlagSchema{
-					{Name: "api-key", Description: "Trello API key", Type: "string", Required: false},
-					{Name: "token", Description: "Trello token", Type: "string", Required: false},
-					{Name: "default-format", Description: "Default output format", Type: "string", Default: "markdown", Required: false},
-					{Name: "max-tokens", Description: "Default maximum tokens", Type: "int", Default: "4000", Required: false},
-				},
-				Examples: []string{"trello-cli config set --api-key \"key\" --token \"token\"", "trello-cli config set --default-format json"},
-			},
+			// Fake server
 			{
-				Name:        "config path",
-				Description: "Display the path to the configuration file",
-				Usage:       "trello-cli config path [flags]",
-				Examples:    []string{"trello-cli config path"},
+				Name:        "fake-server",
+				Description: "Run an in-memory fake of the Trello API for offline testing; point other invocations at it with --base-url or TRELLO_BASE_URL",
+				Usage:       "trello-cli fake-server [flags]",
+				Flags:       []FlagSchema{{Name: "addr", Description: "Address to listen on (port 0 picks a free port)", Type: "string", Default: "127.0.0.1:8080", Required: false}},
+				Examples:    []string{"trello-cli fake-server --addr 127.0.0.1:8080", "TRELLO_BASE_URL=http://127.0.0.1:8080/1 TRELLO_API_KEY=fake TRELLO_TOKEN=fake trello-cli board list"},
 			},
-		},
+		}...),
 		Examples: []string{
 			"trello-cli board list",
 			"trello-cli card create --list <list-id> \"New Card\" --desc \"Task description\"",
@@ -357,3 +263,60 @@ func buildSchema() CommandSchema {
 		},
 	}
 }
+
+// schemaTypeOrder is the order the operation types appear in the schema
+var schemaTypeOrder = []string{"board", "card", "comment", "list", "label", "checklist", "member", "attachment"}
+
+// operationSchemas describes the command of every registered action
+func operationSchemas() []SubcommandSchema {
+	var schemas []SubcommandSchema
+	for _, typ := range schemaTypeOrder {
+		for _, spec := range registry {
+			if spec.Type == typ {
+				schemas = append(schemas, spec.schema())
+			}
+		}
+	}
+	return schemas
+}
+
+// schema describes the command of an action
+func (spec *operationSpec) schema() SubcommandSchema {
+	cmd := spec.command
+	schema := SubcommandSchema{
+		Name:        strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "),
+		Description: strings.TrimSuffix(spec.long, "."),
+		Usage:       cmd.UseLine(),
+		Aliases:     cmd.Aliases,
+		Examples:    spec.examples,
+	}
+
+	// The <...> words of use that are not flag values are the arguments
+	words := strings.Fields(spec.use)
+	for i, word := range words {
+		if !strings.HasPrefix(word, "<") || strings.HasPrefix(words[i-1], "--") {
+			continue
+		}
+		field := spec.args[len(schema.Arguments)]
+		schema.Arguments = append(schema.Arguments, ArgSchema{
+			Name:        strings.Trim(word, "<>"),
+			Description: spec.fields[field],
+			Required:    true,
+			Type:        "string",
+		})
+	}
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+		_, required := flag.Annotations[cobra.BashCompOneRequiredFlag]
+		schema.Flags = append(schema.Flags, FlagSchema{
+			Name:        flag.Name,
+			Description: flag.Usage,
+			Type:        flag.Value.Type(),
+			Required:    required,
+		})
+	})
+	return schema
+}
@@ -43,6 +43,7 @@ export default defineConfig({
                         { text: 'Members', link: '/reference/members' },
                         { text: 'Attachments', link: '/reference/attachments' },
                         { text: 'Batch Operations', link: '/reference/batch' },
+                        { text: 'Board Files', link: '/reference/apply' },
                         { text: 'Configuration', link: '/reference/config' }
                     ]
                 }
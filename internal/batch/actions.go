@@ -0,0 +1,44 @@
+package batch
+
+import "fmt"
+
+// Action describes an action of an operation type: the fields it needs and
+// whether it changes anything
+type Action struct {
+	Type string
+	Name string
+
+	// Aliases are other names batch files and commands can use for the
+	// action, such as upsert for ensure
+	Aliases []string
+
+	// Required lists the fields the action needs: "id" is the operation's
+	// id, everything else a key of data
+	Required []string
+
+	// Optional lists the other keys of data the action reads
+	Optional []string
+
+	Mutating bool
+
+	// Irreversible marks a change a transactional batch cannot undo
+	Irreversible bool
+}
+
+// actions holds the registered actions of each operation type
+var actions = make(map[string]map[string]Action)
+
+// RegisterAction makes an action known to validation, dry runs and the
+// schema, by its name and its aliases. The CLI registers every action of its operation registry when it
+// starts, so batch files support exactly the actions the commands do.
+func RegisterAction(action Action) {
+	if actions[action.Type] == nil {
+		actions[action.Type] = make(map[string]Action)
+	}
+	for _, name := range append([]string{action.Name}, action.Aliases...) {
+		if _, ok := actions[action.Type][name]; ok {
+			panic(fmt.Sprintf("batch: %s %s registered twice", action.Type, name))
+		}
+		actions[action.Type][name] = action
+	}
+}
@@ -0,0 +1,37 @@
+package batch
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain registers the actions the tests of this package use. The CLI
+// registers the real ones from its operation registry.
+func TestMain(m *testing.M) {
+	for _, action := range []Action{
+		{Type: "list", Name: "get", Required: []string{"id"}},
+		{Type: "list", Name: "create", Required: []string{"name", "board_id"}, Mutating: true},
+		{Type: "list", Name: "archive", Required: []string{"id"}, Mutating: true},
+		{Type: "card", Name: "get", Required: []string{"id"}},
+		{Type: "card", Name: "create", Required: []string{"name", "list_id"}, Optional: []string{"desc", "pos"}, Mutating: true},
+		{Type: "card", Name: "update", Required: []string{"id"}, Optional: []string{"name", "desc"}, Mutating: true},
+		{Type: "card", Name: "move", Required: []string{"id", "list_id"}, Mutating: true},
+		{Type: "card", Name: "copy", Required: []string{"id", "list_id"}, Mutating: true},
+		{Type: "card", Name: "delete", Required: []string{"id"}, Mutating: true, Irreversible: true},
+		{Type: "card", Name: "archive", Required: []string{"id"}, Mutating: true},
+		{Type: "label", Name: "create", Required: []string{"name", "color", "board_id"}, Mutating: true},
+		{Type: "comment", Name: "edit", Required: []string{"card_id", "id", "text"}, Mutating: true},
+	} {
+		RegisterAction(action)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRegisterActionTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering card get again to panic")
+		}
+	}()
+	RegisterAction(Action{Type: "card", Name: "get", Required: []string{"id"}})
+}
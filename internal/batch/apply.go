@@ -0,0 +1,723 @@
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// listSpacing is the gap Trello leaves between the positions of lists
+const listSpacing = 16384
+
+// BoardState is the desired state of a board, as kept in a board file for
+// trello-cli apply. Lists are in board order. Lists and cards that are not in
+// the file are archived only with prune. Labels that are not in the file are
+// always left alone: Trello cannot archive labels, and deleting one cannot be
+// undone if a later change fails.
+type BoardState struct {
+	Board  string       `json:"board" yaml:"board"`
+	Desc   *string      `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Prune  bool         `json:"prune,omitempty" yaml:"prune,omitempty"`
+	Lists  []ListState  `json:"lists,omitempty" yaml:"lists,omitempty"`
+	Labels []LabelState `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Cards  []CardState  `json:"cards,omitempty" yaml:"cards,omitempty"`
+}
+
+// ListState is a list of a board file. RenamedFrom holds the names the list
+// had before, so that renaming it in the file renames it on the board.
+type ListState struct {
+	Name        string   `json:"name" yaml:"name"`
+	RenamedFrom []string `json:"renamed_from,omitempty" yaml:"renamed_from,omitempty"`
+}
+
+// LabelState is a label of a board file
+type LabelState struct {
+	Name        string   `json:"name" yaml:"name"`
+	Color       string   `json:"color" yaml:"color"`
+	RenamedFrom []string `json:"renamed_from,omitempty" yaml:"renamed_from,omitempty"`
+}
+
+// CardState is a template card of a board file. Without labels the labels of
+// the card are left alone; with labels, even an empty list, the card gets
+// exactly those.
+type CardState struct {
+	Name        string           `json:"name" yaml:"name"`
+	List        string           `json:"list" yaml:"list"`
+	Desc        *string          `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Labels      []string         `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Checklists  []ChecklistState `json:"checklists,omitempty" yaml:"checklists,omitempty"`
+	RenamedFrom []string         `json:"renamed_from,omitempty" yaml:"renamed_from,omitempty"`
+}
+
+// ChecklistState is a checklist of a template card. Applying it adds the
+// checklist and items that are missing; other items are left alone.
+type ChecklistState struct {
+	Name  string   `json:"name" yaml:"name"`
+	Items []string `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// LoadBoardState loads a board file
+func LoadBoardState(filename string) (*BoardState, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read board file: %w", err)
+	}
+
+	state, err := parseBoardState(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse board file: %w", err)
+	}
+	return state, nil
+}
+
+// parseBoardState decodes a board file as YAML, which JSON files are too, and
+// rejects unknown keys
+func parseBoardState(data []byte) (*BoardState, error) {
+	var state BoardState
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&state); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("the file is empty")
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Validate checks a board state on its own, before it is compared with the
+// board. It reports every problem.
+func (s *BoardState) Validate() error {
+	var problems []error
+	if strings.TrimSpace(s.Board) == "" {
+		problems = append(problems, fmt.Errorf("board is required"))
+	}
+
+	seen := map[string]bool{}
+	for i, list := range s.Lists {
+		switch key := strings.ToLower(list.Name); {
+		case list.Name == "":
+			problems = append(problems, fmt.Errorf("list %d: name is required", i+1))
+		case seen[key]:
+			problems = append(problems, fmt.Errorf("list %d: %q is in the file twice", i+1, list.Name))
+		default:
+			seen[key] = true
+		}
+	}
+
+	seen = map[string]bool{}
+	for i, label := range s.Labels {
+		switch key := strings.ToLower(label.Name); {
+		case label.Name == "":
+			problems = append(problems, fmt.Errorf("label %d: name is required", i+1))
+		case seen[key]:
+			problems = append(problems, fmt.Errorf("label %d: %q is in the file twice", i+1, label.Name))
+		default:
+			seen[key] = true
+		}
+		if label.Color == "" {
+			problems = append(problems, fmt.Errorf("label %d: color is required", i+1))
+		}
+	}
+
+	seen = map[string]bool{}
+	for i, card := range s.Cards {
+		switch key := strings.ToLower(card.Name); {
+		case card.Name == "":
+			problems = append(problems, fmt.Errorf("card %d: name is required", i+1))
+		case seen[key]:
+			problems = append(problems, fmt.Errorf("card %d: %q is in the file twice", i+1, card.Name))
+		default:
+			seen[key] = true
+		}
+		if card.List == "" {
+			problems = append(problems, fmt.Errorf("card %d: list is required", i+1))
+		}
+		for j, checklist := range card.Checklists {
+			if checklist.Name == "" {
+				problems = append(problems, fmt.Errorf("card %d: checklist %d: name is required", i+1, j+1))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// LiveBoard is the current state of a board, as read from Trello. Only open
+// lists, labels and open cards are compared.
+type LiveBoard struct {
+	ID     string
+	Name   string
+	Desc   string
+	Lists  []LiveList
+	Labels []LiveLabel
+	Cards  []LiveCard
+}
+
+// LiveList is an open list of a board
+type LiveList struct {
+	ID   string
+	Name string
+	Pos  float64
+}
+
+// LiveLabel is a label of a board
+type LiveLabel struct {
+	ID    string
+	Name  string
+	Color string
+}
+
+// LiveCard is an open card of a board. Checklists only need to be read for
+// the cards in the board file.
+type LiveCard struct {
+	ID         string
+	Name       string
+	ListID     string
+	Desc       string
+	LabelIDs   []string
+	Checklists []LiveChecklist
+}
+
+// LiveChecklist is a checklist of a card, with the names of its items
+type LiveChecklist struct {
+	ID    string
+	Name  string
+	Items []string
+}
+
+// Change is one difference between a board file and the board, with the
+// operations that remove it
+type Change struct {
+	Action     string      `json:"action"`
+	Type       string      `json:"type"`
+	Name       string      `json:"name"`
+	Detail     string      `json:"detail,omitempty"`
+	Operations []Operation `json:"operations"`
+}
+
+// BoardDiff is every change applying a board file makes, in the order the
+// operations run
+type BoardDiff struct {
+	Board   string   `json:"board"`
+	Changes []Change `json:"changes"`
+}
+
+// Operations returns the operations of every change, in order
+func (d *BoardDiff) Operations() []Operation {
+	var operations []Operation
+	for _, change := range d.Changes {
+		operations = append(operations, change.Operations...)
+	}
+	return operations
+}
+
+// add records a change made by a single operation
+func (d *BoardDiff) add(action, typ, name, detail string, op Operation) {
+	op.Resource = op.Type
+	d.Changes = append(d.Changes, Change{Action: action, Type: typ, Name: name, Detail: detail, Operations: []Operation{op}})
+}
+
+// DiffBoard compares a board file with the board it describes and returns the
+// changes that make the board match the file. A nil live board does not exist
+// yet and is created.
+//
+// Lists, labels and cards are matched by name, or by a name in their
+// renamed_from, ignoring case. New objects are referred to by the ref of the
+// operation creating them, so the operations run as one batch.
+func DiffBoard(state *BoardState, live *LiveBoard) (*BoardDiff, error) {
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+
+	diff := &BoardDiff{Board: state.Board, Changes: []Change{}}
+	boardID := "${ops.board.id}"
+	if live == nil {
+		// The lists of the file replace Trello's default lists
+		data := map[string]interface{}{"name": state.Board, "default_lists": false}
+		if state.Desc != nil && *state.Desc != "" {
+			data["desc"] = *state.Desc
+		}
+		diff.add("create", "board", state.Board, "", Operation{Ref: "board", Type: "board", Action: "create", Data: data})
+		live = &LiveBoard{}
+	} else {
+		boardID = live.ID
+		if state.Desc != nil && *state.Desc != live.Desc {
+			diff.add("update", "board", live.Name, "description", Operation{Type: "board", Action: "update", ID: live.ID,
+				Data: map[string]interface{}{"desc": *state.Desc}})
+		}
+	}
+
+	var problems []error
+	listIDs, err := diff.lists(state, live, boardID)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	labelIDs, err := diff.labels(state, live, boardID)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	if len(problems) == 0 {
+		if err := diff.cards(state, live, listIDs, labelIDs); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return diff, nil
+}
+
+// lists adds the changes to the lists of the board and returns the ID, or
+// placeholder, of every list by lowercase name. Lists on the board but not in
+// the file are known by their own names.
+func (d *BoardDiff) lists(state *BoardState, live *LiveBoard, boardID string) (map[string]string, error) {
+	names := make([]string, len(live.Lists))
+	for i, list := range live.Lists {
+		names[i] = list.Name
+	}
+
+	var problems []error
+	matched := make([]int, len(state.Lists))
+	used := map[int]bool{}
+	for i, list := range state.Lists {
+		j, err := match(names, list.Name, list.RenamedFrom, used)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("list %q: %w", list.Name, err))
+		}
+		matched[i] = j
+		if j >= 0 {
+			used[j] = true
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	ids := map[string]string{}
+	for i, list := range live.Lists {
+		if !used[i] {
+			ids[strings.ToLower(list.Name)] = list.ID
+		}
+	}
+
+	positions := listPositions(matched, live.Lists)
+	for i, list := range state.Lists {
+		j := matched[i]
+		if j < 0 {
+			ref := fmt.Sprintf("list-%d", i+1)
+			data := map[string]interface{}{"name": list.Name, "board_id": boardID}
+			data["pos"] = positions[i]
+			d.add("create", "list", list.Name, "", Operation{Ref: ref, Type: "list", Action: "create", Data: data})
+			ids[strings.ToLower(list.Name)] = "${ops." + ref + ".id}"
+			continue
+		}
+
+		current := live.Lists[j]
+		ids[strings.ToLower(list.Name)] = current.ID
+		data := map[string]interface{}{}
+		var details []string
+		action := ""
+		if current.Name != list.Name {
+			data["name"] = list.Name
+			details = append(details, fmt.Sprintf("renamed from %q", current.Name))
+			action = "rename"
+		}
+		if positions[i] != current.Pos {
+			data["pos"] = positions[i]
+			details = append(details, fmt.Sprintf("moved to position %d", i+1))
+			action = "reorder"
+		}
+		if len(data) == 2 {
+			action = "update"
+		}
+		if len(data) > 0 {
+			d.add(action, "list", list.Name, strings.Join(details, ", "), Operation{Type: "list", Action: "update", ID: current.ID, Data: data})
+		}
+	}
+
+	if state.Prune {
+		for i, list := range live.Lists {
+			if !used[i] {
+				d.add("archive", "list", list.Name, "not in the file", Operation{Type: "list", Action: "archive", ID: list.ID})
+			}
+		}
+	}
+	return ids, nil
+}
+
+// listPositions returns the position every list of the file should have. The
+// lists that are already in order, the longest run of them, keep their
+// positions; the others are spread out evenly between their neighbors.
+func listPositions(matched []int, live []LiveList) []float64 {
+	n := len(matched)
+	keep := make([]bool, n)
+
+	// Longest strictly increasing run of positions among matched lists
+	length := make([]int, n)
+	prev := make([]int, n)
+	best := -1
+	for i := range matched {
+		prev[i] = -1
+		if matched[i] < 0 {
+			continue
+		}
+		length[i] = 1
+		for j := 0; j < i; j++ {
+			if matched[j] >= 0 && live[matched[j]].Pos < live[matched[i]].Pos && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+				prev[i] = j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	for i := best; i >= 0; i = prev[i] {
+		keep[i] = true
+	}
+
+	// Lists outside the file come before or after, so new and moved lists go
+	// after the last list when nothing in the file is kept
+	last := 0.0
+	for _, list := range live {
+		if list.Pos > last {
+			last = list.Pos
+		}
+	}
+
+	positions := make([]float64, n)
+	for i := 0; i < n; {
+		if keep[i] {
+			positions[i] = live[matched[i]].Pos
+			i++
+			continue
+		}
+
+		end := i
+		for end < n && !keep[end] {
+			end++
+		}
+		low := 0.0
+		if i > 0 {
+			low = positions[i-1]
+		}
+		for k := i; k < end; k++ {
+			step := float64(k - i + 1)
+			switch {
+			case end < n:
+				high := live[matched[end]].Pos
+				positions[k] = low + (high-low)*step/float64(end-i+1)
+			case i == 0 && best < 0:
+				positions[k] = last + listSpacing*step
+			default:
+				positions[k] = low + listSpacing*step
+			}
+		}
+		i = end
+	}
+	return positions
+}
+
+// labels adds the changes to the labels of the board and returns the ID, or
+// placeholder, of every label by lowercase name
+func (d *BoardDiff) labels(state *BoardState, live *LiveBoard, boardID string) (map[string]string, error) {
+	names := make([]string, len(live.Labels))
+	ids := map[string]string{}
+	for i, label := range live.Labels {
+		names[i] = label.Name
+		if label.Name != "" {
+			ids[strings.ToLower(label.Name)] = label.ID
+		}
+	}
+
+	var problems []error
+	used := map[int]bool{}
+	for i, label := range state.Labels {
+		j, err := match(names, label.Name, label.RenamedFrom, used)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("label %q: %w", label.Name, err))
+			continue
+		}
+
+		if j < 0 {
+			ref := fmt.Sprintf("label-%d", i+1)
+			d.add("create", "label", label.Name, label.Color, Operation{Ref: ref, Type: "label", Action: "create",
+				Data: map[string]interface{}{"name": label.Name, "color": label.Color, "board_id": boardID}})
+			ids[strings.ToLower(label.Name)] = "${ops." + ref + ".id}"
+			continue
+		}
+
+		used[j] = true
+		current := live.Labels[j]
+		ids[strings.ToLower(label.Name)] = current.ID
+		data := map[string]interface{}{}
+		var details []string
+		action := "update"
+		if current.Name != label.Name {
+			data["name"] = label.Name
+			details = append(details, fmt.Sprintf("renamed from %q", current.Name))
+			action = "rename"
+		}
+		if current.Color != label.Color {
+			data["color"] = label.Color
+			details = append(details, fmt.Sprintf("color %s → %s", current.Color, label.Color))
+			action = "update"
+		}
+		if len(data) > 0 {
+			d.add(action, "label", label.Name, strings.Join(details, ", "), Operation{Type: "label", Action: "update", ID: current.ID, Data: data})
+		}
+	}
+	return ids, errors.Join(problems...)
+}
+
+// cards adds the changes to the template cards of the file, and with prune
+// archives the other cards
+func (d *BoardDiff) cards(state *BoardState, live *LiveBoard, listIDs, labelIDs map[string]string) error {
+	names := make([]string, len(live.Cards))
+	for i, card := range live.Cards {
+		names[i] = card.Name
+	}
+	labelNames := map[string]string{}
+	for _, label := range live.Labels {
+		labelNames[label.ID] = label.Name
+	}
+
+	var problems []error
+	used := map[int]bool{}
+	for i, card := range state.Cards {
+		listID, ok := listIDs[strings.ToLower(card.List)]
+		if !ok {
+			problems = append(problems, fmt.Errorf("card %q: list %q is not in the file or on the board", card.Name, card.List))
+			continue
+		}
+		var labels []string
+		for _, name := range card.Labels {
+			id, ok := labelIDs[strings.ToLower(name)]
+			if !ok {
+				problems = append(problems, fmt.Errorf("card %q: label %q is not in the file or on the board", card.Name, name))
+				continue
+			}
+			labels = append(labels, id)
+		}
+
+		j, err := matchCard(names, live.Cards, card, listID, used)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("card %q: %w", card.Name, err))
+			continue
+		}
+
+		if j < 0 {
+			ref := fmt.Sprintf("card-%d", i+1)
+			data := map[string]interface{}{"name": card.Name, "list_id": listID}
+			if card.Desc != nil && *card.Desc != "" {
+				data["desc"] = *card.Desc
+			}
+			d.add("create", "card", card.Name, "on "+card.List, Operation{Ref: ref, Type: "card", Action: "create", Data: data})
+			cardID := "${ops." + ref + ".id}"
+			for k, id := range labels {
+				d.add("add", "label", card.Labels[k], "on "+card.Name, Operation{Type: "label", Action: "add",
+					Data: map[string]interface{}{"card_id": cardID, "label_id": id}})
+			}
+			d.checklists(card, ref, cardID, nil)
+			continue
+		}
+
+		used[j] = true
+		current := live.Cards[j]
+		data := map[string]interface{}{}
+		var details []string
+		if current.Name != card.Name {
+			data["name"] = card.Name
+			details = append(details, fmt.Sprintf("renamed from %q", current.Name))
+		}
+		if card.Desc != nil && *card.Desc != current.Desc {
+			data["desc"] = *card.Desc
+			details = append(details, "description")
+		}
+		if len(data) > 0 {
+			action := "update"
+			if len(data) == 1 && data["name"] != nil {
+				action = "rename"
+			}
+			d.add(action, "card", card.Name, strings.Join(details, ", "), Operation{Type: "card", Action: "update", ID: current.ID, Data: data})
+		}
+		if current.ListID != listID {
+			d.add("move", "card", card.Name, "to "+card.List, Operation{Type: "card", Action: "move", ID: current.ID,
+				Data: map[string]interface{}{"list_id": listID}})
+		}
+
+		if card.Labels != nil {
+			for k, id := range labels {
+				if !containsID(current.LabelIDs, id) {
+					d.add("add", "label", card.Labels[k], "on "+card.Name, Operation{Type: "label", Action: "add",
+						Data: map[string]interface{}{"card_id": current.ID, "label_id": id}})
+				}
+			}
+			for _, id := range current.LabelIDs {
+				if !containsID(labels, id) {
+					d.add("remove", "label", labelNames[id], "from "+card.Name, Operation{Type: "label", Action: "remove",
+						Data: map[string]interface{}{"card_id": current.ID, "label_id": id}})
+				}
+			}
+		}
+		d.checklists(card, fmt.Sprintf("card-%d", i+1), current.ID, current.Checklists)
+	}
+
+	if state.Prune {
+		// Cards on archived lists go with their list
+		archived := map[string]bool{}
+		for _, change := range d.Changes {
+			if change.Type == "list" && change.Action == "archive" {
+				archived[change.Operations[0].ID] = true
+			}
+		}
+		for i, card := range live.Cards {
+			if !used[i] && !archived[card.ListID] {
+				d.add("archive", "card", card.Name, "not in the file", Operation{Type: "card", Action: "archive", ID: card.ID})
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// checklists adds the checklists and items of a card that are missing
+func (d *BoardDiff) checklists(card CardState, ref, cardID string, live []LiveChecklist) {
+	for i, checklist := range card.Checklists {
+		var current *LiveChecklist
+		for k := range live {
+			if strings.EqualFold(live[k].Name, checklist.Name) {
+				current = &live[k]
+				break
+			}
+		}
+
+		checklistID := ""
+		if current == nil {
+			checklistRef := fmt.Sprintf("%s-checklist-%d", ref, i+1)
+			d.add("create", "checklist", checklist.Name, "on "+card.Name, Operation{Ref: checklistRef, Type: "checklist", Action: "create",
+				Data: map[string]interface{}{"name": checklist.Name, "card_id": cardID}})
+			checklistID = "${ops." + checklistRef + ".id}"
+			current = &LiveChecklist{}
+		} else {
+			checklistID = current.ID
+		}
+
+		for _, item := range checklist.Items {
+			if containsName(current.Items, item) {
+				continue
+			}
+			d.add("add", "item", item, "to "+checklist.Name+" on "+card.Name, Operation{Type: "checklist", Action: "add-item",
+				Data: map[string]interface{}{"checklist_id": checklistID, "item_name": item}})
+		}
+	}
+}
+
+// match returns the index of the name in names matching name, or else one of
+// renamed, ignoring case and the indexes in used; -1 if none does. A name
+// matching more than one is an error.
+func match(names []string, name string, renamed []string, used map[int]bool) (int, error) {
+	for _, candidate := range append([]string{name}, renamed...) {
+		found := -1
+		for i, n := range names {
+			if used[i] || !strings.EqualFold(n, candidate) {
+				continue
+			}
+			if found >= 0 {
+				return -1, fmt.Errorf("%q matches more than one on the board", candidate)
+			}
+			found = i
+		}
+		if found >= 0 {
+			return found, nil
+		}
+	}
+	return -1, nil
+}
+
+// matchCard matches a card like match, except that cards of the same name on
+// other lists than the one in the file do not make it ambiguous
+func matchCard(names []string, live []LiveCard, card CardState, listID string, used map[int]bool) (int, error) {
+	j, err := match(names, card.Name, card.RenamedFrom, used)
+	if err == nil {
+		return j, nil
+	}
+
+	others := map[int]bool{}
+	for i := range live {
+		others[i] = used[i] || live[i].ListID != listID
+	}
+	if j, err := match(names, card.Name, card.RenamedFrom, others); err == nil && j >= 0 {
+		return j, nil
+	}
+	return -1, err
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, v := range names {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Format formats the diff for output
+func (d *BoardDiff) Format(format string, opts formatter.Options) (string, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		return d.formatMarkdown(), nil
+	}
+
+	return formatter.FormatReport(format, opts, struct {
+		Board   string   `json:"board"`
+		Total   int      `json:"total"`
+		Changes []Change `json:"changes"`
+	}{d.Board, len(d.Changes), d.Changes}, d.Changes)
+}
+
+// formatMarkdown formats the diff as markdown, with + for what is added, -
+// for what is archived or removed and ~ for what is changed
+func (d *BoardDiff) formatMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# Board Diff: %s\n\n", d.Board))
+	if len(d.Changes) == 0 {
+		sb.WriteString("No changes; the board matches the file.\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("**Changes:** %d\n\n", len(d.Changes)))
+
+	sb.WriteString("```diff\n")
+	for _, change := range d.Changes {
+		marker := "~"
+		switch change.Action {
+		case "create", "add":
+			marker = "+"
+		case "archive", "remove":
+			marker = "-"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s %q", marker, change.Action, change.Type, change.Name))
+		if change.Detail != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", change.Detail))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("```\n")
+	return sb.String()
+}
@@ -0,0 +1,295 @@
+package batch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+)
+
+const boardFile = `board: Sprint 42
+desc: Team board
+prune: true
+lists:
+  - name: Backlog
+  - name: In Progress
+    renamed_from: [Doing]
+  - name: Review
+  - name: Done
+labels:
+  - name: Bug
+    color: red
+  - name: Feature
+    color: green
+cards:
+  - name: Release checklist
+    list: Backlog
+    desc: Steps for every release
+    labels: [Feature]
+    checklists:
+      - name: Steps
+        items: [Tag, Publish]
+`
+
+func TestLoadBoardState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "board.yaml")
+	if err := os.WriteFile(path, []byte(boardFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadBoardState(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Board != "Sprint 42" || len(state.Lists) != 4 || state.Lists[1].RenamedFrom[0] != "Doing" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if card := state.Cards[0]; *card.Desc != "Steps for every release" || card.Checklists[0].Items[1] != "Publish" {
+		t.Errorf("unexpected card: %+v", card)
+	}
+
+	// JSON is YAML too
+	if err := os.WriteFile(path, []byte(`{"board": "Sprint 42", "lists": [{"name": "Backlog"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := LoadBoardState(path); err != nil || state.Lists[0].Name != "Backlog" {
+		t.Errorf("expected the JSON file to load, got %+v, %v", state, err)
+	}
+
+	// Unknown keys are mistakes
+	if err := os.WriteFile(path, []byte("board: Sprint 42\nlist:\n  - name: Backlog\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBoardState(path); err == nil || !strings.Contains(err.Error(), "list") {
+		t.Errorf("expected the unknown key to be rejected, got %v", err)
+	}
+}
+
+func TestBoardStateValidate(t *testing.T) {
+	state := &BoardState{
+		Lists:  []ListState{{Name: "Backlog"}, {Name: "backlog"}, {}},
+		Labels: []LabelState{{Name: "Bug"}},
+		Cards:  []CardState{{Name: "Task"}},
+	}
+
+	err := state.Validate()
+	if err == nil {
+		t.Fatal("expected problems")
+	}
+	for _, expected := range []string{
+		"board is required",
+		`list 2: "backlog" is in the file twice`,
+		"list 3: name is required",
+		"label 1: color is required",
+		"card 1: list is required",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err.Error())
+		}
+	}
+}
+
+func TestDiffBoardNewBoard(t *testing.T) {
+	state, err := parseBoardState([]byte(boardFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffBoard(state, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	operations := diff.Operations()
+	if err := ValidateReferences(operations); err != nil {
+		t.Errorf("expected valid references, got %v", err)
+	}
+	for _, op := range operations {
+		if err := ValidateOperation(op); err != nil {
+			t.Errorf("invalid operation %+v: %v", op, err)
+		}
+	}
+
+	// board, 4 lists, 2 labels, card, card label, checklist, 2 items
+	if len(operations) != 12 {
+		t.Fatalf("expected 12 operations, got %d", len(operations))
+	}
+	if op := operations[0]; op.Action != "create" || op.Data["default_lists"] != false || op.Data["desc"] != "Team board" {
+		t.Errorf("expected the board to be created first, got %+v", op)
+	}
+	if op := operations[2]; op.Data["board_id"] != "${ops.board.id}" || op.Data["pos"] != 2.0*listSpacing {
+		t.Errorf("expected the second list after the first, got %+v", op)
+	}
+	if op := operations[9]; op.Type != "checklist" || op.Data["card_id"] != "${ops.card-1.id}" {
+		t.Errorf("expected the checklist on the new card, got %+v", op)
+	}
+	if op := operations[11]; op.Data["checklist_id"] != "${ops.card-1-checklist-1.id}" || op.Data["item_name"] != "Publish" {
+		t.Errorf("expected the item on the new checklist, got %+v", op)
+	}
+}
+
+func TestDiffBoard(t *testing.T) {
+	state, err := parseBoardState([]byte(boardFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := &LiveBoard{
+		ID:   "b1",
+		Name: "Sprint 42",
+		Desc: "Team board",
+		Lists: []LiveList{
+			{ID: "l1", Name: "Done", Pos: 1000},
+			{ID: "l2", Name: "Backlog", Pos: 2000},
+			{ID: "l3", Name: "Doing", Pos: 3000},
+			{ID: "l4", Name: "Old", Pos: 4000},
+		},
+		Labels: []LiveLabel{{ID: "g1", Name: "bug", Color: "orange"}, {ID: "g2", Name: "Chore", Color: "sky"}},
+		Cards: []LiveCard{{
+			ID: "c1", Name: "Release checklist", ListID: "l3", Desc: "Steps for every release", LabelIDs: []string{"g2"},
+			Checklists: []LiveChecklist{{ID: "k1", Name: "steps", Items: []string{"Tag"}}},
+		}, {
+			ID: "c2", Name: "Stray", ListID: "l2",
+		}, {
+			ID: "c3", Name: "Leftover", ListID: "l4",
+		}},
+	}
+
+	diff, err := DiffBoard(state, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, change := range diff.Changes {
+		got = append(got, change.Action+" "+change.Type+" "+change.Name)
+	}
+	expected := []string{
+		"rename list In Progress",
+		"create list Review",
+		"reorder list Done",
+		"archive list Old",
+		"update label Bug",
+		"create label Feature",
+		"move card Release checklist",
+		"add label Feature",
+		"remove label Chore",
+		"add item Publish",
+		"archive card Stray",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected changes:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// Backlog and Doing keep their positions; Review and Done go after them
+	if pos := diff.Changes[1].Operations[0].Data["pos"]; pos != 3000.0+listSpacing {
+		t.Errorf("expected Review after In Progress, got %v", pos)
+	}
+	if pos := diff.Changes[2].Operations[0].Data["pos"]; pos != 3000.0+2*listSpacing {
+		t.Errorf("expected Done after Review, got %v", pos)
+	}
+	if op := diff.Changes[4].Operations[0]; op.Data["name"] != "Bug" || op.Data["color"] != "red" {
+		t.Errorf("expected the label to be renamed and recolored, got %+v", op)
+	}
+	if op := diff.Changes[6].Operations[0]; op.Data["list_id"] != "l2" {
+		t.Errorf("expected the card to move to Backlog, got %+v", op)
+	}
+	if op := diff.Changes[7].Operations[0]; op.Data["label_id"] != "${ops.label-2.id}" {
+		t.Errorf("expected the new label to be added, got %+v", op)
+	}
+	if op := diff.Changes[9].Operations[0]; op.Data["checklist_id"] != "k1" {
+		t.Errorf("expected the item on the existing checklist, got %+v", op)
+	}
+	if op := diff.Changes[10].Operations[0]; op.Type != "card" || op.Action != "archive" || op.ID != "c2" {
+		t.Errorf("expected the card outside the file to be archived, got %+v", op)
+	}
+}
+
+func TestDiffBoardNoChanges(t *testing.T) {
+	desc := "Team board"
+	state := &BoardState{
+		Board: "Sprint 42",
+		Desc:  &desc,
+		Lists: []ListState{{Name: "Backlog"}, {Name: "Done"}},
+		Cards: []CardState{{Name: "Task", List: "done"}},
+	}
+	live := &LiveBoard{
+		ID: "b1", Name: "Sprint 42", Desc: "Team board",
+		Lists: []LiveList{{ID: "l1", Name: "Backlog", Pos: 1}, {ID: "l9", Name: "Other", Pos: 2}, {ID: "l2", Name: "Done", Pos: 3}},
+		Cards: []LiveCard{{ID: "c1", Name: "Task", ListID: "l2", LabelIDs: []string{"g1"}}},
+	}
+
+	diff, err := DiffBoard(state, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.Changes) != 0 {
+		t.Errorf("expected no changes, got %+v", diff.Changes)
+	}
+
+	output, err := diff.Format("markdown", formatter.Options{})
+	if err != nil || !strings.Contains(output, "No changes") {
+		t.Errorf("expected no changes in the output, got %q, %v", output, err)
+	}
+}
+
+func TestDiffBoardProblems(t *testing.T) {
+	state := &BoardState{
+		Board: "Sprint 42",
+		Lists: []ListState{{Name: "Backlog"}},
+		Cards: []CardState{{Name: "Task", List: "Later"}, {Name: "Bug", List: "Backlog", Labels: []string{"Urgent"}}},
+	}
+	live := &LiveBoard{ID: "b1", Name: "Sprint 42", Lists: []LiveList{{ID: "l1", Name: "backlog"}, {ID: "l2", Name: "Backlog"}}}
+
+	_, err := DiffBoard(state, live)
+	if err == nil || !strings.Contains(err.Error(), `list "Backlog": "Backlog" matches more than one`) {
+		t.Errorf("expected the ambiguous list to be reported, got %v", err)
+	}
+
+	live.Lists = live.Lists[:1]
+	_, err = DiffBoard(state, live)
+	if err == nil {
+		t.Fatal("expected problems")
+	}
+	for _, expected := range []string{`list "Later" is not in the file`, `label "Urgent" is not in the file`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err.Error())
+		}
+	}
+}
+
+func TestBoardDiffFormat(t *testing.T) {
+	diff := &BoardDiff{Board: "Sprint 42"}
+	diff.add("create", "list", "Review", "", Operation{Type: "list", Action: "create"})
+	diff.add("archive", "list", "Old", "not in the file", Operation{Type: "list", Action: "archive", ID: "l4"})
+
+	markdown, err := diff.Format("markdown", formatter.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"# Board Diff: Sprint 42", `+ create list "Review"`, `- archive list "Old" (not in the file)`} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in:\n%s", expected, markdown)
+		}
+	}
+
+	output, err := diff.Format("json", formatter.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded struct {
+		Total   int      `json:"total"`
+		Changes []Change `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil || decoded.Total != 2 || decoded.Changes[1].Operations[0].Resource != "list" {
+		t.Errorf("unexpected JSON output %s: %v", output, err)
+	}
+
+	if _, err := diff.Format("xml", formatter.Options{}); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+}
@@ -0,0 +1,195 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// journalVersion is the version of the journal format written by this package
+const journalVersion = 1
+
+// Journal is an append-only record of the operations of a batch that have
+// completed, so that an interrupted batch can be resumed without running
+// them again. It is a file of JSON lines: a header holding the batch itself,
+// then one JournalEntry per completed or undone operation.
+type Journal struct {
+	path  string
+	file  *os.File
+	mu    sync.Mutex
+	batch BatchFile
+	done  map[int]JournalEntry
+}
+
+// journalHeader is the first line of a journal
+type journalHeader struct {
+	Version int       `json:"version"`
+	Started time.Time `json:"started"`
+	Batch   BatchFile `json:"batch"`
+}
+
+// JournalEntry records a completed operation by its index in the batch. The
+// output of an operation with a ref is kept for the placeholders of later
+// operations. Undone marks an operation rolled back by a transactional batch.
+type JournalEntry struct {
+	Index  int         `json:"index"`
+	Ref    string      `json:"ref,omitempty"`
+	ID     string      `json:"id,omitempty"`
+	Output interface{} `json:"output,omitempty"`
+	Undone bool        `json:"undone,omitempty"`
+}
+
+// CreateJournal starts a new journal for batchFile at path. It fails with an
+// error matching os.ErrExist if a journal is already there, which would
+// otherwise lose the record of an unfinished run.
+func CreateJournal(path string, batchFile *BatchFile) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create journal: %w", err)
+	}
+
+	j := &Journal{path: path, file: file, batch: *batchFile, done: make(map[int]JournalEntry)}
+	if err := j.write(journalHeader{Version: journalVersion, Started: time.Now().UTC(), Batch: *batchFile}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// OpenJournal reads the journal at path and opens it to record the rest of
+// the batch. A partly written last line, left by a process that was killed
+// while writing it, is ignored.
+func OpenJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var header journalHeader
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	if err := json.Unmarshal(first, &header); err != nil || header.Version == 0 {
+		return nil, fmt.Errorf("%s is not a batch journal", path)
+	}
+	if header.Version > journalVersion {
+		return nil, fmt.Errorf("journal version %d is newer than this trello-cli supports (%d)", header.Version, journalVersion)
+	}
+
+	// Drop a partly written last line so that new entries start on a line of their own
+	if end := bytes.LastIndexByte(data, '\n'); end < len(data)-1 {
+		data = data[:end+1]
+		if err := os.Truncate(path, int64(len(data))); err != nil {
+			return nil, fmt.Errorf("failed to repair journal: %w", err)
+		}
+	}
+
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	j := &Journal{path: path, batch: header.Batch, done: make(map[int]JournalEntry)}
+	for n, line := range lines[1:] {
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("invalid journal entry on line %d: %w", n+2, err)
+		}
+		if entry.Undone {
+			delete(j.done, entry.Index)
+			continue
+		}
+		j.done[entry.Index] = entry
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.file = file
+	return j, nil
+}
+
+// Path returns where the journal is written
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Batch returns the batch the journal belongs to
+func (j *Journal) Batch() *BatchFile {
+	batchFile := j.batch
+	return &batchFile
+}
+
+// Completed returns the operations recorded as completed, by index
+func (j *Journal) Completed() map[int]JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	done := make(map[int]JournalEntry, len(j.done))
+	for index, entry := range j.done {
+		done[index] = entry
+	}
+	return done
+}
+
+// Record appends a completed operation with its result
+func (j *Journal) Record(index int, ref string, data interface{}) error {
+	entry := JournalEntry{Index: index, Ref: ref}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to journal operation %d: %w", index+1, err)
+	}
+	var output interface{}
+	if err := json.Unmarshal(encoded, &output); err != nil {
+		return fmt.Errorf("failed to journal operation %d: %w", index+1, err)
+	}
+	if fields, ok := output.(map[string]interface{}); ok {
+		entry.ID, _ = fields["id"].(string)
+	}
+	if ref != "" {
+		entry.Output = output
+	}
+
+	if err := j.write(entry); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.done[index] = entry
+	j.mu.Unlock()
+	return nil
+}
+
+// RecordUndone appends that a completed operation was rolled back
+func (j *Journal) RecordUndone(index int) error {
+	if err := j.write(JournalEntry{Index: index, Undone: true}); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	delete(j.done, index)
+	j.mu.Unlock()
+	return nil
+}
+
+// write appends v as one line, in a single write
+func (j *Journal) write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// Remove closes and deletes the journal, once nothing is left to resume
+func (j *Journal) Remove() error {
+	j.file.Close()
+	return os.Remove(j.path)
+}
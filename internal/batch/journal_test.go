@@ -0,0 +1,197 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+)
+
+func TestJournal(t *testing.T) {
+	batchFile := &BatchFile{
+		ContinueOnError: true,
+		Operations: []Operation{
+			{Ref: "list", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Doing", "board_id": "b1"}},
+			{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Task", "list_id": "${ops.list.id}"}},
+			{Type: "card", Resource: "card", Action: "archive", ID: "c9"},
+		},
+	}
+
+	t.Run("Entries survive a reopen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "batch.journal")
+		journal, err := CreateJournal(path, batchFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := journal.Record(0, "list", &fakeCard{ID: "l1", Name: "Doing"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := journal.Record(2, "", map[string]string{"status": "success"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := journal.RecordUndone(2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		journal.Close()
+
+		reopened, err := OpenJournal(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer reopened.Close()
+
+		if batch := reopened.Batch(); len(batch.Operations) != 3 || !batch.ContinueOnError || batch.Operations[1].Data["list_id"] != "${ops.list.id}" {
+			t.Errorf("expected the batch to round-trip, got %+v", batch)
+		}
+		done := reopened.Completed()
+		if len(done) != 1 {
+			t.Fatalf("expected only operation 1 to be done, got %+v", done)
+		}
+		if entry := done[0]; entry.ID != "l1" || entry.Ref != "list" || entry.Output.(map[string]interface{})["name"] != "Doing" {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+	})
+
+	t.Run("A partly written last line is ignored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "batch.journal")
+		journal, err := CreateJournal(path, batchFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		journal.Record(0, "list", &fakeCard{ID: "l1"})
+		journal.file.Write([]byte(`{"index":1,"id":"c`))
+		journal.Close()
+
+		reopened, err := OpenJournal(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := reopened.Record(1, "", &fakeCard{ID: "c1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		reopened.Close()
+
+		again, err := OpenJournal(path)
+		if err != nil {
+			t.Fatalf("expected the journal to stay readable, got %v", err)
+		}
+		defer again.Close()
+		if done := again.Completed(); len(done) != 2 || done[1].ID != "c1" {
+			t.Errorf("expected operations 1 and 2 to be done, got %+v", done)
+		}
+	})
+
+	t.Run("An existing journal is not replaced", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "batch.journal")
+		journal, err := CreateJournal(path, batchFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		journal.Close()
+
+		if _, err := CreateJournal(path, batchFile); !errors.Is(err, os.ErrExist) {
+			t.Errorf("expected os.ErrExist, got %v", err)
+		}
+	})
+
+	t.Run("Not a journal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "batch.yaml")
+		os.WriteFile(path, []byte("operations: []\n"), 0600)
+		if _, err := OpenJournal(path); err == nil || !strings.Contains(err.Error(), "not a batch journal") {
+			t.Errorf("expected a not a journal error, got %v", err)
+		}
+	})
+}
+
+func TestBatchProcessorResume(t *testing.T) {
+	operations := []Operation{
+		{Ref: "first", Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "One"}},
+		{Type: "card", Resource: "card", Action: "copy", ID: "${ops.first.id}"},
+		{Type: "card", Resource: "card", Action: "archive", ID: "c3"},
+	}
+	path := filepath.Join(t.TempDir(), "batch.journal")
+
+	// The first run fails at the second operation
+	journal, err := CreateJournal(path, &BatchFile{Operations: operations})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	processor := NewBatchProcessor(false)
+	processor.SetJournal(journal)
+	processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+		if op.Action == "copy" {
+			return nil, &TestError{Message: "network is down"}
+		}
+		return &fakeCard{ID: "c1", Name: "One"}, nil
+	})
+	journal.Close()
+
+	// The resume skips the first and still fills in its ref
+	journal, err = OpenJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer journal.Close()
+
+	var mu sync.Mutex
+	var ran []string
+	processor = NewBatchProcessor(false)
+	processor.SetConcurrency(2)
+	if err := processor.Resume(journal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	processor.ProcessOperations(journal.Batch().Operations, func(op Operation) (interface{}, error) {
+		mu.Lock()
+		ran = append(ran, op.Action+" "+op.ID)
+		mu.Unlock()
+		return &fakeCard{ID: op.ID + "-done"}, nil
+	})
+
+	sort.Strings(ran)
+	if strings.Join(ran, ",") != "archive c3,copy c1" {
+		t.Errorf("expected the copy of c1 and the archive to run, got %v", ran)
+	}
+	results := processor.GetResults()
+	if len(results) != 3 || !results[0].Skipped || results[1].Skipped || processor.GetSkippedCount() != 1 {
+		t.Errorf("expected only the first operation to be skipped, got %+v", results)
+	}
+	if len(journal.Completed()) != 3 {
+		t.Errorf("expected every operation to be journaled, got %+v", journal.Completed())
+	}
+
+	output, _ := processor.FormatResults("markdown", formatter.Options{})
+	if !strings.Contains(output, "**Skipped (already done):** 1") || !strings.Contains(output, "Skipped (completed by an earlier run)") {
+		t.Errorf("expected skipped operations in the report:\n%s", output)
+	}
+}
+
+func TestBatchProcessorInterrupt(t *testing.T) {
+	operations := make([]Operation, 10)
+	for i := range operations {
+		operations[i] = Operation{Type: "card", Resource: "card", Action: "archive"}
+	}
+
+	for _, concurrency := range []int{1, 3} {
+		processor := NewBatchProcessor(true)
+		processor.SetConcurrency(concurrency)
+		var calls atomic.Int32
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			if calls.Add(1) == 2 {
+				processor.Interrupt()
+			}
+			time.Sleep(time.Millisecond)
+			return nil, nil
+		})
+
+		if !processor.Interrupted() || len(processor.GetResults()) == len(operations) {
+			t.Errorf("concurrency %d: expected the batch to stop early, got %d results", concurrency, len(processor.GetResults()))
+		}
+	}
+}
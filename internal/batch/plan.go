@@ -0,0 +1,174 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+)
+
+// ValidateAction checks that the action of a valid operation is supported and
+// that every field it requires is present. It reports every missing field.
+func ValidateAction(op Operation) error {
+	action, ok := actions[op.Type][op.Action]
+	if !ok {
+		supported := make([]string, 0, len(actions[op.Type]))
+		for name := range actions[op.Type] {
+			supported = append(supported, name)
+		}
+		sort.Strings(supported)
+		return fmt.Errorf("unsupported %s action: %s (valid: %s)", op.Type, op.Action, strings.Join(supported, ", "))
+	}
+
+	var problems []error
+	for _, field := range action.Required {
+		if field == "id" {
+			if op.ID == "" {
+				problems = append(problems, fmt.Errorf("%s ID is required for %s action", op.Type, op.Action))
+			}
+			continue
+		}
+		if value, ok := op.Data[field].(string); !ok || value == "" {
+			problems = append(problems, fmt.Errorf("%s is required for %s action", field, op.Action))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// IsMutating reports whether an operation changes anything on Trello
+func IsMutating(op Operation) bool {
+	action, ok := actions[op.Type][op.Action]
+	return !ok || action.Mutating
+}
+
+// IsReversible reports whether a transactional batch can undo an operation
+func IsReversible(op Operation) bool {
+	return !actions[op.Type][op.Action].Irreversible
+}
+
+// Resolution describes how a reference in an operation was resolved
+type Resolution struct {
+	Field string `json:"field"`
+	Input string `json:"input"`
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Note  string `json:"note,omitempty"`
+}
+
+// PlanStep describes what one operation of a batch would do
+type PlanStep struct {
+	Operation Operation    `json:"operation"`
+	Mutating  bool         `json:"mutating"`
+	Resolved  []Resolution `json:"resolved,omitempty"`
+	Errors    []string     `json:"errors,omitempty"`
+}
+
+// resolvedID reports whether the operation's own id is among the resolutions
+func (s PlanStep) resolvedID() bool {
+	for _, r := range s.Resolved {
+		if r.Field == "id" {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan is the outcome of a dry run: every operation with its resolved
+// references and the problems found, without anything having been changed
+type Plan struct {
+	Steps []PlanStep `json:"operations"`
+}
+
+// MutatingCount returns the number of operations that would change something
+func (p *Plan) MutatingCount() int {
+	count := 0
+	for _, step := range p.Steps {
+		if step.Mutating {
+			count++
+		}
+	}
+	return count
+}
+
+// ErrorCount returns the number of operations with problems
+func (p *Plan) ErrorCount() int {
+	count := 0
+	for _, step := range p.Steps {
+		if len(step.Errors) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Format formats the plan for output
+func (p *Plan) Format(format string, opts formatter.Options) (string, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		return p.formatMarkdown(), nil
+	}
+
+	return formatter.FormatReport(format, opts, struct {
+		DryRun     bool       `json:"dry_run"`
+		Total      int        `json:"total"`
+		Mutating   int        `json:"mutating"`
+		Problems   int        `json:"problems"`
+		Operations []PlanStep `json:"operations"`
+	}{true, len(p.Steps), p.MutatingCount(), p.ErrorCount(), p.Steps}, p.Steps)
+}
+
+// formatMarkdown formats the plan as markdown
+func (p *Plan) formatMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Batch Plan (dry run)\n\n")
+	sb.WriteString(fmt.Sprintf("**Total Operations:** %d\n", len(p.Steps)))
+	sb.WriteString(fmt.Sprintf("**Changes:** %d\n", p.MutatingCount()))
+	sb.WriteString(fmt.Sprintf("**Problems:** %d\n\n", p.ErrorCount()))
+
+	for i, step := range p.Steps {
+		status := "✅"
+		if len(step.Errors) > 0 {
+			status = "❌"
+		}
+
+		op := step.Operation
+		sb.WriteString(fmt.Sprintf("## Operation %d %s\n", i+1, status))
+		sb.WriteString(fmt.Sprintf("- **Action:** %s %s\n", op.Action, op.Type))
+		if op.Ref != "" {
+			sb.WriteString(fmt.Sprintf("- **Ref:** %s\n", op.Ref))
+		}
+		if op.ID != "" && !step.resolvedID() {
+			sb.WriteString(fmt.Sprintf("- **ID:** %s\n", op.ID))
+		}
+		if step.Mutating {
+			sb.WriteString("- **Changes Trello:** yes\n")
+		} else {
+			sb.WriteString("- **Changes Trello:** no\n")
+		}
+
+		for _, r := range step.Resolved {
+			sb.WriteString(fmt.Sprintf("- **%s:** %s", r.Field, r.Input))
+			switch {
+			case r.ID != "" && r.Name != "":
+				sb.WriteString(fmt.Sprintf(" → %s (%s)", r.Name, r.ID))
+			case r.ID != "":
+				sb.WriteString(fmt.Sprintf(" → %s", r.ID))
+			}
+			if r.Note != "" {
+				sb.WriteString(fmt.Sprintf(" (%s)", r.Note))
+			}
+			sb.WriteString("\n")
+		}
+
+		for _, e := range step.Errors {
+			sb.WriteString(fmt.Sprintf("- **Error:** %s\n", e))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
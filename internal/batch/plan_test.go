@@ -0,0 +1,147 @@
+package batch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+)
+
+func TestValidateAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     Operation
+		errors []string
+	}{
+		{
+			name: "Card create with everything",
+			op:   Operation{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Task", "list_id": "Doing"}},
+		},
+		{
+			name:   "Card create without list",
+			op:     Operation{Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Task"}},
+			errors: []string{"list_id is required for create action"},
+		},
+		{
+			name: "Label create missing everything",
+			op:   Operation{Type: "label", Resource: "label", Action: "create"},
+			errors: []string{
+				"name is required for create action",
+				"color is required for create action",
+				"board_id is required for create action",
+			},
+		},
+		{
+			name:   "Comment edit without ID",
+			op:     Operation{Type: "comment", Resource: "comment", Action: "edit", Data: map[string]interface{}{"card_id": "c1", "text": "Hi"}},
+			errors: []string{"comment ID is required for edit action"},
+		},
+		{
+			name:   "Unsupported action",
+			op:     Operation{Type: "list", Resource: "list", Action: "delete", ID: "l1"},
+			errors: []string{"unsupported list action: delete (valid: archive, create, get)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAction(tt.op)
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v, got none", tt.errors)
+			}
+			if got := strings.Split(err.Error(), "\n"); len(got) != len(tt.errors) {
+				t.Errorf("expected %d problem(s), got %q", len(tt.errors), got)
+			}
+			for _, expected := range tt.errors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error containing %q, got %q", expected, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestIsMutating(t *testing.T) {
+	if IsMutating(Operation{Type: "card", Action: "get"}) {
+		t.Error("expected card get to be read-only")
+	}
+	if !IsMutating(Operation{Type: "card", Action: "archive"}) {
+		t.Error("expected card archive to change Trello")
+	}
+	if !IsMutating(Operation{Type: "card", Action: "explode"}) {
+		t.Error("expected unknown actions to count as changes")
+	}
+}
+
+func TestPlanFormat(t *testing.T) {
+	plan := &Plan{Steps: []PlanStep{
+		{
+			Operation: Operation{Ref: "list", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"name": "Review", "board_id": "Sprint"}},
+			Mutating:  true,
+			Resolved:  []Resolution{{Field: "board_id", Input: "Sprint", ID: "b1", Name: "Sprint 42"}},
+		},
+		{
+			Operation: Operation{Type: "card", Resource: "card", Action: "move", ID: "Fix bug"},
+			Mutating:  true,
+			Resolved:  []Resolution{{Field: "list_id", Input: "Review", Note: "created by operation 1"}},
+			Errors:    []string{"id: no card matches \"Fix bug\""},
+		},
+	}}
+
+	t.Run("JSON", func(t *testing.T) {
+		output, err := plan.Format("json", formatter.Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var decoded struct {
+			DryRun     bool       `json:"dry_run"`
+			Total      int        `json:"total"`
+			Mutating   int        `json:"mutating"`
+			Problems   int        `json:"problems"`
+			Operations []PlanStep `json:"operations"`
+		}
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if !decoded.DryRun || decoded.Total != 2 || decoded.Mutating != 2 || decoded.Problems != 1 {
+			t.Errorf("unexpected summary: %+v", decoded)
+		}
+		if decoded.Operations[0].Resolved[0].ID != "b1" {
+			t.Errorf("expected the board resolution, got %+v", decoded.Operations[0].Resolved)
+		}
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		output, err := plan.Format("markdown", formatter.Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, expected := range []string{
+			"# Batch Plan (dry run)",
+			"**Problems:** 1",
+			"## Operation 1 ✅",
+			"- **Ref:** list",
+			"- **board_id:** Sprint → Sprint 42 (b1)",
+			"## Operation 2 ❌",
+			"- **list_id:** Review (created by operation 1)",
+			"- **Error:** id: no card matches",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("expected output to contain %q:\n%s", expected, output)
+			}
+		}
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		if _, err := plan.Format("xml", formatter.Options{}); err == nil {
+			t.Error("expected an error for an unsupported format")
+		}
+	})
+}
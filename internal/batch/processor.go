@@ -1,7 +1,11 @@
 // Package batch provides functionality for executing batch operations on Trello resources.
 // It supports loading operations from JSON or YAML files and processing them with
-// configurable error handling. Operations can be executed sequentially with optional
-// continue-on-error behavior for automation and scripting workflows.
+// configurable error handling. Operations can be executed sequentially or by a bounded
+// pool of workers, with optional continue-on-error behavior for automation and
+// scripting workflows. Transactional batches undo completed operations when one
+// fails. Operations with a ref can be used by later operations through
+// ${ops.<ref>.<field>} placeholders. Operations can also be streamed one JSON
+// object per line, with each result reported as it completes.
 package batch
 
 import (
@@ -10,30 +14,50 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/danbruder/trello-cli/internal/formatter"
 	"gopkg.in/yaml.v3"
 )
 
 // Operation represents a single batch operation
 type Operation struct {
+	Ref        string                 `json:"ref,omitempty" yaml:"ref,omitempty"`
 	Type       string                 `json:"type" yaml:"type"`
 	Resource   string                 `json:"resource" yaml:"resource"`
 	Action     string                 `json:"action" yaml:"action"`
 	ID         string                 `json:"id,omitempty" yaml:"id,omitempty"`
 	Data       map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty"`
 	Parameters map[string]string      `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Foreach    *Foreach               `json:"foreach,omitempty" yaml:"foreach,omitempty"`
 }
 
 // BatchFile represents a batch operations file
 type BatchFile struct {
-	Operations      []Operation `json:"operations" yaml:"operations"`
-	ContinueOnError bool        `json:"continue_on_error" yaml:"continue_on_error"`
+	Operations      []Operation            `json:"operations" yaml:"operations"`
+	ContinueOnError bool                   `json:"continue_on_error" yaml:"continue_on_error"`
+	Concurrency     int                    `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	Transactional   bool                   `json:"transactional,omitempty" yaml:"transactional,omitempty"`
+	Vars            map[string]interface{} `json:"vars,omitempty" yaml:"vars,omitempty"`
 }
 
 // BatchProcessor handles batch operations
 type BatchProcessor struct {
 	continueOnError bool
+	concurrency     int
 	results         []BatchResult
+	outputs         outputs
+
+	// journal records completed operations; completed holds those of an
+	// earlier run, which are skipped
+	journal   *Journal
+	completed map[int]JournalEntry
+
+	// interrupts is closed by Interrupt, for a stream waiting on its input
+	interrupted   atomic.Bool
+	interrupts    chan struct{}
+	interruptOnce sync.Once
 }
 
 // BatchResult represents the result of a batch operation
@@ -42,14 +66,70 @@ type BatchResult struct {
 	Success   bool        `json:"success"`
 	Error     string      `json:"error,omitempty"`
 	Data      interface{} `json:"data,omitempty"`
+
+	// Skipped marks an operation completed by an earlier run of a resumed
+	// batch; Data is then its journaled output, if any
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Rollback is the outcome of undoing the operation after a later
+	// operation of a transactional batch failed
+	Rollback *BatchResult `json:"rollback,omitempty"`
 }
 
 // NewBatchProcessor creates a new batch processor
 func NewBatchProcessor(continueOnError bool) *BatchProcessor {
 	return &BatchProcessor{
 		continueOnError: continueOnError,
+		concurrency:     1,
 		results:         make([]BatchResult, 0),
+		interrupts:      make(chan struct{}),
+	}
+}
+
+// SetConcurrency sets how many operations run at the same time. Values below
+// 1 mean one at a time.
+func (bp *BatchProcessor) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	bp.concurrency = n
+}
+
+// SetJournal records every operation that completes from now on in journal
+func (bp *BatchProcessor) SetJournal(journal *Journal) {
+	bp.journal = journal
+}
+
+// Resume records completed operations in journal and skips the operations it
+// already holds, whose journaled outputs stay available to placeholders
+func (bp *BatchProcessor) Resume(journal *Journal) error {
+	bp.journal = journal
+	bp.completed = journal.Completed()
+	for _, entry := range bp.completed {
+		if entry.Ref != "" && entry.Output != nil {
+			if err := bp.outputs.set(entry.Ref, entry.Output); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
+
+// Interrupt stops operations that have not started yet, e.g. on Ctrl-C.
+// Operations already running finish and are reported. It is safe to call
+// from another goroutine.
+func (bp *BatchProcessor) Interrupt() {
+	bp.interrupted.Store(true)
+	bp.interruptOnce.Do(func() {
+		if bp.interrupts != nil {
+			close(bp.interrupts)
+		}
+	})
+}
+
+// Interrupted reports whether Interrupt was called
+func (bp *BatchProcessor) Interrupted() bool {
+	return bp.interrupted.Load()
 }
 
 // LoadBatchFile loads batch operations from a file
@@ -59,19 +139,11 @@ func LoadBatchFile(filename string) (*BatchFile, error) {
 		return nil, fmt.Errorf("failed to read batch file: %w", err)
 	}
 
-	var batchFile BatchFile
-
-	// Try JSON first
-	if err := json.Unmarshal(data, &batchFile); err == nil {
-		return &batchFile, nil
-	}
-
-	// Try YAML
-	if err := yaml.Unmarshal(data, &batchFile); err == nil {
-		return &batchFile, nil
+	batchFile, err := parseBatch(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse batch file: %w", err)
 	}
-
-	return nil, fmt.Errorf("failed to parse batch file as JSON or YAML")
+	return batchFile, nil
 }
 
 // LoadBatchFromReader loads batch operations from an io.Reader
@@ -81,19 +153,36 @@ func LoadBatchFromReader(reader io.Reader) (*BatchFile, error) {
 		return nil, fmt.Errorf("failed to read from reader: %w", err)
 	}
 
+	batchFile, err := parseBatch(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+	return batchFile, nil
+}
+
+// parseBatch decodes a batch file as JSON or YAML, and rejects unknown keys
+// and values of the wrong type
+func parseBatch(data []byte) (*BatchFile, error) {
 	var batchFile BatchFile
 
-	// Try JSON first
-	if err := json.Unmarshal(data, &batchFile); err == nil {
-		return &batchFile, nil
+	// Try JSON first, then YAML
+	if err := json.Unmarshal(data, &batchFile); err != nil {
+		batchFile = BatchFile{}
+		if err := yaml.Unmarshal(data, &batchFile); err != nil {
+			src := parseBatchSource(data)
+			if len(src.problems) > 0 {
+				return nil, joinProblems(src.problems)
+			}
+			return nil, fmt.Errorf("not valid JSON or YAML: %w", err)
+		}
 	}
 
-	// Try YAML
-	if err := yaml.Unmarshal(data, &batchFile); err == nil {
-		return &batchFile, nil
+	// A file JSON accepts but YAML does not has no lines to report, and
+	// was checked for types by the JSON decoder
+	if src := parseBatchSource(data); src.parsed && len(src.problems) > 0 {
+		return nil, joinProblems(src.problems)
 	}
-
-	return nil, fmt.Errorf("failed to parse input as JSON or YAML")
+	return &batchFile, nil
 }
 
 // LoadBatchFromStdin loads batch operations from stdin
@@ -101,29 +190,164 @@ func LoadBatchFromStdin() (*BatchFile, error) {
 	return LoadBatchFromReader(os.Stdin)
 }
 
-// ProcessOperations processes a list of operations
+// ProcessOperations processes a list of operations. With a concurrency above
+// 1 the operations run in parallel, except that an operation using the ref of
+// another waits for it; results are still reported in input order.
+//
+// Placeholders are filled in just before an operation runs, so callers should
+// check them with ValidateReferences first.
 func (bp *BatchProcessor) ProcessOperations(operations []Operation, processor func(Operation) (interface{}, error)) {
-	for _, op := range operations {
-		result := BatchResult{
-			Operation: op,
+	bp.process(operations, func(_ int, op Operation) (interface{}, error) {
+		return processor(op)
+	})
+}
+
+// process runs operations, passing their index to processor
+func (bp *BatchProcessor) process(operations []Operation, processor func(int, Operation) (interface{}, error)) {
+	if bp.concurrency > 1 && len(operations) > 1 {
+		bp.processConcurrently(operations, processor)
+		return
+	}
+
+	for i, op := range operations {
+		if bp.Interrupted() {
+			return
 		}
+		result := bp.runOperation(i, op, processor)
+		bp.results = append(bp.results, result)
 
-		data, err := processor(op)
-		if err != nil {
-			result.Success = false
-			result.Error = err.Error()
+		if !result.Success && !bp.continueOnError {
+			return
+		}
+	}
+}
+
+// processConcurrently runs operations on a pool of workers. Unless
+// continueOnError is set, the first failure stops operations that have not
+// started yet; operations already running finish and are reported.
+//
+// Jobs are handed out in input order and references only point backwards, so
+// the operations a job waits for have already been picked up by a worker.
+func (bp *BatchProcessor) processConcurrently(operations []Operation, processor func(int, Operation) (interface{}, error)) {
+	results := make([]*BatchResult, len(operations))
+	done := make([]chan struct{}, len(operations))
+	refs := make(map[string]int)
+	for i, op := range operations {
+		done[i] = make(chan struct{})
+		if _, ok := refs[op.Ref]; op.Ref != "" && !ok {
+			refs[op.Ref] = i
+		}
+	}
 
-			if !bp.continueOnError {
-				bp.results = append(bp.results, result)
-				return
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+
+	workers := bp.concurrency
+	if workers > len(operations) {
+		workers = len(operations)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				for _, ref := range operations[i].References() {
+					if j, ok := refs[ref]; ok && j < i {
+						<-done[j]
+					}
+				}
+
+				select {
+				case <-stop:
+					// A job handed out or waiting while another worker failed
+					close(done[i])
+					continue
+				default:
+				}
+				if bp.Interrupted() {
+					close(done[i])
+					continue
+				}
+
+				result := bp.runOperation(i, operations[i], processor)
+				results[i] = &result
+				close(done[i])
+				if !result.Success && !bp.continueOnError {
+					stopOnce.Do(func() { close(stop) })
+				}
 			}
-		} else {
-			result.Success = true
-			result.Data = data
+		}()
+	}
+
+dispatch:
+	for i := range operations {
+		if bp.Interrupted() {
+			break
 		}
+		select {
+		case <-stop:
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		bp.results = append(bp.results, result)
+	for _, result := range results {
+		if result != nil {
+			bp.results = append(bp.results, *result)
+		}
+	}
+}
+
+// runOperation fills in the placeholders of a single operation, runs it and
+// records its outcome. The result holds the operation as it was run.
+// Operations completed by an earlier run are skipped.
+func (bp *BatchProcessor) runOperation(index int, op Operation, processor func(int, Operation) (interface{}, error)) BatchResult {
+	result := BatchResult{
+		Operation: op,
+	}
+
+	if entry, ok := bp.completed[index]; ok {
+		result.Success = true
+		result.Skipped = true
+		result.Data = entry.Output
+		return result
+	}
+
+	expanded, err := bp.outputs.expand(op)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		return result
+	}
+	result.Operation = expanded
+
+	data, err := processor(index, expanded)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+		result.Data = data
+	}
+
+	if result.Success && op.Ref != "" {
+		if err := bp.outputs.set(op.Ref, data); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		}
 	}
+	if result.Success && bp.journal != nil {
+		if err := bp.journal.Record(index, op.Ref, data); err != nil {
+			// Without the entry a resume would run the operation again
+			result.Success = false
+			result.Error = fmt.Sprintf("operation completed, but %v", err)
+		}
+	}
+	return result
 }
 
 // GetResults returns the processing results
@@ -142,6 +366,18 @@ func (bp *BatchProcessor) GetSuccessCount() int {
 	return count
 }
 
+// GetSkippedCount returns the number of operations skipped because an
+// earlier run completed them
+func (bp *BatchProcessor) GetSkippedCount() int {
+	count := 0
+	for _, result := range bp.results {
+		if result.Skipped {
+			count++
+		}
+	}
+	return count
+}
+
 // GetErrorCount returns the number of failed operations
 func (bp *BatchProcessor) GetErrorCount() int {
 	count := 0
@@ -154,21 +390,57 @@ func (bp *BatchProcessor) GetErrorCount() int {
 }
 
 // FormatResults formats the batch results for output
-func (bp *BatchProcessor) FormatResults(format string) (string, error) {
+func (bp *BatchProcessor) FormatResults(format string, opts formatter.Options) (string, error) {
 	switch strings.ToLower(format) {
-	case "json":
-		data, err := json.MarshalIndent(bp.results, "", "  ")
-		if err != nil {
-			return "", err
-		}
-		return string(data), nil
-
 	case "markdown", "md":
 		return bp.formatMarkdown(), nil
+	}
+	return formatter.FormatReport(format, opts, bp.results, bp.resultRows())
+}
+
+// resultRow is a batch result as one row of csv or tsv: the operation, its
+// outcome and what identifies the object it returned
+type resultRow struct {
+	Index      int    `json:"index"`
+	Ref        string `json:"ref"`
+	Type       string `json:"type"`
+	Action     string `json:"action"`
+	ID         string `json:"id"`
+	Success    bool   `json:"success"`
+	Skipped    bool   `json:"skipped"`
+	Error      string `json:"error"`
+	ResultID   string `json:"result_id"`
+	ResultName string `json:"result_name"`
+	RolledBack bool   `json:"rolled_back"`
+}
 
-	default:
-		return "", fmt.Errorf("unsupported format: %s", format)
+// resultRows returns a row for each result. The data of results is left out
+// but for its id and name, which keeps every row the same width.
+func (bp *BatchProcessor) resultRows() []resultRow {
+	rows := make([]resultRow, len(bp.results))
+	for i, result := range bp.results {
+		rows[i] = resultRow{
+			Index:      i + 1,
+			Ref:        result.Operation.Ref,
+			Type:       result.Operation.Type,
+			Action:     result.Operation.Action,
+			ID:         result.Operation.ID,
+			Success:    result.Success,
+			Skipped:    result.Skipped,
+			Error:      result.Error,
+			RolledBack: result.Rollback != nil && result.Rollback.Success,
+		}
+
+		var data struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if encoded, err := json.Marshal(result.Data); err == nil {
+			json.Unmarshal(encoded, &data)
+		}
+		rows[i].ResultID, rows[i].ResultName = data.ID, data.Name
 	}
+	return rows
 }
 
 // formatMarkdown formats results as markdown
@@ -178,7 +450,15 @@ func (bp *BatchProcessor) formatMarkdown() string {
 	sb.WriteString("# Batch Operation Results\n\n")
 	sb.WriteString(fmt.Sprintf("**Total Operations:** %d\n", len(bp.results)))
 	sb.WriteString(fmt.Sprintf("**Successful:** %d\n", bp.GetSuccessCount()))
-	sb.WriteString(fmt.Sprintf("**Failed:** %d\n\n", bp.GetErrorCount()))
+	sb.WriteString(fmt.Sprintf("**Failed:** %d\n", bp.GetErrorCount()))
+	if skipped := bp.GetSkippedCount(); skipped > 0 {
+		sb.WriteString(fmt.Sprintf("**Skipped (already done):** %d\n", skipped))
+	}
+	if undone, failed := bp.GetRollbackCount(), bp.GetRollbackErrorCount(); undone+failed > 0 {
+		sb.WriteString(fmt.Sprintf("**Rolled Back:** %d\n", undone))
+		sb.WriteString(fmt.Sprintf("**Rollback Failures:** %d\n", failed))
+	}
+	sb.WriteString("\n")
 
 	for i, result := range bp.results {
 		status := "✅"
@@ -191,17 +471,35 @@ func (bp *BatchProcessor) formatMarkdown() string {
 		sb.WriteString(fmt.Sprintf("- **Resource:** %s\n", result.Operation.Resource))
 		sb.WriteString(fmt.Sprintf("- **Action:** %s\n", result.Operation.Action))
 
+		if result.Operation.Ref != "" {
+			sb.WriteString(fmt.Sprintf("- **Ref:** %s\n", result.Operation.Ref))
+		}
+
 		if result.Operation.ID != "" {
 			sb.WriteString(fmt.Sprintf("- **ID:** %s\n", result.Operation.ID))
 		}
 
-		if result.Success {
+		if result.Skipped {
+			sb.WriteString("- **Status:** Skipped (completed by an earlier run)\n")
+		} else if result.Success {
 			sb.WriteString("- **Status:** Success\n")
 		} else {
 			sb.WriteString("- **Status:** Failed\n")
 			sb.WriteString(fmt.Sprintf("- **Error:** %s\n", result.Error))
 		}
 
+		if rollback := result.Rollback; rollback != nil {
+			undo := fmt.Sprintf("%s %s", rollback.Operation.Action, rollback.Operation.Type)
+			if rollback.Operation.ID != "" {
+				undo += " " + rollback.Operation.ID
+			}
+			if rollback.Success {
+				sb.WriteString(fmt.Sprintf("- **Rollback:** ✅ %s\n", undo))
+			} else {
+				sb.WriteString(fmt.Sprintf("- **Rollback:** ❌ %s: %s\n", undo, rollback.Error))
+			}
+		}
+
 		sb.WriteString("\n")
 	}
 
@@ -223,7 +521,7 @@ func ValidateOperation(op Operation) error {
 	}
 
 	// Validate operation types
-	validTypes := []string{"board", "list", "card", "label", "checklist", "member", "attachment"}
+	validTypes := []string{"board", "list", "card", "label", "checklist", "member", "attachment", "comment"}
 	validType := false
 	for _, vt := range validTypes {
 		if op.Type == vt {
@@ -2,9 +2,14 @@ package batch
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
 )
 
 func TestValidateOperation(t *testing.T) {
@@ -34,6 +39,16 @@ func TestValidateOperation(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "valid comment operation",
+			operation: Operation{
+				Type:     "comment",
+				Resource: "comment",
+				Action:   "add",
+				Data:     map[string]interface{}{"card_id": "test", "text": "hello"},
+			},
+			expectError: false,
+		},
 		{
 			name: "missing type",
 			operation: Operation{
@@ -325,6 +340,97 @@ func TestBatchProcessor(t *testing.T) {
 	}
 }
 
+func TestBatchProcessorConcurrency(t *testing.T) {
+	operations := make([]Operation, 20)
+	for i := range operations {
+		operations[i] = Operation{Type: "card", Resource: "card", Action: "archive", ID: fmt.Sprintf("card-%02d", i)}
+	}
+
+	t.Run("Results stay in input order", func(t *testing.T) {
+		var running, maxRunning int32
+		processor := NewBatchProcessor(true)
+		processor.SetConcurrency(4)
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				seen := atomic.LoadInt32(&maxRunning)
+				if n <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, n) {
+					break
+				}
+			}
+			// Later operations finish first
+			var i int
+			fmt.Sscanf(op.ID, "card-%d", &i)
+			time.Sleep(time.Duration(len(operations)-i) * time.Millisecond)
+			return op.ID, nil
+		})
+
+		results := processor.GetResults()
+		if len(results) != len(operations) {
+			t.Fatalf("expected %d results, got %d", len(operations), len(results))
+		}
+		for i, result := range results {
+			if result.Operation.ID != operations[i].ID || result.Data != operations[i].ID {
+				t.Errorf("result %d: expected %s, got %s", i, operations[i].ID, result.Operation.ID)
+			}
+		}
+		if maxRunning > 4 {
+			t.Errorf("expected at most 4 operations at a time, got %d", maxRunning)
+		}
+	})
+
+	t.Run("First failure stops operations that have not started", func(t *testing.T) {
+		var started int32
+		processor := NewBatchProcessor(false)
+		processor.SetConcurrency(3)
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			atomic.AddInt32(&started, 1)
+			if op.ID == "card-01" {
+				return nil, &TestError{Message: "rate limited"}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return op.ID, nil
+		})
+
+		if int(started) == len(operations) {
+			t.Error("expected outstanding operations to be cancelled")
+		}
+		results := processor.GetResults()
+		if len(results) != int(started) {
+			t.Errorf("expected a result for each of the %d started operations, got %d", started, len(results))
+		}
+		if processor.GetErrorCount() != 1 || results[1].Error != "rate limited" {
+			t.Errorf("expected the failure to be reported in place, got %+v", results)
+		}
+	})
+
+	t.Run("Continue on error runs everything", func(t *testing.T) {
+		processor := NewBatchProcessor(true)
+		processor.SetConcurrency(5)
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			if strings.HasSuffix(op.ID, "3") {
+				return nil, &TestError{Message: "failed"}
+			}
+			return op.ID, nil
+		})
+
+		if len(processor.GetResults()) != len(operations) || processor.GetErrorCount() != 2 {
+			t.Errorf("expected %d results with 2 errors, got %d with %d", len(operations), len(processor.GetResults()), processor.GetErrorCount())
+		}
+	})
+
+	t.Run("Concurrency key", func(t *testing.T) {
+		batchFile, err := LoadBatchFromReader(strings.NewReader("concurrency: 8\noperations: []\n"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if batchFile.Concurrency != 8 {
+			t.Errorf("expected concurrency 8, got %d", batchFile.Concurrency)
+		}
+	})
+}
+
 func TestFormatResults(t *testing.T) {
 	processor := NewBatchProcessor(true)
 
@@ -342,7 +448,7 @@ func TestFormatResults(t *testing.T) {
 	})
 
 	// Test JSON formatting
-	jsonResult, err := processor.FormatResults("json")
+	jsonResult, err := processor.FormatResults("json", formatter.Options{})
 	if err != nil {
 		t.Errorf("failed to format JSON results: %v", err)
 	}
@@ -358,7 +464,7 @@ func TestFormatResults(t *testing.T) {
 	}
 
 	// Test Markdown formatting
-	markdownResult, err := processor.FormatResults("markdown")
+	markdownResult, err := processor.FormatResults("markdown", formatter.Options{})
 	if err != nil {
 		t.Errorf("failed to format Markdown results: %v", err)
 	}
@@ -371,8 +477,20 @@ func TestFormatResults(t *testing.T) {
 		t.Error("expected markdown result to contain success indicator")
 	}
 
+	// Test CSV formatting
+	csvResult, err := processor.FormatResults("csv", formatter.Options{})
+	if err != nil {
+		t.Errorf("failed to format CSV results: %v", err)
+	}
+
+	expectedCSV := "index,ref,type,action,id,success,skipped,error,result_id,result_name,rolled_back\n" +
+		"1,,board,get,test-id,true,false,,test-result,,false"
+	if csvResult != expectedCSV {
+		t.Errorf("expected CSV results:\n%s\ngot:\n%s", expectedCSV, csvResult)
+	}
+
 	// Test unsupported format
-	_, err = processor.FormatResults("xml")
+	_, err = processor.FormatResults("xml", formatter.Options{})
 	if err == nil {
 		t.Error("expected error for unsupported format")
 	}
@@ -395,7 +513,7 @@ func TestFormatResultsWithErrors(t *testing.T) {
 	})
 
 	// Test JSON formatting with errors
-	jsonResult, err := processor.FormatResults("json")
+	jsonResult, err := processor.FormatResults("json", formatter.Options{})
 	if err != nil {
 		t.Errorf("failed to format JSON results: %v", err)
 	}
@@ -419,7 +537,7 @@ func TestFormatResultsWithErrors(t *testing.T) {
 	}
 
 	// Test Markdown formatting with errors
-	markdownResult, err := processor.FormatResults("markdown")
+	markdownResult, err := processor.FormatResults("markdown", formatter.Options{})
 	if err != nil {
 		t.Errorf("failed to format Markdown results: %v", err)
 	}
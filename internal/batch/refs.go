@@ -0,0 +1,319 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// refNamePattern is what an operation's ref may look like
+	refNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	// placeholderPattern matches ${ops.<ref>.<path>}, where path is a dotted
+	// list of JSON field names and slice indexes, e.g. ${ops.card.labels.0.id}
+	placeholderPattern = regexp.MustCompile(`\$\{ops\.([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_]+)+)\}`)
+)
+
+// placeholder is a single ${ops.<ref>.<path>} reference
+type placeholder struct {
+	text string
+	ref  string
+	path []string
+}
+
+// findPlaceholders returns the placeholders in s, and an error for text that
+// starts like a placeholder but is not one
+func findPlaceholders(s string) ([]placeholder, error) {
+	var found []placeholder
+	for _, m := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+		found = append(found, placeholder{text: m[0], ref: m[1], path: strings.Split(m[2][1:], ".")})
+	}
+	if rest := placeholderPattern.ReplaceAllString(s, ""); ContainsReference(rest) {
+		return nil, fmt.Errorf("malformed reference in %q (expected ${ops.<ref>.<field>})", s)
+	}
+	return found, nil
+}
+
+// ContainsReference reports whether s uses the output of another operation
+func ContainsReference(s string) bool {
+	return strings.Contains(s, "${ops.")
+}
+
+// walkStrings calls fn for every string in the id, data and parameters of op
+func walkStrings(op Operation, fn func(s string) error) error {
+	if err := fn(op.ID); err != nil {
+		return err
+	}
+	if err := walkValue(op.Data, fn); err != nil {
+		return err
+	}
+	for _, v := range op.Parameters {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkValue(value interface{}, fn func(s string) error) error {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := walkValue(item, fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := walkValue(item, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// References returns the refs of the earlier operations op uses, in the order
+// they first appear
+func (op Operation) References() []string {
+	var refs []string
+	seen := make(map[string]bool)
+	walkStrings(op, func(s string) error {
+		found, _ := findPlaceholders(s)
+		for _, p := range found {
+			if !seen[p.ref] {
+				seen[p.ref] = true
+				refs = append(refs, p.ref)
+			}
+		}
+		return nil
+	})
+	return refs
+}
+
+// ValidateReferences checks the refs and placeholders of a batch before
+// anything runs: refs must be unique and well-formed, and placeholders may
+// only use refs of earlier operations. It reports every problem found.
+func ValidateReferences(operations []Operation) error {
+	var problems []error
+	for _, p := range referenceProblems(operations) {
+		problems = append(problems, fmt.Errorf("operation %d: %w", p.index+1, p.err))
+	}
+	return errors.Join(problems...)
+}
+
+// operationError is a problem with the operation at index
+type operationError struct {
+	index int
+	err   error
+}
+
+// referenceProblems returns the problems ValidateReferences reports, with
+// the operation each one belongs to
+func referenceProblems(operations []Operation) []operationError {
+	var problems []operationError
+	defined := make(map[string]int)
+	for i, op := range operations {
+		if op.Ref == "" {
+			continue
+		}
+		if !refNamePattern.MatchString(op.Ref) {
+			problems = append(problems, operationError{i, fmt.Errorf("invalid ref %q (use letters, digits, _ and -)", op.Ref)})
+			continue
+		}
+		if first, ok := defined[op.Ref]; ok {
+			problems = append(problems, operationError{i, fmt.Errorf("duplicate ref %q (already used by operation %d)", op.Ref, first+1)})
+			continue
+		}
+		defined[op.Ref] = i
+	}
+
+	for i, op := range operations {
+		err := walkStrings(op, func(s string) error {
+			found, err := findPlaceholders(s)
+			if err != nil {
+				return err
+			}
+			for _, p := range found {
+				j, ok := defined[p.ref]
+				switch {
+				case !ok:
+					problems = append(problems, operationError{i, fmt.Errorf("unknown reference %s", p.text)})
+				case j >= i:
+					problems = append(problems, operationError{i, fmt.Errorf("reference %s must point to an earlier operation (%q is operation %d)", p.text, p.ref, j+1)})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			problems = append(problems, operationError{i, err})
+		}
+	}
+
+	return problems
+}
+
+// outputs holds the results of the operations with a ref, as decoded JSON
+type outputs struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// set records the result of the operation with ref
+func (o *outputs) set(ref string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to record output of %q: %w", ref, err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return fmt.Errorf("failed to record output of %q: %w", ref, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	o.values[ref] = value
+	return nil
+}
+
+// lookup returns the value a placeholder points to
+func (o *outputs) lookup(p placeholder) (interface{}, error) {
+	o.mu.Lock()
+	value, ok := o.values[p.ref]
+	o.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: operation %q did not succeed", p.text, p.ref)
+	}
+
+	for _, key := range p.path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value, ok = v[key]
+		case []interface{}:
+			n, err := strconv.Atoi(key)
+			ok = err == nil && n >= 0 && n < len(v)
+			if ok {
+				value = v[n]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s: the output of %q has no %q", p.text, p.ref, key)
+		}
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%s: the value is empty", p.text)
+	}
+	return value, nil
+}
+
+// expand returns a copy of op with its placeholders replaced by the outputs
+// of earlier operations
+func (o *outputs) expand(op Operation) (Operation, error) {
+	id, err := o.expandValue(op.ID)
+	if err != nil {
+		return op, err
+	}
+	data, err := o.expandValue(op.Data)
+	if err != nil {
+		return op, err
+	}
+
+	idString, ok := id.(string)
+	if !ok {
+		return op, fmt.Errorf("%s: the referenced field is not a string ID", op.ID)
+	}
+
+	expanded := op
+	expanded.ID = idString
+	if op.Data != nil {
+		expanded.Data = data.(map[string]interface{})
+	}
+	if op.Parameters != nil {
+		expanded.Parameters = make(map[string]string, len(op.Parameters))
+		for key, v := range op.Parameters {
+			value, err := o.expandString(v)
+			if err != nil {
+				return op, err
+			}
+			expanded.Parameters[key] = fmt.Sprint(value)
+		}
+	}
+	return expanded, nil
+}
+
+func (o *outputs) expandValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return o.expandString(v)
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			value, err := o.expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = value
+		}
+		return expanded, nil
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := o.expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i] = value
+		}
+		return expanded, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandString replaces the placeholders in s. A string that is exactly one
+// placeholder takes the referenced value as is, e.g. a number stays a number.
+func (o *outputs) expandString(s string) (interface{}, error) {
+	found, err := findPlaceholders(s)
+	if err != nil || len(found) == 0 {
+		return s, err
+	}
+	if len(found) == 1 && found[0].text == s {
+		return o.lookup(found[0])
+	}
+
+	for _, p := range found {
+		value, err := o.lookup(p)
+		if err != nil {
+			return nil, err
+		}
+		s = strings.Replace(s, p.text, formatOutput(value), 1)
+	}
+	return s, nil
+}
+
+// formatOutput formats a decoded JSON value for use inside a string
+func formatOutput(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
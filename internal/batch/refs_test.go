@@ -0,0 +1,236 @@
+package batch
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations []Operation
+		errors     []string
+	}{
+		{
+			name: "Backward references",
+			operations: []Operation{
+				{Ref: "board", Type: "board", Resource: "board", Action: "create"},
+				{Ref: "list", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"board_id": "${ops.board.id}"}},
+				{Type: "card", Resource: "card", Action: "create", Parameters: map[string]string{"desc": "On ${ops.list.name}"},
+					Data: map[string]interface{}{"list_id": "${ops.list.id}", "labels": []interface{}{"${ops.board.labels.0.id}"}}},
+			},
+		},
+		{
+			name: "Unknown reference",
+			operations: []Operation{
+				{Type: "card", Resource: "card", Action: "archive", ID: "${ops.missing.id}"},
+			},
+			errors: []string{"operation 1: unknown reference ${ops.missing.id}"},
+		},
+		{
+			name: "Forward and self references",
+			operations: []Operation{
+				{Ref: "first", Type: "list", Resource: "list", Action: "create", Data: map[string]interface{}{"board_id": "${ops.second.id}"}},
+				{Ref: "second", Type: "card", Resource: "card", Action: "update", ID: "${ops.second.id}"},
+			},
+			errors: []string{
+				`operation 1: reference ${ops.second.id} must point to an earlier operation ("second" is operation 2)`,
+				`operation 2: reference ${ops.second.id} must point to an earlier operation ("second" is operation 2)`,
+			},
+		},
+		{
+			name: "Duplicate and invalid refs",
+			operations: []Operation{
+				{Ref: "card", Type: "card", Resource: "card", Action: "create"},
+				{Ref: "card", Type: "card", Resource: "card", Action: "create"},
+				{Ref: "my.card", Type: "card", Resource: "card", Action: "create"},
+			},
+			errors: []string{
+				`operation 2: duplicate ref "card" (already used by operation 1)`,
+				`operation 3: invalid ref "my.card"`,
+			},
+		},
+		{
+			name: "Malformed placeholder",
+			operations: []Operation{
+				{Ref: "card", Type: "card", Resource: "card", Action: "create"},
+				{Type: "card", Resource: "card", Action: "archive", ID: "${ops.card}"},
+			},
+			errors: []string{"operation 2: malformed reference"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateReferences(tt.operations)
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v, got none", tt.errors)
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.errors) {
+				t.Errorf("expected %d problem(s), got %q", len(tt.errors), err.Error())
+			}
+			for _, expected := range tt.errors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error containing %q, got %q", expected, err.Error())
+				}
+			}
+		})
+	}
+}
+
+// fakeCard is what a processor returns for created resources in these tests
+type fakeCard struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Pos    float64  `json:"pos"`
+	Labels []string `json:"labels"`
+}
+
+func TestProcessOperationsWithReferences(t *testing.T) {
+	operations := []Operation{
+		{Ref: "card", Type: "card", Resource: "card", Action: "create", Data: map[string]interface{}{"name": "Release"}},
+		{Type: "card", Resource: "card", Action: "update", ID: "${ops.card.id}",
+			Data:       map[string]interface{}{"pos": "${ops.card.pos}", "items": []interface{}{"${ops.card.labels.1}"}},
+			Parameters: map[string]string{"desc": "Follow-up to ${ops.card.name} (${ops.card.id}) at ${ops.card.pos}"}},
+	}
+
+	t.Run("Placeholders are filled from earlier results", func(t *testing.T) {
+		var seen []Operation
+		processor := NewBatchProcessor(false)
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			seen = append(seen, op)
+			if op.Action == "create" {
+				return &fakeCard{ID: "c1", Name: "Release", Pos: 16384.5, Labels: []string{"l1", "l2"}}, nil
+			}
+			return "updated", nil
+		})
+
+		if processor.GetErrorCount() != 0 {
+			t.Fatalf("unexpected errors: %+v", processor.GetResults())
+		}
+		update := seen[1]
+		if update.ID != "c1" {
+			t.Errorf("expected ID c1, got %s", update.ID)
+		}
+		if pos, ok := update.Data["pos"].(float64); !ok || pos != 16384.5 {
+			t.Errorf("expected a whole-value placeholder to keep its type, got %#v", update.Data["pos"])
+		}
+		if items := update.Data["items"].([]interface{}); items[0] != "l2" {
+			t.Errorf("expected l2, got %v", items[0])
+		}
+		if desc := update.Parameters["desc"]; desc != "Follow-up to Release (c1) at 16384.5" {
+			t.Errorf("unexpected desc %q", desc)
+		}
+		if operations[1].ID != "${ops.card.id}" {
+			t.Error("expected the input operation to be left alone")
+		}
+		if got := processor.GetResults()[1].Operation.ID; got != "c1" {
+			t.Errorf("expected the result to hold the operation as run, got %s", got)
+		}
+	})
+
+	t.Run("Failed dependencies fail dependents", func(t *testing.T) {
+		processor := NewBatchProcessor(true)
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			if op.Action == "create" {
+				return nil, &TestError{Message: "board is closed"}
+			}
+			return "updated", nil
+		})
+
+		results := processor.GetResults()
+		if len(results) != 2 || results[1].Success {
+			t.Fatalf("expected the dependent operation to fail, got %+v", results)
+		}
+		if !strings.Contains(results[1].Error, `operation "card" did not succeed`) {
+			t.Errorf("unexpected error %q", results[1].Error)
+		}
+	})
+
+	t.Run("Missing fields", func(t *testing.T) {
+		processor := NewBatchProcessor(true)
+		processor.ProcessOperations([]Operation{
+			{Ref: "card", Type: "card", Resource: "card", Action: "create"},
+			{Type: "card", Resource: "card", Action: "archive", ID: "${ops.card.shortUrl}"},
+		}, func(op Operation) (interface{}, error) {
+			return &fakeCard{ID: "c1"}, nil
+		})
+
+		results := processor.GetResults()
+		if results[1].Success || !strings.Contains(results[1].Error, `has no "shortUrl"`) {
+			t.Errorf("expected a missing field error, got %+v", results[1])
+		}
+	})
+
+	t.Run("IDs must be strings", func(t *testing.T) {
+		processor := NewBatchProcessor(true)
+		processor.ProcessOperations([]Operation{
+			{Ref: "card", Type: "card", Resource: "card", Action: "create"},
+			{Type: "card", Resource: "card", Action: "archive", ID: "${ops.card.labels}"},
+		}, func(op Operation) (interface{}, error) {
+			return &fakeCard{ID: "c1", Labels: []string{"l1"}}, nil
+		})
+
+		results := processor.GetResults()
+		if results[1].Success || !strings.Contains(results[1].Error, "${ops.card.labels}: the referenced field is not a string ID") {
+			t.Errorf("expected a non-string ID error, got %+v", results[1])
+		}
+	})
+
+	t.Run("Dependents wait under concurrency", func(t *testing.T) {
+		chain := []Operation{
+			{Ref: "slow", Type: "card", Resource: "card", Action: "create"},
+			{Type: "card", Resource: "card", Action: "archive", ID: "independent"},
+			{Ref: "next", Type: "card", Resource: "card", Action: "copy", ID: "${ops.slow.id}"},
+			{Type: "card", Resource: "card", Action: "archive", ID: "${ops.next.id}"},
+		}
+
+		var slowDone int32
+		processor := NewBatchProcessor(false)
+		processor.SetConcurrency(4)
+		processor.ProcessOperations(chain, func(op Operation) (interface{}, error) {
+			switch op.Action {
+			case "create":
+				time.Sleep(20 * time.Millisecond)
+				atomic.StoreInt32(&slowDone, 1)
+				return &fakeCard{ID: "c1"}, nil
+			case "copy":
+				if atomic.LoadInt32(&slowDone) == 0 {
+					return nil, &TestError{Message: "ran before its dependency"}
+				}
+				return &fakeCard{ID: op.ID + "-copy"}, nil
+			}
+			return op.ID, nil
+		})
+
+		results := processor.GetResults()
+		if len(results) != len(chain) || processor.GetErrorCount() != 0 {
+			t.Fatalf("expected %d successful results, got %+v", len(chain), results)
+		}
+		if results[3].Operation.ID != "c1-copy" {
+			t.Errorf("expected the chain to resolve to c1-copy, got %s", results[3].Operation.ID)
+		}
+	})
+
+	t.Run("Waiting dependents stop after a failure", func(t *testing.T) {
+		processor := NewBatchProcessor(false)
+		processor.SetConcurrency(2)
+		processor.ProcessOperations(operations, func(op Operation) (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+			return nil, &TestError{Message: "failed"}
+		})
+
+		if results := processor.GetResults(); len(results) != 1 {
+			t.Errorf("expected only the failed operation to be reported, got %+v", results)
+		}
+	})
+}
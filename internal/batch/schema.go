@@ -0,0 +1,182 @@
+package batch
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Schema returns the JSON Schema (draft 2020-12) for batch files. Each
+// operation type only allows its own actions, and each action requires its
+// id and data keys, so editors and LLMs can check a file before it runs.
+func Schema() map[string]interface{} {
+	types := make([]string, 0, len(actions))
+	for typ := range actions {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	nonEmpty := map[string]interface{}{"type": "string", "minLength": 1}
+	var rules []interface{}
+	for _, typ := range types {
+		names := make([]string, 0, len(actions[typ]))
+		for action := range actions[typ] {
+			names = append(names, action)
+		}
+		sort.Strings(names)
+
+		rules = append(rules, map[string]interface{}{
+			"if":   map[string]interface{}{"properties": map[string]interface{}{"type": map[string]interface{}{"const": typ}}, "required": []string{"type"}},
+			"then": map[string]interface{}{"properties": map[string]interface{}{"action": map[string]interface{}{"enum": names}}},
+		})
+
+		for _, action := range names {
+			spec := actions[typ][action]
+			required := []string{}
+			dataRequired := []string{}
+			dataProperties := map[string]interface{}{}
+			for _, field := range spec.Required {
+				if field == "id" {
+					required = append(required, "id")
+					continue
+				}
+				dataRequired = append(dataRequired, field)
+				dataProperties[field] = nonEmpty
+			}
+			for _, field := range spec.Optional {
+				dataProperties[field] = map[string]interface{}{}
+			}
+
+			then := map[string]interface{}{}
+			if len(dataRequired) > 0 {
+				required = append(required, "data")
+			}
+			if len(required) > 0 {
+				then["required"] = required
+			}
+			if len(dataProperties) > 0 {
+				data := map[string]interface{}{"properties": dataProperties}
+				if len(dataRequired) > 0 {
+					data["required"] = dataRequired
+				}
+				then["properties"] = map[string]interface{}{"data": data}
+			}
+			if len(then) == 0 {
+				continue
+			}
+
+			rules = append(rules, map[string]interface{}{
+				"if": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"type":   map[string]interface{}{"const": typ},
+						"action": map[string]interface{}{"const": action},
+					},
+					"required": []string{"type", "action"},
+				},
+				"then": then,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "trello-cli batch file",
+		"description":          "Operations for trello-cli batch file, batch stdin and batch validate",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"operations": map[string]interface{}{
+				"description": "Operations to run, in order",
+				"type":        "array",
+				"items":       map[string]interface{}{"$ref": "#/$defs/operation"},
+			},
+			"continue_on_error": map[string]interface{}{
+				"description": "Keep going after an operation fails",
+				"type":        "boolean",
+				"default":     false,
+			},
+			"concurrency": map[string]interface{}{
+				"description": "Number of operations to run in parallel",
+				"type":        "integer",
+				"minimum":     1,
+				"default":     1,
+			},
+			"transactional": map[string]interface{}{
+				"description": "Undo the completed operations when one fails",
+				"type":        "boolean",
+				"default":     false,
+			},
+			"vars": map[string]interface{}{
+				"description": "Values for {{ .vars.<name> }} in templates, overridden with --var name=value",
+				"type":        "object",
+			},
+		},
+		"$defs": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"type", "resource", "action"},
+				"properties": map[string]interface{}{
+					"ref": map[string]interface{}{
+						"description": "Name later operations use in ${ops.<ref>.<field>} placeholders",
+						"type":        "string",
+						"pattern":     refNamePattern.String(),
+					},
+					"type": map[string]interface{}{
+						"description": "Kind of object the operation works on",
+						"enum":        types,
+					},
+					"resource": map[string]interface{}{
+						"description": "Same as type",
+						"type":        "string",
+						"minLength":   1,
+					},
+					"action": map[string]interface{}{
+						"description": "What to do; the valid actions depend on type",
+						"type":        "string",
+					},
+					"id": map[string]interface{}{
+						"description": "ID, short link, URL or name of the object the action works on",
+						"type":        "string",
+					},
+					"data": map[string]interface{}{
+						"description": "Fields of the action",
+						"type":        "object",
+					},
+					"parameters": map[string]interface{}{
+						"description":          "Extra parameters passed to the Trello API",
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"type": "string"},
+					},
+					"foreach": map[string]interface{}{
+						"description": "Repeat the operation for each item, available as {{ .item }} and {{ .index }} in templates",
+						"oneOf": []interface{}{
+							map[string]interface{}{"type": "array"},
+							map[string]interface{}{
+								"type":                 "object",
+								"additionalProperties": false,
+								"required":             []string{"file"},
+								"properties": map[string]interface{}{
+									"file": map[string]interface{}{
+										"description": "CSV file, with a header row, or JSON file of an array, relative to the batch file",
+										"type":        "string",
+										"minLength":   1,
+									},
+								},
+							},
+						},
+					},
+				},
+				"allOf": rules,
+			},
+		},
+	}
+}
+
+// SchemaJSON returns the schema as indented JSON
+func SchemaJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
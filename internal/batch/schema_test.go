@@ -0,0 +1,56 @@
+package batch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema, err := SchemaJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		Defs struct {
+			Operation struct {
+				AllOf []struct {
+					If struct {
+						Properties map[string]struct {
+							Const string `json:"const"`
+						} `json:"properties"`
+					} `json:"if"`
+					Then struct {
+						Required   []string `json:"required"`
+						Properties struct {
+							Data struct {
+								Required []string `json:"required"`
+							} `json:"data"`
+						} `json:"properties"`
+					} `json:"then"`
+				} `json:"allOf"`
+			} `json:"operation"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(schema, &decoded); err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+
+	// Every action has a rule with its required fields
+	covered := make(map[string][]string)
+	for _, rule := range decoded.Defs.Operation.AllOf {
+		if action := rule.If.Properties["action"].Const; action != "" {
+			covered[rule.If.Properties["type"].Const+" "+action] = append(rule.Then.Required, rule.Then.Properties.Data.Required...)
+		}
+	}
+	for typ, byAction := range actions {
+		for action := range byAction {
+			if _, ok := covered[typ+" "+action]; !ok {
+				t.Errorf("no schema rule for %s %s", typ, action)
+			}
+		}
+	}
+	if got := covered["card create"]; len(got) != 3 || got[0] != "data" || got[1] != "name" || got[2] != "list_id" {
+		t.Errorf("expected card create to require data.name and data.list_id, got %v", got)
+	}
+}
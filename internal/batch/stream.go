@@ -0,0 +1,215 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxStreamLine is the longest operation line ProcessStream accepts
+const maxStreamLine = 1024 * 1024
+
+// StreamResult is the result of one operation of a streamed batch
+type StreamResult struct {
+	// Index is the 1-based position of the operation in the input, not
+	// counting blank lines
+	Index int `json:"index"`
+	BatchResult
+}
+
+// StreamSummary is written after the results of a streamed batch
+type StreamSummary struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+
+	// Stopped means the batch stopped early, because an operation failed
+	// or it was interrupted, and the rest of the input was not run
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// ProcessStream runs operations read from r, one JSON object per line
+// (NDJSON), as they arrive instead of after the whole input has been read.
+// emit is called with each result as soon as its operation completes, so
+// with a concurrency above 1 results can come out of input order; their
+// Index tells them apart. emit is never called concurrently.
+//
+// An operation may use the ref of an operation on an earlier line, and
+// waits for it. A line that is not a valid operation fails like an operation
+// would. Unless continueOnError is set, the first failure stops reading the
+// input; operations already running finish and are reported.
+func (bp *BatchProcessor) ProcessStream(r io.Reader, processor func(Operation) (interface{}, error), emit func(StreamResult)) (StreamSummary, error) {
+	type line struct {
+		number int
+		text   []byte
+	}
+	lines := make(chan line)
+	var readErr error
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+		for n := 1; scanner.Scan(); n++ {
+			if text := bytes.TrimSpace(scanner.Bytes()); len(text) > 0 {
+				lines <- line{n, append([]byte(nil), text...)}
+			}
+		}
+		readErr = scanner.Err()
+	}()
+
+	var mu sync.Mutex
+	var summary StreamSummary
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	finish := func(index int, result BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		bp.results = append(bp.results, result)
+		summary.Total++
+		if result.Success {
+			summary.Successful++
+		} else {
+			summary.Failed++
+			if !bp.continueOnError {
+				stopOnce.Do(func() { close(stop) })
+			}
+		}
+		emit(StreamResult{Index: index + 1, BatchResult: result})
+	}
+
+	slots := make(chan struct{}, bp.concurrency)
+	var wg sync.WaitGroup
+	var done []chan struct{}
+	refs := make(map[string]int)
+
+read:
+	for {
+		var next line
+		var ok bool
+		select {
+		case next, ok = <-lines:
+			if !ok {
+				break read
+			}
+		case <-stop:
+			break read
+		case <-bp.interrupts:
+			break read
+		}
+
+		i := len(done)
+		finished := make(chan struct{})
+		done = append(done, finished)
+		op, err := decodeStreamOperation(next.text)
+		if err == nil {
+			err = checkStreamReferences(op, refs)
+		}
+		var waits []chan struct{}
+		if err == nil {
+			if op.Ref != "" {
+				refs[op.Ref] = i
+			}
+			for _, ref := range op.References() {
+				waits = append(waits, done[refs[ref]])
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-stop:
+			close(finished)
+			break read
+		case <-bp.interrupts:
+			close(finished)
+			break read
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer close(finished)
+			for _, wait := range waits {
+				<-wait
+			}
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if bp.Interrupted() {
+				return
+			}
+
+			// An invalid line takes its turn too, so that with a
+			// concurrency of 1 results stay in input order
+			if err != nil {
+				finish(i, BatchResult{Operation: op, Error: fmt.Sprintf("line %d: %v", next.number, err)})
+				return
+			}
+			finish(i, bp.runOperation(i, op, func(_ int, op Operation) (interface{}, error) {
+				return processor(op)
+			}))
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-stop:
+		summary.Stopped = true
+	default:
+		summary.Stopped = bp.Interrupted()
+	}
+	if summary.Stopped {
+		return summary, nil
+	}
+	return summary, readErr
+}
+
+// decodeStreamOperation decodes one line of a stream, rejecting unknown keys
+func decodeStreamOperation(text []byte) (Operation, error) {
+	var op Operation
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&op); err != nil {
+		return Operation{}, fmt.Errorf("invalid operation: %w", err)
+	}
+	if decoder.More() {
+		return Operation{}, fmt.Errorf("invalid operation: one JSON object per line is expected")
+	}
+	if op.Foreach != nil {
+		return Operation{}, fmt.Errorf("invalid operation: foreach is only expanded in batch files")
+	}
+	return op, nil
+}
+
+// checkStreamReferences checks the ref and placeholders of an operation
+// against the refs of the lines before it, like ValidateReferences does for
+// a whole batch
+func checkStreamReferences(op Operation, refs map[string]int) error {
+	if op.Ref != "" {
+		if !refNamePattern.MatchString(op.Ref) {
+			return fmt.Errorf("invalid ref %q (use letters, digits, _ and -)", op.Ref)
+		}
+		if first, ok := refs[op.Ref]; ok {
+			return fmt.Errorf("duplicate ref %q (already used by operation %d)", op.Ref, first+1)
+		}
+	}
+
+	return walkStrings(op, func(s string) error {
+		found, err := findPlaceholders(s)
+		if err != nil {
+			return err
+		}
+		for _, p := range found {
+			if _, ok := refs[p.ref]; !ok {
+				return fmt.Errorf("unknown reference %s (refs must be defined on an earlier line)", p.text)
+			}
+		}
+		return nil
+	})
+}
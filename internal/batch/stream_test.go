@@ -0,0 +1,124 @@
+package batch
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessStream(t *testing.T) {
+	process := func(op Operation) (interface{}, error) {
+		if op.ID == "fail" {
+			return nil, &TestError{Message: "card not found"}
+		}
+		return &fakeCard{ID: op.Action + "-" + op.ID}, nil
+	}
+
+	t.Run("Each line runs and is reported", func(t *testing.T) {
+		input := `{"ref": "first", "type": "card", "resource": "card", "action": "create", "id": "one"}
+
+{"type": "card", "resource": "card", "action": "copy", "id": "${ops.first.id}"}
+not json
+{"type": "card", "resource": "card", "action": "get", "id": "two", "colour": "red"}
+{"type": "card", "resource": "card", "action": "get", "id": "${ops.later.id}"}
+`
+		processor := NewBatchProcessor(true)
+		var results []StreamResult
+		summary, err := processor.ProcessStream(strings.NewReader(input), process, func(result StreamResult) {
+			results = append(results, result)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if summary != (StreamSummary{Total: 5, Successful: 2, Failed: 3}) {
+			t.Errorf("unexpected summary %+v", summary)
+		}
+		if len(results) != 5 {
+			t.Fatalf("expected 5 results, got %d", len(results))
+		}
+		if results[1].Index != 2 || results[1].Operation.ID != "create-one" || !results[1].Success {
+			t.Errorf("expected the copy to use the ref of the first line, got %+v", results[1])
+		}
+		for i, expected := range []string{
+			"line 4: invalid operation",
+			`line 5: invalid operation: json: unknown field "colour"`,
+			"line 6: unknown reference ${ops.later.id} (refs must be defined on an earlier line)",
+		} {
+			if result := results[i+2]; result.Success || result.Index != i+3 || !strings.HasPrefix(result.Error, expected) {
+				t.Errorf("expected error %q, got %+v", expected, result)
+			}
+		}
+	})
+
+	t.Run("A failure stops reading the input", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.Write([]byte(`{"type": "card", "resource": "card", "action": "archive", "id": "fail"}` + "\n"))
+			// The rest of the input never arrives
+		}()
+
+		processor := NewBatchProcessor(false)
+		stopped := make(chan StreamSummary)
+		go func() {
+			summary, _ := processor.ProcessStream(reader, process, func(StreamResult) {})
+			stopped <- summary
+		}()
+
+		select {
+		case summary := <-stopped:
+			if !summary.Stopped || summary.Failed != 1 {
+				t.Errorf("expected a stopped batch with 1 failure, got %+v", summary)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the stream to stop without waiting for more input")
+		}
+	})
+
+	t.Run("Results are emitted as operations complete", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		defer writer.Close()
+
+		processor := NewBatchProcessor(false)
+		processor.SetConcurrency(2)
+		emitted := make(chan StreamResult)
+		go processor.ProcessStream(reader, process, func(result StreamResult) {
+			emitted <- result
+		})
+
+		for i, id := range []string{"one", "two"} {
+			writer.Write([]byte(`{"type": "card", "resource": "card", "action": "archive", "id": "` + id + `"}` + "\n"))
+			select {
+			case result := <-emitted:
+				if result.Index != i+1 || !result.Success {
+					t.Errorf("unexpected result %+v", result)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected the result of line %d before the input ends", i+1)
+			}
+		}
+	})
+
+	t.Run("An interrupt stops a stream waiting for input", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		defer writer.Close()
+
+		processor := NewBatchProcessor(false)
+		stopped := make(chan StreamSummary)
+		go func() {
+			summary, _ := processor.ProcessStream(reader, process, func(StreamResult) {})
+			stopped <- summary
+		}()
+		processor.Interrupt()
+
+		select {
+		case summary := <-stopped:
+			if !summary.Stopped {
+				t.Errorf("expected a stopped batch, got %+v", summary)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the interrupt to stop the stream")
+		}
+	})
+}
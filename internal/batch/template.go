@@ -0,0 +1,272 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Foreach is what an operation is repeated for: the items of an inline list,
+// or the rows of a CSV file or the items of a JSON array in a file
+type Foreach struct {
+	Items []interface{}
+	File  string
+}
+
+// UnmarshalYAML decodes a list of items or an object with a file
+func (f *Foreach) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return node.Decode(&f.Items)
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i].Value; key != "file" {
+				return fmt.Errorf("unknown foreach key %q (valid: file)", key)
+			}
+		}
+		var source struct {
+			File string `yaml:"file"`
+		}
+		if err := node.Decode(&source); err != nil {
+			return err
+		}
+		if source.File == "" {
+			return fmt.Errorf("foreach file is required")
+		}
+		f.File = source.File
+		return nil
+	}
+	return fmt.Errorf("foreach must be a list, or an object with a file")
+}
+
+// UnmarshalJSON decodes a JSON foreach the way UnmarshalYAML does, as JSON is
+// YAML too
+func (f *Foreach) UnmarshalJSON(data []byte) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	return f.UnmarshalYAML(node.Content[0])
+}
+
+// MarshalJSON encodes the foreach as it is written in a batch file
+func (f Foreach) MarshalJSON() ([]byte, error) {
+	if f.File != "" {
+		return json.Marshal(map[string]string{"file": f.File})
+	}
+	if f.Items == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(f.Items)
+}
+
+// origin is where an expanded operation comes from: the index of the
+// operation in the batch file and, for a foreach, the 1-based item
+type origin struct {
+	index int
+	item  int
+}
+
+// prefix names the item of a foreach in problems with the operation
+func (o origin) prefix() string {
+	if o.item == 0 {
+		return ""
+	}
+	return fmt.Sprintf("item %d: ", o.item)
+}
+
+// Expand expands the templates of a batch before it runs: every operation
+// with foreach is repeated for each of its items, and the text/template
+// expressions in its ref, id, data and parameters are executed with .vars,
+// and in a foreach with .item and .index (from 0). vars override the vars of
+// the file; foreach files are relative to dir. Expand reports every problem.
+func (bf *BatchFile) Expand(vars map[string]string, dir string) error {
+	operations, _, problems := expandOperations(bf, vars, dir)
+	if len(problems) > 0 {
+		var errs []error
+		for _, p := range problems {
+			errs = append(errs, fmt.Errorf("operation %d: %w", p.index+1, p.err))
+		}
+		return errors.Join(errs...)
+	}
+
+	bf.Operations = operations
+	bf.Vars = nil
+	return nil
+}
+
+// expandOperations expands the operations of a batch and returns them with
+// the origin of each, and the problems with the operations of the file
+func expandOperations(bf *BatchFile, overrides map[string]string, dir string) ([]Operation, []origin, []operationError) {
+	vars := make(map[string]interface{}, len(bf.Vars)+len(overrides))
+	for key, value := range bf.Vars {
+		vars[key] = value
+	}
+	for key, value := range overrides {
+		vars[key] = value
+	}
+
+	var operations []Operation
+	var origins []origin
+	var problems []operationError
+	for i, op := range bf.Operations {
+		if op.Foreach == nil {
+			expanded, err := expandOperation(op, map[string]interface{}{"vars": vars})
+			if err != nil {
+				problems = append(problems, operationError{i, err})
+				continue
+			}
+			operations = append(operations, expanded)
+			origins = append(origins, origin{index: i})
+			continue
+		}
+
+		items, err := op.Foreach.items(vars, dir)
+		if err != nil {
+			problems = append(problems, operationError{i, err})
+			continue
+		}
+		for j, item := range items {
+			expanded, err := expandOperation(op, map[string]interface{}{"vars": vars, "item": item, "index": j})
+			if err != nil {
+				problems = append(problems, operationError{i, fmt.Errorf("item %d: %w", j+1, err)})
+				continue
+			}
+			operations = append(operations, expanded)
+			origins = append(origins, origin{index: i, item: j + 1})
+		}
+	}
+	return operations, origins, problems
+}
+
+// items returns the items of a foreach: the inline list, the rows of a CSV
+// file as objects keyed by the header row, or the items of a JSON array
+func (f *Foreach) items(vars map[string]interface{}, dir string) ([]interface{}, error) {
+	if f.File == "" {
+		return f.Items, nil
+	}
+
+	name, err := expandString("foreach.file", f.File, map[string]interface{}{"vars": vars})
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreach file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return csvItems(data)
+	case ".json":
+		var items []interface{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("foreach file %s must hold a JSON array: %w", name, err)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("foreach file %s must be a .csv or .json file", name)
+}
+
+// csvItems returns the rows of a CSV file after the header row, each as an
+// object of its values keyed by the column names
+func csvItems(data []byte) ([]interface{}, error) {
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV in foreach file: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	items := make([]interface{}, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		item := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			item[strings.TrimSpace(column)] = row[i]
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// expandOperation returns a copy of op without foreach and with the templates
+// in its ref, id, data and parameters executed with data
+func expandOperation(op Operation, data map[string]interface{}) (Operation, error) {
+	var problems []error
+	expand := func(field, s string) string {
+		expanded, err := expandString(field, s, data)
+		if err != nil {
+			problems = append(problems, err)
+		}
+		return expanded
+	}
+
+	op.Foreach = nil
+	op.Ref = expand("ref", op.Ref)
+	op.ID = expand("id", op.ID)
+	if op.Data != nil {
+		op.Data = expandValue("data", op.Data, expand).(map[string]interface{})
+	}
+	if op.Parameters != nil {
+		parameters := make(map[string]string, len(op.Parameters))
+		for key, value := range op.Parameters {
+			parameters[key] = expand("parameters."+key, value)
+		}
+		op.Parameters = parameters
+	}
+	return op, errors.Join(problems...)
+}
+
+// expandValue returns a copy of a data value with expand applied to every
+// string in it
+func expandValue(field string, value interface{}, expand func(field, s string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return expand(field, v)
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			copied[key] = expandValue(field+"."+key, val, expand)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, val := range v {
+			copied[i] = expandValue(fmt.Sprintf("%s.%d", field, i), val, expand)
+		}
+		return copied
+	}
+	return value
+}
+
+// expandString executes s as a template with data. Strings without {{ are
+// returned as they are; a key that data does not have is an error.
+func expandString(field, s string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return s, err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return s, err
+	}
+	return sb.String(), nil
+}
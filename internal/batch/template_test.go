@@ -0,0 +1,194 @@
+package batch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cards.csv"), []byte("title,due\nWrite docs,2026-11-01\n\"Fix bug, fast\",2026-11-02\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "steps.json"), []byte(`[{"name": "Tag"}, {"name": "Publish"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	batchFile, err := parseBatch([]byte(`vars:
+  board: Sprint 41
+  list: Doing
+operations:
+  - ref: list
+    type: list
+    resource: list
+    action: create
+    data:
+      name: "{{ .vars.list }}"
+      board_id: "{{ .vars.board }}"
+  - ref: "card-{{ .index }}"
+    type: card
+    resource: card
+    action: create
+    foreach:
+      file: cards.csv
+    data:
+      name: "{{ .item.title }}"
+      list_id: ${ops.list.id}
+  - type: card
+    resource: card
+    action: update
+    id: ${ops.card-0.id}
+    foreach: [a, b]
+    data:
+      desc: "{{ .item }} {{ .index }}"
+  - type: checklist
+    resource: checklist
+    action: add-item
+    foreach: {file: steps.json}
+    data:
+      checklist_id: c1
+      item_name: "{{ .item.name }}"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := batchFile.Expand(map[string]string{"board": "Sprint 42"}, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batchFile.Vars != nil {
+		t.Error("expected the vars to be dropped once expanded")
+	}
+
+	ops := batchFile.Operations
+	if len(ops) != 7 {
+		t.Fatalf("expected 7 operations, got %d", len(ops))
+	}
+	if ops[0].Data["board_id"] != "Sprint 42" || ops[0].Data["name"] != "Doing" {
+		t.Errorf("expected --var to override the file, got %v", ops[0].Data)
+	}
+	if ops[2].Ref != "card-1" || ops[2].Data["name"] != "Fix bug, fast" || ops[2].Data["list_id"] != "${ops.list.id}" {
+		t.Errorf("expected the second CSV row, got %+v", ops[2])
+	}
+	if ops[4].Data["desc"] != "b 1" || ops[4].Foreach != nil {
+		t.Errorf("expected the second inline item, got %+v", ops[4])
+	}
+	if ops[5].Data["item_name"] != "Tag" || ops[6].Data["item_name"] != "Publish" {
+		t.Errorf("expected the JSON items, got %v and %v", ops[5].Data, ops[6].Data)
+	}
+	if err := ValidateReferences(ops); err != nil {
+		t.Errorf("expected valid references, got %v", err)
+	}
+}
+
+func TestExpandProblems(t *testing.T) {
+	batchFile, err := parseBatch([]byte(`{
+	"operations": [
+		{"type": "card", "resource": "card", "action": "get", "id": "{{ .vars.card }}"},
+		{"type": "card", "resource": "card", "action": "get", "id": "{{ .item.id }}", "foreach": [{"id": "a"}, {"name": "b"}]},
+		{"type": "card", "resource": "card", "action": "get", "id": "x", "foreach": {"file": "cards.txt"}}
+	]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = batchFile.Expand(nil, t.TempDir())
+	if err == nil {
+		t.Fatal("expected problems")
+	}
+	for _, expected := range []string{
+		`operation 1: template: id:1:8: executing "id" at <.vars.card>: map has no entry for key "card"`,
+		`operation 2: item 2: template: id:1:8`,
+		"operation 3: failed to read foreach file",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err.Error())
+		}
+	}
+	if len(batchFile.Operations) != 3 {
+		t.Error("expected the batch to be left as it was")
+	}
+}
+
+func TestForeachDecoding(t *testing.T) {
+	var op Operation
+	if err := json.Unmarshal([]byte(`{"type": "card", "foreach": {"file": "rows.csv"}}`), &op); err != nil || op.Foreach.File != "rows.csv" {
+		t.Errorf("expected a foreach file, got %+v, %v", op.Foreach, err)
+	}
+	if err := json.Unmarshal([]byte(`{"type": "card", "foreach": {"path": "rows.csv"}}`), &op); err == nil {
+		t.Error("expected an unknown foreach key to be rejected")
+	}
+
+	validation := ValidateBatch([]byte(`operations:
+  - type: card
+    resource: card
+    action: get
+    id: abc
+    foreach: rows.csv
+`))
+	if len(validation.Problems) != 1 || validation.Problems[0].Message != "foreach must be a list, or an object with a file" {
+		t.Errorf("unexpected problems %+v", validation.Problems)
+	}
+
+	data, err := json.Marshal(Operation{Type: "card", Foreach: &Foreach{Items: []interface{}{"a"}}})
+	if err != nil || !strings.Contains(string(data), `"foreach":["a"]`) {
+		t.Errorf("expected the items to be encoded as a list, got %s, %v", data, err)
+	}
+}
+
+func TestValidateBatchWithVars(t *testing.T) {
+	data := []byte(`vars:
+  list: Doing
+operations:
+  - type: card
+    resource: card
+    action: create
+    foreach:
+      - name: Write docs
+      - title: Fix bug
+    data:
+      name: "{{ .item.name }}"
+      list_id: "{{ .vars.list }}"
+  - type: card
+    resource: card
+    action: move
+    id: "{{ .vars.card }}"
+    data:
+      list_id: Done
+`)
+
+	validation := ValidateBatchWithVars(data, map[string]string{"card": "abc"}, "")
+	if len(validation.Problems) != 1 {
+		t.Fatalf("expected 1 problem, got %+v", validation.Problems)
+	}
+	if p := validation.Problems[0]; p.Operation != 1 || p.Line != 4 || !strings.HasPrefix(p.Message, "item 2: template: data.name") {
+		t.Errorf("unexpected problem %+v", p)
+	}
+
+	// Without the var, the move cannot be expanded either
+	validation = ValidateBatch(data)
+	if len(validation.Problems) != 2 || validation.Problems[1].Operation != 2 {
+		t.Errorf("expected a problem with each operation, got %+v", validation.Problems)
+	}
+
+	// Problems found after expanding name the item
+	validation = ValidateBatch([]byte(`operations:
+  - type: card
+    resource: card
+    action: create
+    foreach: [Doing, ""]
+    data:
+      name: Task
+      list_id: "{{ .item }}"
+`))
+	if len(validation.Problems) != 1 || validation.Problems[0].String() != "line 2: operation 1: item 2: list_id is required for create action" {
+		t.Errorf("unexpected problems %+v", validation.Problems)
+	}
+	if validation.Operations != 2 {
+		t.Errorf("expected 2 operations once expanded, got %d", validation.Operations)
+	}
+}
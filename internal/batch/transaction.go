@@ -0,0 +1,91 @@
+package batch
+
+import "fmt"
+
+// Rollback undoes a completed operation of a transactional batch
+type Rollback struct {
+	// Operation describes the undo for the report
+	Operation Operation
+
+	// Undo reverts the change made by the operation
+	Undo func() (interface{}, error)
+}
+
+// TransactionalProcessor runs an operation like the processor passed to
+// ProcessOperations and also returns how to undo it. A nil Rollback means
+// there is nothing to undo, as for a get.
+type TransactionalProcessor func(op Operation) (interface{}, *Rollback, error)
+
+// ProcessTransaction runs operations one at a time and stops at the first
+// failure, whatever continueOnError and the concurrency are set to. After a
+// failure or an interrupt every operation completed by this run is undone in
+// reverse order, and the outcome of each undo is reported in the Rollback of
+// its result. A failed undo does not stop the others.
+func (bp *BatchProcessor) ProcessTransaction(operations []Operation, processor TransactionalProcessor) {
+	continueOnError, concurrency := bp.continueOnError, bp.concurrency
+	bp.continueOnError, bp.concurrency = false, 1
+	defer func() {
+		bp.continueOnError, bp.concurrency = continueOnError, concurrency
+	}()
+
+	// Operations run in order, so operation i has results[first+i]
+	first := len(bp.results)
+	rollbacks := make([]*Rollback, len(operations))
+	bp.process(operations, func(i int, op Operation) (interface{}, error) {
+		data, rollback, err := processor(op)
+		if err == nil {
+			rollbacks[i] = rollback
+		}
+		return data, err
+	})
+
+	failed := bp.Interrupted() && len(bp.results)-first < len(operations)
+	for _, result := range bp.results[first:] {
+		failed = failed || !result.Success
+	}
+	if !failed {
+		return
+	}
+
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		if rollbacks[i] == nil {
+			continue
+		}
+		result := BatchResult{Operation: rollbacks[i].Operation}
+		data, err := rollbacks[i].Undo()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+			result.Data = data
+			if bp.journal != nil {
+				if err := bp.journal.RecordUndone(i); err != nil {
+					result.Error = fmt.Sprintf("undone, but %v", err)
+				}
+			}
+		}
+		bp.results[first+i].Rollback = &result
+	}
+}
+
+// GetRollbackCount returns the number of operations that were undone
+func (bp *BatchProcessor) GetRollbackCount() int {
+	count := 0
+	for _, result := range bp.results {
+		if result.Rollback != nil && result.Rollback.Success {
+			count++
+		}
+	}
+	return count
+}
+
+// GetRollbackErrorCount returns the number of operations that could not be undone
+func (bp *BatchProcessor) GetRollbackErrorCount() int {
+	count := 0
+	for _, result := range bp.results {
+		if result.Rollback != nil && !result.Rollback.Success {
+			count++
+		}
+	}
+	return count
+}
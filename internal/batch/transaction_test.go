@@ -0,0 +1,105 @@
+package batch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+)
+
+func TestProcessTransaction(t *testing.T) {
+	operations := []Operation{
+		{Type: "card", Resource: "card", Action: "create", ID: "one"},
+		{Type: "card", Resource: "card", Action: "get", ID: "two"},
+		{Type: "card", Resource: "card", Action: "create", ID: "three"},
+		{Type: "card", Resource: "card", Action: "create", ID: "four"},
+		{Type: "card", Resource: "card", Action: "create", ID: "five"},
+	}
+
+	// run returns a processor that fails at failID and records undos in order
+	run := func(failID, failUndo string, undone *[]string) TransactionalProcessor {
+		return func(op Operation) (interface{}, *Rollback, error) {
+			if op.ID == failID {
+				return nil, &Rollback{Operation: op}, &TestError{Message: "board is closed"}
+			}
+			if op.Action == "get" {
+				return op.ID, nil, nil
+			}
+			return op.ID, &Rollback{
+				Operation: Operation{Type: "card", Resource: "card", Action: "delete", ID: op.ID},
+				Undo: func() (interface{}, error) {
+					*undone = append(*undone, op.ID)
+					if op.ID == failUndo {
+						return nil, &TestError{Message: "card is gone"}
+					}
+					return nil, nil
+				},
+			}, nil
+		}
+	}
+
+	t.Run("Completed operations are undone in reverse order", func(t *testing.T) {
+		var undone []string
+		processor := NewBatchProcessor(true)
+		processor.SetConcurrency(4)
+		processor.ProcessTransaction(operations, run("four", "", &undone))
+
+		results := processor.GetResults()
+		if len(results) != 4 {
+			t.Fatalf("expected the transaction to stop at the failure, got %d results", len(results))
+		}
+		if strings.Join(undone, ",") != "three,one" {
+			t.Errorf("expected three and one to be undone in that order, got %v", undone)
+		}
+		if results[1].Rollback != nil || results[3].Rollback != nil {
+			t.Error("expected no rollback for the get and the failed operation")
+		}
+		if rb := results[0].Rollback; rb == nil || !rb.Success || rb.Operation.Action != "delete" {
+			t.Errorf("expected a successful delete rollback, got %+v", rb)
+		}
+		if processor.GetRollbackCount() != 2 || processor.GetRollbackErrorCount() != 0 {
+			t.Errorf("expected 2 undone operations, got %d (%d failed)", processor.GetRollbackCount(), processor.GetRollbackErrorCount())
+		}
+	})
+
+	t.Run("A failed undo does not stop the others", func(t *testing.T) {
+		var undone []string
+		processor := NewBatchProcessor(false)
+		processor.ProcessTransaction(operations, run("five", "three", &undone))
+
+		if strings.Join(undone, ",") != "four,three,one" {
+			t.Errorf("expected every completed operation to be undone, got %v", undone)
+		}
+		results := processor.GetResults()
+		if rb := results[2].Rollback; rb == nil || rb.Success || rb.Error != "card is gone" {
+			t.Errorf("expected the failed undo to be reported, got %+v", rb)
+		}
+		if processor.GetRollbackCount() != 2 || processor.GetRollbackErrorCount() != 1 {
+			t.Errorf("expected 2 undone and 1 failed, got %d and %d", processor.GetRollbackCount(), processor.GetRollbackErrorCount())
+		}
+
+		output, err := processor.FormatResults("markdown", formatter.Options{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, expected := range []string{"**Rolled Back:** 2", "**Rollback Failures:** 1", "- **Rollback:** ✅ delete card one", "- **Rollback:** ❌ delete card three: card is gone"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("expected output to contain %q:\n%s", expected, output)
+			}
+		}
+	})
+
+	t.Run("Nothing is undone on success", func(t *testing.T) {
+		var undone []string
+		processor := NewBatchProcessor(false)
+		processor.ProcessTransaction(operations, run("", "", &undone))
+
+		if len(processor.GetResults()) != len(operations) || len(undone) != 0 {
+			t.Errorf("expected every operation to run and none to be undone, got %d results and %v", len(processor.GetResults()), undone)
+		}
+		output, _ := processor.FormatResults("markdown", formatter.Options{})
+		if strings.Contains(output, "Rolled Back") {
+			t.Error("expected no rollback summary")
+		}
+	})
+}
@@ -0,0 +1,391 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/danbruder/trello-cli/internal/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is something wrong with a batch file
+type Problem struct {
+	// Operation is the 1-based index of the operation, or 0 for the batch as a whole
+	Operation int    `json:"operation,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Message   string `json:"message"`
+}
+
+// String formats the problem as "line 12: operation 3: message"
+func (p Problem) String() string {
+	var sb strings.Builder
+	if p.Line > 0 {
+		sb.WriteString(fmt.Sprintf("line %d: ", p.Line))
+	}
+	if p.Operation > 0 {
+		sb.WriteString(fmt.Sprintf("operation %d: ", p.Operation))
+	}
+	sb.WriteString(p.Message)
+	return sb.String()
+}
+
+// Validation is the outcome of ValidateBatch
+type Validation struct {
+	Operations int       `json:"operations"`
+	Problems   []Problem `json:"problems"`
+}
+
+// batchSource is a batch file decoded from its YAML or JSON nodes, keeping
+// the line of each operation and the problems with its structure
+type batchSource struct {
+	batch BatchFile
+
+	// lines holds the line of each operation, and isObject whether it
+	// is an object at all
+	lines    []int
+	isObject []bool
+
+	// origins holds the operation of the file each operation was expanded
+	// from, once the templates are expanded
+	origins []origin
+
+	// keyLines holds the line of each key of the batch file with a valid value
+	keyLines map[string]int
+
+	// parsed is false when the file is not valid JSON or YAML
+	parsed   bool
+	problems []Problem
+}
+
+// yamlErrorPattern splits the line number off a YAML syntax error
+var yamlErrorPattern = regexp.MustCompile(`^yaml: (?:line (\d+): )?`)
+
+// parseBatchSource parses a batch file and checks its structure: the file
+// and each operation must be objects, with only known keys, each holding a
+// value of the right type. JSON is parsed as YAML, which it is a subset of,
+// so that both report line numbers.
+func parseBatchSource(data []byte) *batchSource {
+	src := &batchSource{keyLines: make(map[string]int)}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line := 0
+		if match := yamlErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		src.add(0, line, "invalid JSON or YAML: %s", yamlErrorPattern.ReplaceAllString(err.Error(), ""))
+		return src
+	}
+	src.parsed = true
+	if len(doc.Content) == 0 {
+		// An empty file is a batch without operations
+		return src
+	}
+
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		src.add(0, root.Line, "a batch file must be an object with an operations list")
+		return src
+	}
+
+	fields := src.checkFields(root, reflect.TypeOf(BatchFile{}), 0)
+	for key, value := range fields {
+		src.keyLines[key] = value.Line
+	}
+	var options struct {
+		ContinueOnError bool                   `yaml:"continue_on_error"`
+		Concurrency     int                    `yaml:"concurrency"`
+		Transactional   bool                   `yaml:"transactional"`
+		Vars            map[string]interface{} `yaml:"vars"`
+	}
+	root.Decode(&options) // type errors were reported by checkFields
+	src.batch.ContinueOnError = options.ContinueOnError
+	src.batch.Concurrency = options.Concurrency
+	src.batch.Transactional = options.Transactional
+	src.batch.Vars = options.Vars
+
+	operations, ok := fields["operations"]
+	if !ok || operations.Kind != yaml.SequenceNode { // missing or null
+		return src
+	}
+	for i, item := range operations.Content {
+		item = resolveAlias(item)
+		var op Operation
+		if item.Kind == yaml.MappingNode {
+			src.checkFields(item, reflect.TypeOf(op), i+1)
+			item.Decode(&op) // type errors were reported by checkFields
+		} else {
+			src.add(i+1, item.Line, "an operation must be an object")
+		}
+		src.batch.Operations = append(src.batch.Operations, op)
+		src.lines = append(src.lines, item.Line)
+		src.isObject = append(src.isObject, item.Kind == yaml.MappingNode)
+	}
+	return src
+}
+
+// checkFields reports unknown keys of an object and values that do not fit
+// the field of typ they belong to, and returns the other values by key.
+// A list is only checked to be a list; its items are up to the caller.
+func (src *batchSource) checkFields(node *yaml.Node, typ reflect.Type, operation int) map[string]*yaml.Node {
+	known := make(map[string]reflect.Type)
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		known[name] = typ.Field(i).Type
+		names = append(names, name)
+	}
+
+	fields := make(map[string]*yaml.Node)
+	pairs := mappingPairs(node)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key, value := pairs[i], resolveAlias(pairs[i+1])
+		fieldType, ok := known[key.Value]
+		if !ok {
+			src.add(operation, key.Line, "unknown key %q (valid: %s)", key.Value, strings.Join(names, ", "))
+			continue
+		}
+
+		valid := true
+		switch {
+		case value.Tag == "!!null":
+		case fieldType.Kind() == reflect.Slice:
+			valid = value.Kind == yaml.SequenceNode
+		default:
+			valid = value.Decode(reflect.New(fieldType).Interface()) == nil
+		}
+		if !valid {
+			src.add(operation, value.Line, "%s must be %s", key.Value, describeType(fieldType))
+			continue
+		}
+		fields[key.Value] = value
+	}
+	return fields
+}
+
+// describeType names the kind of value a field holds, for problems
+func describeType(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int:
+		return "a whole number"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Map:
+		if typ.Elem().Kind() == reflect.String {
+			return "an object of strings"
+		}
+		return "an object"
+	case reflect.Ptr:
+		if typ.Elem() == reflect.TypeOf(Foreach{}) {
+			return "a list, or an object with a file"
+		}
+	}
+	return typ.String()
+}
+
+// mappingPairs returns the keys and values of a mapping node, in turn, with
+// the keys of YAML merges (<<: *defaults) included
+func mappingPairs(node *yaml.Node) []*yaml.Node {
+	var pairs []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		if key.Tag != "!!merge" {
+			pairs = append(pairs, key, value)
+			continue
+		}
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, m := range merged {
+			if m = resolveAlias(m); m.Kind == yaml.MappingNode {
+				pairs = append(pairs, mappingPairs(m)...)
+			}
+		}
+	}
+	return pairs
+}
+
+// resolveAlias returns the node an alias points to
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// add records a problem
+func (src *batchSource) add(operation, line int, format string, args ...interface{}) {
+	src.problems = append(src.problems, Problem{Operation: operation, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// addError records each error joined in err as a problem of the operation at
+// index, reported as the operation of the file it was expanded from
+func (src *batchSource) addError(index int, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	from := origin{index: index}
+	if src.origins != nil {
+		from = src.origins[index]
+	}
+	for _, e := range errs {
+		src.add(from.index+1, src.lines[from.index], "%s%s", from.prefix(), e.Error())
+	}
+}
+
+// expand expands the templates of the operations that are objects, and
+// records the problems with expanding them. Operations that are not objects
+// are kept as they are, to be skipped by the checks.
+func (src *batchSource) expand(vars map[string]string, dir string) {
+	var objects BatchFile
+	objects.Vars = src.batch.Vars
+	var indexes []int
+	for i, op := range src.batch.Operations {
+		if src.isObject[i] {
+			objects.Operations = append(objects.Operations, op)
+			indexes = append(indexes, i)
+		}
+	}
+	expanded, origins, problems := expandOperations(&objects, vars, dir)
+	for _, p := range problems {
+		src.addError(indexes[p.index], p.err)
+	}
+
+	src.batch.Operations = nil
+	src.origins = nil
+	next := 0
+	for i, isObject := range src.isObject {
+		if !isObject {
+			src.batch.Operations = append(src.batch.Operations, Operation{})
+			src.origins = append(src.origins, origin{index: i})
+			continue
+		}
+		for ; next < len(expanded) && indexes[origins[next].index] == i; next++ {
+			src.batch.Operations = append(src.batch.Operations, expanded[next])
+			src.origins = append(src.origins, origin{index: i, item: origins[next].item})
+		}
+	}
+}
+
+// objectAt reports whether the operation at index of the expanded batch was
+// an object in the file
+func (src *batchSource) objectAt(index int) bool {
+	return src.isObject[src.origins[index].index]
+}
+
+// joinProblems joins problems into one error, one per line
+func joinProblems(problems []Problem) error {
+	var errs []error
+	for _, p := range problems {
+		errs = append(errs, errors.New(p.String()))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateBatch checks a batch file without running it and reports every
+// problem found with the operation and line it is on: the structure of the
+// file, unknown keys, unsupported actions and missing required fields,
+// references between operations and the rules of transactional batches.
+// Each check is also run on every operation of a supported type, for checks
+// only the caller can make.
+func ValidateBatch(data []byte, checks ...func(Operation) error) *Validation {
+	return ValidateBatchWithVars(data, nil, "", checks...)
+}
+
+// ValidateBatchWithVars validates a batch file like ValidateBatch, after
+// expanding its templates with vars the way Expand does. Problems with an
+// operation of a foreach name its item.
+func ValidateBatchWithVars(data []byte, vars map[string]string, dir string, checks ...func(Operation) error) *Validation {
+	src := parseBatchSource(data)
+	src.expand(vars, dir)
+	operations := src.batch.Operations
+
+	for i, op := range operations {
+		if !src.objectAt(i) {
+			continue
+		}
+		if err := ValidateOperation(op); err != nil {
+			src.addError(i, err)
+			continue
+		}
+		if err := ValidateAction(op); err != nil {
+			src.addError(i, err)
+		}
+		for _, check := range checks {
+			if err := check(op); err != nil {
+				src.addError(i, err)
+			}
+		}
+	}
+
+	for _, p := range referenceProblems(operations) {
+		src.addError(p.index, p.err)
+	}
+
+	if line, ok := src.keyLines["concurrency"]; ok && src.batch.Concurrency < 1 {
+		src.add(0, line, "concurrency must be at least 1")
+	}
+	if src.batch.Transactional {
+		line := src.keyLines["transactional"]
+		if src.batch.ContinueOnError {
+			src.add(0, line, "a transactional batch stops at the first failure; remove continue_on_error")
+		}
+		if src.batch.Concurrency > 1 {
+			src.add(0, line, "a transactional batch runs one operation at a time; remove concurrency")
+		}
+		for i, op := range operations {
+			if src.objectAt(i) && !IsReversible(op) {
+				src.addError(i, fmt.Errorf("%s %s cannot be undone in a transactional batch", op.Type, op.Action))
+			}
+		}
+	}
+
+	sort.SliceStable(src.problems, func(i, j int) bool {
+		return src.problems[i].Line < src.problems[j].Line
+	})
+	return &Validation{Operations: len(operations), Problems: src.problems}
+}
+
+// Err returns the problems found as one error, or nil for a valid batch
+func (v *Validation) Err() error {
+	return joinProblems(v.Problems)
+}
+
+// Format formats the validation for output
+func (v *Validation) Format(format string, opts formatter.Options) (string, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		var sb strings.Builder
+		sb.WriteString("# Batch Validation\n\n")
+		sb.WriteString(fmt.Sprintf("**Operations:** %d\n", v.Operations))
+		sb.WriteString(fmt.Sprintf("**Problems:** %d\n", len(v.Problems)))
+		if len(v.Problems) > 0 {
+			sb.WriteString("\n")
+		}
+		for _, p := range v.Problems {
+			sb.WriteString(fmt.Sprintf("- %s\n", p))
+		}
+		return sb.String(), nil
+	}
+
+	problems := v.Problems
+	if problems == nil {
+		problems = []Problem{}
+	}
+	return formatter.FormatReport(format, opts, struct {
+		Valid      bool      `json:"valid"`
+		Operations int       `json:"operations"`
+		Problems   []Problem `json:"problems"`
+	}{len(v.Problems) == 0, v.Operations, problems}, problems)
+}
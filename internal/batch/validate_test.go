@@ -0,0 +1,152 @@
+package batch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateBatch(t *testing.T) {
+	t.Run("Problems are reported with their operation and line", func(t *testing.T) {
+		validation := ValidateBatch([]byte(`continue_on_error: true
+colour: red
+operations:
+  - ref: list
+    type: list
+    resource: list
+    action: create
+    data:
+      name: Doing
+  - type: card
+    resource: card
+    action: explode
+    id: abc
+  - type: card
+    resource: card
+    action: move
+    id: abc
+    data:
+      list_id: ${ops.lsit.id}
+    extra: true
+  - type: card
+    resource: card
+    action: archive
+    id: [abc]
+  - archive everything
+`))
+
+		expected := []Problem{
+			{Line: 2, Message: `unknown key "colour" (valid: operations, continue_on_error, concurrency, transactional, vars)`},
+			{Operation: 1, Line: 4, Message: "board_id is required for create action"},
+			{Operation: 2, Line: 10, Message: "unsupported card action: explode (valid: archive, copy, create, delete, get, move, update)"},
+			{Operation: 3, Line: 14, Message: "unknown reference ${ops.lsit.id}"},
+			{Operation: 3, Line: 20, Message: `unknown key "extra" (valid: ref, type, resource, action, id, data, parameters, foreach)`},
+			{Operation: 4, Line: 21, Message: "card ID is required for archive action"},
+			{Operation: 4, Line: 24, Message: "id must be a string"},
+			{Operation: 5, Line: 25, Message: "an operation must be an object"},
+		}
+		if validation.Operations != 5 {
+			t.Errorf("expected 5 operations, got %d", validation.Operations)
+		}
+		if len(validation.Problems) != len(expected) {
+			t.Fatalf("expected %d problems, got %+v", len(expected), validation.Problems)
+		}
+		for i, p := range validation.Problems {
+			if p != expected[i] {
+				t.Errorf("problem %d: expected %+v, got %+v", i+1, expected[i], p)
+			}
+		}
+	})
+
+	t.Run("JSON reports lines too", func(t *testing.T) {
+		validation := ValidateBatch([]byte(`{
+	"operations": [
+		{"type": "card", "resource": "card", "action": "get", "id": "abc"},
+		{"type": "card", "resource": "card", "action": "create",
+		 "data": {"name": "Task"}}
+	],
+	"concurrency": "4"
+}`))
+		if len(validation.Problems) != 2 {
+			t.Fatalf("expected 2 problems, got %+v", validation.Problems)
+		}
+		if p := validation.Problems[0]; p.Operation != 2 || p.Line != 4 || p.Message != "list_id is required for create action" {
+			t.Errorf("unexpected problem %+v", p)
+		}
+		if p := validation.Problems[1]; p.Operation != 0 || p.Line != 7 || p.Message != "concurrency must be a whole number" {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	})
+
+	t.Run("Transactional batches", func(t *testing.T) {
+		validation := ValidateBatch([]byte(`transactional: true
+continue_on_error: true
+operations:
+  - {type: card, resource: card, action: archive, id: abc}
+  - {type: card, resource: card, action: delete, id: abc}
+`))
+		messages := make([]string, len(validation.Problems))
+		for i, p := range validation.Problems {
+			messages[i] = p.String()
+		}
+		expected := "line 1: a transactional batch stops at the first failure; remove continue_on_error\n" +
+			"line 5: operation 2: card delete cannot be undone in a transactional batch"
+		if strings.Join(messages, "\n") != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, strings.Join(messages, "\n"))
+		}
+	})
+
+	t.Run("Valid batches", func(t *testing.T) {
+		for name, input := range map[string]string{
+			"empty": "",
+			"merge keys": `defaults: &card
+  type: card
+  resource: card
+operations:
+  - <<: *card
+    action: archive
+    id: abc
+`,
+		} {
+			validation := ValidateBatch([]byte(input))
+			if name == "merge keys" {
+				// defaults is not a batch file key
+				if len(validation.Problems) != 1 || !strings.Contains(validation.Problems[0].Message, `"defaults"`) {
+					t.Errorf("%s: expected only the defaults key to be reported, got %+v", name, validation.Problems)
+				}
+				continue
+			}
+			if validation.Err() != nil {
+				t.Errorf("%s: unexpected problems %v", name, validation.Err())
+			}
+		}
+	})
+
+	t.Run("Checks of the caller run on each operation", func(t *testing.T) {
+		validation := ValidateBatch([]byte(`operations:
+  - {type: card, resource: card, action: update, id: abc, data: {due: soon}}
+`), func(op Operation) error {
+			if op.Action == "update" {
+				return errors.New("invalid due")
+			}
+			return nil
+		})
+		if len(validation.Problems) != 1 || validation.Problems[0].String() != "line 2: operation 1: invalid due" {
+			t.Errorf("unexpected problems %+v", validation.Problems)
+		}
+	})
+
+	t.Run("Syntax errors", func(t *testing.T) {
+		validation := ValidateBatch([]byte("operations:\n  - {type: card\n"))
+		if len(validation.Problems) != 1 || validation.Problems[0].Line == 0 || !strings.HasPrefix(validation.Problems[0].Message, "invalid JSON or YAML") {
+			t.Errorf("expected a syntax error with its line, got %+v", validation.Problems)
+		}
+	})
+}
+
+func TestLoadBatchFromReaderRejectsUnknownKeys(t *testing.T) {
+	_, err := LoadBatchFromReader(strings.NewReader(`{"operations": [{"type": "card", "resource": "card", "action": "get", "id": "abc", "nmae": "x"}]}`))
+	if err == nil || !strings.Contains(err.Error(), `line 1: operation 1: unknown key "nmae"`) {
+		t.Errorf("expected the unknown key to be reported, got %v", err)
+	}
+}
@@ -4,75 +4,173 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds the Trello API credentials and default settings
 type Config struct {
-	APIKey        string `yaml:"api_key" mapstructure:"api_key"`
-	Token         string `yaml:"token" mapstructure:"token"`
-	DefaultFormat string `yaml:"default_format" mapstructure:"default_format"`
-	MaxTokens     int    `yaml:"max_tokens" mapstructure:"max_tokens"`
+	APIKey        string `yaml:"api_key,omitempty"`
+	Token         string `yaml:"token,omitempty"`
+	DefaultFormat string `yaml:"default_format,omitempty"`
+	MaxTokens     int    `yaml:"max_tokens,omitempty"`
+	BaseURL       string `yaml:"base_url,omitempty"`
+
+	// CurrentProfile is the profile used when neither --profile nor
+	// TRELLO_PROFILE is set. Empty means the top-level credentials.
+	CurrentProfile string              `yaml:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+
+	// Templates are named output templates, used with --template <name>.
+	// They are shared by every profile.
+	Templates map[string]string `yaml:"templates,omitempty"`
 }
 
+// Profile holds the credentials and default settings of a named profile
+type Profile struct {
+	APIKey        string `yaml:"api_key"`
+	Token         string `yaml:"token"`
+	DefaultFormat string `yaml:"default_format,omitempty"`
+	MaxTokens     int    `yaml:"max_tokens,omitempty"`
+	BaseURL       string `yaml:"base_url,omitempty"`
+}
+
+// DefaultProfile is the name of the implicit profile made of the top-level
+// credentials in the config file
+const DefaultProfile = "default"
+
 // AuthConfig holds authentication credentials with their sources
 type AuthConfig struct {
-	APIKey string
-	Token  string
-	Source string // "env", "config", or "flags"
+	APIKey  string
+	Token   string
+	Source  string // where the credentials came from, e.g. "environment variables"
+	Profile string // profile name when any credential came from the config file
 }
 
-// LoadAuth loads authentication credentials with precedence order:
-// 1. Environment variables
-// 2. Config file
-// 3. Command-line flags
-func LoadAuth(flagAPIKey, flagToken string) (*AuthConfig, error) {
-	// First, try environment variables
-	envAPIKey := os.Getenv("TRELLO_API_KEY")
-	envToken := os.Getenv("TRELLO_TOKEN")
-
-	if envAPIKey != "" && envToken != "" {
-		return &AuthConfig{
-			APIKey: envAPIKey,
-			Token:  envToken,
-			Source: "environment variables",
+// SelectProfile returns the name of the profile to use, in order of
+// precedence: the --profile flag, TRELLO_PROFILE, then current_profile in
+// the config file. It returns DefaultProfile when none of them is set.
+func SelectProfile(flagProfile string, config *Config) string {
+	return selectProfile(flagProfile, config).Value
+}
+
+// Profile returns the named profile. DefaultProfile returns the top-level
+// credentials and settings.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" || name == DefaultProfile {
+		return &Profile{
+			APIKey:        c.APIKey,
+			Token:         c.Token,
+			DefaultFormat: c.DefaultFormat,
+			MaxTokens:     c.MaxTokens,
+			BaseURL:       c.BaseURL,
 		}, nil
 	}
+	profile, ok := c.Profiles[name]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf("profile %q not found in config file", name)
+	}
+	return profile, nil
+}
 
-	// Second, try config file
-	config, err := LoadConfig()
-	if err == nil && config.APIKey != "" && config.Token != "" {
-		return &AuthConfig{
-			APIKey: config.APIKey,
-			Token:  config.Token,
-			Source: "config file",
-		}, nil
+// ProfileNames returns the names of the configured profiles in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// Third, try command-line flags
-	if flagAPIKey != "" && flagToken != "" {
-		return &AuthConfig{
-			APIKey: flagAPIKey,
-			Token:  flagToken,
-			Source: "command-line flags",
-		}, nil
+// TemplateNames returns the names of the named templates in sorted order
+func (c *Config) TemplateNames() []string {
+	names := make([]string, 0, len(c.Templates))
+	for name := range c.Templates {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// No valid credentials found
-	return nil, fmt.Errorf("no valid Trello credentials found. Please set TRELLO_API_KEY and TRELLO_TOKEN environment variables, create a config file, or use --api-key and --token flags")
+// LoadAuth loads authentication credentials. Each credential is resolved with
+// precedence order:
+// 1. Command-line flags
+// 2. Environment variables
+// 3. Config file, using the profile picked by SelectProfile
+func LoadAuth(flagAPIKey, flagToken, flagProfile string) (*AuthConfig, error) {
+	settings, err := ResolveSettings(SettingFlags{
+		APIKey:  flagAPIKey,
+		Token:   flagToken,
+		Profile: flagProfile,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings.Auth()
 }
 
-// LoadConfig loads configuration from the config file
+// Auth returns the resolved credentials, or an error naming the ones that are missing
+func (s *Settings) Auth() (*AuthConfig, error) {
+	apiKey, token := s.APIKey, s.Token
+
+	switch {
+	case !apiKey.IsSet() && !token.IsSet():
+		return nil, fmt.Errorf("no valid Trello credentials found. Please use --api-key and --token flags, set TRELLO_API_KEY and TRELLO_TOKEN environment variables, or create a config file")
+	case !apiKey.IsSet():
+		return nil, fmt.Errorf("no Trello API key found (token from %s). Please use --api-key, set TRELLO_API_KEY, or add api_key to the config file", token.Source)
+	case !token.IsSet():
+		return nil, fmt.Errorf("no Trello token found (API key from %s). Please use --token, set TRELLO_TOKEN, or add token to the config file", apiKey.Source)
+	}
+
+	auth := &AuthConfig{
+		APIKey: apiKey.Value,
+		Token:  token.Value,
+	}
+	if apiKey.Origin == OriginConfig || token.Origin == OriginConfig {
+		auth.Profile = s.Profile.Value
+	}
+
+	if apiKey.Origin == token.Origin {
+		switch apiKey.Origin {
+		case OriginFlag:
+			auth.Source = "command-line flags"
+		case OriginEnv:
+			auth.Source = "environment variables"
+		default:
+			auth.Source = apiKey.Source
+		}
+	} else {
+		auth.Source = fmt.Sprintf("API key from %s, token from %s", apiKey.Source, token.Source)
+	}
+
+	return auth, nil
+}
+
+// LoadConfig loads configuration from the config file, filling in defaults
+// for unset settings
 func LoadConfig() (*Config, error) {
-	home, err := os.UserHomeDir()
+	config, err := ReadConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	configDir := filepath.Join(home, ".trello-cli")
-	configFile := filepath.Join(configDir, "config.yaml")
+	// Set defaults. A MaxTokens of 0 is already DefaultMaxTokens, unlimited.
+	if config.DefaultFormat == "" {
+		config.DefaultFormat = DefaultOutputFormat
+	}
+
+	return config, nil
+}
+
+// ReadConfig reads the config file as stored, without filling in defaults.
+// Use it to modify the file so that SaveConfig does not persist the defaults.
+func ReadConfig() (*Config, error) {
+	configFile, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if config file exists
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
@@ -90,14 +188,6 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// Set defaults
-	if config.DefaultFormat == "" {
-		config.DefaultFormat = "json"
-	}
-	if config.MaxTokens == 0 {
-		config.MaxTokens = 4000
-	}
-
 	return &config, nil
 }
 
@@ -138,28 +228,3 @@ func GetConfigPath() (string, error) {
 	}
 	return filepath.Join(home, ".trello-cli", "config.yaml"), nil
 }
-
-// InitViper initializes viper for configuration management
-func InitViper() error {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
-	viper.AddConfigPath(filepath.Join(home, ".trello-cli"))
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-
-	// Set defaults
-	viper.SetDefault("default_format", "json")
-	viper.SetDefault("max_tokens", 4000)
-
-	// Read config file
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return err
-		}
-	}
-
-	return nil
-}
@@ -3,20 +3,47 @@ package client
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
 )
 
 func TestLoadAuth(t *testing.T) {
-	t.Run("Environment variables take precedence", func(t *testing.T) {
+	t.Run("Command-line flags take precedence", func(t *testing.T) {
 		// Set environment variables
 		os.Setenv("TRELLO_API_KEY", "env-key")
 		os.Setenv("TRELLO_TOKEN", "env-token")
 		defer os.Unsetenv("TRELLO_API_KEY")
 		defer os.Unsetenv("TRELLO_TOKEN")
 
-		auth, err := LoadAuth("flag-key", "flag-token")
+		auth, err := LoadAuth("flag-key", "flag-token", "")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+
+		if auth.Source != "command-line flags" {
+			t.Errorf("Expected source 'command-line flags', got %s", auth.Source)
+		}
+
+		if auth.APIKey != "flag-key" {
+			t.Errorf("Expected API key 'flag-key', got %s", auth.APIKey)
+		}
+
+		if auth.Token != "flag-token" {
+			t.Errorf("Expected token 'flag-token', got %s", auth.Token)
+		}
+	})
+
+	t.Run("Environment variables over config file", func(t *testing.T) {
+		// Set environment variables
+		os.Setenv("TRELLO_API_KEY", "env-key")
+		os.Setenv("TRELLO_TOKEN", "env-token")
+		defer os.Unsetenv("TRELLO_API_KEY")
+		defer os.Unsetenv("TRELLO_TOKEN")
+
+		auth, err := LoadAuth("", "", "")
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 			return
@@ -35,12 +62,33 @@ func TestLoadAuth(t *testing.T) {
 		}
 	})
 
+	t.Run("Sources can be mixed", func(t *testing.T) {
+		os.Setenv("TRELLO_API_KEY", "env-key")
+		os.Unsetenv("TRELLO_TOKEN")
+		defer os.Unsetenv("TRELLO_API_KEY")
+
+		auth, err := LoadAuth("", "flag-token", "")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+
+		if auth.APIKey != "env-key" || auth.Token != "flag-token" {
+			t.Errorf("Expected env-key/flag-token, got %s/%s", auth.APIKey, auth.Token)
+		}
+
+		expected := "API key from TRELLO_API_KEY environment variable, token from --token flag"
+		if auth.Source != expected {
+			t.Errorf("Expected source %q, got %q", expected, auth.Source)
+		}
+	})
+
 	t.Run("Config file as fallback", func(t *testing.T) {
 		// Clear environment variables
 		os.Unsetenv("TRELLO_API_KEY")
 		os.Unsetenv("TRELLO_TOKEN")
 
-		auth, err := LoadAuth("", "")
+		auth, err := LoadAuth("", "", "")
 		// Config file may or may not exist, so error is acceptable
 		if err != nil {
 			t.Logf("No config file found (expected): %v", err)
@@ -87,7 +135,7 @@ func TestLoadAuth(t *testing.T) {
 			}() // Restore after test
 		}
 
-		auth, err := LoadAuth("flag-key", "flag-token")
+		auth, err := LoadAuth("flag-key", "flag-token", "")
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 			return
@@ -135,8 +183,8 @@ func TestLoadConfig(t *testing.T) {
 				Token:  "test-token",
 			},
 			expectError:    false,
-			expectedFormat: "markdown",
-			expectedTokens: 4000,
+			expectedFormat: DefaultOutputFormat,
+			expectedTokens: DefaultMaxTokens,
 		},
 	}
 
@@ -144,7 +192,11 @@ func TestLoadConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create temporary config file
 			tempDir := t.TempDir()
-			configFile := filepath.Join(tempDir, "config.yaml")
+			t.Setenv("HOME", tempDir)
+			configFile := filepath.Join(tempDir, ".trello-cli", "config.yaml")
+			if err := os.MkdirAll(filepath.Dir(configFile), 0700); err != nil {
+				t.Fatalf("Failed to create config dir: %v", err)
+			}
 
 			// Write config directly to file
 			data, err := yaml.Marshal(&tt.configData)
@@ -157,23 +209,9 @@ func TestLoadConfig(t *testing.T) {
 				t.Fatalf("Failed to write config file: %v", err)
 			}
 
-			// Load config directly from file
-			data, err = os.ReadFile(configFile)
-			if err != nil {
-				t.Fatalf("Failed to read config file: %v", err)
-			}
-
-			var config Config
-			if err := yaml.Unmarshal(data, &config); err != nil {
-				t.Fatalf("Failed to parse config: %v", err)
-			}
-
-			// Set defaults
-			if config.DefaultFormat == "" {
-				config.DefaultFormat = "markdown"
-			}
-			if config.MaxTokens == 0 {
-				config.MaxTokens = 4000
+			config, err := LoadConfig()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Unexpected error: %v", err)
 			}
 
 			if config.DefaultFormat != tt.expectedFormat {
@@ -273,3 +311,187 @@ func TestNewClient(t *testing.T) {
 			client.Config.DefaultFormat, client.Config.MaxTokens)
 	}
 }
+
+func TestLoadAuthProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TRELLO_API_KEY", "")
+	t.Setenv("TRELLO_TOKEN", "")
+	t.Setenv("TRELLO_PROFILE", "")
+
+	config := &Config{
+		APIKey:         "default-key",
+		Token:          "default-token",
+		CurrentProfile: "work",
+		Profiles: map[string]*Profile{
+			"work": {APIKey: "work-key", Token: "work-token"},
+			"bot":  {APIKey: "bot-key", Token: "bot-token"},
+		},
+	}
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		flagProfile    string
+		flagAPIKey     string
+		envProfile     string
+		expectedKey    string
+		expectedSource string
+		errorMsg       string
+	}{
+		{
+			name:           "Current profile from config",
+			expectedKey:    "work-key",
+			expectedSource: `config file (profile "work")`,
+		},
+		{
+			name:           "TRELLO_PROFILE overrides current profile",
+			envProfile:     "bot",
+			expectedKey:    "bot-key",
+			expectedSource: `config file (profile "bot")`,
+		},
+		{
+			name:           "Flag overrides TRELLO_PROFILE",
+			flagProfile:    "default",
+			envProfile:     "bot",
+			expectedKey:    "default-key",
+			expectedSource: "config file",
+		},
+		{
+			name:           "Flag credentials override the profile",
+			flagAPIKey:     "flag-key",
+			expectedKey:    "flag-key",
+			expectedSource: `API key from --api-key flag, token from config file (profile "work")`,
+		},
+		{
+			name:        "Unknown profile",
+			flagProfile: "missing",
+			errorMsg:    `profile "missing" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRELLO_PROFILE", tt.envProfile)
+
+			auth, err := LoadAuth(tt.flagAPIKey, "", tt.flagProfile)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if auth.APIKey != tt.expectedKey {
+				t.Errorf("Expected API key %s, got %s", tt.expectedKey, auth.APIKey)
+			}
+			if auth.Source != tt.expectedSource {
+				t.Errorf("Expected source %q, got %q", tt.expectedSource, auth.Source)
+			}
+		})
+	}
+}
+
+func TestResolveSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TRELLO_API_KEY", "env-key")
+	t.Setenv("TRELLO_TOKEN", "")
+	t.Setenv("TRELLO_PROFILE", "")
+	t.Setenv("TRELLO_FORMAT", "")
+	t.Setenv("TRELLO_MAX_TOKENS", "")
+	t.Setenv("TRELLO_BASE_URL", "")
+
+	config := &Config{
+		DefaultFormat: "markdown",
+		MaxTokens:     3000,
+		Profiles: map[string]*Profile{
+			"work": {APIKey: "work-key", Token: "work-token", MaxTokens: 1000},
+		},
+	}
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	t.Run("Each setting reports its source", func(t *testing.T) {
+		settings, err := ResolveSettings(SettingFlags{Format: "json"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []Setting{
+			{Key: "profile", Value: DefaultProfile, Origin: OriginDefault, Source: "default"},
+			{Key: "api_key", Value: "env-key", Origin: OriginEnv, Source: "TRELLO_API_KEY environment variable"},
+			{Key: "token", Value: "", Origin: OriginDefault, Source: "not set"},
+			{Key: "format", Value: "json", Origin: OriginFlag, Source: "--format flag"},
+			{Key: "max_tokens", Value: "3000", Origin: OriginConfig, Source: "config file"},
+			{Key: "base_url", Value: DefaultBaseURL, Origin: OriginDefault, Source: "default"},
+		}
+		for i, setting := range settings.All() {
+			if setting != expected[i] {
+				t.Errorf("Expected %+v, got %+v", expected[i], setting)
+			}
+		}
+
+		if _, err := settings.Auth(); err == nil || !strings.Contains(err.Error(), "no Trello token found") {
+			t.Errorf("Expected missing token error, got %v", err)
+		}
+	})
+
+	t.Run("Profile output settings fall back to the top level", func(t *testing.T) {
+		t.Setenv("TRELLO_FORMAT", "")
+		settings, err := ResolveSettings(SettingFlags{Profile: "work"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if settings.Format.Value != "markdown" || settings.Format.Source != "config file" {
+			t.Errorf("Expected markdown from config file, got %+v", settings.Format)
+		}
+		if settings.MaxTokensValue() != 1000 {
+			t.Errorf("Expected max tokens 1000 from the profile, got %d", settings.MaxTokensValue())
+		}
+	})
+
+	t.Run("Environment overrides config", func(t *testing.T) {
+		t.Setenv("TRELLO_FORMAT", "markdown")
+		t.Setenv("TRELLO_MAX_TOKENS", "0")
+		settings, err := ResolveSettings(SettingFlags{Profile: "work"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if settings.Format.Origin != OriginEnv {
+			t.Errorf("Expected format from the environment, got %+v", settings.Format)
+		}
+		// "0" is a valid value (unlimited), not an unset one
+		if settings.MaxTokensValue() != 0 || settings.MaxTokens.Origin != OriginEnv {
+			t.Errorf("Expected unlimited max tokens from the environment, got %+v", settings.MaxTokens)
+		}
+	})
+
+	t.Run("Invalid max tokens", func(t *testing.T) {
+		t.Setenv("TRELLO_MAX_TOKENS", "lots")
+		if _, err := ResolveSettings(SettingFlags{}); err == nil || !strings.Contains(err.Error(), "invalid max_tokens") {
+			t.Errorf("Expected invalid max_tokens error, got %v", err)
+		}
+	})
+
+	t.Run("Base URL from the environment", func(t *testing.T) {
+		t.Setenv("TRELLO_BASE_URL", "http://127.0.0.1:8080/1/")
+		settings, err := ResolveSettings(SettingFlags{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if settings.BaseURL.Value != "http://127.0.0.1:8080/1" || settings.BaseURL.Origin != OriginEnv {
+			t.Errorf("Expected base URL without trailing slash from the environment, got %+v", settings.BaseURL)
+		}
+	})
+
+	t.Run("Invalid base URL", func(t *testing.T) {
+		if _, err := ResolveSettings(SettingFlags{BaseURL: "localhost:8080"}); err == nil || !strings.Contains(err.Error(), "invalid base_url from --base-url flag") {
+			t.Errorf("Expected invalid base_url error, got %v", err)
+		}
+	})
+}
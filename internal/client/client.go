@@ -2,32 +2,97 @@ package client
 
 import (
 	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unsafe"
 
 	"github.com/adlio/trello"
+	"golang.org/x/time/rate"
 )
 
 // Client wraps the Trello client with additional context
 type Client struct {
 	*trello.Client
 	Config *Config
+
+	cache resolverCache
+}
+
+// Options holds the connection settings of a Client
+type Options struct {
+	// BaseURL is the root of the Trello API, e.g. a fake server for offline
+	// testing. Empty means trello.DefaultBaseURL.
+	BaseURL string
+
+	// MaxRetries is how often requests failing with 429, 5xx or a network
+	// error are retried. Zero disables retries.
+	MaxRetries int
+
+	// RateLimit caps the requests per second sent with the client's token, on
+	// top of Trello's per-key budget, and then replaces the fixed 8 requests
+	// per second of the Trello API client. Zero keeps that fixed limit.
+	RateLimit float64
 }
 
 // NewClient creates a new Trello client with authentication
 func NewClient(apiKey, token string) *Client {
+	// Without a rate limit the Trello API client keeps its own, which cannot fail
+	c, _ := NewClientWithOptions(apiKey, token, Options{})
+	return c
+}
+
+// NewClientWithOptions creates a new Trello client with authentication and
+// the given connection options. It fails when the rate limit cannot replace
+// the Trello API client's own.
+func NewClientWithOptions(apiKey, token string, opts Options) (*Client, error) {
 	trelloClient := trello.NewClient(apiKey, token)
+	if opts.BaseURL != "" {
+		trelloClient.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	}
+	if opts.MaxRetries > 0 || opts.RateLimit > 0 {
+		limiters := budgetLimiters(apiKey, token, opts.RateLimit)
+		if len(limiters) > 0 {
+			// The transport's limiters take over from the fixed limit
+			if err := removeThrottle(trelloClient); err != nil {
+				return nil, err
+			}
+		}
+		trelloClient.Client = &http.Client{
+			Transport: &RetryTransport{
+				MaxRetries: opts.MaxRetries,
+				Limiters:   limiters,
+			},
+		}
+	}
 
 	config, _ := LoadConfig()
 	if config == nil {
 		config = &Config{
-			DefaultFormat: "markdown",
-			MaxTokens:     4000,
+			DefaultFormat: DefaultOutputFormat,
+			MaxTokens:     DefaultMaxTokens,
 		}
 	}
 
 	return &Client{
 		Client: trelloClient,
 		Config: config,
+	}, nil
+}
+
+// removeThrottle lifts the limit of 8 requests per second that
+// trello.NewClient puts on every client, which has no option to turn it off,
+// so that the limiters of RetryTransport apply instead. The limit is an
+// unexported field, so a version of the library without it is an error
+// rather than a second, silent limit.
+func removeThrottle(c *trello.Client) error {
+	field := reflect.ValueOf(c).Elem().FieldByName("throttle")
+	if !field.IsValid() || field.Type() != reflect.TypeOf((*rate.Limiter)(nil)) {
+		return fmt.Errorf("cannot replace the rate limit of this version of the Trello API client; use --rate-limit 0")
 	}
+	throttle := (**rate.Limiter)(unsafe.Pointer(field.UnsafeAddr()))
+	*throttle = rate.NewLimiter(rate.Inf, 0)
+	return nil
 }
 
 // UpdateCheckItemState updates the state of a check item (complete/incomplete)
@@ -37,5 +102,119 @@ func (c *Client) UpdateCheckItemState(cardID, checkItemID, state string) error {
 		"state": state,
 	}
 
-	return c.Put(path, args, nil)
+	var item trello.CheckItem
+	return c.Put(path, args, &item)
+}
+
+// GetCardComments returns the comment actions on a card, newest first
+func (c *Client) GetCardComments(cardID string) ([]*trello.Action, error) {
+	path := fmt.Sprintf("cards/%s/actions", cardID)
+	args := trello.Arguments{
+		"filter": "commentCard",
+	}
+
+	var comments []*trello.Action
+	if err := c.Get(path, args, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// AddCardComment adds a comment to a card
+func (c *Client) AddCardComment(cardID, text string) (*trello.Action, error) {
+	path := fmt.Sprintf("cards/%s/actions/comments", cardID)
+	args := trello.Arguments{
+		"text": text,
+	}
+
+	var comment trello.Action
+	if err := c.Post(path, args, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateCardComment replaces the text of an existing comment on a card
+func (c *Client) UpdateCardComment(cardID, commentID, text string) (*trello.Action, error) {
+	path := fmt.Sprintf("cards/%s/actions/%s/comments", cardID, commentID)
+	args := trello.Arguments{
+		"text": text,
+	}
+
+	var comment trello.Action
+	if err := c.Put(path, args, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// DeleteCardComment removes a comment from a card
+func (c *Client) DeleteCardComment(cardID, commentID string) error {
+	path := fmt.Sprintf("cards/%s/actions/%s/comments", cardID, commentID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// RemoveCardLabel removes a label from a card
+func (c *Client) RemoveCardLabel(cardID, labelID string) error {
+	path := fmt.Sprintf("cards/%s/idLabels/%s", cardID, labelID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// DeleteLabel deletes a label from its board and every card using it
+func (c *Client) DeleteLabel(labelID string) error {
+	path := fmt.Sprintf("labels/%s", labelID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// DeleteChecklist deletes a checklist and its items
+func (c *Client) DeleteChecklist(checklistID string) error {
+	path := fmt.Sprintf("checklists/%s", checklistID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// DeleteCheckItem deletes an item from a checklist
+func (c *Client) DeleteCheckItem(checklistID, checkItemID string) error {
+	path := fmt.Sprintf("checklists/%s/checkItems/%s", checklistID, checkItemID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// DeleteAttachment removes an attachment from a card
+func (c *Client) DeleteAttachment(cardID, attachmentID string) error {
+	path := fmt.Sprintf("cards/%s/attachments/%s", cardID, attachmentID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// UpdateBoard sends the given board attributes, leaving the others as they are
+func (c *Client) UpdateBoard(boardID string, args trello.Arguments) (*trello.Board, error) {
+	path := fmt.Sprintf("boards/%s", boardID)
+	board := &trello.Board{}
+	if err := c.Put(path, args, board); err != nil {
+		return nil, err
+	}
+	board.SetClient(c.Client)
+	return board, nil
+}
+
+// RemoveBoardMember removes a member from a board
+func (c *Client) RemoveBoardMember(boardID, memberID string) error {
+	path := fmt.Sprintf("boards/%s/members/%s", boardID, memberID)
+	var result map[string]interface{}
+	return c.Delete(path, trello.Defaults(), &result)
+}
+
+// UpdateLabel sends the given label attributes, leaving the others as they are
+func (c *Client) UpdateLabel(labelID string, args trello.Arguments) (*trello.Label, error) {
+	path := fmt.Sprintf("labels/%s", labelID)
+	label := &trello.Label{}
+	if err := c.Put(path, args, label); err != nil {
+		return nil, err
+	}
+	label.SetClient(c.Client)
+	return label, nil
 }
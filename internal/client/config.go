@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConfigKeys lists the settings that can be read and changed by key, in the
+// order they appear in the config file
+var ConfigKeys = []string{"api_key", "token", "default_format", "max_tokens", "base_url"}
+
+// settingRefs points at the editable settings of the top-level config or of a profile
+type settingRefs struct {
+	apiKey        *string
+	token         *string
+	defaultFormat *string
+	maxTokens     *int
+	baseURL       *string
+}
+
+func (c *Config) settingRefs(profile string) (*settingRefs, error) {
+	if profile == "" || profile == DefaultProfile {
+		return &settingRefs{&c.APIKey, &c.Token, &c.DefaultFormat, &c.MaxTokens, &c.BaseURL}, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok || p == nil {
+		return nil, fmt.Errorf("profile %q not found in config file", profile)
+	}
+	return &settingRefs{&p.APIKey, &p.Token, &p.DefaultFormat, &p.MaxTokens, &p.BaseURL}, nil
+}
+
+// NormalizeConfigKey converts a flag-style key such as "default-format" to its
+// config file form and checks that it is a known setting
+func NormalizeConfigKey(key string) (string, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+	for _, k := range ConfigKeys {
+		if k == normalized {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown config key: %s (supported: %s)", key, strings.Join(ConfigKeys, ", "))
+}
+
+// Get returns the stored value of key in the given profile (DefaultProfile for
+// the top-level settings). Unset settings return an empty string.
+func (c *Config) Get(profile, key string) (string, error) {
+	key, err := NormalizeConfigKey(key)
+	if err != nil {
+		return "", err
+	}
+	refs, err := c.settingRefs(profile)
+	if err != nil {
+		return "", err
+	}
+
+	switch key {
+	case "api_key":
+		return *refs.apiKey, nil
+	case "token":
+		return *refs.token, nil
+	case "default_format":
+		return *refs.defaultFormat, nil
+	case "base_url":
+		return *refs.baseURL, nil
+	default:
+		if *refs.maxTokens == 0 {
+			return "", nil
+		}
+		return strconv.Itoa(*refs.maxTokens), nil
+	}
+}
+
+// Set changes the value of key in the given profile, leaving every other
+// setting untouched
+func (c *Config) Set(profile, key, value string) error {
+	key, err := NormalizeConfigKey(key)
+	if err != nil {
+		return err
+	}
+	refs, err := c.settingRefs(profile)
+	if err != nil {
+		return err
+	}
+
+	switch key {
+	case "api_key":
+		*refs.apiKey = value
+	case "token":
+		*refs.token = value
+	case "default_format":
+		*refs.defaultFormat = value
+	case "base_url":
+		if err := ValidateBaseURL(value); err != nil {
+			return fmt.Errorf("invalid base_url: %w", err)
+		}
+		*refs.baseURL = strings.TrimRight(value, "/")
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_tokens: %q (must be a non-negative integer)", value)
+		}
+		*refs.maxTokens = n
+	}
+	return nil
+}
+
+// Unset clears key in the given profile so that its default applies again
+func (c *Config) Unset(profile, key string) error {
+	key, err := NormalizeConfigKey(key)
+	if err != nil {
+		return err
+	}
+	refs, err := c.settingRefs(profile)
+	if err != nil {
+		return err
+	}
+
+	switch key {
+	case "api_key":
+		*refs.apiKey = ""
+	case "token":
+		*refs.token = ""
+	case "default_format":
+		*refs.defaultFormat = ""
+	case "base_url":
+		*refs.baseURL = ""
+	default:
+		*refs.maxTokens = 0
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigGetSetUnset(t *testing.T) {
+	config := &Config{
+		APIKey:        "top-key",
+		Token:         "top-token",
+		DefaultFormat: "json",
+		Profiles: map[string]*Profile{
+			"work": {APIKey: "work-key", Token: "work-token"},
+		},
+	}
+
+	t.Run("Set changes only the given key", func(t *testing.T) {
+		if err := config.Set(DefaultProfile, "default-format", "markdown"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.DefaultFormat != "markdown" {
+			t.Errorf("Expected default format markdown, got %s", config.DefaultFormat)
+		}
+		if config.APIKey != "top-key" || config.Token != "top-token" {
+			t.Errorf("Expected credentials to be kept, got %s/%s", config.APIKey, config.Token)
+		}
+	})
+
+	t.Run("Set in a profile", func(t *testing.T) {
+		if err := config.Set("work", "max_tokens", "2000"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		value, err := config.Get("work", "max_tokens")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if value != "2000" {
+			t.Errorf("Expected 2000, got %s", value)
+		}
+		if config.MaxTokens != 0 {
+			t.Errorf("Expected top-level max tokens to be untouched, got %d", config.MaxTokens)
+		}
+	})
+
+	t.Run("Unset clears the value", func(t *testing.T) {
+		if err := config.Unset("", "token"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		value, err := config.Get("", "token")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if value != "" {
+			t.Errorf("Expected empty token, got %s", value)
+		}
+	})
+
+	errorTests := []struct {
+		name     string
+		run      func() error
+		errorMsg string
+	}{
+		{
+			name:     "Unknown key",
+			run:      func() error { _, err := config.Get("", "color"); return err },
+			errorMsg: "unknown config key: color",
+		},
+		{
+			name:     "Unknown profile",
+			run:      func() error { return config.Set("bot", "token", "x") },
+			errorMsg: `profile "bot" not found`,
+		},
+		{
+			name:     "Invalid max tokens",
+			run:      func() error { return config.Set("", "max_tokens", "lots") },
+			errorMsg: "invalid max_tokens",
+		},
+		{
+			name:     "Invalid base URL",
+			run:      func() error { return config.Set("", "base_url", "ftp://example.com") },
+			errorMsg: "invalid base_url",
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
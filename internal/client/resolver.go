@@ -0,0 +1,475 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/adlio/trello"
+)
+
+var (
+	idPattern        = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+	shortLinkPattern = regexp.MustCompile(`^[A-Za-z0-9]{8}$`)
+)
+
+// resolverCache holds the name lookups made while resolving references so
+// that repeated lookups (e.g. across batch operations) hit the API only once.
+type resolverCache struct {
+	mu     sync.Mutex
+	boards []*trello.Board
+	lists  map[string][]*trello.List
+	cards  map[string][]*trello.Card
+	labels map[string][]*trello.Label
+}
+
+// NameMatch selects how names are matched when resolving references
+type NameMatch int
+
+const (
+	// MatchExact only accepts a whole name, ignoring case. Operations that
+	// change or remove the object use it, so that a partial name cannot hit
+	// the wrong one.
+	MatchExact NameMatch = iota
+	// MatchPartial also accepts a unique part of a name when no whole name matches
+	MatchPartial
+)
+
+// candidate is a named object that a reference may resolve to
+type candidate struct {
+	id   string
+	name string
+}
+
+// IsID reports whether ref looks like a 24-character Trello object ID
+func IsID(ref string) bool {
+	return idPattern.MatchString(ref)
+}
+
+// ResolveBoard resolves a board ID, short link, Trello board URL or board name
+// to a board ID. Names are matched against the open boards of the current member.
+func (c *Client) ResolveBoard(ref string, match NameMatch) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("board reference is required")
+	}
+	if IsID(ref) {
+		return ref, nil
+	}
+
+	if shortLink, ok, err := parseTrelloURL(ref, "b"); ok || err != nil {
+		if err != nil {
+			return "", err
+		}
+		return c.boardIDFromShortLink(shortLink)
+	}
+
+	// A name that looks like a short link but is not one is looked up by name
+	if shortLinkPattern.MatchString(ref) {
+		id, found, err := c.probeShortLink("boards", ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up board %q: %w", ref, err)
+		}
+		if found {
+			return id, nil
+		}
+	}
+
+	boards, err := c.cachedBoards()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up board %q: %w", ref, err)
+	}
+
+	candidates := make([]candidate, len(boards))
+	for i, board := range boards {
+		candidates[i] = candidate{id: board.ID, name: board.Name}
+	}
+	return matchCandidates("board", ref, candidates, match)
+}
+
+// ResolveList resolves a list ID or list name to a list ID. Names are matched
+// against the open lists of the board given by boardRef.
+func (c *Client) ResolveList(boardRef, ref string, match NameMatch) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("list reference is required")
+	}
+	if IsID(ref) {
+		return ref, nil
+	}
+	if boardRef == "" {
+		return "", fmt.Errorf("list %q is not an ID; use --board (or board_id in batch data) to look it up by name", ref)
+	}
+
+	boardID, err := c.ResolveBoard(boardRef, match)
+	if err != nil {
+		return "", err
+	}
+
+	lists, err := c.cachedLists(boardID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up list %q: %w", ref, err)
+	}
+
+	candidates := make([]candidate, len(lists))
+	for i, list := range lists {
+		candidates[i] = candidate{id: list.ID, name: list.Name}
+	}
+	return matchCandidates("list", ref, candidates, match)
+}
+
+// ResolveCard resolves a card ID, short link, Trello card URL or card name to
+// a card ID. Names are matched against the open cards of the board given by boardRef.
+func (c *Client) ResolveCard(boardRef, ref string, match NameMatch) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("card reference is required")
+	}
+	if IsID(ref) {
+		return ref, nil
+	}
+
+	if shortLink, ok, err := parseTrelloURL(ref, "c"); ok || err != nil {
+		if err != nil {
+			return "", err
+		}
+		return c.cardIDFromShortLink(shortLink)
+	}
+
+	// A name that looks like a short link but is not one is looked up by name
+	if shortLinkPattern.MatchString(ref) {
+		id, found, err := c.probeShortLink("cards", ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up card %q: %w", ref, err)
+		}
+		if found {
+			return id, nil
+		}
+	}
+
+	if boardRef == "" {
+		return "", fmt.Errorf("card %q is not an ID, short link or URL; use --board (or board_id in batch data) to look it up by name", ref)
+	}
+
+	boardID, err := c.ResolveBoard(boardRef, match)
+	if err != nil {
+		return "", err
+	}
+
+	cards, err := c.cachedCards(boardID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up card %q: %w", ref, err)
+	}
+
+	candidates := make([]candidate, len(cards))
+	for i, card := range cards {
+		candidates[i] = candidate{id: card.ID, name: card.Name}
+	}
+	return matchCandidates("card", ref, candidates, match)
+}
+
+// ResolveLabel resolves a label ID, label name or label color to a label ID.
+// Names and colors are matched against the labels of the board given by boardRef.
+func (c *Client) ResolveLabel(boardRef, ref string, match NameMatch) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("label reference is required")
+	}
+	if IsID(ref) {
+		return ref, nil
+	}
+	if boardRef == "" {
+		return "", fmt.Errorf("label %q is not an ID; a board is required to look it up by name", ref)
+	}
+
+	boardID, err := c.ResolveBoard(boardRef, match)
+	if err != nil {
+		return "", err
+	}
+
+	labels, err := c.cachedLabels(boardID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up label %q: %w", ref, err)
+	}
+
+	named := make([]candidate, 0, len(labels))
+	colored := make([]candidate, 0, len(labels))
+	for _, label := range labels {
+		if label.Name != "" {
+			named = append(named, candidate{id: label.ID, name: label.Name})
+		}
+		colored = append(colored, candidate{id: label.ID, name: label.Color})
+	}
+
+	id, err := matchCandidates("label", ref, named, match)
+	if err == nil {
+		return id, nil
+	}
+	// Unnamed labels are identified by color, so fall back to an exact color match
+	if id, colorErr := matchExact("label", ref, colored); colorErr == nil {
+		return id, nil
+	}
+	return "", err
+}
+
+// CardBoardID returns the ID of the board a card belongs to
+func (c *Client) CardBoardID(cardID string) (string, error) {
+	card, err := c.GetCard(cardID, trello.Arguments{"fields": "idBoard"})
+	if err != nil {
+		return "", fmt.Errorf("failed to get card: %w", err)
+	}
+	return card.IDBoard, nil
+}
+
+func (c *Client) boardIDFromShortLink(shortLink string) (string, error) {
+	board, err := c.GetBoard(shortLink, trello.Arguments{"fields": "id"})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up board %q: %w", shortLink, err)
+	}
+	return board.ID, nil
+}
+
+func (c *Client) cardIDFromShortLink(shortLink string) (string, error) {
+	card, err := c.GetCard(shortLink, trello.Arguments{"fields": "id"})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up card %q: %w", shortLink, err)
+	}
+	return card.ID, nil
+}
+
+// ForgetLookups clears the boards, lists, cards and labels cached by name
+// lookups, so that objects created since are found by name
+func (c *Client) ForgetLookups() {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	c.cache.boards = nil
+	c.cache.lists = nil
+	c.cache.cards = nil
+	c.cache.labels = nil
+}
+
+// probeShortLink looks up the ID of the board or card ("boards" or "cards")
+// with the given short link. found is false when Trello rejects the request
+// with a 4xx other than a credential failure or rate limit, as it does for a
+// name that only looks like a short link. The Trello API client reports these
+// statuses only as text, so the request is sent here.
+func (c *Client) probeShortLink(kind, shortLink string) (id string, found bool, err error) {
+	c.Throttle()
+
+	params := url.Values{"fields": {"id"}}
+	if c.Key != "" {
+		params.Set("key", c.Key)
+	}
+	if c.Token != "" {
+		params.Set("token", c.Token)
+	}
+	resp, err := c.Client.Client.Get(fmt.Sprintf("%s/%s/%s?%s", c.BaseURL, kind, url.PathEscape(shortLink), params.Encode()))
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusUnauthorized && isCredentialFailure(body):
+		return "", false, fmt.Errorf("%d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return "", false, nil
+	case resp.StatusCode >= 300:
+		return "", false, fmt.Errorf("%d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var object struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return "", false, fmt.Errorf("invalid response: %w", err)
+	}
+	return object.ID, true, nil
+}
+
+// isCredentialFailure reports whether the body of a 401 response rejects the
+// API key or token, rather than access to the requested object
+func isCredentialFailure(body []byte) bool {
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "invalid key") ||
+		strings.Contains(message, "invalid token") ||
+		strings.Contains(message, "expired token")
+}
+
+func (c *Client) cachedBoards() ([]*trello.Board, error) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	if c.cache.boards != nil {
+		return c.cache.boards, nil
+	}
+
+	var boards []*trello.Board
+	err := c.Get("members/me/boards", trello.Arguments{"filter": "open", "fields": "id,name"}, &boards)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.boards = boards
+	return boards, nil
+}
+
+func (c *Client) cachedLists(boardID string) ([]*trello.List, error) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	if lists, ok := c.cache.lists[boardID]; ok {
+		return lists, nil
+	}
+
+	var lists []*trello.List
+	err := c.Get(fmt.Sprintf("boards/%s/lists", boardID), trello.Arguments{"filter": "open", "fields": "id,name"}, &lists)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache.lists == nil {
+		c.cache.lists = make(map[string][]*trello.List)
+	}
+	c.cache.lists[boardID] = lists
+	return lists, nil
+}
+
+func (c *Client) cachedCards(boardID string) ([]*trello.Card, error) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	if cards, ok := c.cache.cards[boardID]; ok {
+		return cards, nil
+	}
+
+	var cards []*trello.Card
+	err := c.Get(fmt.Sprintf("boards/%s/cards", boardID), trello.Arguments{"filter": "open", "fields": "id,name"}, &cards)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache.cards == nil {
+		c.cache.cards = make(map[string][]*trello.Card)
+	}
+	c.cache.cards[boardID] = cards
+	return cards, nil
+}
+
+func (c *Client) cachedLabels(boardID string) ([]*trello.Label, error) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	if labels, ok := c.cache.labels[boardID]; ok {
+		return labels, nil
+	}
+
+	var labels []*trello.Label
+	err := c.Get(fmt.Sprintf("boards/%s/labels", boardID), trello.Arguments{"fields": "id,name,color"}, &labels)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache.labels == nil {
+		c.cache.labels = make(map[string][]*trello.Label)
+	}
+	c.cache.labels[boardID] = labels
+	return labels, nil
+}
+
+// parseTrelloURL extracts the short link from a trello.com board ("b") or
+// card ("c") URL. ok is false when ref is not a Trello URL at all.
+func parseTrelloURL(ref, kind string) (shortLink string, ok bool, err error) {
+	if !strings.HasPrefix(ref, "http://") && !strings.HasPrefix(ref, "https://") {
+		return "", false, nil
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || (u.Host != "trello.com" && u.Host != "www.trello.com") {
+		return "", true, fmt.Errorf("%q is not a Trello URL", ref)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[1] == "" {
+		return "", true, fmt.Errorf("%q is not a Trello board or card URL", ref)
+	}
+	if parts[0] != kind {
+		expected := "board"
+		if kind == "c" {
+			expected = "card"
+		}
+		return "", true, fmt.Errorf("%q is not a Trello %s URL", ref, expected)
+	}
+	return parts[1], true, nil
+}
+
+// matchCandidates finds the single candidate whose name matches ref, first by
+// case-insensitive exact match and then, with MatchPartial, by
+// case-insensitive substring match.
+func matchCandidates(kind, ref string, candidates []candidate, match NameMatch) (string, error) {
+	if id, err := matchExact(kind, ref, candidates); err == nil || !isNotFound(err) {
+		return id, err
+	}
+
+	needle := strings.ToLower(ref)
+	var matches []candidate
+	for _, c := range candidates {
+		if strings.Contains(strings.ToLower(c.name), needle) {
+			matches = append(matches, c)
+		}
+	}
+	if match != MatchPartial {
+		return "", &notFoundError{kind: kind, ref: ref, partial: len(matches) > 0}
+	}
+	return pickOne(kind, ref, matches)
+}
+
+func matchExact(kind, ref string, candidates []candidate) (string, error) {
+	var matches []candidate
+	for _, c := range candidates {
+		if strings.EqualFold(c.name, ref) {
+			matches = append(matches, c)
+		}
+	}
+	return pickOne(kind, ref, matches)
+}
+
+type notFoundError struct {
+	kind string
+	ref  string
+	// partial is set when ref is only part of a name, which was not accepted
+	partial bool
+}
+
+func (e *notFoundError) Error() string {
+	if e.partial {
+		return fmt.Sprintf("no %s matches %q: this operation needs the whole name or the ID", e.kind, e.ref)
+	}
+	return fmt.Sprintf("no %s matches %q", e.kind, e.ref)
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+func pickOne(kind, ref string, matches []candidate) (string, error) {
+	switch len(matches) {
+	case 0:
+		return "", &notFoundError{kind: kind, ref: ref}
+	case 1:
+		return matches[0].id, nil
+	default:
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = fmt.Sprintf("%q (%s)", m.name, m.id)
+		}
+		return "", fmt.Errorf("ambiguous %s %q: candidates are %s", kind, ref, strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testBoardID = "5f8b8c8d8e8f8a8b8c8d8e01"
+	testListID  = "5f8b8c8d8e8f8a8b8c8d8e02"
+	testCardID  = "5f8b8c8d8e8f8a8b8c8d8e03"
+	testLabelID = "5f8b8c8d8e8f8a8b8c8d8e04"
+)
+
+// newResolverTestClient returns a client backed by a small fake Trello API
+// and a counter of the requests it served.
+func newResolverTestClient(t *testing.T) (*Client, *int) {
+	requests := 0
+	responses := map[string]interface{}{
+		"/members/me/boards": []map[string]string{
+			{"id": testBoardID, "name": "Sprint 42"},
+			{"id": "5f8b8c8d8e8f8a8b8c8d8e11", "name": "Sprint 41"},
+			{"id": "5f8b8c8d8e8f8a8b8c8d8e12", "name": "Roadmap"},
+			{"id": "5f8b8c8d8e8f8a8b8c8d8e13", "name": "Private1"},
+		},
+		"/boards/AbCd1234": map[string]string{"id": testBoardID},
+		"/boards/" + testBoardID + "/lists": []map[string]string{
+			{"id": testListID, "name": "Doing"},
+			{"id": "5f8b8c8d8e8f8a8b8c8d8e21", "name": "Done"},
+		},
+		"/boards/" + testBoardID + "/cards": []map[string]string{
+			{"id": testCardID, "name": "Fix login bug"},
+			{"id": "5f8b8c8d8e8f8a8b8c8d8e31", "name": "Fix logout bug"},
+		},
+		"/boards/" + testBoardID + "/labels": []map[string]string{
+			{"id": testLabelID, "name": "Urgent", "color": "red"},
+			{"id": "5f8b8c8d8e8f8a8b8c8d8e41", "name": "", "color": "green"},
+		},
+		"/cards/XyZw9876": map[string]string{"id": testCardID},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case strings.Contains(r.URL.Path, "Denied"):
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		case strings.Contains(r.URL.Path, "Private"):
+			http.Error(w, "unauthorized permission requested", http.StatusUnauthorized)
+			return
+		case strings.Contains(r.URL.Path, "Invalid"):
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient("test-key", "test-token")
+	c.BaseURL = server.URL
+	return c, &requests
+}
+
+func TestResolveBoard(t *testing.T) {
+	c, requests := newResolverTestClient(t)
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected string
+		errorMsg string
+	}{
+		{name: "ID is returned as-is", ref: testBoardID, expected: testBoardID},
+		{name: "Exact name", ref: "Sprint 42", expected: testBoardID},
+		{name: "Case-insensitive name", ref: "sprint 42", expected: testBoardID},
+		{name: "Unique partial name", ref: "road", expected: "5f8b8c8d8e8f8a8b8c8d8e12"},
+		{name: "Board URL", ref: "https://trello.com/b/AbCd1234/sprint-42", expected: testBoardID},
+		{name: "Short link", ref: "AbCd1234", expected: testBoardID},
+		{name: "Ambiguous partial name", ref: "Sprint", errorMsg: "ambiguous board \"Sprint\": candidates are"},
+		{name: "Unknown name", ref: "Nope", errorMsg: "no board matches"},
+		{name: "Name that looks like a short link", ref: "Roadmap1", errorMsg: "no board matches \"Roadmap1\""},
+		{name: "Name rejected as a short link", ref: "Invalid1", errorMsg: "no board matches \"Invalid1\""},
+		{name: "Name of an inaccessible short link", ref: "Private1", expected: "5f8b8c8d8e8f8a8b8c8d8e13"},
+		{name: "Failed short link lookup", ref: "Denied12", errorMsg: "401: invalid token"},
+		{name: "Card URL for a board", ref: "https://trello.com/c/XyZw9876/1-card", errorMsg: "not a Trello board URL"},
+		{name: "Foreign URL", ref: "https://example.com/b/AbCd1234", errorMsg: "not a Trello URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := c.ResolveBoard(tt.ref, MatchPartial)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if id != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, id)
+			}
+		})
+	}
+
+	// Operations that change a board need its whole name
+	if _, err := c.ResolveBoard("road", MatchExact); err == nil || !strings.Contains(err.Error(), "needs the whole name") {
+		t.Errorf("Expected partial name to be rejected, got %v", err)
+	}
+	if id, err := c.ResolveBoard("ROADMAP", MatchExact); err != nil || id != "5f8b8c8d8e8f8a8b8c8d8e12" {
+		t.Errorf("Expected whole name to resolve, got %s, %v", id, err)
+	}
+
+	// The member's boards are fetched once and cached
+	before := *requests
+	if _, err := c.ResolveBoard("Roadmap", MatchPartial); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *requests != before {
+		t.Errorf("Expected cached board lookup, got %d new request(s)", *requests-before)
+	}
+
+	// Until the lookups are forgotten, e.g. after creating a board
+	c.ForgetLookups()
+	if _, err := c.ResolveBoard("Roadmap", MatchPartial); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *requests != before+1 {
+		t.Errorf("Expected the boards to be fetched again, got %d new request(s)", *requests-before)
+	}
+}
+
+func TestResolveListCardLabel(t *testing.T) {
+	c, _ := newResolverTestClient(t)
+
+	t.Run("List by name within a board name", func(t *testing.T) {
+		id, err := c.ResolveList("Sprint 42", "doing", MatchPartial)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != testListID {
+			t.Errorf("Expected %s, got %s", testListID, id)
+		}
+	})
+
+	t.Run("List name without board", func(t *testing.T) {
+		_, err := c.ResolveList("", "Doing", MatchPartial)
+		if err == nil || !strings.Contains(err.Error(), "--board") {
+			t.Errorf("Expected error mentioning --board, got %v", err)
+		}
+	})
+
+	t.Run("Exact name wins over partial matches", func(t *testing.T) {
+		// "Do" is a substring of both lists, "Done" is an exact match for one
+		id, err := c.ResolveList(testBoardID, "Done", MatchPartial)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != "5f8b8c8d8e8f8a8b8c8d8e21" {
+			t.Errorf("Expected Done list, got %s", id)
+		}
+	})
+
+	t.Run("Ambiguous card name", func(t *testing.T) {
+		_, err := c.ResolveCard(testBoardID, "Fix", MatchPartial)
+		if err == nil || !strings.Contains(err.Error(), "ambiguous card") {
+			t.Errorf("Expected ambiguous card error, got %v", err)
+		}
+	})
+
+	t.Run("Failed card short link lookup", func(t *testing.T) {
+		_, err := c.ResolveCard(testBoardID, "Denied34", MatchPartial)
+		if err == nil || !strings.Contains(err.Error(), "401: invalid token") {
+			t.Errorf("Expected the lookup error, got %v", err)
+		}
+	})
+
+	t.Run("Card by name", func(t *testing.T) {
+		id, err := c.ResolveCard(testBoardID, "fix login", MatchPartial)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != testCardID {
+			t.Errorf("Expected %s, got %s", testCardID, id)
+		}
+	})
+
+	t.Run("Partial card name for a change", func(t *testing.T) {
+		_, err := c.ResolveCard(testBoardID, "fix login", MatchExact)
+		if err == nil || !strings.Contains(err.Error(), "needs the whole name") {
+			t.Errorf("Expected partial name to be rejected, got %v", err)
+		}
+		id, err := c.ResolveCard(testBoardID, "fix login bug", MatchExact)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != testCardID {
+			t.Errorf("Expected %s, got %s", testCardID, id)
+		}
+	})
+
+	t.Run("Card URL without board", func(t *testing.T) {
+		id, err := c.ResolveCard("", "https://trello.com/c/XyZw9876/12-fix-login-bug", MatchPartial)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != testCardID {
+			t.Errorf("Expected %s, got %s", testCardID, id)
+		}
+	})
+
+	t.Run("Label by name", func(t *testing.T) {
+		id, err := c.ResolveLabel(testBoardID, "urgent", MatchPartial)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != testLabelID {
+			t.Errorf("Expected %s, got %s", testLabelID, id)
+		}
+	})
+
+	t.Run("Unnamed label by color", func(t *testing.T) {
+		id, err := c.ResolveLabel(testBoardID, "green", MatchPartial)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id != "5f8b8c8d8e8f8a8b8c8d8e41" {
+			t.Errorf("Expected green label, got %s", id)
+		}
+	})
+}
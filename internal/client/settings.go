@@ -0,0 +1,189 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adlio/trello"
+)
+
+// Setting origins, from highest to lowest precedence
+const (
+	OriginFlag    = "flag"
+	OriginEnv     = "env"
+	OriginConfig  = "config"
+	OriginDefault = "default"
+)
+
+// Built-in defaults for settings that are not set anywhere
+const (
+	DefaultOutputFormat = "json"
+	DefaultMaxTokens    = 0 // unlimited
+	DefaultBaseURL      = trello.DefaultBaseURL
+)
+
+// Setting is a resolved configuration value and the source that won
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"` // OriginFlag, OriginEnv, OriginConfig or OriginDefault
+	Source string `json:"source"` // human-readable source, e.g. "TRELLO_TOKEN environment variable"
+}
+
+// IsSet reports whether the setting has a value
+func (s Setting) IsSet() bool {
+	return s.Value != ""
+}
+
+// SettingFlags holds the command-line values that override other sources.
+// An empty field means the flag was not given.
+type SettingFlags struct {
+	APIKey    string
+	Token     string
+	Profile   string
+	Format    string
+	MaxTokens string
+	BaseURL   string
+}
+
+// Settings holds every resolved setting
+type Settings struct {
+	Profile   Setting
+	APIKey    Setting
+	Token     Setting
+	Format    Setting
+	MaxTokens Setting
+	BaseURL   Setting
+}
+
+// All returns the settings in display order
+func (s *Settings) All() []Setting {
+	return []Setting{s.Profile, s.APIKey, s.Token, s.Format, s.MaxTokens, s.BaseURL}
+}
+
+// MaxTokensValue returns the resolved max tokens as a number
+func (s *Settings) MaxTokensValue() int {
+	n, _ := strconv.Atoi(s.MaxTokens.Value)
+	return n
+}
+
+// ResolveSettings resolves every setting, reporting for each one which source
+// won. Each setting is resolved on its own with precedence:
+//  1. Command-line flags
+//  2. Environment variables (TRELLO_API_KEY, TRELLO_TOKEN, TRELLO_FORMAT,
+//     TRELLO_MAX_TOKENS, TRELLO_BASE_URL)
+//  3. Config file profile picked by SelectProfile
+//  4. Top-level config file settings (everything but the credentials)
+//  5. Built-in defaults
+//
+// Sources can therefore be mixed, e.g. the API key from TRELLO_API_KEY and the
+// token from --token.
+func ResolveSettings(flags SettingFlags) (*Settings, error) {
+	// An unreadable config file is treated like a missing one so that flags
+	// and environment variables keep working
+	config, err := ReadConfig()
+	if err != nil {
+		config = &Config{}
+	}
+
+	profileSetting := selectProfile(flags.Profile, config)
+	profile, err := config.Profile(profileSetting.Value)
+	if err != nil {
+		return nil, err
+	}
+	source := configSource(profileSetting.Value)
+
+	settings := &Settings{
+		Profile: profileSetting,
+		APIKey:  resolveSetting("api_key", "api-key", flags.APIKey, "TRELLO_API_KEY", profile.APIKey, source),
+		Token:   resolveSetting("token", "token", flags.Token, "TRELLO_TOKEN", profile.Token, source),
+		Format:  resolveSetting("format", "format", flags.Format, "TRELLO_FORMAT", profile.DefaultFormat, source),
+		MaxTokens: resolveSetting("max_tokens", "max-tokens", flags.MaxTokens, "TRELLO_MAX_TOKENS",
+			intSetting(profile.MaxTokens), source),
+		BaseURL: resolveSetting("base_url", "base-url", flags.BaseURL, "TRELLO_BASE_URL", profile.BaseURL, source),
+	}
+
+	// Output defaults missing from a named profile fall back to the top-level ones
+	settings.Format = withDefault(settings.Format, config.DefaultFormat, "config file", DefaultOutputFormat)
+	settings.MaxTokens = withDefault(settings.MaxTokens, intSetting(config.MaxTokens), "config file", strconv.Itoa(DefaultMaxTokens))
+	settings.BaseURL = withDefault(settings.BaseURL, config.BaseURL, "config file", DefaultBaseURL)
+
+	if n, err := strconv.Atoi(settings.MaxTokens.Value); err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid max_tokens %q from %s: must be a non-negative integer", settings.MaxTokens.Value, settings.MaxTokens.Source)
+	}
+
+	if err := ValidateBaseURL(settings.BaseURL.Value); err != nil {
+		return nil, fmt.Errorf("invalid base_url from %s: %w", settings.BaseURL.Source, err)
+	}
+	settings.BaseURL.Value = strings.TrimRight(settings.BaseURL.Value, "/")
+
+	return settings, nil
+}
+
+// ValidateBaseURL checks that baseURL is an absolute http or https URL
+func ValidateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", baseURL)
+	}
+	return nil
+}
+
+func selectProfile(flagProfile string, config *Config) Setting {
+	if flagProfile != "" {
+		return Setting{Key: "profile", Value: flagProfile, Origin: OriginFlag, Source: "--profile flag"}
+	}
+	if envProfile := os.Getenv("TRELLO_PROFILE"); envProfile != "" {
+		return Setting{Key: "profile", Value: envProfile, Origin: OriginEnv, Source: "TRELLO_PROFILE environment variable"}
+	}
+	if config != nil && config.CurrentProfile != "" {
+		return Setting{Key: "profile", Value: config.CurrentProfile, Origin: OriginConfig, Source: "config file (current_profile)"}
+	}
+	return Setting{Key: "profile", Value: DefaultProfile, Origin: OriginDefault, Source: "default"}
+}
+
+// configSource describes the config file profile settings are read from
+func configSource(profile string) string {
+	if profile == DefaultProfile {
+		return "config file"
+	}
+	return fmt.Sprintf("config file (profile %q)", profile)
+}
+
+// resolveSetting picks the value of a setting with precedence
+// flag > environment variable > config file
+func resolveSetting(key, flagName, flagValue, envVar, configValue, configSource string) Setting {
+	if flagValue != "" {
+		return Setting{Key: key, Value: flagValue, Origin: OriginFlag, Source: fmt.Sprintf("--%s flag", flagName)}
+	}
+	if envValue := os.Getenv(envVar); envValue != "" {
+		return Setting{Key: key, Value: envValue, Origin: OriginEnv, Source: fmt.Sprintf("%s environment variable", envVar)}
+	}
+	if configValue != "" {
+		return Setting{Key: key, Value: configValue, Origin: OriginConfig, Source: configSource}
+	}
+	return Setting{Key: key, Origin: OriginDefault, Source: "not set"}
+}
+
+// withDefault fills an unset setting from the top-level config value, then
+// from the built-in default
+func withDefault(setting Setting, configValue, configSource, defaultValue string) Setting {
+	if setting.IsSet() {
+		return setting
+	}
+	if configValue != "" {
+		return Setting{Key: setting.Key, Value: configValue, Origin: OriginConfig, Source: configSource}
+	}
+	return Setting{Key: setting.Key, Value: defaultValue, Origin: OriginDefault, Source: "default"}
+}
+
+// intSetting converts a numeric config value to a setting value, treating 0 as unset
+func intSetting(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultMaxRetries is how often a failed request is retried by default
+	DefaultMaxRetries = 3
+
+	// DefaultRateLimit is Trello's per-token budget of 100 requests per 10
+	// seconds, in requests per second
+	DefaultRateLimit = 10.0
+
+	// keyRateLimit is Trello's per-API-key budget of 300 requests per 10
+	// seconds, shared by every token of the key
+	keyRateLimit = 30.0
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+
+	// maxRetryAfter is the longest Retry-After the transport waits for
+	maxRetryAfter = 2 * time.Minute
+)
+
+// budgets holds one limiter per API key and per token, so that every client
+// in the process using the same credentials shares Trello's request budget
+var budgets = struct {
+	sync.Mutex
+	byKey   map[string]*rate.Limiter
+	byToken map[string]*rate.Limiter
+}{
+	byKey:   make(map[string]*rate.Limiter),
+	byToken: make(map[string]*rate.Limiter),
+}
+
+// budgetLimiters returns the shared limiters for apiKey and token. A
+// requestsPerSecond of 0 or less returns none.
+func budgetLimiters(apiKey, token string, requestsPerSecond float64) []*rate.Limiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+
+	budgets.Lock()
+	defer budgets.Unlock()
+
+	keyLimiter, ok := budgets.byKey[apiKey]
+	if !ok {
+		keyLimiter = rate.NewLimiter(rate.Limit(keyRateLimit), int(keyRateLimit))
+		budgets.byKey[apiKey] = keyLimiter
+	}
+
+	tokenLimiter, ok := budgets.byToken[token]
+	if !ok {
+		tokenLimiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burst(requestsPerSecond))
+		budgets.byToken[token] = tokenLimiter
+	} else {
+		// The most recently requested rate applies to every client of the token
+		tokenLimiter.SetLimit(rate.Limit(requestsPerSecond))
+		tokenLimiter.SetBurst(burst(requestsPerSecond))
+	}
+
+	return []*rate.Limiter{tokenLimiter, keyLimiter}
+}
+
+// burst allows up to one second's worth of requests at once
+func burst(requestsPerSecond float64) int {
+	if requestsPerSecond < 1 {
+		return 1
+	}
+	return int(requestsPerSecond)
+}
+
+// RetryTransport is an http.RoundTripper that waits for the request budget
+// before each attempt and retries requests that failed with 429 Too Many
+// Requests, a 5xx status or a network error.
+//
+// Only idempotent requests (GET, HEAD, OPTIONS, PUT and DELETE) are retried
+// after a 5xx or a network error, since the server may have applied them.
+// Other requests, such as POST, are retried only after a 429, which Trello
+// returns before doing any work.
+type RetryTransport struct {
+	// Base performs the requests; nil means http.DefaultTransport
+	Base http.RoundTripper
+
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+
+	// Limiters must all allow a request before it is sent
+	Limiters []*rate.Limiter
+
+	// sleep waits for d or until ctx is done; replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	sleep := t.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	for attempt := 0; ; attempt++ {
+		for _, limiter := range t.Limiters {
+			if err := limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := base.RoundTrip(attemptReq)
+		if attempt >= t.MaxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > maxRetryAfter {
+					// Waiting that long is worse than failing
+					return resp, nil
+				}
+				delay = retryAfter
+			}
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether a request that got resp or err can be retried
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		// The body cannot be sent again
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return isIdempotent(req.Method)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return isIdempotent(req.Method)
+	default:
+		return false
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before retry number attempt+1: exponential from
+// retryBaseDelay with jitter, so that concurrent clients spread out
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << uint(attempt)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adlio/trello"
+)
+
+// newFlakyServer returns a server answering with the given statuses in order
+// and 200 afterwards, and a counter of the requests it served
+func newFlakyServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "try again", statuses[requests-1])
+			return
+		}
+		w.Write([]byte(`{"id":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// recordSleeps returns a sleep function that records delays instead of waiting
+func recordSleeps(delays *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		retryAfter string
+		maxRetries int
+		status     int
+		requests   int
+	}{
+		{name: "Success is not retried", method: http.MethodGet, status: 200, requests: 1, maxRetries: 3},
+		{name: "Rate limit then success", method: http.MethodGet, statuses: []int{429, 429}, status: 200, requests: 3, maxRetries: 3},
+		{name: "Server error then success", method: http.MethodPut, statuses: []int{503}, status: 200, requests: 2, maxRetries: 3},
+		{name: "Retries run out", method: http.MethodGet, statuses: []int{500, 500, 500}, status: 500, requests: 3, maxRetries: 2},
+		{name: "POST is retried after a rate limit", method: http.MethodPost, statuses: []int{429}, status: 200, requests: 2, maxRetries: 3},
+		{name: "POST is not retried after a server error", method: http.MethodPost, statuses: []int{502}, status: 502, requests: 1, maxRetries: 3},
+		{name: "Client errors are not retried", method: http.MethodGet, statuses: []int{404}, status: 404, requests: 1, maxRetries: 3},
+		{name: "Not implemented is not retried", method: http.MethodDelete, statuses: []int{501}, status: 501, requests: 1, maxRetries: 3},
+		{name: "Retries disabled", method: http.MethodGet, statuses: []int{429}, status: 429, requests: 1, maxRetries: 0},
+		{name: "Too long Retry-After is not waited for", method: http.MethodGet, statuses: []int{429}, retryAfter: "3600", status: 429, requests: 1, maxRetries: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newFlakyServer(t, tt.retryAfter, tt.statuses...)
+
+			var delays []time.Duration
+			transport := &RetryTransport{MaxRetries: tt.maxRetries, sleep: recordSleeps(&delays)}
+
+			req, _ := http.NewRequest(tt.method, server.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if *requests != tt.requests {
+				t.Errorf("Expected %d request(s), got %d", tt.requests, *requests)
+			}
+			if len(delays) != tt.requests-1 {
+				t.Errorf("Expected %d wait(s), got %d", tt.requests-1, len(delays))
+			}
+		})
+	}
+}
+
+func TestRetryTransportBackoff(t *testing.T) {
+	t.Run("Exponential with jitter", func(t *testing.T) {
+		server, _ := newFlakyServer(t, "", 429, 429, 429)
+
+		var delays []time.Duration
+		transport := &RetryTransport{MaxRetries: 3, sleep: recordSleeps(&delays)}
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		for i, d := range delays {
+			full := retryBaseDelay << uint(i)
+			if d < full/2 || d > full {
+				t.Errorf("Retry %d: expected a delay between %v and %v, got %v", i+1, full/2, full, d)
+			}
+		}
+	})
+
+	t.Run("Retry-After in seconds", func(t *testing.T) {
+		server, _ := newFlakyServer(t, "7", 429)
+
+		var delays []time.Duration
+		transport := &RetryTransport{MaxRetries: 3, sleep: recordSleeps(&delays)}
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if len(delays) != 1 || delays[0] != 7*time.Second {
+			t.Errorf("Expected a single 7s wait, got %v", delays)
+		}
+	})
+
+	t.Run("Retry-After as a date", func(t *testing.T) {
+		now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+		delay, ok := parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now)
+		if !ok || delay != 90*time.Second {
+			t.Errorf("Expected 90s, got %v (ok=%v)", delay, ok)
+		}
+		if _, ok := parseRetryAfter("soon", now); ok {
+			t.Error("Expected an invalid Retry-After to be ignored")
+		}
+	})
+
+	t.Run("Cancelled while waiting", func(t *testing.T) {
+		server, requests := newFlakyServer(t, "", 503, 503)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		transport := &RetryTransport{MaxRetries: 3, sleep: func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return sleepContext(ctx, d)
+		}}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if _, err := transport.RoundTrip(req); err == nil {
+			t.Error("Expected an error after cancelling")
+		}
+		if *requests != 1 {
+			t.Errorf("Expected 1 request, got %d", *requests)
+		}
+	})
+
+	t.Run("Request bodies are sent again", func(t *testing.T) {
+		var bodies []string
+		failed := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if !failed {
+				failed = true
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer server.Close()
+
+		var delays []time.Duration
+		transport := &RetryTransport{MaxRetries: 3, sleep: recordSleeps(&delays)}
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("name=file"))
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if len(bodies) != 2 || bodies[0] != "name=file" || bodies[1] != "name=file" {
+			t.Errorf("Expected the body twice, got %q", bodies)
+		}
+	})
+}
+
+func TestBudgetLimiters(t *testing.T) {
+	if limiters := budgetLimiters("key", "token", 0); limiters != nil {
+		t.Errorf("Expected no limiters when rate limiting is disabled, got %d", len(limiters))
+	}
+
+	first := budgetLimiters("shared-key", "token-a", 5)
+	second := budgetLimiters("shared-key", "token-b", 5)
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("Expected token and key limiters, got %d and %d", len(first), len(second))
+	}
+	if first[0] == second[0] {
+		t.Error("Expected separate budgets for separate tokens")
+	}
+	if first[1] != second[1] {
+		t.Error("Expected tokens of one key to share the key budget")
+	}
+
+	again := budgetLimiters("shared-key", "token-a", 2)
+	if again[0] != first[0] || float64(again[0].Limit()) != 2 {
+		t.Errorf("Expected the token budget to be shared and updated, got %v", again[0].Limit())
+	}
+}
+
+func TestNewClientWithOptionsRetries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server, requests := newFlakyServer(t, "0", 429, 503)
+
+	c, err := NewClientWithOptions("retry-key", "retry-token", Options{BaseURL: server.URL, MaxRetries: 2, RateLimit: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var target map[string]string
+	if err := c.Get("boards/x", nil, &target); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *requests != 3 || target["id"] != "ok" {
+		t.Errorf("Expected success on the third request, got %d request(s) and %v", *requests, target)
+	}
+}
+
+func TestNewClientWithOptionsRateLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server, requests := newFlakyServer(t, "")
+
+	if err := removeThrottle(trello.NewClient("key", "token")); err != nil {
+		t.Fatalf("Expected the Trello client's own rate limit to be lifted: %v", err)
+	}
+
+	get := func(c *Client, n int) time.Duration {
+		t.Helper()
+		*requests = 0
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			var target map[string]string
+			if err := c.Get("boards/x", nil, &target); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if *requests != n {
+			t.Errorf("Expected %d requests, got %d", n, *requests)
+		}
+		return time.Since(start)
+	}
+
+	// The Trello client alone would take over 3 seconds for 30 requests
+	c, err := NewClientWithOptions("rate-key-fast", "fast-token", Options{BaseURL: server.URL, RateLimit: 20})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := get(c, 30); elapsed > 1500*time.Millisecond {
+		t.Errorf("Expected 30 requests at 20 per second within 1.5s, took %v", elapsed)
+	}
+
+	// The rate limit still applies below the library's
+	c, err = NewClientWithOptions("rate-key-slow", "slow-token", Options{BaseURL: server.URL, RateLimit: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := get(c, 6); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected 6 requests at 4 per second to take at least 400ms, took %v", elapsed)
+	}
+
+	// Without a rate limit, even without retries, the library's own applies
+	for _, opts := range []Options{{BaseURL: server.URL}, {BaseURL: server.URL, MaxRetries: 2}} {
+		c, err = NewClientWithOptions("rate-key-none", "none-token", opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if elapsed := get(c, 6); elapsed < 500*time.Millisecond {
+			t.Errorf("Expected 6 requests at 8 per second to take at least 500ms, took %v", elapsed)
+		}
+	}
+}
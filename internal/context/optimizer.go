@@ -50,20 +50,51 @@ func (o *Optimizer) TruncateToTokenLimit(text string) string {
 	return text[:maxChars] + "\n\n... (truncated to fit token limit)"
 }
 
-// ShouldIncludeField checks if a field should be included based on field filter
+// ShouldIncludeField checks if a field should be included based on field filter.
+// A dotted field such as labels.name is included along with labels, and an
+// excluded field such as -desc is never included.
 func (o *Optimizer) ShouldIncludeField(field string) bool {
-	if len(o.fields) == 0 {
+	if o.IsExcluded(field) {
+		return false
+	}
+
+	include, _ := SplitFields(o.fields)
+	if len(include) == 0 {
 		return true
 	}
+	for _, f := range include {
+		if f == field || strings.HasPrefix(f, field+".") || strings.HasPrefix(field, f+".") {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, f := range o.fields {
-		if f == field {
+// IsExcluded checks if a field, or the object it is in, is excluded with a
+// leading "-", as in -desc or -badges
+func (o *Optimizer) IsExcluded(field string) bool {
+	_, exclude := SplitFields(o.fields)
+	for _, f := range exclude {
+		if f == field || strings.HasPrefix(field, f+".") {
 			return true
 		}
 	}
 	return false
 }
 
+// SplitFields splits a field filter into the fields to include and the
+// fields to exclude, which are written with a leading "-"
+func SplitFields(fields []string) (include, exclude []string) {
+	for _, field := range fields {
+		if name, ok := strings.CutPrefix(field, "-"); ok {
+			exclude = append(exclude, name)
+		} else {
+			include = append(include, field)
+		}
+	}
+	return include, exclude
+}
+
 // GetDefaultFields returns default field sets based on verbosity
 func (o *Optimizer) GetDefaultFields(entityType string) []string {
 	switch entityType {
@@ -91,6 +122,30 @@ func (o *Optimizer) GetDefaultFields(entityType string) []string {
 		}
 		return []string{"id", "username", "fullName"}
 
+	case "label":
+		if o.verbose {
+			return []string{"id", "name", "color", "idBoard", "uses"}
+		}
+		return []string{"id", "name", "color"}
+
+	case "checklist":
+		if o.verbose {
+			return []string{"id", "name", "idCard", "pos", "checkItems"}
+		}
+		return []string{"id", "name", "checkItems"}
+
+	case "attachment":
+		if o.verbose {
+			return []string{"id", "name", "url", "mimeType", "bytes", "date"}
+		}
+		return []string{"id", "name", "url"}
+
+	case "comment":
+		if o.verbose {
+			return []string{"id", "date", "memberCreator.username", "memberCreator.fullName", "data.text"}
+		}
+		return []string{"id", "date", "memberCreator.username", "data.text"}
+
 	default:
 		return []string{}
 	}
@@ -131,10 +186,26 @@ func (o *Optimizer) FormatSummary(title string, count int, fields []string) stri
 	return sb.String()
 }
 
-// GetRelevantFields filters fields based on context and verbosity
+// GetRelevantFields filters fields based on context and verbosity: the
+// fields to include, or else the default fields but for the excluded ones
 func (o *Optimizer) GetRelevantFields(entityType string) []string {
-	if len(o.fields) > 0 {
-		return o.fields
+	if include, _ := SplitFields(o.fields); len(include) > 0 {
+		return include
+	}
+	return o.WithoutExcluded(o.GetDefaultFields(entityType))
+}
+
+// WithoutExcluded returns fields but for the excluded ones
+func (o *Optimizer) WithoutExcluded(fields []string) []string {
+	if _, exclude := SplitFields(o.fields); len(exclude) == 0 {
+		return fields
+	}
+
+	kept := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !o.IsExcluded(field) {
+			kept = append(kept, field)
+		}
 	}
-	return o.GetDefaultFields(entityType)
+	return kept
 }
@@ -133,6 +133,30 @@ func TestShouldIncludeField(t *testing.T) {
 			field:    "url",
 			expected: false,
 		},
+		{
+			name:     "Parent of a dotted field",
+			fields:   []string{"labels.name"},
+			field:    "labels",
+			expected: true,
+		},
+		{
+			name:     "Part of a field",
+			fields:   []string{"badges"},
+			field:    "badges.checkItems",
+			expected: true,
+		},
+		{
+			name:     "Excluded field",
+			fields:   []string{"-desc"},
+			field:    "desc",
+			expected: false,
+		},
+		{
+			name:     "Field not excluded",
+			fields:   []string{"-desc"},
+			field:    "name",
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -178,6 +202,18 @@ func TestGetDefaultFields(t *testing.T) {
 			verbose:    false,
 			expected:   []string{"id", "name", "desc", "due", "labels", "closed"},
 		},
+		{
+			name:       "Label non-verbose",
+			entityType: "label",
+			verbose:    false,
+			expected:   []string{"id", "name", "color"},
+		},
+		{
+			name:       "Comment non-verbose",
+			entityType: "comment",
+			verbose:    false,
+			expected:   []string{"id", "date", "memberCreator.username", "data.text"},
+		},
 		{
 			name:       "Unknown entity",
 			entityType: "unknown",
@@ -260,6 +296,20 @@ func TestGetRelevantFields(t *testing.T) {
 			verbose:        false,
 			expectedLength: 6, // Card non-verbose fields
 		},
+		{
+			name:           "Excluded field",
+			fields:         []string{"-desc"},
+			entityType:     "card",
+			verbose:        false,
+			expectedLength: 5,
+		},
+		{
+			name:           "Custom and excluded fields",
+			fields:         []string{"id", "labels.name", "-desc"},
+			entityType:     "card",
+			verbose:        false,
+			expectedLength: 2,
+		},
 	}
 
 	for _, tt := range tests {
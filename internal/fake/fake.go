@@ -0,0 +1,328 @@
+// Package fake provides an in-memory implementation of the parts of the Trello
+// REST API that trello-cli uses. It lets commands, batch files and scripts run
+// offline, e.g. with --base-url pointing at an httptest server or at
+// 'trello-cli fake-server'.
+//
+// The fake covers members, boards, lists, cards, labels, checklists,
+// attachments and card comments. Like the real API it accepts every parameter
+// as a query or form value and requires a key and token, but it accepts any
+// non-empty credentials.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adlio/trello"
+)
+
+// posStep is the gap Trello leaves between positions of appended items
+const posStep = 16384
+
+// defaultListNames and defaultLabelColors are what Trello adds to new boards
+var (
+	defaultListNames   = []string{"To Do", "Doing", "Done"}
+	defaultLabelColors = []string{"green", "yellow", "orange", "red", "purple", "blue"}
+)
+
+// Server is an in-memory fake of the Trello REST API. It is safe for
+// concurrent use and implements http.Handler.
+type Server struct {
+	mu  sync.Mutex
+	mux *http.ServeMux
+	seq int
+	now func() time.Time
+
+	me           *trello.Member
+	members      []*trello.Member
+	boards       []*trello.Board
+	boardMembers map[string][]string
+	lists        []*trello.List
+	cards        []*trello.Card
+	labels       []*trello.Label
+	checklists   []*trello.Checklist
+	attachments  []*attachment
+	comments     []*trello.Action
+	shortLinks   map[string]string
+}
+
+// attachment is a card attachment together with the card it belongs to
+type attachment struct {
+	*trello.Attachment
+	cardID string
+}
+
+// apiError is an error response with its HTTP status
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+var errNotFound = &apiError{status: http.StatusNotFound, message: "The requested resource was not found."}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// New returns an empty fake with a single member, "me", who owns every board
+// created through it
+func New() *Server {
+	s := &Server{
+		mux:          http.NewServeMux(),
+		now:          time.Now,
+		boardMembers: make(map[string][]string),
+		shortLinks:   make(map[string]string),
+	}
+	s.me = &trello.Member{
+		ID:       s.newID(),
+		Username: "fake-user",
+		FullName: "Fake User",
+		Initials: "FU",
+		Email:    "fake-user@example.com",
+	}
+	s.members = append(s.members, s.me)
+	s.routes()
+	return s
+}
+
+// Me returns a copy of the member the fake authenticates every request as
+func (s *Server) Me() trello.Member {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.me
+}
+
+// ServeHTTP serves the API under "/" and, like api.trello.com, under "/1"
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/1" || strings.HasPrefix(r.URL.Path, "/1/") {
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/1")
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, badRequest("invalid parameters: %v", err))
+		return
+	}
+	if r.Form.Get("key") == "" {
+		writeError(w, &apiError{status: http.StatusUnauthorized, message: "invalid key"})
+		return
+	}
+	if r.Form.Get("token") == "" {
+		writeError(w, &apiError{status: http.StatusUnauthorized, message: "missing scopes"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.ServeHTTP(w, r)
+}
+
+// handlerFunc handles a request with the server lock held and returns the
+// value to encode as the JSON response
+type handlerFunc func(r *http.Request, params url.Values) (interface{}, error)
+
+func (s *Server) handle(pattern string, h handlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		result, err := h(r, r.Form)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if apiErr, ok := err.(*apiError); ok {
+		status = apiErr.status
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprint(w, err.Error())
+}
+
+func (s *Server) routes() {
+	s.handle("GET /members/{id}", s.getMember)
+	s.handle("GET /members/{id}/boards", s.getMemberBoards)
+
+	s.handle("POST /boards", s.createBoard)
+	s.handle("GET /boards/{id}", s.getBoard)
+	s.handle("PUT /boards/{id}", s.updateBoard)
+	s.handle("DELETE /boards/{id}", s.deleteBoard)
+	s.handle("GET /boards/{id}/lists", s.getBoardLists)
+	s.handle("GET /boards/{id}/cards", s.getBoardCards)
+	s.handle("GET /boards/{id}/labels", s.getBoardLabels)
+	s.handle("POST /boards/{id}/labels", s.createLabel)
+	s.handle("POST /boards/{id}/labels/", s.createLabel)
+	s.handle("GET /boards/{id}/members", s.getBoardMembers)
+	s.handle("PUT /boards/{id}/members", s.addBoardMember)
+	s.handle("DELETE /boards/{id}/members/{member}", s.removeBoardMember)
+
+	s.handle("POST /lists", s.createList)
+	s.handle("GET /lists/{id}", s.getList)
+	s.handle("PUT /lists/{id}", s.updateList)
+	s.handle("GET /lists/{id}/cards", s.getListCards)
+
+	s.handle("POST /cards", s.createCard)
+	s.handle("GET /cards/{id}", s.getCard)
+	s.handle("PUT /cards/{id}", s.updateCard)
+	s.handle("DELETE /cards/{id}", s.deleteCard)
+	s.handle("POST /cards/{id}/idLabels", s.addCardLabel)
+	s.handle("DELETE /cards/{id}/idLabels/{label}", s.removeCardLabel)
+	s.handle("POST /cards/{id}/idMembers", s.addCardMember)
+	s.handle("DELETE /cards/{id}/idMembers/{member}", s.removeCardMember)
+	s.handle("GET /cards/{id}/attachments", s.getAttachments)
+	s.handle("POST /cards/{id}/attachments", s.createAttachment)
+	s.handle("DELETE /cards/{id}/attachments/{attachment}", s.deleteAttachment)
+	s.handle("GET /cards/{id}/checklists", s.getCardChecklists)
+	s.handle("POST /cards/{id}/checklists", s.createChecklist)
+	s.handle("PUT /cards/{id}/checkItem/{item}", s.updateCheckItem)
+	s.handle("GET /cards/{id}/actions", s.getCardActions)
+	s.handle("POST /cards/{id}/actions/comments", s.createComment)
+	s.handle("PUT /cards/{id}/actions/{action}/comments", s.updateComment)
+	s.handle("DELETE /cards/{id}/actions/{action}/comments", s.deleteComment)
+
+	s.handle("GET /checklists/{id}", s.getChecklist)
+	s.handle("PUT /checklists/{id}", s.updateChecklist)
+	s.handle("DELETE /checklists/{id}", s.deleteChecklist)
+	s.handle("POST /checklists/{id}/checkItems", s.createCheckItem)
+	s.handle("DELETE /checklists/{id}/checkItems/{item}", s.deleteCheckItem)
+
+	s.handle("GET /labels/{id}", s.getLabel)
+	s.handle("PUT /labels/{id}", s.updateLabel)
+	s.handle("DELETE /labels/{id}", s.deleteLabel)
+}
+
+// newID returns a new 24-character hex object ID
+func (s *Server) newID() string {
+	s.seq++
+	return fmt.Sprintf("fa4e%020x", s.seq)
+}
+
+// newShortLink returns a new 8-character short link pointing at id
+func (s *Server) newShortLink(id string) string {
+	s.seq++
+	shortLink := fmt.Sprintf("Fk%06d", s.seq)
+	s.shortLinks[shortLink] = id
+	return shortLink
+}
+
+// resolveID maps a short link to the object ID it belongs to
+func (s *Server) resolveID(idOrShortLink string) string {
+	if id, ok := s.shortLinks[idOrShortLink]; ok {
+		return id
+	}
+	return idOrShortLink
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(name string) string {
+	return strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// parseBool parses a "true"/"false" parameter
+func parseBool(params url.Values, key string) (bool, bool, error) {
+	if _, ok := params[key]; !ok {
+		return false, false, nil
+	}
+	value, err := strconv.ParseBool(params.Get(key))
+	if err != nil {
+		return false, true, badRequest("invalid value for %s", key)
+	}
+	return value, true, nil
+}
+
+// parseDate parses a date parameter; "null" and "" clear the date
+func parseDate(params url.Values, key string) (*time.Time, bool, error) {
+	if _, ok := params[key]; !ok {
+		return nil, false, nil
+	}
+	value := params.Get(key)
+	if value == "" || value == "null" {
+		return nil, true, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, true, nil
+		}
+	}
+	return nil, true, badRequest("invalid date for %s", key)
+}
+
+// position computes a new position from a "top", "bottom" or numeric pos
+// parameter, given the positions of the siblings
+func position(value string, siblings []float64) (float64, error) {
+	lowest, highest := math.Inf(1), 0.0
+	for _, p := range siblings {
+		lowest = math.Min(lowest, p)
+		highest = math.Max(highest, p)
+	}
+
+	switch value {
+	case "", "bottom":
+		return highest + posStep, nil
+	case "top":
+		if len(siblings) == 0 {
+			return posStep, nil
+		}
+		return lowest / 2, nil
+	default:
+		pos, err := strconv.ParseFloat(value, 64)
+		if err != nil || pos < 0 {
+			return 0, badRequest("invalid value for pos")
+		}
+		return pos, nil
+	}
+}
+
+// filterClosed keeps the items matching a Trello "filter" parameter of
+// open, closed or all. defaultFilter applies when the parameter is missing.
+func filterClosed(params url.Values, defaultFilter string, closed func(i int) bool, n int) ([]int, error) {
+	filter := params.Get("filter")
+	if filter == "" {
+		filter = defaultFilter
+	}
+
+	var keep []int
+	for i := 0; i < n; i++ {
+		switch filter {
+		case "all":
+			keep = append(keep, i)
+		case "open", "visible":
+			if !closed(i) {
+				keep = append(keep, i)
+			}
+		case "closed":
+			if closed(i) {
+				keep = append(keep, i)
+			}
+		default:
+			return nil, badRequest("invalid value for filter")
+		}
+	}
+	return keep, nil
+}
+
+func sortByPos[T any](items []T, pos func(T) float64) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return pos(items[i]) < pos(items[j])
+	})
+}
@@ -0,0 +1,219 @@
+package fake
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/client"
+)
+
+// newTestClient returns a client talking to a fresh fake through the same
+// /1 prefix as api.trello.com, with a rate limit of its own
+func newTestClient(t *testing.T) (*client.Client, *Server) {
+	t.Setenv("HOME", t.TempDir())
+
+	fake := New()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c, err := client.NewClientWithOptions(t.Name(), t.Name(), client.Options{BaseURL: server.URL + "/1/", RateLimit: 1000})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return c, fake
+}
+
+func TestWorkflow(t *testing.T) {
+	c, fake := newTestClient(t)
+
+	board := trello.NewBoard("Sprint 42")
+	if err := c.CreateBoard(&board, nil); err != nil {
+		t.Fatalf("CreateBoard failed: %v", err)
+	}
+	if board.ID == "" || !strings.HasSuffix(board.URL, "/sprint-42") {
+		t.Errorf("Unexpected board: %+v", board)
+	}
+
+	lists, err := board.GetLists(nil)
+	if err != nil {
+		t.Fatalf("GetLists failed: %v", err)
+	}
+	if len(lists) != 3 || lists[0].Name != "To Do" || lists[2].Name != "Done" {
+		t.Errorf("Expected the default lists, got %d list(s)", len(lists))
+	}
+
+	backlog, err := board.CreateList("Backlog", trello.Arguments{"pos": "top"})
+	if err != nil {
+		t.Fatalf("CreateList failed: %v", err)
+	}
+
+	card := trello.Card{Name: "Fix login bug", Desc: "Steps to reproduce", IDList: backlog.ID}
+	if err := c.CreateCard(&card, nil); err != nil {
+		t.Fatalf("CreateCard failed: %v", err)
+	}
+	if card.IDBoard != board.ID || card.IDShort != 1 {
+		t.Errorf("Unexpected card: board %s, short ID %d", card.IDBoard, card.IDShort)
+	}
+
+	label := trello.Label{Name: "Urgent", Color: "red"}
+	if err := board.CreateLabel(&label, nil); err != nil {
+		t.Fatalf("CreateLabel failed: %v", err)
+	}
+	if err := card.AddIDLabel(label.ID); err != nil {
+		t.Fatalf("AddIDLabel failed: %v", err)
+	}
+	if err := card.AddIDLabel(label.ID); err == nil {
+		t.Error("Expected an error adding the same label twice")
+	}
+
+	checklist, err := c.CreateChecklist(&card, "Release", nil)
+	if err != nil {
+		t.Fatalf("CreateChecklist failed: %v", err)
+	}
+	item, err := checklist.CreateCheckItem("Tag release", nil)
+	if err != nil {
+		t.Fatalf("CreateCheckItem failed: %v", err)
+	}
+	if _, err := checklist.CreateCheckItem("Announce", nil); err != nil {
+		t.Fatalf("CreateCheckItem failed: %v", err)
+	}
+	if err := c.UpdateCheckItemState(card.ID, item.ID, "complete"); err != nil {
+		t.Fatalf("UpdateCheckItemState failed: %v", err)
+	}
+
+	attachment := trello.Attachment{URL: "https://example.com/spec.pdf"}
+	if err := card.AddURLAttachment(&attachment); err != nil {
+		t.Fatalf("AddURLAttachment failed: %v", err)
+	}
+
+	comment, err := c.AddCardComment(card.ID, "Looking into it")
+	if err != nil {
+		t.Fatalf("AddCardComment failed: %v", err)
+	}
+	if _, err := c.UpdateCardComment(card.ID, comment.ID, "Fixed"); err != nil {
+		t.Fatalf("UpdateCardComment failed: %v", err)
+	}
+	comments, err := c.GetCardComments(card.ID)
+	if err != nil {
+		t.Fatalf("GetCardComments failed: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Data.Text != "Fixed" {
+		t.Errorf("Expected the edited comment, got %d comment(s)", len(comments))
+	}
+
+	got, err := c.GetCard(card.ShortLink, trello.Arguments{"checklists": "all", "attachments": "true"})
+	if err != nil {
+		t.Fatalf("GetCard by short link failed: %v", err)
+	}
+	if len(got.Labels) != 1 || got.Labels[0].Name != "Urgent" {
+		t.Errorf("Expected the Urgent label, got %v", got.Labels)
+	}
+	if got.Badges.CheckItems != 2 || got.Badges.CheckItemsChecked != 1 {
+		t.Errorf("Expected 1/2 check items, got %d/%d", got.Badges.CheckItemsChecked, got.Badges.CheckItems)
+	}
+	if got.Badges.Comments != 1 || len(got.Attachments) != 1 || len(got.Checklists) != 1 {
+		t.Errorf("Unexpected card contents: %d comment(s), %d attachment(s), %d checklist(s)",
+			got.Badges.Comments, len(got.Attachments), len(got.Checklists))
+	}
+
+	// Names, short links and URLs resolve through the same endpoints
+	if id, err := c.ResolveCard("Sprint 42", "fix login", client.MatchPartial); err != nil || id != card.ID {
+		t.Errorf("ResolveCard = %s, %v; expected %s", id, err, card.ID)
+	}
+	if id, err := c.ResolveBoard(board.URL, client.MatchExact); err != nil || id != board.ID {
+		t.Errorf("ResolveBoard = %s, %v; expected %s", id, err, board.ID)
+	}
+
+	if err := card.MoveToList(lists[1].ID, nil); err != nil {
+		t.Fatalf("MoveToList failed: %v", err)
+	}
+	copied, err := card.CopyToList(lists[2].ID, nil)
+	if err != nil {
+		t.Fatalf("CopyToList failed: %v", err)
+	}
+	if copied.Name != card.Name || len(copied.IDCheckLists) != 1 {
+		t.Errorf("Expected a copy with its checklist, got %+v", copied)
+	}
+
+	if err := card.Archive(); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	cards, err := board.GetCards(nil)
+	if err != nil {
+		t.Fatalf("GetCards failed: %v", err)
+	}
+	if len(cards) != 1 || cards[0].ID != copied.ID {
+		t.Errorf("Expected only the copy to be open, got %d card(s)", len(cards))
+	}
+
+	member := trello.Member{Email: "alex@example.com"}
+	if _, err := board.AddMember(&member, nil); err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+	members, err := board.GetMembers(nil)
+	if err != nil {
+		t.Fatalf("GetMembers failed: %v", err)
+	}
+	if len(members) != 2 || members[0].ID != fake.Me().ID {
+		t.Errorf("Expected the owner and the new member, got %d member(s)", len(members))
+	}
+
+	if err := board.Delete(nil); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.GetCard(copied.ID, nil); err == nil {
+		t.Error("Expected cards of a deleted board to be gone")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	tests := []struct {
+		name   string
+		call   func() error
+		status string
+	}{
+		{
+			name:   "Unknown board",
+			call:   func() error { _, err := c.GetBoard("nope", nil); return err },
+			status: "404",
+		},
+		{
+			name:   "List on unknown board",
+			call:   func() error { _, err := c.CreateList(&trello.Board{ID: "nope"}, "Doing", nil); return err },
+			status: "400",
+		},
+		{
+			name:   "Card without name",
+			call:   func() error { return c.CreateCard(&trello.Card{IDList: "nope"}, nil) },
+			status: "400",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if err == nil || !strings.Contains(err.Error(), tt.status) {
+				t.Errorf("Expected a %s error, got %v", tt.status, err)
+			}
+		})
+	}
+
+	t.Run("Missing credentials", func(t *testing.T) {
+		server := httptest.NewServer(New())
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/1/members/me")
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", resp.StatusCode)
+		}
+	})
+}
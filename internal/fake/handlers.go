@@ -0,0 +1,1218 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/adlio/trello"
+)
+
+// Members
+
+func (s *Server) findMember(idOrUsername string) *trello.Member {
+	if idOrUsername == "me" {
+		return s.me
+	}
+	for _, m := range s.members {
+		if m.ID == idOrUsername || m.Username == idOrUsername {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Server) memberJSON(m *trello.Member) *trello.Member {
+	out := *m
+	out.IDBoards = []string{}
+	for _, b := range s.boards {
+		if s.isBoardMember(b.ID, m.ID) {
+			out.IDBoards = append(out.IDBoards, b.ID)
+		}
+	}
+	return &out
+}
+
+func (s *Server) getMember(r *http.Request, params url.Values) (interface{}, error) {
+	m := s.findMember(r.PathValue("id"))
+	if m == nil {
+		return nil, errNotFound
+	}
+	return s.memberJSON(m), nil
+}
+
+func (s *Server) getMemberBoards(r *http.Request, params url.Values) (interface{}, error) {
+	m := s.findMember(r.PathValue("id"))
+	if m == nil {
+		return nil, errNotFound
+	}
+
+	var boards []*trello.Board
+	for _, b := range s.boards {
+		if s.isBoardMember(b.ID, m.ID) {
+			boards = append(boards, b)
+		}
+	}
+	keep, err := filterClosed(params, "all", func(i int) bool { return boards[i].Closed }, len(boards))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*trello.Board{}
+	for _, i := range keep {
+		result = append(result, boards[i])
+	}
+	return result, nil
+}
+
+// Boards
+
+func (s *Server) findBoard(idOrShortLink string) *trello.Board {
+	id := s.resolveID(idOrShortLink)
+	for _, b := range s.boards {
+		if b.ID == id {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *Server) isBoardMember(boardID, memberID string) bool {
+	for _, id := range s.boardMembers[boardID] {
+		if id == memberID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) createBoard(r *http.Request, params url.Values) (interface{}, error) {
+	name := strings.TrimSpace(params.Get("name"))
+	if name == "" {
+		return nil, badRequest("invalid value for name")
+	}
+
+	b := trello.NewBoard(name)
+	b.ID = s.newID()
+	b.Desc = params.Get("desc")
+	b.IDOrganization = params.Get("idOrganization")
+	b.Prefs.PermissionLevel = "private"
+	shortLink := s.newShortLink(b.ID)
+	b.ShortURL = "https://trello.com/b/" + shortLink
+	b.URL = fmt.Sprintf("https://trello.com/b/%s/%s", shortLink, slug(name))
+
+	s.boards = append(s.boards, &b)
+	s.boardMembers[b.ID] = []string{s.me.ID}
+
+	// Like Trello, new boards get the default lists unless asked not to, and
+	// always get the six unnamed default labels
+	if params.Get("defaultLists") != "false" {
+		for i, listName := range defaultListNames {
+			s.lists = append(s.lists, &trello.List{
+				ID:      s.newID(),
+				Name:    listName,
+				IDBoard: b.ID,
+				Pos:     float32((i + 1) * posStep),
+			})
+		}
+	}
+	if params.Get("defaultLabels") != "false" {
+		for _, color := range defaultLabelColors {
+			s.labels = append(s.labels, &trello.Label{ID: s.newID(), IDBoard: b.ID, Color: color})
+		}
+	}
+
+	return &b, nil
+}
+
+func (s *Server) boardJSON(b *trello.Board, params url.Values) (*trello.Board, error) {
+	out := *b
+	out.Lists = nil
+	if filter := params.Get("lists"); filter != "" && filter != "none" {
+		lists, err := s.boardLists(b.ID, url.Values{"filter": {filter}})
+		if err != nil {
+			return nil, err
+		}
+		out.Lists = lists
+	}
+	return &out, nil
+}
+
+func (s *Server) getBoard(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+	return s.boardJSON(b, params)
+}
+
+func (s *Server) updateBoard(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+
+	if _, ok := params["name"]; ok {
+		name := strings.TrimSpace(params.Get("name"))
+		if name == "" {
+			return nil, badRequest("invalid value for name")
+		}
+		b.Name = name
+	}
+	if _, ok := params["desc"]; ok {
+		b.Desc = params.Get("desc")
+	}
+	closed, ok, err := parseBool(params, "closed")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		b.Closed = closed
+	}
+	return s.boardJSON(b, url.Values{})
+}
+
+func (s *Server) deleteBoard(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+
+	s.boards = removeWhere(s.boards, func(x *trello.Board) bool { return x.ID == b.ID })
+	s.lists = removeWhere(s.lists, func(l *trello.List) bool { return l.IDBoard == b.ID })
+	s.labels = removeWhere(s.labels, func(l *trello.Label) bool { return l.IDBoard == b.ID })
+	for _, c := range s.cards {
+		if c.IDBoard == b.ID {
+			s.removeCardData(c.ID)
+		}
+	}
+	s.cards = removeWhere(s.cards, func(c *trello.Card) bool { return c.IDBoard == b.ID })
+	delete(s.boardMembers, b.ID)
+
+	return map[string]interface{}{"_value": nil}, nil
+}
+
+func (s *Server) boardLists(boardID string, params url.Values) ([]*trello.List, error) {
+	var lists []*trello.List
+	for _, l := range s.lists {
+		if l.IDBoard == boardID {
+			lists = append(lists, l)
+		}
+	}
+	keep, err := filterClosed(params, "open", func(i int) bool { return lists[i].Closed }, len(lists))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*trello.List{}
+	for _, i := range keep {
+		result = append(result, lists[i])
+	}
+	sortByPos(result, func(l *trello.List) float64 { return float64(l.Pos) })
+	return result, nil
+}
+
+func (s *Server) getBoardLists(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+	return s.boardLists(b.ID, params)
+}
+
+func (s *Server) getBoardCards(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+	return s.cardsWhere(params, func(c *trello.Card) bool { return c.IDBoard == b.ID })
+}
+
+func (s *Server) getBoardLabels(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+
+	result := []*trello.Label{}
+	for _, l := range s.labels {
+		if l.IDBoard == b.ID {
+			result = append(result, s.labelJSON(l))
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) getBoardMembers(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+
+	result := []*trello.Member{}
+	for _, id := range s.boardMembers[b.ID] {
+		result = append(result, s.memberJSON(s.findMember(id)))
+	}
+	return result, nil
+}
+
+func (s *Server) addBoardMember(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+
+	email := strings.TrimSpace(params.Get("email"))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, badRequest("invalid value for email")
+	}
+
+	var member *trello.Member
+	for _, m := range s.members {
+		if strings.EqualFold(m.Email, email) {
+			member = m
+		}
+	}
+	if member == nil {
+		username := slug(strings.SplitN(email, "@", 2)[0])
+		member = &trello.Member{ID: s.newID(), Username: username, FullName: username, Email: email}
+		s.members = append(s.members, member)
+	}
+	if !s.isBoardMember(b.ID, member.ID) {
+		s.boardMembers[b.ID] = append(s.boardMembers[b.ID], member.ID)
+	}
+
+	response := &trello.AddedMembersResponse{ID: b.ID}
+	for _, id := range s.boardMembers[b.ID] {
+		response.Members = append(response.Members, s.memberJSON(s.findMember(id)))
+		response.Memberships = append(response.Memberships, &trello.Membership{ID: id, MemberID: id, Type: "normal"})
+	}
+	return response, nil
+}
+
+func (s *Server) removeBoardMember(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+	member := s.findMember(r.PathValue("member"))
+	if member == nil || !s.isBoardMember(b.ID, member.ID) {
+		return nil, errNotFound
+	}
+
+	s.boardMembers[b.ID] = removeWhere(s.boardMembers[b.ID], func(id string) bool { return id == member.ID })
+	return s.boardJSON(b, url.Values{})
+}
+
+// Lists
+
+func (s *Server) findList(id string) *trello.List {
+	for _, l := range s.lists {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+func (s *Server) listPositions(boardID, exceptID string) []float64 {
+	var positions []float64
+	for _, l := range s.lists {
+		if l.IDBoard == boardID && l.ID != exceptID && !l.Closed {
+			positions = append(positions, float64(l.Pos))
+		}
+	}
+	return positions
+}
+
+func (s *Server) createList(r *http.Request, params url.Values) (interface{}, error) {
+	name := strings.TrimSpace(params.Get("name"))
+	if name == "" {
+		return nil, badRequest("invalid value for name")
+	}
+	b := s.findBoard(params.Get("idBoard"))
+	if b == nil {
+		return nil, badRequest("invalid value for idBoard")
+	}
+
+	pos, err := position(params.Get("pos"), s.listPositions(b.ID, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &trello.List{ID: s.newID(), Name: name, IDBoard: b.ID, Pos: float32(pos)}
+	s.lists = append(s.lists, l)
+	return l, nil
+}
+
+func (s *Server) getList(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findList(r.PathValue("id"))
+	if l == nil {
+		return nil, errNotFound
+	}
+	return l, nil
+}
+
+func (s *Server) updateList(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findList(r.PathValue("id"))
+	if l == nil {
+		return nil, errNotFound
+	}
+
+	if _, ok := params["name"]; ok {
+		name := strings.TrimSpace(params.Get("name"))
+		if name == "" {
+			return nil, badRequest("invalid value for name")
+		}
+		l.Name = name
+	}
+	if _, ok := params["idBoard"]; ok {
+		b := s.findBoard(params.Get("idBoard"))
+		if b == nil {
+			return nil, badRequest("invalid value for idBoard")
+		}
+		l.IDBoard = b.ID
+		for _, c := range s.cards {
+			if c.IDList == l.ID {
+				c.IDBoard = b.ID
+			}
+		}
+	}
+	if _, ok := params["pos"]; ok {
+		pos, err := position(params.Get("pos"), s.listPositions(l.IDBoard, l.ID))
+		if err != nil {
+			return nil, err
+		}
+		l.Pos = float32(pos)
+	}
+	closed, ok, err := parseBool(params, "closed")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		l.Closed = closed
+	}
+	return l, nil
+}
+
+func (s *Server) getListCards(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findList(r.PathValue("id"))
+	if l == nil {
+		return nil, errNotFound
+	}
+	return s.cardsWhere(params, func(c *trello.Card) bool { return c.IDList == l.ID })
+}
+
+// Cards
+
+func (s *Server) findCard(idOrShortLink string) *trello.Card {
+	id := s.resolveID(idOrShortLink)
+	for _, c := range s.cards {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) cardPositions(listID, exceptID string) []float64 {
+	var positions []float64
+	for _, c := range s.cards {
+		if c.IDList == listID && c.ID != exceptID && !c.Closed {
+			positions = append(positions, c.Pos)
+		}
+	}
+	return positions
+}
+
+// cardsWhere returns the cards matching match and the filter parameter. Like
+// Trello it pages by object ID with before and since, which adlio/trello uses
+// to fetch every card of a board; IDs from newID sort by creation.
+func (s *Server) cardsWhere(params url.Values, match func(c *trello.Card) bool) ([]*trello.Card, error) {
+	before, since := params.Get("before"), params.Get("since")
+	var cards []*trello.Card
+	for _, c := range s.cards {
+		if (before != "" && c.ID >= before) || (since != "" && c.ID <= since) {
+			continue
+		}
+		if match(c) {
+			cards = append(cards, c)
+		}
+	}
+	keep, err := filterClosed(params, "open", func(i int) bool { return cards[i].Closed }, len(cards))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*trello.Card{}
+	for _, i := range keep {
+		result = append(result, s.cardJSON(cards[i], params))
+	}
+	sortByPos(result, func(c *trello.Card) float64 { return c.Pos })
+	return result, nil
+}
+
+// cardJSON returns a copy of c with its labels, badges and, when asked for
+// with the checklists/attachments parameters, nested resources filled in
+func (s *Server) cardJSON(c *trello.Card, params url.Values) *trello.Card {
+	out := *c
+	out.IDLabels = append([]string{}, c.IDLabels...)
+	out.Labels = []*trello.Label{}
+	for _, id := range c.IDLabels {
+		if l := s.findLabel(id); l != nil {
+			out.Labels = append(out.Labels, s.labelJSON(l))
+		}
+	}
+
+	checklists := s.cardChecklists(c.ID)
+	out.IDCheckLists = []string{}
+	out.Badges = trello.CardBadges{Description: c.Desc != "", Due: c.Due}
+	for _, cl := range checklists {
+		out.IDCheckLists = append(out.IDCheckLists, cl.ID)
+		for _, item := range cl.CheckItems {
+			out.Badges.CheckItems++
+			if item.State == "complete" {
+				out.Badges.CheckItemsChecked++
+			}
+		}
+	}
+	attachments := s.cardAttachments(c.ID)
+	out.Badges.Attachments = len(attachments)
+	for _, a := range s.comments {
+		if a.Data.Card.ID == c.ID {
+			out.Badges.Comments++
+		}
+	}
+
+	out.Checklists = nil
+	if filter := params.Get("checklists"); filter != "" && filter != "none" {
+		out.Checklists = checklists
+	}
+	out.Attachments = nil
+	if params.Get("attachments") == "true" {
+		out.Attachments = attachments
+	}
+	return &out
+}
+
+func (s *Server) createCard(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findList(params.Get("idList"))
+	if l == nil {
+		return nil, badRequest("invalid value for idList")
+	}
+
+	c := &trello.Card{}
+	if sourceID := params.Get("idCardSource"); sourceID != "" {
+		source := s.findCard(sourceID)
+		if source == nil {
+			return nil, badRequest("invalid value for idCardSource")
+		}
+		c.Name = source.Name
+		c.Desc = source.Desc
+		c.Due = source.Due
+		c.Start = source.Start
+		c.IDLabels = append([]string{}, source.IDLabels...)
+		c.IDMembers = append([]string{}, source.IDMembers...)
+	}
+
+	if _, ok := params["name"]; ok {
+		c.Name = params.Get("name")
+	}
+	if _, ok := params["desc"]; ok {
+		c.Desc = params.Get("desc")
+	}
+	if c.Name == "" {
+		return nil, badRequest("invalid value for name")
+	}
+	for _, key := range []string{"idLabels", "idMembers"} {
+		if value := params.Get(key); value != "" {
+			ids := strings.Split(value, ",")
+			if key == "idLabels" {
+				c.IDLabels = ids
+			} else {
+				c.IDMembers = ids
+			}
+		}
+	}
+	if err := s.applyCardDates(c, params); err != nil {
+		return nil, err
+	}
+
+	pos := params.Get("pos")
+	if pos == "0" {
+		// adlio/trello sends the zero value when no position was chosen
+		pos = "bottom"
+	}
+	p, err := position(pos, s.cardPositions(l.ID, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	c.ID = s.newID()
+	c.Pos = p
+	c.IDList = l.ID
+	c.IDBoard = l.IDBoard
+	c.IDShort = s.nextIDShort(l.IDBoard)
+	c.ShortLink = s.newShortLink(c.ID)
+	c.ShortURL = "https://trello.com/c/" + c.ShortLink
+	c.URL = fmt.Sprintf("https://trello.com/c/%s/%d-%s", c.ShortLink, c.IDShort, slug(c.Name))
+	now := s.now().UTC()
+	c.DateLastActivity = &now
+	s.cards = append(s.cards, c)
+
+	if sourceID := params.Get("idCardSource"); sourceID != "" {
+		s.copyCardData(s.resolveID(sourceID), c, params.Get("keepFromSource"))
+	}
+
+	return s.cardJSON(c, url.Values{}), nil
+}
+
+// copyCardData copies the checklists and attachments of a source card when
+// keepFromSource asks for them (Trello's default is "all")
+func (s *Server) copyCardData(sourceID string, c *trello.Card, keepFromSource string) {
+	if keepFromSource == "" {
+		keepFromSource = "all"
+	}
+	keep := func(what string) bool {
+		return keepFromSource == "all" || strings.Contains(keepFromSource, what)
+	}
+
+	if keep("checklists") {
+		for _, cl := range s.cardChecklists(sourceID) {
+			copied := &trello.Checklist{ID: s.newID(), Name: cl.Name, IDBoard: c.IDBoard, IDCard: c.ID, Pos: cl.Pos}
+			for _, item := range cl.CheckItems {
+				item.ID = s.newID()
+				item.IDChecklist = copied.ID
+				copied.CheckItems = append(copied.CheckItems, item)
+			}
+			s.checklists = append(s.checklists, copied)
+		}
+	}
+	if keep("attachments") {
+		for _, a := range s.cardAttachments(sourceID) {
+			copied := *a
+			copied.ID = s.newID()
+			s.attachments = append(s.attachments, &attachment{Attachment: &copied, cardID: c.ID})
+		}
+	}
+}
+
+func (s *Server) nextIDShort(boardID string) int {
+	highest := 0
+	for _, c := range s.cards {
+		if c.IDBoard == boardID && c.IDShort > highest {
+			highest = c.IDShort
+		}
+	}
+	return highest + 1
+}
+
+func (s *Server) applyCardDates(c *trello.Card, params url.Values) error {
+	due, ok, err := parseDate(params, "due")
+	if err != nil {
+		return err
+	}
+	if ok {
+		c.Due = due
+	}
+	start, ok, err := parseDate(params, "start")
+	if err != nil {
+		return err
+	}
+	if ok {
+		c.Start = start
+	}
+	return nil
+}
+
+func (s *Server) getCard(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	return s.cardJSON(c, params), nil
+}
+
+func (s *Server) updateCard(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+
+	// Validate everything before changing anything
+	updated := *c
+	if _, ok := params["name"]; ok {
+		updated.Name = strings.TrimSpace(params.Get("name"))
+		if updated.Name == "" {
+			return nil, badRequest("invalid value for name")
+		}
+	}
+	if _, ok := params["desc"]; ok {
+		updated.Desc = params.Get("desc")
+	}
+	if err := s.applyCardDates(&updated, params); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"dueComplete", "closed"} {
+		value, ok, err := parseBool(params, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok && key == "dueComplete" {
+			updated.DueComplete = value
+		} else if ok {
+			updated.Closed = value
+		}
+	}
+	if _, ok := params["idList"]; ok {
+		l := s.findList(params.Get("idList"))
+		if l == nil {
+			return nil, badRequest("invalid value for idList")
+		}
+		if l.ID != c.IDList {
+			updated.IDList = l.ID
+			updated.IDBoard = l.IDBoard
+			if _, ok := params["pos"]; !ok {
+				updated.Pos, _ = position("bottom", s.cardPositions(l.ID, c.ID))
+			}
+		}
+	}
+	if _, ok := params["pos"]; ok {
+		pos, err := position(params.Get("pos"), s.cardPositions(updated.IDList, c.ID))
+		if err != nil {
+			return nil, err
+		}
+		updated.Pos = pos
+	}
+	if _, ok := params["idLabels"]; ok {
+		updated.IDLabels = splitIDs(params.Get("idLabels"))
+	}
+	if _, ok := params["idMembers"]; ok {
+		updated.IDMembers = splitIDs(params.Get("idMembers"))
+	}
+
+	now := s.now().UTC()
+	updated.DateLastActivity = &now
+	*c = updated
+	return s.cardJSON(c, url.Values{}), nil
+}
+
+func splitIDs(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func (s *Server) deleteCard(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	s.removeCardData(c.ID)
+	s.cards = removeWhere(s.cards, func(x *trello.Card) bool { return x.ID == c.ID })
+	return map[string]interface{}{"limits": map[string]interface{}{}}, nil
+}
+
+// removeCardData removes the checklists, attachments and comments of a card
+func (s *Server) removeCardData(cardID string) {
+	s.checklists = removeWhere(s.checklists, func(cl *trello.Checklist) bool { return cl.IDCard == cardID })
+	s.attachments = removeWhere(s.attachments, func(a *attachment) bool { return a.cardID == cardID })
+	s.comments = removeWhere(s.comments, func(a *trello.Action) bool { return a.Data.Card.ID == cardID })
+}
+
+func (s *Server) addCardLabel(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	l := s.findLabel(params.Get("value"))
+	if l == nil || l.IDBoard != c.IDBoard {
+		return nil, badRequest("invalid value for value")
+	}
+	for _, id := range c.IDLabels {
+		if id == l.ID {
+			return nil, badRequest("that label is already on the card")
+		}
+	}
+
+	c.IDLabels = append(c.IDLabels, l.ID)
+	return c.IDLabels, nil
+}
+
+func (s *Server) removeCardLabel(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	labelID := r.PathValue("label")
+
+	before := len(c.IDLabels)
+	c.IDLabels = removeWhere(c.IDLabels, func(id string) bool { return id == labelID })
+	if len(c.IDLabels) == before {
+		return nil, badRequest("that label is not on the card")
+	}
+	return map[string]interface{}{"_value": nil}, nil
+}
+
+func (s *Server) addCardMember(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	member := s.findMember(params.Get("value"))
+	if member == nil || !s.isBoardMember(c.IDBoard, member.ID) {
+		return nil, badRequest("invalid value for value")
+	}
+	for _, id := range c.IDMembers {
+		if id == member.ID {
+			return nil, badRequest("member is already on the card")
+		}
+	}
+
+	c.IDMembers = append(c.IDMembers, member.ID)
+	result := []*trello.Member{}
+	for _, id := range c.IDMembers {
+		if m := s.findMember(id); m != nil {
+			result = append(result, s.memberJSON(m))
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) removeCardMember(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	member := s.findMember(r.PathValue("member"))
+	if member == nil {
+		return nil, errNotFound
+	}
+
+	before := len(c.IDMembers)
+	c.IDMembers = removeWhere(c.IDMembers, func(id string) bool { return id == member.ID })
+	if len(c.IDMembers) == before {
+		return nil, badRequest("member is not on the card")
+	}
+	return map[string]interface{}{"_value": nil}, nil
+}
+
+// Labels
+
+func (s *Server) findLabel(id string) *trello.Label {
+	for _, l := range s.labels {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+func (s *Server) labelJSON(l *trello.Label) *trello.Label {
+	out := *l
+	out.Uses = 0
+	for _, c := range s.cards {
+		for _, id := range c.IDLabels {
+			if id == l.ID {
+				out.Uses++
+			}
+		}
+	}
+	return &out
+}
+
+func labelColor(params url.Values) string {
+	color := params.Get("color")
+	if color == "null" {
+		return ""
+	}
+	return color
+}
+
+func (s *Server) createLabel(r *http.Request, params url.Values) (interface{}, error) {
+	b := s.findBoard(r.PathValue("id"))
+	if b == nil {
+		return nil, errNotFound
+	}
+
+	l := &trello.Label{ID: s.newID(), IDBoard: b.ID, Name: params.Get("name"), Color: labelColor(params)}
+	s.labels = append(s.labels, l)
+	return s.labelJSON(l), nil
+}
+
+func (s *Server) getLabel(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findLabel(r.PathValue("id"))
+	if l == nil {
+		return nil, errNotFound
+	}
+	return s.labelJSON(l), nil
+}
+
+func (s *Server) updateLabel(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findLabel(r.PathValue("id"))
+	if l == nil {
+		return nil, errNotFound
+	}
+	if _, ok := params["name"]; ok {
+		l.Name = params.Get("name")
+	}
+	if _, ok := params["color"]; ok {
+		l.Color = labelColor(params)
+	}
+	return s.labelJSON(l), nil
+}
+
+func (s *Server) deleteLabel(r *http.Request, params url.Values) (interface{}, error) {
+	l := s.findLabel(r.PathValue("id"))
+	if l == nil {
+		return nil, errNotFound
+	}
+	s.labels = removeWhere(s.labels, func(x *trello.Label) bool { return x.ID == l.ID })
+	for _, c := range s.cards {
+		c.IDLabels = removeWhere(c.IDLabels, func(id string) bool { return id == l.ID })
+	}
+	return map[string]interface{}{}, nil
+}
+
+// Checklists
+
+func (s *Server) findChecklist(id string) *trello.Checklist {
+	for _, cl := range s.checklists {
+		if cl.ID == id {
+			return cl
+		}
+	}
+	return nil
+}
+
+func (s *Server) cardChecklists(cardID string) []*trello.Checklist {
+	result := []*trello.Checklist{}
+	for _, cl := range s.checklists {
+		if cl.IDCard == cardID {
+			result = append(result, cl)
+		}
+	}
+	sortByPos(result, func(cl *trello.Checklist) float64 { return cl.Pos })
+	return result
+}
+
+func (s *Server) getCardChecklists(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	return s.cardChecklists(c.ID), nil
+}
+
+func (s *Server) createChecklist(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+
+	var positions []float64
+	for _, cl := range s.cardChecklists(c.ID) {
+		positions = append(positions, cl.Pos)
+	}
+	pos, err := position(params.Get("pos"), positions)
+	if err != nil {
+		return nil, err
+	}
+
+	name := params.Get("name")
+	if name == "" {
+		name = "Checklist"
+	}
+	cl := &trello.Checklist{ID: s.newID(), Name: name, IDBoard: c.IDBoard, IDCard: c.ID, Pos: pos}
+	s.checklists = append(s.checklists, cl)
+	return cl, nil
+}
+
+func (s *Server) getChecklist(r *http.Request, params url.Values) (interface{}, error) {
+	cl := s.findChecklist(r.PathValue("id"))
+	if cl == nil {
+		return nil, errNotFound
+	}
+	return cl, nil
+}
+
+func (s *Server) updateChecklist(r *http.Request, params url.Values) (interface{}, error) {
+	cl := s.findChecklist(r.PathValue("id"))
+	if cl == nil {
+		return nil, errNotFound
+	}
+	if _, ok := params["name"]; ok {
+		name := strings.TrimSpace(params.Get("name"))
+		if name == "" {
+			return nil, badRequest("invalid value for name")
+		}
+		cl.Name = name
+	}
+	if _, ok := params["pos"]; ok {
+		var positions []float64
+		for _, other := range s.cardChecklists(cl.IDCard) {
+			if other.ID != cl.ID {
+				positions = append(positions, other.Pos)
+			}
+		}
+		pos, err := position(params.Get("pos"), positions)
+		if err != nil {
+			return nil, err
+		}
+		cl.Pos = pos
+	}
+	return cl, nil
+}
+
+func (s *Server) deleteChecklist(r *http.Request, params url.Values) (interface{}, error) {
+	cl := s.findChecklist(r.PathValue("id"))
+	if cl == nil {
+		return nil, errNotFound
+	}
+	s.checklists = removeWhere(s.checklists, func(x *trello.Checklist) bool { return x.ID == cl.ID })
+	return map[string]interface{}{"limits": map[string]interface{}{}}, nil
+}
+
+func (s *Server) createCheckItem(r *http.Request, params url.Values) (interface{}, error) {
+	cl := s.findChecklist(r.PathValue("id"))
+	if cl == nil {
+		return nil, errNotFound
+	}
+	name := strings.TrimSpace(params.Get("name"))
+	if name == "" {
+		return nil, badRequest("invalid value for name")
+	}
+	checked, _, err := parseBool(params, "checked")
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []float64
+	for _, item := range cl.CheckItems {
+		positions = append(positions, item.Pos)
+	}
+	pos, err := position(params.Get("pos"), positions)
+	if err != nil {
+		return nil, err
+	}
+
+	item := trello.CheckItem{ID: s.newID(), Name: name, State: "incomplete", IDChecklist: cl.ID, Pos: pos}
+	if checked {
+		item.State = "complete"
+	}
+	cl.CheckItems = append(cl.CheckItems, item)
+	sortByPos(cl.CheckItems, func(i trello.CheckItem) float64 { return i.Pos })
+	return &item, nil
+}
+
+func (s *Server) deleteCheckItem(r *http.Request, params url.Values) (interface{}, error) {
+	cl := s.findChecklist(r.PathValue("id"))
+	if cl == nil {
+		return nil, errNotFound
+	}
+	itemID := r.PathValue("item")
+
+	before := len(cl.CheckItems)
+	cl.CheckItems = removeWhere(cl.CheckItems, func(item trello.CheckItem) bool { return item.ID == itemID })
+	if len(cl.CheckItems) == before {
+		return nil, errNotFound
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (s *Server) updateCheckItem(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	itemID := r.PathValue("item")
+
+	for _, cl := range s.cardChecklists(c.ID) {
+		for i := range cl.CheckItems {
+			item := &cl.CheckItems[i]
+			if item.ID != itemID {
+				continue
+			}
+
+			if _, ok := params["state"]; ok {
+				switch state := params.Get("state"); state {
+				case "complete", "true":
+					item.State = "complete"
+				case "incomplete", "false":
+					item.State = "incomplete"
+				default:
+					return nil, badRequest("invalid value for state")
+				}
+			}
+			if _, ok := params["name"]; ok {
+				name := strings.TrimSpace(params.Get("name"))
+				if name == "" {
+					return nil, badRequest("invalid value for name")
+				}
+				item.Name = name
+			}
+			return item, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+// Attachments
+
+func (s *Server) cardAttachments(cardID string) []*trello.Attachment {
+	result := []*trello.Attachment{}
+	for _, a := range s.attachments {
+		if a.cardID == cardID {
+			result = append(result, a.Attachment)
+		}
+	}
+	return result
+}
+
+func (s *Server) getAttachments(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	return s.cardAttachments(c.ID), nil
+}
+
+func (s *Server) createAttachment(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+
+	link := params.Get("url")
+	u, err := url.Parse(link)
+	if link == "" || err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, badRequest("invalid value for url")
+	}
+	name := params.Get("name")
+	if name == "" {
+		name = link
+	}
+
+	a := &trello.Attachment{
+		ID:       s.newID(),
+		Name:     name,
+		URL:      link,
+		MimeType: params.Get("mimeType"),
+		IDMember: s.me.ID,
+		Date:     s.now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		Pos:      float32(len(s.cardAttachments(c.ID))+1) * posStep,
+	}
+	s.attachments = append(s.attachments, &attachment{Attachment: a, cardID: c.ID})
+	return a, nil
+}
+
+func (s *Server) deleteAttachment(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	attachmentID := r.PathValue("attachment")
+
+	before := len(s.attachments)
+	s.attachments = removeWhere(s.attachments, func(a *attachment) bool {
+		return a.cardID == c.ID && a.ID == attachmentID
+	})
+	if len(s.attachments) == before {
+		return nil, errNotFound
+	}
+	return map[string]interface{}{"limits": map[string]interface{}{}}, nil
+}
+
+// Comments
+
+func (s *Server) findComment(cardID, actionID string) *trello.Action {
+	for _, a := range s.comments {
+		if a.ID == actionID && a.Data.Card.ID == cardID {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Server) getCardActions(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+
+	// Only comments are recorded, so other action filters return nothing
+	result := []*trello.Action{}
+	filter := params.Get("filter")
+	if filter != "" && filter != "all" && !strings.Contains(filter, "commentCard") {
+		return result, nil
+	}
+	for i := len(s.comments) - 1; i >= 0; i-- {
+		if s.comments[i].Data.Card.ID == c.ID {
+			result = append(result, s.comments[i])
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) createComment(r *http.Request, params url.Values) (interface{}, error) {
+	c := s.findCard(r.PathValue("id"))
+	if c == nil {
+		return nil, errNotFound
+	}
+	text := params.Get("text")
+	if strings.TrimSpace(text) == "" {
+		return nil, badRequest("invalid value for text")
+	}
+
+	board := s.findBoard(c.IDBoard)
+	action := &trello.Action{
+		ID:              s.newID(),
+		IDMemberCreator: s.me.ID,
+		Type:            "commentCard",
+		Date:            s.now().UTC(),
+		Data: &trello.ActionData{
+			Text:  text,
+			Card:  &trello.ActionDataCard{ID: c.ID, Name: c.Name, IDShort: c.IDShort, ShortLink: c.ShortLink},
+			Board: &trello.Board{ID: board.ID, Name: board.Name},
+		},
+		MemberCreator: s.memberJSON(s.me),
+	}
+	s.comments = append(s.comments, action)
+	return action, nil
+}
+
+func (s *Server) updateComment(r *http.Request, params url.Values) (interface{}, error) {
+	action := s.findComment(s.resolveID(r.PathValue("id")), r.PathValue("action"))
+	if action == nil {
+		return nil, errNotFound
+	}
+	text := params.Get("text")
+	if strings.TrimSpace(text) == "" {
+		return nil, badRequest("invalid value for text")
+	}
+
+	action.Data.Text = text
+	action.Data.DateLastEdited = s.now().UTC()
+	return action, nil
+}
+
+func (s *Server) deleteComment(r *http.Request, params url.Values) (interface{}, error) {
+	action := s.findComment(s.resolveID(r.PathValue("id")), r.PathValue("action"))
+	if action == nil {
+		return nil, errNotFound
+	}
+	s.comments = removeWhere(s.comments, func(a *trello.Action) bool { return a.ID == action.ID })
+	return map[string]interface{}{"_value": nil}, nil
+}
+
+func removeWhere[T any](items []T, match func(T) bool) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if !match(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,146 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/danbruder/trello-cli/internal/context"
+)
+
+// selectFields returns the JSON form of data with only the given fields: of
+// each object in a list, or of data itself. A dotted field such as
+// labels.name or badges.checkItems keeps the objects it passes through, so
+// the result has the shape of the full output. A field with a leading "-",
+// such as -desc, is left out; with no other fields, every field but the
+// excluded ones is kept.
+func selectFields(data interface{}, fields []string) interface{} {
+	value, err := jsonValue(data)
+	if err != nil {
+		return data
+	}
+
+	if items, ok := value.([]interface{}); ok {
+		selected := make([]interface{}, len(items))
+		for i, item := range items {
+			selected[i] = selectObjectFields(item, fields)
+		}
+		return selected
+	}
+	return selectObjectFields(value, fields)
+}
+
+// extractFields returns the given fields of a single object, like
+// selectFields
+func extractFields(obj interface{}, fields []string) map[string]interface{} {
+	value, err := jsonValue(obj)
+	if err != nil {
+		return nil
+	}
+	object, _ := selectObjectFields(value, fields).(map[string]interface{})
+	return object
+}
+
+// selectObjectFields returns the given fields of an object. Values that are
+// not objects are returned as they are.
+func selectObjectFields(value interface{}, fields []string) interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	include, exclude := context.SplitFields(fields)
+	result := object
+	if len(include) > 0 {
+		result = map[string]interface{}{}
+		for _, field := range include {
+			if picked, found := pickPath(object, strings.Split(field, ".")); found {
+				result = mergeValues(result, picked).(map[string]interface{})
+			}
+		}
+	}
+	for _, field := range exclude {
+		removePath(result, strings.Split(field, "."))
+	}
+	return result
+}
+
+// pickPath returns value with only what is at path, keeping the objects and
+// lists on the way to it. In a list, every object item keeps what is at the
+// rest of the path, so that picks from the same list line up.
+func pickPath(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return nil, false
+		}
+		picked, found := pickPath(child, path[1:])
+		if !found {
+			return nil, false
+		}
+		return map[string]interface{}{path[0]: picked}, true
+
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			picked, found := pickPath(item, path)
+			if !found {
+				picked = map[string]interface{}{}
+			}
+			items[i] = picked
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// mergeValues merges the objects picked from the same value
+func mergeValues(a, b interface{}) interface{} {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return b
+		}
+		for key, value := range bv {
+			if existing, ok := av[key]; ok {
+				av[key] = mergeValues(existing, value)
+			} else {
+				av[key] = value
+			}
+		}
+		return av
+
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return b
+		}
+		for i := range av {
+			av[i] = mergeValues(av[i], bv[i])
+		}
+		return av
+	}
+	return b
+}
+
+// removePath deletes what is at path in value, from every item of the lists
+// on the way to it
+func removePath(value interface{}, path []string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		removePath(v[path[0]], path[1:])
+
+	case []interface{}:
+		for _, item := range v {
+			removePath(item, path)
+		}
+	}
+}
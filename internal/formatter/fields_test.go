@@ -0,0 +1,135 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/adlio/trello"
+)
+
+func TestSelectFields(t *testing.T) {
+	cards := []*trello.Card{
+		{
+			ID:        "c1",
+			Name:      "Write the docs",
+			Desc:      "Long description",
+			IDMembers: []string{"m1", "m2"},
+			Labels:    []*trello.Label{{ID: "l1", Name: "Docs", Color: "sky"}, {ID: "l2", Color: "red"}},
+		},
+		{ID: "c2", Name: "Ship", Desc: "Another description"},
+	}
+	cards[0].Badges.CheckItems = 3
+	cards[0].Badges.Comments = 2
+
+	tests := []struct {
+		name     string
+		fields   []string
+		expected string
+	}{
+		{
+			name:     "Top-level fields of every item",
+			fields:   []string{"id", "idMembers"},
+			expected: `[{"id":"c1","idMembers":["m1","m2"]},{"id":"c2"}]`,
+		},
+		{
+			name:     "Dotted paths",
+			fields:   []string{"id", "labels.name", "badges.checkItems"},
+			expected: `[{"badges":{"checkItems":3},"id":"c1","labels":[{"name":"Docs"},{"name":""}]},{"badges":{"checkItems":0},"id":"c2"}]`,
+		},
+		{
+			name:     "Fields and exclusions",
+			fields:   []string{"id", "labels", "-labels.id", "-labels.color"},
+			expected: `[{"id":"c1","labels":[{"idBoard":"","name":"Docs","uses":0},{"idBoard":"","name":"","uses":0}]},{"id":"c2"}]`,
+		},
+		{
+			name:     "Unknown fields are left out",
+			fields:   []string{"id", "nope.deeper"},
+			expected: `[{"id":"c1"},{"id":"c2"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := json.Marshal(selectFields(cards, tt.fields))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(encoded) != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, encoded)
+			}
+		})
+	}
+
+	t.Run("Exclusions alone keep every other field", func(t *testing.T) {
+		selected := selectFields(cards[0], []string{"-desc", "-badges"})
+		card, ok := selected.(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected an object, got %T", selected)
+		}
+		if _, found := card["desc"]; found {
+			t.Error("desc should be excluded")
+		}
+		if _, found := card["badges"]; found {
+			t.Error("badges should be excluded")
+		}
+		if card["name"] != "Write the docs" {
+			t.Errorf("name should be kept, got %v", card["name"])
+		}
+	})
+}
+
+func TestFieldsAcrossFormats(t *testing.T) {
+	cards := []*trello.Card{
+		{ID: "c1", Name: "Write the docs", Desc: "Long description", Labels: []*trello.Label{{Name: "Docs"}}},
+		{ID: "c2", Name: "Ship", Desc: "Another description"},
+	}
+	cards[0].Badges.CheckItems = 3
+
+	t.Run("JSON lists keep every item", func(t *testing.T) {
+		output, err := NewJSONFormatter([]string{"name", "badges.checkItems"}, 0, false).FormatCards(cards)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var result []map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("invalid JSON %q: %v", output, err)
+		}
+		if len(result) != 2 || result[1]["name"] != "Ship" || len(result[0]) != 2 {
+			t.Errorf("unexpected result %v", result)
+		}
+	})
+
+	t.Run("Markdown shows the same fields", func(t *testing.T) {
+		output, err := NewMarkdownFormatter([]string{"name", "labels.name", "badges.checkItems"}, 0, false).FormatCards(cards)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "# Cards (2)\n\n" +
+			"## Write the docs\n- **badges.checkItems:** 3\n- **labels:** Docs\n- **name:** Write the docs\n\n" +
+			"## Ship\n- **badges.checkItems:** 0\n- **name:** Ship\n\n"
+		if output != expected {
+			t.Errorf("expected:\n%q\ngot:\n%q", expected, output)
+		}
+	})
+
+	t.Run("Markdown leaves out excluded fields", func(t *testing.T) {
+		output, err := NewMarkdownFormatter([]string{"-desc"}, 0, false).FormatCard(cards[0])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(output, "Long description") || !strings.Contains(output, "# Card: Write the docs") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+	})
+
+	t.Run("CSV leaves out excluded columns", func(t *testing.T) {
+		output, err := NewCSVFormatter([]string{"-desc", "-labels", "-due"}, 0, false).FormatCards(cards)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(output, "id,name,") || strings.Contains(output, "desc") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+	})
+}
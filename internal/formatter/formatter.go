@@ -1,5 +1,5 @@
 // Package formatter provides flexible output formatting for Trello API responses.
-// It supports multiple output formats (JSON, Markdown) with features optimized for
+// It supports multiple output formats (JSON, Markdown, YAML, CSV, TSV, table and text/template) with features optimized for
 // LLM integration including field filtering, token limiting, and context optimization.
 package formatter
 
@@ -23,18 +23,84 @@ type Formatter interface {
 	FormatMembers(members interface{}) (string, error)
 	FormatAttachment(attachment interface{}) (string, error)
 	FormatAttachments(attachments interface{}) (string, error)
+	FormatComment(comment interface{}) (string, error)
+	FormatComments(comments interface{}) (string, error)
 	FormatError(err error) string
 	FormatSuccess(message string) string
 }
 
+// Options holds the output settings of a formatter beyond its fields, token
+// limit and verbosity
+type Options struct {
+	// NoColor turns off colors in table output even on a terminal
+	NoColor bool
+
+	// Template is the text of the template that the template format renders
+	Template string
+
+	// Query is a jq expression that formatters apply to results before
+	// formatting them, validated with ValidateQuery
+	Query string
+}
+
 // NewFormatter creates a new formatter based on the format type
 func NewFormatter(format string, fields []string, maxTokens int, verbose bool) (Formatter, error) {
+	return NewFormatterWithOptions(format, fields, maxTokens, verbose, Options{})
+}
+
+// NewFormatterWithOptions creates a new formatter based on the format type,
+// with the given output options
+func NewFormatterWithOptions(format string, fields []string, maxTokens int, verbose bool, opts Options) (Formatter, error) {
 	switch format {
 	case "json":
-		return NewJSONFormatter(fields, maxTokens, verbose), nil
+		f := NewJSONFormatter(fields, maxTokens, verbose)
+		f.query = opts.Query
+		return f, nil
 	case "markdown", "md":
-		return NewMarkdownFormatter(fields, maxTokens, verbose), nil
+		f := NewMarkdownFormatter(fields, maxTokens, verbose)
+		f.query = opts.Query
+		return f, nil
+	case "yaml", "yml":
+		f := NewYAMLFormatter(fields, maxTokens, verbose)
+		f.query = opts.Query
+		return f, nil
+	case "csv":
+		f := NewCSVFormatter(fields, maxTokens, verbose)
+		f.query = opts.Query
+		return f, nil
+	case "tsv":
+		f := NewTSVFormatter(fields, maxTokens, verbose)
+		f.query = opts.Query
+		return f, nil
+	case "table":
+		return newTableFormatter(fields, maxTokens, verbose, opts), nil
+	case "template":
+		f, err := NewTemplateFormatter(opts.Template, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		f.query = opts.Query
+		return f, nil
 	default:
-		return nil, fmt.Errorf("unsupported format: %s (supported: json, markdown)", format)
+		return nil, unsupportedFormat(format)
 	}
 }
+
+// formats are the names of the formats that NewFormatter supports
+var formats = []string{"json", "markdown", "md", "yaml", "yml", "csv", "tsv", "table", "template"}
+
+// ValidateFormat reports whether format is one that NewFormatter supports.
+// It checks only the name, so the template format is valid without a
+// template.
+func ValidateFormat(format string) error {
+	for _, name := range formats {
+		if format == name {
+			return nil
+		}
+	}
+	return unsupportedFormat(format)
+}
+
+func unsupportedFormat(format string) error {
+	return fmt.Errorf("unsupported format: %s (supported: json, markdown, yaml, csv, tsv, table, template)", format)
+}
@@ -41,6 +41,27 @@ func TestNewFormatter(t *testing.T) {
 			verbose:     true,
 			expectError: false,
 		},
+		{
+			name:        "Valid YAML formatter",
+			format:      "yaml",
+			expectError: false,
+		},
+		{
+			name:        "Valid CSV formatter",
+			format:      "csv",
+			fields:      []string{"id", "name"},
+			expectError: false,
+		},
+		{
+			name:        "Valid TSV formatter",
+			format:      "tsv",
+			expectError: false,
+		},
+		{
+			name:        "Valid table formatter",
+			format:      "table",
+			expectError: false,
+		},
 		{
 			name:        "Invalid format",
 			format:      "xml",
@@ -55,6 +76,10 @@ func TestNewFormatter(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			formatter, err := NewFormatter(tt.format, tt.fields, tt.maxTokens, tt.verbose)
 
+			if validateErr := ValidateFormat(tt.format); (validateErr != nil) != tt.expectError {
+				t.Errorf("ValidateFormat(%q) = %v, expected error: %v", tt.format, validateErr, tt.expectError)
+			}
+
 			if tt.expectError {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -72,6 +97,17 @@ func TestNewFormatter(t *testing.T) {
 			}
 		})
 	}
+
+	// The template format is valid on its own but needs a template to format with
+	if err := ValidateFormat("template"); err != nil {
+		t.Errorf("ValidateFormat(\"template\") = %v, expected no error", err)
+	}
+	if _, err := NewFormatter("template", nil, 0, false); err == nil {
+		t.Error("Expected an error for the template format without a template")
+	}
+	if _, err := NewFormatterWithOptions("template", nil, 0, false, Options{Template: "{{.Name}}"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
 }
 
 func TestJSONFormatter(t *testing.T) {
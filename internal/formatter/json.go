@@ -2,8 +2,6 @@ package formatter
 
 import (
 	"encoding/json"
-
-	"github.com/adlio/trello"
 )
 
 // JSONFormatter formats output as JSON
@@ -11,6 +9,7 @@ type JSONFormatter struct {
 	fields    []string
 	maxTokens int
 	verbose   bool
+	query     string
 }
 
 // NewJSONFormatter creates a new JSON formatter
@@ -23,13 +22,17 @@ func NewJSONFormatter(fields []string, maxTokens int, verbose bool) *JSONFormatt
 }
 
 func (f *JSONFormatter) format(data interface{}) (string, error) {
+	data, _, err := applyQuery(f.query, data)
+	if err != nil {
+		return "", err
+	}
+
 	// Apply field filtering if specified
 	if len(f.fields) > 0 {
-		data = extractFields(data, f.fields)
+		data = selectFields(data, f.fields)
 	}
 
 	var output []byte
-	var err error
 
 	if f.verbose {
 		output, err = json.MarshalIndent(data, "", "  ")
@@ -107,6 +110,14 @@ func (f *JSONFormatter) FormatAttachments(attachments interface{}) (string, erro
 	return f.format(attachments)
 }
 
+func (f *JSONFormatter) FormatComment(comment interface{}) (string, error) {
+	return f.format(comment)
+}
+
+func (f *JSONFormatter) FormatComments(comments interface{}) (string, error) {
+	return f.format(comments)
+}
+
 func (f *JSONFormatter) FormatError(err error) string {
 	output, _ := json.MarshalIndent(map[string]string{
 		"error": err.Error(),
@@ -131,46 +142,3 @@ func truncateToTokenLimit(text string, maxTokens int) string {
 	}
 	return text[:maxChars] + "\n... (truncated)"
 }
-
-// Helper function to extract specific fields from Trello objects
-func extractFields(obj interface{}, fields []string) map[string]interface{} {
-	result := make(map[string]interface{})
-
-	if len(fields) == 0 {
-		// No filtering, return all
-		data, _ := json.Marshal(obj)
-		if err := json.Unmarshal(data, &result); err != nil {
-			return nil
-		}
-		return result
-	}
-
-	// Convert to map for field extraction
-	data, _ := json.Marshal(obj)
-	var fullData map[string]interface{}
-	if err := json.Unmarshal(data, &fullData); err != nil {
-		return nil
-	}
-
-	for _, field := range fields {
-		if val, ok := fullData[field]; ok {
-			result[field] = val
-		}
-	}
-
-	return result
-}
-
-// FormatBoardsWithFields formats boards with field filtering
-func (f *JSONFormatter) FormatBoardsWithFields(boards []*trello.Board) (string, error) {
-	if len(f.fields) == 0 {
-		return f.format(boards)
-	}
-
-	filtered := make([]map[string]interface{}, len(boards))
-	for i, board := range boards {
-		filtered[i] = extractFields(board, f.fields)
-	}
-
-	return f.format(filtered)
-}
@@ -2,10 +2,12 @@ package formatter
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/adlio/trello"
+	"github.com/danbruder/trello-cli/internal/context"
 )
 
 // MarkdownFormatter formats output as Markdown
@@ -13,6 +15,7 @@ type MarkdownFormatter struct {
 	fields    []string
 	maxTokens int
 	verbose   bool
+	query     string
 }
 
 // NewMarkdownFormatter creates a new Markdown formatter
@@ -25,6 +28,10 @@ func NewMarkdownFormatter(fields []string, maxTokens int, verbose bool) *Markdow
 }
 
 func (f *MarkdownFormatter) FormatBoard(board interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&board, "Board"); done {
+		return output, err
+	}
+
 	b, ok := board.(*trello.Board)
 	if !ok {
 		return "", fmt.Errorf("invalid board type")
@@ -34,17 +41,17 @@ func (f *MarkdownFormatter) FormatBoard(board interface{}) (string, error) {
 	sb.WriteString(fmt.Sprintf("# Board: %s\n\n", b.Name))
 	sb.WriteString(fmt.Sprintf("**ID:** `%s`\n\n", b.ID))
 
-	if f.verbose || (len(f.fields) > 0 && f.shouldIncludeField("desc")) {
+	if f.showField("desc", false) {
 		if b.Desc != "" {
 			sb.WriteString(fmt.Sprintf("**Description:** %s\n\n", b.Desc))
 		}
 	}
 
-	if f.verbose || (len(f.fields) > 0 && f.shouldIncludeField("url")) {
+	if f.showField("url", false) {
 		sb.WriteString(fmt.Sprintf("**URL:** %s\n\n", b.URL))
 	}
 
-	if f.verbose || (len(f.fields) > 0 && f.shouldIncludeField("closed")) {
+	if f.showField("closed", false) {
 		sb.WriteString(fmt.Sprintf("**Closed:** %t\n\n", b.Closed))
 	}
 
@@ -52,6 +59,10 @@ func (f *MarkdownFormatter) FormatBoard(board interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatBoards(boards interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&boards, "Boards"); done {
+		return output, err
+	}
+
 	boardList, ok := boards.([]*trello.Board)
 	if !ok {
 		return "", fmt.Errorf("invalid boards type")
@@ -64,13 +75,13 @@ func (f *MarkdownFormatter) FormatBoards(boards interface{}) (string, error) {
 		sb.WriteString(fmt.Sprintf("## %s\n", board.Name))
 		sb.WriteString(fmt.Sprintf("- **ID:** `%s`\n", board.ID))
 
-		if f.verbose || f.shouldIncludeField("desc") {
+		if f.showField("desc", true) {
 			if board.Desc != "" {
 				sb.WriteString(fmt.Sprintf("- **Description:** %s\n", truncateText(board.Desc, 100)))
 			}
 		}
 
-		if f.verbose || f.shouldIncludeField("url") {
+		if f.showField("url", true) {
 			sb.WriteString(fmt.Sprintf("- **URL:** %s\n", board.URL))
 		}
 
@@ -81,6 +92,10 @@ func (f *MarkdownFormatter) FormatBoards(boards interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatList(list interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&list, "List"); done {
+		return output, err
+	}
+
 	l, ok := list.(*trello.List)
 	if !ok {
 		return "", fmt.Errorf("invalid list type")
@@ -90,11 +105,11 @@ func (f *MarkdownFormatter) FormatList(list interface{}) (string, error) {
 	sb.WriteString(fmt.Sprintf("# List: %s\n\n", l.Name))
 	sb.WriteString(fmt.Sprintf("**ID:** `%s`\n\n", l.ID))
 
-	if f.verbose || f.shouldIncludeField("closed") {
+	if f.showField("closed", true) {
 		sb.WriteString(fmt.Sprintf("**Closed:** %t\n\n", l.Closed))
 	}
 
-	if f.verbose || f.shouldIncludeField("pos") {
+	if f.showField("pos", true) {
 		sb.WriteString(fmt.Sprintf("**Position:** %.2f\n\n", l.Pos))
 	}
 
@@ -102,6 +117,10 @@ func (f *MarkdownFormatter) FormatList(list interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatLists(lists interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&lists, "Lists"); done {
+		return output, err
+	}
+
 	listList, ok := lists.([]*trello.List)
 	if !ok {
 		return "", fmt.Errorf("invalid lists type")
@@ -118,7 +137,7 @@ func (f *MarkdownFormatter) FormatLists(lists interface{}) (string, error) {
 		sb.WriteString(fmt.Sprintf("## %s [%s]\n", list.Name, status))
 		sb.WriteString(fmt.Sprintf("- **ID:** `%s`\n", list.ID))
 
-		if f.verbose || f.shouldIncludeField("pos") {
+		if f.showField("pos", true) {
 			sb.WriteString(fmt.Sprintf("- **Position:** %.2f\n", list.Pos))
 		}
 
@@ -129,6 +148,10 @@ func (f *MarkdownFormatter) FormatLists(lists interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatCard(card interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&card, "Card"); done {
+		return output, err
+	}
+
 	c, ok := card.(*trello.Card)
 	if !ok {
 		return "", fmt.Errorf("invalid card type")
@@ -138,23 +161,23 @@ func (f *MarkdownFormatter) FormatCard(card interface{}) (string, error) {
 	sb.WriteString(fmt.Sprintf("# Card: %s\n\n", c.Name))
 	sb.WriteString(fmt.Sprintf("**ID:** `%s`\n\n", c.ID))
 
-	if f.verbose || f.shouldIncludeField("desc") {
+	if f.showField("desc", true) {
 		if c.Desc != "" {
 			sb.WriteString(fmt.Sprintf("**Description:**\n\n%s\n\n", c.Desc))
 		}
 	}
 
-	if f.verbose || f.shouldIncludeField("url") {
+	if f.showField("url", true) {
 		sb.WriteString(fmt.Sprintf("**URL:** %s\n\n", c.URL))
 	}
 
-	if f.verbose || f.shouldIncludeField("due") {
+	if f.showField("due", true) {
 		if c.Due != nil {
 			sb.WriteString(fmt.Sprintf("**Due:** %s\n\n", c.Due.Format(time.RFC3339)))
 		}
 	}
 
-	if f.verbose || f.shouldIncludeField("labels") {
+	if f.showField("labels", true) {
 		if len(c.Labels) > 0 {
 			sb.WriteString("**Labels:**\n")
 			for _, label := range c.Labels {
@@ -168,7 +191,7 @@ func (f *MarkdownFormatter) FormatCard(card interface{}) (string, error) {
 		}
 	}
 
-	if f.verbose || f.shouldIncludeField("closed") {
+	if f.showField("closed", true) {
 		sb.WriteString(fmt.Sprintf("**Closed:** %t\n\n", c.Closed))
 	}
 
@@ -176,6 +199,10 @@ func (f *MarkdownFormatter) FormatCard(card interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatCards(cards interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&cards, "Cards"); done {
+		return output, err
+	}
+
 	cardList, ok := cards.([]*trello.Card)
 	if !ok {
 		return "", fmt.Errorf("invalid cards type")
@@ -192,13 +219,13 @@ func (f *MarkdownFormatter) FormatCards(cards interface{}) (string, error) {
 		sb.WriteString(fmt.Sprintf("## %s [%s]\n", card.Name, status))
 		sb.WriteString(fmt.Sprintf("- **ID:** `%s`\n", card.ID))
 
-		if f.verbose || f.shouldIncludeField("desc") {
+		if f.showField("desc", true) {
 			if card.Desc != "" {
 				sb.WriteString(fmt.Sprintf("- **Description:** %s\n", truncateText(card.Desc, 150)))
 			}
 		}
 
-		if f.verbose || f.shouldIncludeField("labels") {
+		if f.showField("labels", true) {
 			if len(card.Labels) > 0 {
 				labelNames := make([]string, len(card.Labels))
 				for i, label := range card.Labels {
@@ -212,7 +239,7 @@ func (f *MarkdownFormatter) FormatCards(cards interface{}) (string, error) {
 			}
 		}
 
-		if f.verbose || f.shouldIncludeField("due") {
+		if f.showField("due", true) {
 			if card.Due != nil {
 				sb.WriteString(fmt.Sprintf("- **Due:** %s\n", card.Due.Format("2006-01-02")))
 			}
@@ -225,6 +252,10 @@ func (f *MarkdownFormatter) FormatCards(cards interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatLabel(label interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&label, "Label"); done {
+		return output, err
+	}
+
 	l, ok := label.(*trello.Label)
 	if !ok {
 		return "", fmt.Errorf("invalid label type")
@@ -239,6 +270,10 @@ func (f *MarkdownFormatter) FormatLabel(label interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatLabels(labels interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&labels, "Labels"); done {
+		return output, err
+	}
+
 	labelList, ok := labels.([]*trello.Label)
 	if !ok {
 		return "", fmt.Errorf("invalid labels type")
@@ -259,6 +294,10 @@ func (f *MarkdownFormatter) FormatLabels(labels interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatChecklist(checklist interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&checklist, "Checklist"); done {
+		return output, err
+	}
+
 	cl, ok := checklist.(*trello.Checklist)
 	if !ok {
 		return "", fmt.Errorf("invalid checklist type")
@@ -283,6 +322,10 @@ func (f *MarkdownFormatter) FormatChecklist(checklist interface{}) (string, erro
 }
 
 func (f *MarkdownFormatter) FormatChecklists(checklists interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&checklists, "Checklists"); done {
+		return output, err
+	}
+
 	checklistList, ok := checklists.([]*trello.Checklist)
 	if !ok {
 		return "", fmt.Errorf("invalid checklists type")
@@ -307,6 +350,10 @@ func (f *MarkdownFormatter) FormatChecklists(checklists interface{}) (string, er
 }
 
 func (f *MarkdownFormatter) FormatMember(member interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&member, "Member"); done {
+		return output, err
+	}
+
 	m, ok := member.(*trello.Member)
 	if !ok {
 		return "", fmt.Errorf("invalid member type")
@@ -317,7 +364,7 @@ func (f *MarkdownFormatter) FormatMember(member interface{}) (string, error) {
 	sb.WriteString(fmt.Sprintf("**ID:** `%s`\n\n", m.ID))
 	sb.WriteString(fmt.Sprintf("**Username:** %s\n\n", m.Username))
 
-	if f.verbose || f.shouldIncludeField("url") {
+	if f.showField("url", true) {
 		sb.WriteString(fmt.Sprintf("**URL:** %s\n\n", m.Username))
 	}
 
@@ -325,6 +372,10 @@ func (f *MarkdownFormatter) FormatMember(member interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatMembers(members interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&members, "Members"); done {
+		return output, err
+	}
+
 	memberList, ok := members.([]*trello.Member)
 	if !ok {
 		return "", fmt.Errorf("invalid members type")
@@ -341,6 +392,10 @@ func (f *MarkdownFormatter) FormatMembers(members interface{}) (string, error) {
 }
 
 func (f *MarkdownFormatter) FormatAttachment(attachment interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&attachment, "Attachment"); done {
+		return output, err
+	}
+
 	a, ok := attachment.(*trello.Attachment)
 	if !ok {
 		return "", fmt.Errorf("invalid attachment type")
@@ -355,6 +410,10 @@ func (f *MarkdownFormatter) FormatAttachment(attachment interface{}) (string, er
 }
 
 func (f *MarkdownFormatter) FormatAttachments(attachments interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&attachments, "Attachments"); done {
+		return output, err
+	}
+
 	attachmentList, ok := attachments.([]*trello.Attachment)
 	if !ok {
 		return "", fmt.Errorf("invalid attachments type")
@@ -370,6 +429,59 @@ func (f *MarkdownFormatter) FormatAttachments(attachments interface{}) (string,
 	return sb.String(), nil
 }
 
+func (f *MarkdownFormatter) FormatComment(comment interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&comment, "Comment"); done {
+		return output, err
+	}
+
+	c, ok := comment.(*trello.Action)
+	if !ok {
+		return "", fmt.Errorf("invalid comment type")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Comment by %s\n\n", commentAuthor(c)))
+	sb.WriteString(fmt.Sprintf("**ID:** `%s`\n\n", c.ID))
+	if !c.Date.IsZero() {
+		sb.WriteString(fmt.Sprintf("**Date:** %s\n\n", c.Date.Format(time.RFC3339)))
+	}
+	if c.Data != nil && c.Data.Text != "" {
+		sb.WriteString(fmt.Sprintf("%s\n\n", c.Data.Text))
+	}
+
+	return f.applyTokenLimit(sb.String()), nil
+}
+
+func (f *MarkdownFormatter) FormatComments(comments interface{}) (string, error) {
+	if output, done, err := f.formatGeneric(&comments, "Comments"); done {
+		return output, err
+	}
+
+	var commentList []*trello.Action
+	switch c := comments.(type) {
+	case []*trello.Action:
+		commentList = c
+	case trello.ActionCollection:
+		commentList = c
+	default:
+		return "", fmt.Errorf("invalid comments type")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Comments (%d)\n\n", len(commentList)))
+
+	for _, comment := range commentList {
+		sb.WriteString(fmt.Sprintf("## %s - %s\n", commentAuthor(comment), comment.Date.Format("2006-01-02 15:04")))
+		sb.WriteString(fmt.Sprintf("- **ID:** `%s`\n", comment.ID))
+		if comment.Data != nil && comment.Data.Text != "" {
+			sb.WriteString(fmt.Sprintf("\n%s\n", comment.Data.Text))
+		}
+		sb.WriteString("\n")
+	}
+
+	return f.applyTokenLimit(sb.String()), nil
+}
+
 func (f *MarkdownFormatter) FormatError(err error) string {
 	return fmt.Sprintf("❌ **Error:** %s\n", err.Error())
 }
@@ -380,16 +492,130 @@ func (f *MarkdownFormatter) FormatSuccess(message string) string {
 
 // Helper functions
 
-func (f *MarkdownFormatter) shouldIncludeField(field string) bool {
-	if len(f.fields) == 0 {
-		return true
+// formatGeneric formats *data when the layout of its type does not apply:
+// when --query reshaped it, or --fields names the fields to show. Otherwise,
+// it leaves *data queried for the layout and returns done as false.
+func (f *MarkdownFormatter) formatGeneric(data *interface{}, title string) (output string, done bool, err error) {
+	result, typed, err := applyQuery(f.query, *data)
+	if err != nil {
+		return "", true, err
+	}
+	if !typed {
+		return f.formatQueried(result), true, nil
+	}
+	*data = result
+
+	if include, _ := context.SplitFields(f.fields); len(include) > 0 {
+		return f.formatFields(title, result), true, nil
+	}
+	return "", false, nil
+}
+
+// formatQueried formats a result that a query reshaped, which has no layout
+// of its own: a list as bullets, an object as a bullet per key and anything
+// else as text
+func (f *MarkdownFormatter) formatQueried(result interface{}) string {
+	var sb strings.Builder
+	switch v := result.(type) {
+	case []interface{}:
+		for _, item := range v {
+			sb.WriteString("- " + markdownValue(item) + "\n")
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			sb.WriteString(fmt.Sprintf("- **%s:** %s\n", key, cell(v[key])))
+		}
+	default:
+		sb.WriteString(cell(result) + "\n")
+	}
+	return f.applyTokenLimit(sb.String())
+}
+
+// formatFields formats the fields picked with --fields, the same ones as
+// the JSON output has: a bullet for each field under the name of each object
+func (f *MarkdownFormatter) formatFields(title string, data interface{}) string {
+	full, _ := jsonValue(data)
+	selected := selectFields(data, f.fields)
+
+	var sb strings.Builder
+	items, isList := selected.([]interface{})
+	if isList {
+		fullItems, _ := full.([]interface{})
+		sb.WriteString(fmt.Sprintf("# %s (%d)\n\n", title, len(items)))
+		for i, item := range items {
+			var name string
+			if i < len(fullItems) {
+				name = markdownName(fullItems[i])
+			}
+			sb.WriteString(fmt.Sprintf("## %s\n", name))
+			f.writeFieldBullets(&sb, item)
+			sb.WriteString("\n")
+		}
+	} else {
+		sb.WriteString(fmt.Sprintf("# %s: %s\n\n", title, markdownName(full)))
+		f.writeFieldBullets(&sb, selected)
 	}
-	for _, f := range f.fields {
-		if f == field {
-			return true
+	return f.applyTokenLimit(sb.String())
+}
+
+// writeFieldBullets writes a bullet for each field of object, with nested
+// values flattened the way CSV columns are
+func (f *MarkdownFormatter) writeFieldBullets(sb *strings.Builder, object interface{}) {
+	records, err := toRecords(object)
+	if err != nil {
+		return
+	}
+	columns := recordColumns(records)
+	values := cells(records, columns)
+	for i, column := range columns {
+		sb.WriteString(fmt.Sprintf("- **%s:** %s\n", column, values[0][i]))
+	}
+}
+
+// markdownName returns what names an object in a heading: its name, full
+// name, username or ID, whichever it has first
+func markdownName(value interface{}) string {
+	object, _ := value.(map[string]interface{})
+	for _, key := range []string{"name", "fullName", "username", "id"} {
+		if s, ok := object[key].(string); ok && s != "" {
+			return s
 		}
 	}
-	return false
+	return "(unnamed)"
+}
+
+// markdownValue writes a value on one line, an object as its keys and values
+func markdownValue(value interface{}) string {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return cell(value)
+	}
+	pairs := make([]string, 0, len(object))
+	for _, key := range sortedKeys(object) {
+		pairs = append(pairs, fmt.Sprintf("**%s:** %s", key, cell(object[key])))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// sortedKeys returns the keys of an object in sorted order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *MarkdownFormatter) shouldIncludeField(field string) bool {
+	return context.NewOptimizer(f.maxTokens, f.fields, f.verbose).ShouldIncludeField(field)
+}
+
+// showField reports whether a layout shows field, which it shows by default
+// when byDefault is set and with --verbose otherwise. Fields excluded with
+// --fields, such as -desc, are never shown.
+func (f *MarkdownFormatter) showField(field string, byDefault bool) bool {
+	return f.shouldIncludeField(field) && (f.verbose || byDefault)
 }
 
 func (f *MarkdownFormatter) applyTokenLimit(text string) string {
@@ -406,6 +632,18 @@ func (f *MarkdownFormatter) applyTokenLimit(text string) string {
 	return text[:maxChars] + "\n\n... (output truncated to fit token limit)"
 }
 
+func commentAuthor(comment *trello.Action) string {
+	if comment.MemberCreator != nil {
+		if comment.MemberCreator.FullName != "" {
+			return comment.MemberCreator.FullName
+		}
+		if comment.MemberCreator.Username != "" {
+			return "@" + comment.MemberCreator.Username
+		}
+	}
+	return "unknown"
+}
+
 func truncateText(text string, maxLen int) string {
 	if len(text) <= maxLen {
 		return text
@@ -0,0 +1,108 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/itchyny/gojq"
+)
+
+// ValidateQuery reports whether query is a valid jq expression
+func ValidateQuery(query string) error {
+	_, err := compileQuery(query)
+	return err
+}
+
+func compileQuery(query string) (*gojq.Code, error) {
+	parsed, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	code, err := gojq.Compile(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return code, nil
+}
+
+// applyQuery runs query on the JSON form of data. A query that outputs one
+// value results in that value, and one that outputs none or several in a
+// list of them.
+//
+// When the result still has the shape of data, as after filtering or sorting
+// a list, it is returned with the type of data and typed is true, so that
+// formatters lay it out as usual. A projection such as map({name, due})
+// results in plain JSON values.
+func applyQuery(query string, data interface{}) (result interface{}, typed bool, err error) {
+	if query == "" {
+		return data, true, nil
+	}
+
+	code, err := compileQuery(query)
+	if err != nil {
+		return nil, false, err
+	}
+	input, err := jsonValue(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := []interface{}{}
+	iter := code.Run(input)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := value.(error); ok {
+			return nil, false, fmt.Errorf("query failed: %w", err)
+		}
+		results = append(results, value)
+	}
+	result = results
+	if len(results) == 1 {
+		result = results[0]
+	}
+
+	if data == nil {
+		return result, false, nil
+	}
+	if converted, ok := asTypeOf(result, data); ok {
+		return converted, true, nil
+	}
+	return result, false, nil
+}
+
+// jsonValue returns the JSON form of data as plain values
+func jsonValue(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// asTypeOf converts value to the type of like, when that loses nothing of
+// value and adds nothing to it
+func asTypeOf(value, like interface{}) (interface{}, bool) {
+	converted := reflect.New(reflect.TypeOf(like))
+	encoded, err := json.Marshal(value)
+	if err != nil || json.Unmarshal(encoded, converted.Interface()) != nil {
+		return nil, false
+	}
+
+	roundTrip, err := jsonValue(converted.Elem().Interface())
+	if err != nil {
+		return nil, false
+	}
+	original, err := jsonValue(value)
+	if err != nil || !reflect.DeepEqual(roundTrip, original) {
+		return nil, false
+	}
+	return converted.Elem().Interface(), true
+}
@@ -0,0 +1,121 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/adlio/trello"
+)
+
+// withQuery returns a formatter for format that applies query
+func withQuery(t *testing.T, format string, fields []string, query string) Formatter {
+	t.Helper()
+	f, err := NewFormatterWithOptions(format, fields, 0, false, Options{Query: query})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	cards := []*trello.Card{
+		{ID: "c1", Name: "Zeta", Closed: true},
+		{ID: "c2", Name: "Alpha", Desc: "First"},
+		{ID: "c3", Name: "Mid"},
+	}
+
+	t.Run("JSON filter and sort", func(t *testing.T) {
+		t.Parallel()
+		f := withQuery(t, "json", []string{"id"}, "map(select(.closed | not)) | sort_by(.name)")
+		output, err := f.FormatCards(cards)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var result []map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("invalid JSON %q: %v", output, err)
+		}
+		if len(result) != 2 || result[0]["id"] != "c2" || result[1]["id"] != "c3" || len(result[0]) != 1 {
+			t.Errorf("unexpected result %v", result)
+		}
+	})
+
+	t.Run("Markdown keeps its layout after sorting", func(t *testing.T) {
+		t.Parallel()
+		output, err := withQuery(t, "markdown", nil, "sort_by(.name)").FormatCards(cards)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(output, "# Cards (3)\n\n## Alpha") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+	})
+
+	t.Run("Projections", func(t *testing.T) {
+		t.Parallel()
+		output, err := withQuery(t, "csv", nil, "map({name, desc})").FormatCards(cards)
+		if err != nil || output != "desc,name\n,Zeta\nFirst,Alpha\n,Mid" {
+			t.Errorf("unexpected CSV output %q, %v", output, err)
+		}
+
+		output, err = withQuery(t, "markdown", nil, "map({name, desc})").FormatCards(cards)
+		if err != nil || !strings.Contains(output, "- **desc:** First, **name:** Alpha\n") {
+			t.Errorf("unexpected Markdown output %q, %v", output, err)
+		}
+	})
+
+	t.Run("Values have no header", func(t *testing.T) {
+		t.Parallel()
+		output, err := withQuery(t, "tsv", nil, ".[].name").FormatCards(cards)
+		if err != nil || output != "Zeta\nAlpha\nMid" {
+			t.Errorf("unexpected TSV output %q, %v", output, err)
+		}
+	})
+
+	t.Run("One value", func(t *testing.T) {
+		t.Parallel()
+		output, err := withQuery(t, "json", nil, "length").FormatCards(cards)
+		if err != nil || output != "3" {
+			t.Errorf("unexpected output %q, %v", output, err)
+		}
+	})
+
+	t.Run("No query", func(t *testing.T) {
+		t.Parallel()
+		output, err := NewCSVFormatter([]string{"id"}, 0, false).FormatCards(cards)
+		if err != nil || output != "id\nc1\nc2\nc3" {
+			t.Errorf("unexpected output %q, %v", output, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		if _, err := withQuery(t, "json", nil, ".[] | .name + 1").FormatCards(cards); err == nil || !strings.Contains(err.Error(), "query failed") {
+			t.Errorf("expected a query error, got %v", err)
+		}
+	})
+
+	if err := ValidateQuery("map(.name"); err == nil || !strings.Contains(err.Error(), "invalid query") {
+		t.Errorf("expected an invalid query error, got %v", err)
+	}
+}
+
+func TestFormatReportQuery(t *testing.T) {
+	t.Parallel()
+	rows := []struct {
+		Name string `json:"name"`
+		OK   bool   `json:"ok"`
+	}{{"first", true}, {"second", false}}
+
+	output, err := FormatReport("csv", Options{Query: "map(select(.ok))"}, rows, rows)
+	if err != nil || output != "name,ok\nfirst,true" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+
+	output, err = FormatReport("json", Options{Query: "length"}, rows, rows)
+	if err != nil || output != "2" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+}
@@ -0,0 +1,401 @@
+package formatter
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/danbruder/trello-cli/internal/context"
+	"golang.org/x/term"
+)
+
+// TableFormatter formats output as aligned columns for reading in a
+// terminal. The columns are the same as for CSV: the --fields, or the
+// default fields of the entity, with nested values flattened.
+//
+// On a terminal, the table is fitted to its width by shortening names and
+// other text first, with an ellipsis where a value is cut, and labels and due
+// dates are colored unless Options.NoColor is set. Elsewhere, the table is
+// as wide as its values unless the COLUMNS environment variable gives a
+// width.
+type TableFormatter struct {
+	fields    []string
+	maxTokens int
+	verbose   bool
+	query     string
+
+	// width is the width to fit the table to, or 0 not to limit it
+	width int
+	color bool
+	now   func() time.Time
+}
+
+// NewTableFormatter creates a new table formatter for standard output
+func NewTableFormatter(fields []string, maxTokens int, verbose bool) *TableFormatter {
+	return newTableFormatter(fields, maxTokens, verbose, Options{})
+}
+
+func newTableFormatter(fields []string, maxTokens int, verbose bool, opts Options) *TableFormatter {
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	return &TableFormatter{
+		fields:    fields,
+		maxTokens: maxTokens,
+		verbose:   verbose,
+		query:     opts.Query,
+		width:     terminalWidth(tty),
+		color:     tty && !opts.NoColor,
+		now:       time.Now,
+	}
+}
+
+// terminalWidth returns the width of the terminal on standard output, or
+// else the COLUMNS environment variable, or 0 when neither is known
+func terminalWidth(tty bool) int {
+	if tty {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+func (f *TableFormatter) FormatBoard(board interface{}) (string, error) {
+	return f.format("board", board)
+}
+
+func (f *TableFormatter) FormatBoards(boards interface{}) (string, error) {
+	return f.format("board", boards)
+}
+
+func (f *TableFormatter) FormatList(list interface{}) (string, error) {
+	return f.format("list", list)
+}
+
+func (f *TableFormatter) FormatLists(lists interface{}) (string, error) {
+	return f.format("list", lists)
+}
+
+func (f *TableFormatter) FormatCard(card interface{}) (string, error) {
+	return f.format("card", card)
+}
+
+func (f *TableFormatter) FormatCards(cards interface{}) (string, error) {
+	return f.format("card", cards)
+}
+
+func (f *TableFormatter) FormatLabel(label interface{}) (string, error) {
+	return f.format("label", label)
+}
+
+func (f *TableFormatter) FormatLabels(labels interface{}) (string, error) {
+	return f.format("label", labels)
+}
+
+func (f *TableFormatter) FormatChecklist(checklist interface{}) (string, error) {
+	return f.format("checklist", checklist)
+}
+
+func (f *TableFormatter) FormatChecklists(checklists interface{}) (string, error) {
+	return f.format("checklist", checklists)
+}
+
+func (f *TableFormatter) FormatMember(member interface{}) (string, error) {
+	return f.format("member", member)
+}
+
+func (f *TableFormatter) FormatMembers(members interface{}) (string, error) {
+	return f.format("member", members)
+}
+
+func (f *TableFormatter) FormatAttachment(attachment interface{}) (string, error) {
+	return f.format("attachment", attachment)
+}
+
+func (f *TableFormatter) FormatAttachments(attachments interface{}) (string, error) {
+	return f.format("attachment", attachments)
+}
+
+func (f *TableFormatter) FormatComment(comment interface{}) (string, error) {
+	return f.format("comment", comment)
+}
+
+func (f *TableFormatter) FormatComments(comments interface{}) (string, error) {
+	return f.format("comment", comments)
+}
+
+func (f *TableFormatter) FormatError(err error) string {
+	return f.paint("Error: "+err.Error(), ansiRed)
+}
+
+func (f *TableFormatter) FormatSuccess(message string) string {
+	return f.paint(message, ansiGreen)
+}
+
+// format writes one row for each object in data, which is an entity or a
+// slice of entities
+func (f *TableFormatter) format(entity string, data interface{}) (string, error) {
+	data, typed, err := applyQuery(f.query, data)
+	if err != nil {
+		return "", err
+	}
+	records, err := toRecords(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Results reshaped by a query show their own columns
+	optimizer := context.NewOptimizer(f.maxTokens, f.fields, f.verbose)
+	columns := optimizer.GetRelevantFields(entity)
+	if include, _ := context.SplitFields(f.fields); len(columns) == 0 || (!typed && len(include) == 0) {
+		columns = optimizer.WithoutExcluded(recordColumns(records))
+	}
+
+	output := f.table(columns, records)
+	if f.maxTokens > 0 {
+		output = truncateToTokenLimit(output, f.maxTokens)
+	}
+	return output, nil
+}
+
+// table lays out records in columns under a header, without a final newline
+func (f *TableFormatter) table(columns []string, records []json.RawMessage) string {
+	values := make([]interface{}, len(records))
+	for i, raw := range records {
+		json.Unmarshal(raw, &values[i])
+	}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	rows := cells(records, columns)
+	for _, row := range rows {
+		for i, value := range row {
+			row[i] = tableCellEscaper.Replace(value)
+		}
+	}
+
+	widths := f.columnWidths(columns, header, rows)
+
+	var sb strings.Builder
+	if !valuesOnly(columns) {
+		for i, title := range header {
+			f.writeCell(&sb, i == len(header)-1, fitText(title, widths[i]), widths[i], ansiBold)
+		}
+	}
+	for r, row := range rows {
+		if sb.Len() > 0 || r > 0 {
+			sb.WriteString("\n")
+		}
+		// Empty cells at the end of a row are left out, not padded
+		for len(row) > 0 && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		for i, value := range row {
+			text := fitText(value, widths[i])
+			last := i == len(row)-1
+			if !f.color {
+				f.writeCell(&sb, last, text, widths[i], "")
+				continue
+			}
+
+			switch {
+			case columns[i] == "labels" || columns[i] == "labels.name":
+				f.writeLabels(&sb, last, text, widths[i], lookupPath(values[r], "labels"))
+			case columns[i] == "due":
+				f.writeCell(&sb, last, text, widths[i], f.dueColor(values[r]))
+			default:
+				f.writeCell(&sb, last, text, widths[i], "")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// tableCellEscaper keeps every row of a table on one line
+var tableCellEscaper = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// columnWidths returns the width of each column: the widest of its values,
+// narrowed to fit the table to the terminal. The widest text columns give
+// way first, then the widest of the others, but no column gets narrower than
+// its header.
+func (f *TableFormatter) columnWidths(columns, header []string, rows [][]string) []int {
+	widths := make([]int, len(header))
+	minimums := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = textWidth(title)
+		minimums[i] = widths[i]
+		if minimums[i] < minColumnWidth {
+			minimums[i] = minColumnWidth
+		}
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			if w := textWidth(value); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	if f.width <= 0 {
+		return widths
+	}
+
+	total := columnGap * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for _, textOnly := range []bool{true, false} {
+		for total > f.width {
+			widest := -1
+			for i, w := range widths {
+				if textOnly && !textColumns[lastSegment(columns[i])] {
+					continue
+				}
+				if w > minimums[i] && (widest < 0 || w > widths[widest]) {
+					widest = i
+				}
+			}
+			if widest < 0 {
+				break
+			}
+			widths[widest]--
+			total--
+		}
+	}
+	return widths
+}
+
+// textColumns are the columns of free text, which give way before IDs,
+// dates and the like when a table is too wide
+var textColumns = map[string]bool{
+	"name":     true,
+	"desc":     true,
+	"text":     true,
+	"fullName": true,
+	"url":      true,
+}
+
+// lastSegment returns the last part of a dotted column
+func lastSegment(column string) string {
+	return column[strings.LastIndex(column, ".")+1:]
+}
+
+const (
+	// columnGap is the number of spaces between columns
+	columnGap = 2
+
+	// minColumnWidth is the narrowest a column gets to fit the terminal
+	minColumnWidth = 5
+)
+
+// writeCell writes text padded to width, in color when one is given. The
+// last cell of a row is not padded.
+func (f *TableFormatter) writeCell(sb *strings.Builder, last bool, text string, width int, color string) {
+	sb.WriteString(f.paint(text, color))
+	if !last {
+		sb.WriteString(strings.Repeat(" ", width-textWidth(text)+columnGap))
+	}
+}
+
+// writeLabels writes the names of labels, each in the color of its label
+func (f *TableFormatter) writeLabels(sb *strings.Builder, last bool, text string, width int, labels interface{}) {
+	items, _ := labels.([]interface{})
+	names := strings.Split(text, ";")
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(";")
+		}
+		color := ""
+		if i < len(items) {
+			if label, ok := items[i].(map[string]interface{}); ok {
+				labelColor, _ := label["color"].(string)
+				color = labelColors[strings.SplitN(labelColor, "_", 2)[0]]
+			}
+		}
+		sb.WriteString(f.paint(name, color))
+	}
+	if !last {
+		sb.WriteString(strings.Repeat(" ", width-textWidth(text)+columnGap))
+	}
+}
+
+// dueColor returns the color of a card's due date: green once complete, red
+// when overdue and yellow when due within a day
+func (f *TableFormatter) dueColor(record interface{}) string {
+	card, _ := record.(map[string]interface{})
+	due, _ := card["due"].(string)
+	dueDate, err := time.Parse(time.RFC3339, due)
+	if err != nil {
+		return ""
+	}
+
+	switch now := f.now(); {
+	case card["dueComplete"] == true:
+		return ansiGreen
+	case dueDate.Before(now):
+		return ansiRed
+	case dueDate.Before(now.Add(24 * time.Hour)):
+		return ansiYellow
+	}
+	return ""
+}
+
+// paint wraps text in an ANSI color when colors are on
+func (f *TableFormatter) paint(text, color string) string {
+	if !f.color || color == "" || text == "" {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// ANSI escape codes for the colors of table output
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiGray    = "\x1b[90m"
+)
+
+// labelColors maps Trello label colors, without their _light or _dark
+// shade, to the nearest ANSI color
+var labelColors = map[string]string{
+	"green":  ansiGreen,
+	"yellow": ansiYellow,
+	"orange": ansiYellow,
+	"red":    ansiRed,
+	"purple": ansiMagenta,
+	"blue":   ansiBlue,
+	"sky":    ansiCyan,
+	"lime":   ansiGreen,
+	"pink":   ansiMagenta,
+	"black":  ansiGray,
+}
+
+// textWidth returns the number of characters in text
+func textWidth(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// fitText shortens text to width characters, ending it with an ellipsis
+// when it is cut
+func fitText(text string, width int) string {
+	if textWidth(text) <= width {
+		return text
+	}
+	if width <= 1 {
+		return "…"
+	}
+	runes := []rune(text)
+	return string(runes[:width-1]) + "…"
+}
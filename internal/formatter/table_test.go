@@ -0,0 +1,83 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adlio/trello"
+)
+
+func TestTableFormatter(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	overdue := now.Add(-time.Hour)
+	soon := now.Add(2 * time.Hour)
+	cards := []*trello.Card{
+		{ID: "c1", Name: "Write the release notes for the next version", Due: &overdue, Labels: []*trello.Label{{Name: "Bug", Color: "red"}, {Name: "Docs", Color: "sky_light"}}},
+		{ID: "c2", Name: "Ship", Due: &soon},
+	}
+	newTable := func(width int, color bool) *TableFormatter {
+		f := NewTableFormatter([]string{"id", "name", "due", "labels"}, 0, false)
+		f.width, f.color = width, color
+		f.now = func() time.Time { return now }
+		return f
+	}
+
+	// Without a width, columns take their widest value
+	output, err := newTable(0, false).FormatCards(cards)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "ID  NAME                                          DUE                   LABELS\n" +
+		"c1  Write the release notes for the next version  2026-10-17T11:00:00Z  Bug;Docs\n" +
+		"c2  Ship                                          2026-10-17T14:00:00Z"
+	if output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	// Fitting a width shortens the widest column with an ellipsis
+	output, err = newTable(50, false).FormatCards(cards)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if n := textWidth(line); n > 50 {
+			t.Errorf("line is %d characters wide, want at most 50: %q", n, line)
+		}
+	}
+	if !strings.Contains(output, "Write the rel…") {
+		t.Errorf("expected a shortened name, got:\n%s", output)
+	}
+
+	// Colors mark labels and due dates
+	output, err = newTable(0, true).FormatCards(cards)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		ansiBold + "ID" + ansiReset,
+		ansiRed + "2026-10-17T11:00:00Z" + ansiReset,
+		ansiYellow + "2026-10-17T14:00:00Z" + ansiReset,
+		ansiRed + "Bug" + ansiReset + ";" + ansiCyan + "Docs" + ansiReset,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%q", want, output)
+		}
+	}
+}
+
+func TestTableFormatterNoColor(t *testing.T) {
+	f := newTableFormatter(nil, 0, false, Options{NoColor: true})
+	if f.color {
+		t.Error("expected no colors with NoColor set")
+	}
+	if output := f.FormatSuccess("Card archived"); output != "Card archived" {
+		t.Errorf("unexpected success output %q", output)
+	}
+
+	// Rows stay on one line
+	output, err := f.FormatLabels([]*trello.Label{{ID: "l1", Name: "Needs\nreview", Color: "green"}})
+	if err != nil || output != "ID  NAME          COLOR\nl1  Needs review  green" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+}
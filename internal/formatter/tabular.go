@@ -0,0 +1,379 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/danbruder/trello-cli/internal/context"
+)
+
+// TabularFormatter formats output as rows of delimited values under a header
+// row of column names: CSV for spreadsheets, TSV for awk and cut.
+//
+// The columns are the --fields, or the default fields of the entity. A
+// dotted column such as memberCreator.username reaches into nested objects,
+// and one such as labels.name collects the values from every item of a list.
+// Lists are joined with ";", objects in lists show their name (or color, or
+// ID) and other objects are encoded as JSON.
+type TabularFormatter struct {
+	comma     rune
+	fields    []string
+	maxTokens int
+	verbose   bool
+	query     string
+}
+
+// NewCSVFormatter creates a new CSV formatter
+func NewCSVFormatter(fields []string, maxTokens int, verbose bool) *TabularFormatter {
+	return &TabularFormatter{comma: ',', fields: fields, maxTokens: maxTokens, verbose: verbose}
+}
+
+// NewTSVFormatter creates a new TSV formatter. Tabs, newlines and
+// backslashes in values are escaped as \t, \n and \\, so every row is one line.
+func NewTSVFormatter(fields []string, maxTokens int, verbose bool) *TabularFormatter {
+	return &TabularFormatter{comma: '\t', fields: fields, maxTokens: maxTokens, verbose: verbose}
+}
+
+func (f *TabularFormatter) FormatBoard(board interface{}) (string, error) {
+	return f.format("board", board)
+}
+
+func (f *TabularFormatter) FormatBoards(boards interface{}) (string, error) {
+	return f.format("board", boards)
+}
+
+func (f *TabularFormatter) FormatList(list interface{}) (string, error) {
+	return f.format("list", list)
+}
+
+func (f *TabularFormatter) FormatLists(lists interface{}) (string, error) {
+	return f.format("list", lists)
+}
+
+func (f *TabularFormatter) FormatCard(card interface{}) (string, error) {
+	return f.format("card", card)
+}
+
+func (f *TabularFormatter) FormatCards(cards interface{}) (string, error) {
+	return f.format("card", cards)
+}
+
+func (f *TabularFormatter) FormatLabel(label interface{}) (string, error) {
+	return f.format("label", label)
+}
+
+func (f *TabularFormatter) FormatLabels(labels interface{}) (string, error) {
+	return f.format("label", labels)
+}
+
+func (f *TabularFormatter) FormatChecklist(checklist interface{}) (string, error) {
+	return f.format("checklist", checklist)
+}
+
+func (f *TabularFormatter) FormatChecklists(checklists interface{}) (string, error) {
+	return f.format("checklist", checklists)
+}
+
+func (f *TabularFormatter) FormatMember(member interface{}) (string, error) {
+	return f.format("member", member)
+}
+
+func (f *TabularFormatter) FormatMembers(members interface{}) (string, error) {
+	return f.format("member", members)
+}
+
+func (f *TabularFormatter) FormatAttachment(attachment interface{}) (string, error) {
+	return f.format("attachment", attachment)
+}
+
+func (f *TabularFormatter) FormatAttachments(attachments interface{}) (string, error) {
+	return f.format("attachment", attachments)
+}
+
+func (f *TabularFormatter) FormatComment(comment interface{}) (string, error) {
+	return f.format("comment", comment)
+}
+
+func (f *TabularFormatter) FormatComments(comments interface{}) (string, error) {
+	return f.format("comment", comments)
+}
+
+func (f *TabularFormatter) FormatError(err error) string {
+	output, _ := f.write([]string{"error"}, [][]string{{err.Error()}})
+	return output
+}
+
+func (f *TabularFormatter) FormatSuccess(message string) string {
+	output, _ := f.write([]string{"status", "message"}, [][]string{{"success", message}})
+	return output
+}
+
+// format writes one row for each object in data, which is an entity or a
+// slice of entities
+func (f *TabularFormatter) format(entity string, data interface{}) (string, error) {
+	data, typed, err := applyQuery(f.query, data)
+	if err != nil {
+		return "", err
+	}
+	records, err := toRecords(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Results reshaped by a query show their own columns
+	optimizer := context.NewOptimizer(f.maxTokens, f.fields, f.verbose)
+	columns := optimizer.GetRelevantFields(entity)
+	if include, _ := context.SplitFields(f.fields); len(columns) == 0 || (!typed && len(include) == 0) {
+		columns = optimizer.WithoutExcluded(recordColumns(records))
+	}
+
+	output, err := f.write(columns, cells(records, columns))
+	if err != nil {
+		return "", err
+	}
+	if f.maxTokens > 0 {
+		output = truncateToTokenLimit(output, f.maxTokens)
+	}
+	return output, nil
+}
+
+// write writes the header row and rows, without a final newline
+func (f *TabularFormatter) write(header []string, rows [][]string) (string, error) {
+	records := rows
+	if !valuesOnly(header) {
+		records = append([][]string{header}, rows...)
+	}
+
+	if f.comma == '\t' {
+		var sb strings.Builder
+		for _, row := range records {
+			for i, value := range row {
+				if i > 0 {
+					sb.WriteString("\t")
+				}
+				sb.WriteString(tsvEscaper.Replace(value))
+			}
+			sb.WriteString("\n")
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = f.comma
+	if err := w.WriteAll(records); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// valuesOnly reports whether columns are those of records that are not
+// objects, such as the names a query picked out. They are written without a
+// header.
+func valuesOnly(columns []string) bool {
+	return len(columns) == 1 && columns[0] == ""
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// toRecords returns the JSON form of data as a list of records: the items of
+// a slice, or data itself
+func toRecords(data interface{}) ([]json.RawMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []json.RawMessage
+	if err := json.Unmarshal(encoded, &records); err == nil {
+		return records, nil
+	}
+	if string(encoded) == "null" {
+		return nil, nil
+	}
+	return []json.RawMessage{encoded}, nil
+}
+
+// recordColumns returns the columns of records with no columns of their own:
+// every key, in the order of first appearance, with objects expanded into
+// dotted columns
+func recordColumns(records []json.RawMessage) []string {
+	var columns []string
+	seen := map[string]bool{}
+	var add func(prefix string, value json.RawMessage)
+	add = func(prefix string, value json.RawMessage) {
+		keys := objectKeys(value)
+		if keys == nil || (len(keys) == 0 && prefix != "") {
+			if !seen[prefix] {
+				seen[prefix] = true
+				columns = append(columns, prefix)
+			}
+			return
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return
+		}
+		for _, key := range keys {
+			column := key
+			if prefix != "" {
+				column = prefix + "." + key
+			}
+			add(column, object[key])
+		}
+	}
+
+	for _, record := range records {
+		add("", record)
+	}
+	return columns
+}
+
+// objectKeys returns the keys of a JSON object in the order they are
+// written, or nil when value is not an object
+func objectKeys(value json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+
+	keys := []string{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			break
+		}
+		keys = append(keys, token.(string))
+		var skipped json.RawMessage
+		if err := dec.Decode(&skipped); err != nil {
+			break
+		}
+	}
+	return keys
+}
+
+// cells returns the row of each record, with the value of each column
+func cells(records []json.RawMessage, columns []string) [][]string {
+	rows := make([][]string, len(records))
+	for i, raw := range records {
+		var record interface{}
+		json.Unmarshal(raw, &record)
+		rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			rows[i][j] = cell(lookupPath(record, column))
+		}
+	}
+	return rows
+}
+
+// lookupPath returns the value at a dotted path in a JSON value. Through a
+// list, it returns the values at the rest of the path in every item.
+func lookupPath(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	key, rest, _ := strings.Cut(path, ".")
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return lookupPath(v[key], rest)
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if found := lookupPath(item, path); found != nil {
+				values = append(values, found)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// cell returns the text of a JSON value in a row
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = cell(itemName(item))
+		}
+		return strings.Join(items, ";")
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// itemName returns what stands for an object in a list: its name, color or
+// ID, whichever it has first
+func itemName(item interface{}) interface{} {
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		return item
+	}
+	for _, key := range []string{"name", "color", "id"} {
+		if s, ok := object[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return item
+}
+
+// FormatReport formats a value that has no formatter method of its own,
+// such as the results of a batch, in one of the data formats: json and yaml
+// encode data whole, while csv, tsv, table and template write a row for each
+// of records. The query applies to what is written.
+// Markdown reports are laid out by their callers.
+func FormatReport(format string, opts Options, data, records interface{}) (string, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		f := NewJSONFormatter(nil, 0, false)
+		f.query = opts.Query
+		return f.format(data)
+
+	case "yaml", "yml":
+		f := NewYAMLFormatter(nil, 0, false)
+		f.query = opts.Query
+		return f.format(data)
+
+	case "csv", "tsv", "table":
+		records, _, err := applyQuery(opts.Query, records)
+		if err != nil {
+			return "", err
+		}
+		rows, err := toRecords(records)
+		if err != nil {
+			return "", err
+		}
+		columns := recordColumns(rows)
+
+		switch strings.ToLower(format) {
+		case "csv":
+			return NewCSVFormatter(nil, 0, false).write(columns, cells(rows, columns))
+		case "tsv":
+			return NewTSVFormatter(nil, 0, false).write(columns, cells(rows, columns))
+		}
+		return newTableFormatter(nil, 0, false, Options{NoColor: opts.NoColor}).table(columns, rows), nil
+
+	case "template":
+		f, err := NewTemplateFormatter(opts.Template, 0)
+		if err != nil {
+			return "", err
+		}
+		f.query = opts.Query
+		return f.format(records)
+	}
+	return "", fmt.Errorf("unsupported format: %s", format)
+}
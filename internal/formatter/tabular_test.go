@@ -0,0 +1,113 @@
+package formatter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adlio/trello"
+)
+
+func TestCSVFormatter(t *testing.T) {
+	due := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	cards := []*trello.Card{
+		{ID: "c1", Name: "Write docs, fast", Desc: "Line one\nLine two", Due: &due, Labels: []*trello.Label{{Name: "Bug", Color: "red"}, {Color: "green"}}},
+		{ID: "c2", Name: "Ship", Closed: true, IDMembers: []string{"m1", "m2"}},
+	}
+
+	output, err := NewCSVFormatter(nil, 0, false).FormatCards(cards)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "id,name,desc,due,labels,closed\n" +
+		"c1,\"Write docs, fast\",\"Line one\nLine two\",2026-11-01T09:00:00Z,Bug;green,false\n" +
+		"c2,Ship,,,,true"
+	if output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	// Fields choose the columns, and dotted fields reach into lists
+	output, err = NewCSVFormatter([]string{"name", "labels.color", "idMembers", "badges.comments"}, 0, false).FormatCards(cards)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(output, "\n"); lines[0] != "name,labels.color,idMembers,badges.comments" || lines[1] != "\"Write docs, fast\",red;green,,0" || lines[2] != "Ship,,m1;m2,0" {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	// A single object is one row
+	output, err = NewCSVFormatter(nil, 0, false).FormatLabel(&trello.Label{ID: "l1", Name: "Bug", Color: "red"})
+	if err != nil || output != "id,name,color\nl1,Bug,red" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+
+	if output := NewCSVFormatter(nil, 0, false).FormatSuccess("Card archived"); output != "status,message\nsuccess,Card archived" {
+		t.Errorf("unexpected success output %q", output)
+	}
+}
+
+func TestTSVFormatter(t *testing.T) {
+	comment := &trello.Action{ID: "a1", Data: &trello.ActionData{Text: "Looks good\tthanks\nShip it"}, MemberCreator: &trello.Member{Username: "dana"}}
+
+	output, err := NewTSVFormatter(nil, 0, false).FormatComments([]*trello.Action{comment})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) != 2 || lines[0] != "id\tdate\tmemberCreator.username\tdata.text" {
+		t.Fatalf("unexpected output:\n%s", output)
+	}
+	if !strings.HasSuffix(lines[1], "\tdana\tLooks good\\tthanks\\nShip it") {
+		t.Errorf("expected the text escaped on one line, got %q", lines[1])
+	}
+}
+
+func TestYAMLFormatter(t *testing.T) {
+	board := &trello.Board{ID: "b1", Name: "Sprint 42", Desc: "Team board"}
+
+	output, err := NewYAMLFormatter([]string{"id", "name"}, 0, false).FormatBoard(board)
+	if err != nil || output != "id: b1\nname: Sprint 42" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+
+	output, err = NewYAMLFormatter(nil, 0, false).FormatBoards([]*trello.Board{board})
+	if err != nil || !strings.Contains(output, "- ") || !strings.Contains(output, "desc: Team board") {
+		t.Errorf("expected a YAML list with the JSON keys, got %q, %v", output, err)
+	}
+
+	if output := NewYAMLFormatter(nil, 0, false).FormatError(errors.New("not found")); output != "error: not found" {
+		t.Errorf("unexpected error output %q", output)
+	}
+}
+
+func TestFormatReport(t *testing.T) {
+	type result struct {
+		Index     int                    `json:"index"`
+		Operation map[string]interface{} `json:"operation"`
+		Success   bool                   `json:"success"`
+		Error     string                 `json:"error,omitempty"`
+	}
+	results := []result{
+		{Index: 0, Operation: map[string]interface{}{"type": "card", "action": "create"}, Success: true},
+		{Index: 1, Operation: map[string]interface{}{"type": "list", "action": "archive"}, Error: "not found"},
+	}
+
+	output, err := FormatReport("csv", Options{}, results, results)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "index,operation.action,operation.type,success,error\n0,create,card,true,\n1,archive,list,false,not found"
+	if output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	output, err = FormatReport("yaml", Options{}, map[string]interface{}{"total": 2}, nil)
+	if err != nil || output != "total: 2" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+
+	if _, err := FormatReport("xml", Options{}, results, results); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+}
@@ -0,0 +1,246 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateFormatter renders output with a text/template. An entity is
+// rendered once, and a slice of entities once for each item, one per line.
+// The template sees the entities as the Trello API client returns them, so
+// fields are named as in Go, such as {{.ShortLink}} and {{.IDMembers}}.
+type TemplateFormatter struct {
+	tmpl      *template.Template
+	maxTokens int
+	query     string
+	now       func() time.Time
+}
+
+// NewTemplateFormatter parses text into a new template formatter
+func NewTemplateFormatter(text string, maxTokens int) (*TemplateFormatter, error) {
+	if text == "" {
+		return nil, errors.New("the template format needs a template (--template)")
+	}
+
+	f := &TemplateFormatter{maxTokens: maxTokens, now: time.Now}
+	tmpl, err := template.New("output").Funcs(f.funcs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	f.tmpl = tmpl
+	return f, nil
+}
+
+// funcs returns the functions templates can call:
+//
+//	date     formats a date as 2006-01-02, or with the layout given
+//	ago      describes how long ago or how soon a date is, e.g. "3 days ago"
+//	truncate shortens text to a number of characters, ending it with "…"
+//	labels   joins the names of labels (or their colors when unnamed) with ", "
+//	join     joins the items of a list with a separator
+//	json     encodes a value as JSON
+func (f *TemplateFormatter) funcs() template.FuncMap {
+	return template.FuncMap{
+		"date": func(value interface{}, layout ...string) string {
+			t, ok := templateTime(value)
+			if !ok {
+				return ""
+			}
+			if len(layout) > 0 {
+				return t.Format(layout[0])
+			}
+			return t.Format("2006-01-02")
+		},
+		"ago": func(value interface{}) string {
+			t, ok := templateTime(value)
+			if !ok {
+				return ""
+			}
+			return relativeTime(t, f.now())
+		},
+		"truncate": func(width int, text string) string {
+			return fitText(text, width)
+		},
+		"labels": func(labels interface{}) string {
+			return joinItems(", ", labels)
+		},
+		"join": joinItems,
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+	}
+}
+
+func (f *TemplateFormatter) FormatBoard(board interface{}) (string, error) {
+	return f.format(board)
+}
+
+func (f *TemplateFormatter) FormatBoards(boards interface{}) (string, error) {
+	return f.format(boards)
+}
+
+func (f *TemplateFormatter) FormatList(list interface{}) (string, error) {
+	return f.format(list)
+}
+
+func (f *TemplateFormatter) FormatLists(lists interface{}) (string, error) {
+	return f.format(lists)
+}
+
+func (f *TemplateFormatter) FormatCard(card interface{}) (string, error) {
+	return f.format(card)
+}
+
+func (f *TemplateFormatter) FormatCards(cards interface{}) (string, error) {
+	return f.format(cards)
+}
+
+func (f *TemplateFormatter) FormatLabel(label interface{}) (string, error) {
+	return f.format(label)
+}
+
+func (f *TemplateFormatter) FormatLabels(labels interface{}) (string, error) {
+	return f.format(labels)
+}
+
+func (f *TemplateFormatter) FormatChecklist(checklist interface{}) (string, error) {
+	return f.format(checklist)
+}
+
+func (f *TemplateFormatter) FormatChecklists(checklists interface{}) (string, error) {
+	return f.format(checklists)
+}
+
+func (f *TemplateFormatter) FormatMember(member interface{}) (string, error) {
+	return f.format(member)
+}
+
+func (f *TemplateFormatter) FormatMembers(members interface{}) (string, error) {
+	return f.format(members)
+}
+
+func (f *TemplateFormatter) FormatAttachment(attachment interface{}) (string, error) {
+	return f.format(attachment)
+}
+
+func (f *TemplateFormatter) FormatAttachments(attachments interface{}) (string, error) {
+	return f.format(attachments)
+}
+
+func (f *TemplateFormatter) FormatComment(comment interface{}) (string, error) {
+	return f.format(comment)
+}
+
+func (f *TemplateFormatter) FormatComments(comments interface{}) (string, error) {
+	return f.format(comments)
+}
+
+func (f *TemplateFormatter) FormatError(err error) string {
+	return "Error: " + err.Error()
+}
+
+func (f *TemplateFormatter) FormatSuccess(message string) string {
+	return message
+}
+
+// format renders the template for data, or for each item when data is a
+// slice
+func (f *TemplateFormatter) format(data interface{}) (string, error) {
+	data, _, err := applyQuery(f.query, data)
+	if err != nil {
+		return "", err
+	}
+
+	items := []interface{}{data}
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+		items = make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).Interface()
+		}
+	}
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		var buf bytes.Buffer
+		if err := f.tmpl.Execute(&buf, item); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+		lines[i] = strings.TrimSuffix(buf.String(), "\n")
+	}
+
+	output := strings.Join(lines, "\n")
+	if f.maxTokens > 0 {
+		output = truncateToTokenLimit(output, f.maxTokens)
+	}
+	return output, nil
+}
+
+// templateTime returns the time in a template value: a time, a pointer to
+// one, or an RFC 3339 string. A nil pointer or empty string is no time.
+func templateTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, false
+		}
+		return *v, !v.IsZero()
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// relativeTime describes t relative to now in the largest whole unit
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := "ago"
+	if d < 0 {
+		d, suffix = -d, "from now"
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	default:
+		n, unit = int(d/(24*time.Hour)), "day"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s %s", n, unit, suffix)
+}
+
+// joinItems joins the items of a list with sep, writing objects as their
+// name, color or ID the way CSV cells do
+func joinItems(sep string, list interface{}) string {
+	encoded, err := json.Marshal(list)
+	if err != nil {
+		return ""
+	}
+	var items []interface{}
+	if err := json.Unmarshal(encoded, &items); err != nil {
+		return ""
+	}
+
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = cell(itemName(item))
+	}
+	return strings.Join(names, sep)
+}
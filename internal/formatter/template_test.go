@@ -0,0 +1,99 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adlio/trello"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	due := now.Add(-3 * 24 * time.Hour)
+	cards := []*trello.Card{
+		{ShortLink: "abc123", Name: "Write the release notes", Due: &due, Labels: []*trello.Label{{Name: "Docs", Color: "sky"}, {Color: "red"}}},
+		{ShortLink: "def456", Name: "Ship"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "One line per item",
+			template: "{{.ShortLink}} {{.Name}}",
+			expected: "abc123 Write the release notes\ndef456 Ship",
+		},
+		{
+			name:     "Dates",
+			template: "{{.ShortLink}} {{date .Due}} {{date .Due \"Jan 2\"}} {{ago .Due}}",
+			expected: "abc123 2026-10-14 Oct 14 3 days ago\ndef456   ",
+		},
+		{
+			name:     "Truncation",
+			template: "{{.Name | truncate 10}}\n",
+			expected: "Write the…\nShip",
+		},
+		{
+			name:     "Labels and JSON",
+			template: "{{labels .Labels}}|{{json .ShortLink}}",
+			expected: "Docs, red|\"abc123\"\n|\"def456\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTemplateFormatter(tt.template, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			f.now = func() time.Time { return now }
+
+			output, err := f.FormatCards(cards)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, output)
+			}
+		})
+	}
+
+	// A single entity renders once
+	f, _ := NewTemplateFormatter("{{.Name}} ({{.Color}})", 0)
+	if output, err := f.FormatLabel(&trello.Label{Name: "Bug", Color: "red"}); err != nil || output != "Bug (red)" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+
+	// Templates are checked when parsed and when rendered
+	if _, err := NewTemplateFormatter("{{.Name", 0); err == nil || !strings.Contains(err.Error(), "invalid template") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	f, _ = NewTemplateFormatter("{{.Missing}}", 0)
+	if _, err := f.FormatCard(cards[0]); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+	if _, err := NewTemplateFormatter("", 0); err == nil {
+		t.Error("expected an error for an empty template")
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(5 * time.Hour), "5 hours from now"},
+		{now.Add(-49 * time.Hour), "2 days ago"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(tt.t, now); got != tt.expected {
+			t.Errorf("relativeTime(%v) = %q, want %q", tt.t, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLFormatter formats output as YAML, with the same keys as JSON
+type YAMLFormatter struct {
+	fields    []string
+	maxTokens int
+	verbose   bool
+	query     string
+}
+
+// NewYAMLFormatter creates a new YAML formatter
+func NewYAMLFormatter(fields []string, maxTokens int, verbose bool) *YAMLFormatter {
+	return &YAMLFormatter{
+		fields:    fields,
+		maxTokens: maxTokens,
+		verbose:   verbose,
+	}
+}
+
+func (f *YAMLFormatter) format(data interface{}) (string, error) {
+	data, _, err := applyQuery(f.query, data)
+	if err != nil {
+		return "", err
+	}
+
+	// Apply field filtering if specified
+	if len(f.fields) > 0 {
+		data = selectFields(data, f.fields)
+	}
+
+	result, err := encodeYAML(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Apply token limit if specified
+	if f.maxTokens > 0 {
+		result = truncateToTokenLimit(result, f.maxTokens)
+	}
+
+	return result, nil
+}
+
+func (f *YAMLFormatter) FormatBoard(board interface{}) (string, error) {
+	return f.format(board)
+}
+
+func (f *YAMLFormatter) FormatBoards(boards interface{}) (string, error) {
+	return f.format(boards)
+}
+
+func (f *YAMLFormatter) FormatList(list interface{}) (string, error) {
+	return f.format(list)
+}
+
+func (f *YAMLFormatter) FormatLists(lists interface{}) (string, error) {
+	return f.format(lists)
+}
+
+func (f *YAMLFormatter) FormatCard(card interface{}) (string, error) {
+	return f.format(card)
+}
+
+func (f *YAMLFormatter) FormatCards(cards interface{}) (string, error) {
+	return f.format(cards)
+}
+
+func (f *YAMLFormatter) FormatLabel(label interface{}) (string, error) {
+	return f.format(label)
+}
+
+func (f *YAMLFormatter) FormatLabels(labels interface{}) (string, error) {
+	return f.format(labels)
+}
+
+func (f *YAMLFormatter) FormatChecklist(checklist interface{}) (string, error) {
+	return f.format(checklist)
+}
+
+func (f *YAMLFormatter) FormatChecklists(checklists interface{}) (string, error) {
+	return f.format(checklists)
+}
+
+func (f *YAMLFormatter) FormatMember(member interface{}) (string, error) {
+	return f.format(member)
+}
+
+func (f *YAMLFormatter) FormatMembers(members interface{}) (string, error) {
+	return f.format(members)
+}
+
+func (f *YAMLFormatter) FormatAttachment(attachment interface{}) (string, error) {
+	return f.format(attachment)
+}
+
+func (f *YAMLFormatter) FormatAttachments(attachments interface{}) (string, error) {
+	return f.format(attachments)
+}
+
+func (f *YAMLFormatter) FormatComment(comment interface{}) (string, error) {
+	return f.format(comment)
+}
+
+func (f *YAMLFormatter) FormatComments(comments interface{}) (string, error) {
+	return f.format(comments)
+}
+
+func (f *YAMLFormatter) FormatError(err error) string {
+	output, _ := encodeYAML(map[string]string{"error": err.Error()})
+	return output
+}
+
+func (f *YAMLFormatter) FormatSuccess(message string) string {
+	output, _ := encodeYAML(map[string]string{"status": "success", "message": message})
+	return output
+}
+
+// encodeYAML encodes data as YAML through its JSON form, so that the keys
+// are the JSON names of the Trello types
+func encodeYAML(data interface{}) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(decoded); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -50,7 +50,7 @@ func TestAuthenticationFlow(t *testing.T) {
 		defer os.Unsetenv("TRELLO_API_KEY")
 		defer os.Unsetenv("TRELLO_TOKEN")
 
-		auth, err := client.LoadAuth("", "")
+		auth, err := client.LoadAuth("", "", "")
 		if err != nil {
 			t.Errorf("Failed to load auth from environment: %v", err)
 		}
@@ -90,7 +90,7 @@ func TestAuthenticationFlow(t *testing.T) {
 			defer os.Rename(backupPath, configPath) // Restore after test
 		}
 
-		auth, err := client.LoadAuth("flag-key", "flag-token")
+		auth, err := client.LoadAuth("flag-key", "flag-token", "")
 		if err != nil {
 			t.Errorf("Failed to load auth from flags: %v", err)
 		}
@@ -130,7 +130,7 @@ func TestAuthenticationFlow(t *testing.T) {
 			defer os.Rename(backupPath, configPath) // Restore after test
 		}
 
-		_, err = client.LoadAuth("", "")
+		_, err = client.LoadAuth("", "", "")
 		if err == nil {
 			t.Errorf("Expected error when no authentication provided")
 		}